@@ -0,0 +1,76 @@
+// Command rollup-backfill builds the daily analytics rollups of past days,
+// for example after the rollups were introduced or a bug in them was fixed.
+//
+//	go run ./cmd/rollup-backfill -tenant 1 -from 2024-01-01
+//
+// Without -tenant every tenant is backfilled; without -from each tenant
+// starts at the day it was created. Days start at midnight in each tenant's
+// timezone.
+package main
+
+import (
+	"flag"
+	"log"
+	"time"
+
+	"backend/core"
+	"backend/internal/rollup"
+)
+
+const dateLayout = "2006-01-02"
+
+func main() {
+	tenantID := flag.Int("tenant", 0, "tenant to backfill, 0 for all")
+	from := flag.String("from", "", "first day (YYYY-MM-DD), defaults to the day the tenant was created")
+	to := flag.String("to", "", "last day (YYYY-MM-DD), defaults to today")
+	flag.Parse()
+
+	var start time.Time
+	if *from != "" {
+		parsed, err := time.Parse(dateLayout, *from)
+		if err != nil {
+			log.Fatalf("Invalid -from %q: %v", *from, err)
+		}
+		start = parsed
+	}
+	var end time.Time
+	if *to != "" {
+		parsed, err := time.Parse(dateLayout, *to)
+		if err != nil {
+			log.Fatalf("Invalid -to %q: %v", *to, err)
+		}
+		end = parsed
+	}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		log.Fatal("-from must not be after -to")
+	}
+
+	cfg := core.LoadConfig()
+	db, err := core.InitializeDatabase(cfg.GetDatabaseURL())
+	if err != nil {
+		log.Fatal("Failed to initialize database:", err)
+	}
+	rollupService := rollup.NewServiceWire(db)
+
+	tenantIDs := []int{*tenantID}
+	if *tenantID == 0 {
+		tenantIDs, err = rollup.NewRepository(db).GetTenantIDs()
+		if err != nil {
+			log.Fatal("Failed to load tenants:", err)
+		}
+	}
+
+	failed := false
+	for _, id := range tenantIDs {
+		refreshed, err := rollupService.Backfill(id, start, end)
+		if err != nil {
+			log.Printf("Failed to backfill tenant %d after %d days: %v", id, refreshed, err)
+			failed = true
+			continue
+		}
+		log.Printf("Backfilled %d days of tenant %d", refreshed, id)
+	}
+	if failed {
+		log.Fatal("Backfill finished with errors")
+	}
+}
@@ -0,0 +1,27 @@
+package clock
+
+import "time"
+
+// Clock tells the current time. Services that compute periods relative to
+// now take one so tests can pin the time, e.g. to either side of a DST change.
+type Clock interface {
+	Now() time.Time
+}
+
+// System is the wall clock
+type System struct{}
+
+func New() Clock {
+	return System{}
+}
+
+func (System) Now() time.Time {
+	return time.Now()
+}
+
+// Fixed always tells the same time
+type Fixed time.Time
+
+func (f Fixed) Now() time.Time {
+	return time.Time(f)
+}
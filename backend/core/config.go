@@ -3,6 +3,7 @@ package core
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -23,6 +24,39 @@ type Config struct {
 	SMTPUser       string
 	SMTPPass       string
 	SendRealEmail  bool
+
+	PublicRateLimit   int
+	PublicCacheMaxAge int
+
+	SchedulerEnabled   bool
+	LifecycleInterval  time.Duration
+	PastDueGracePeriod time.Duration
+	TrialReminderDays  int
+
+	PaymentProvider      string
+	PaymentWebhookSecret string
+	PaymentActionTimeout time.Duration
+	WebhookRetryInterval time.Duration
+	WebhookMaxAttempts   int
+
+	IdempotencyKeyTTL      time.Duration
+	IdempotencyLockTimeout time.Duration
+
+	RollupRefreshInterval   time.Duration
+	RollupReconcileInterval time.Duration
+	RollupReconcileDays     int
+
+	AppBaseURL     string
+	DigestInterval time.Duration
+	DigestHour     int
+
+	ExportSyncRows int
+	ExportInterval time.Duration
+	ExportTTL      time.Duration
+
+	EventBus       string
+	EventHeartbeat time.Duration
+	EventTokenTTL  time.Duration
 }
 
 func LoadConfig() *Config {
@@ -44,6 +78,39 @@ func LoadConfig() *Config {
 		SMTPUser:       getEnv("SMTP_USER", ""),
 		SMTPPass:       getEnv("SMTP_PASS", ""),
 		SendRealEmail:  getBoolEnv("SEND_REAL_EMAIL", false),
+
+		PublicRateLimit:   getIntEnv("PUBLIC_RATE_LIMIT", 60),
+		PublicCacheMaxAge: getIntEnv("PUBLIC_CACHE_MAX_AGE", 60),
+
+		SchedulerEnabled:   getBoolEnv("SCHEDULER_ENABLED", true),
+		LifecycleInterval:  getDurationEnv("LIFECYCLE_INTERVAL", time.Minute),
+		PastDueGracePeriod: getDurationEnv("PAST_DUE_GRACE_PERIOD", 7*24*time.Hour),
+		TrialReminderDays:  getIntEnv("TRIAL_REMINDER_DAYS", 3),
+
+		PaymentProvider:      getEnv("PAYMENT_PROVIDER", "fake"),
+		PaymentWebhookSecret: getEnv("PAYMENT_WEBHOOK_SECRET", "your-payment-webhook-secret"),
+		PaymentActionTimeout: getDurationEnv("PAYMENT_ACTION_TIMEOUT", 24*time.Hour),
+		WebhookRetryInterval: getDurationEnv("WEBHOOK_RETRY_INTERVAL", 10*time.Second),
+		WebhookMaxAttempts:   getIntEnv("WEBHOOK_MAX_ATTEMPTS", 8),
+
+		IdempotencyKeyTTL:      getDurationEnv("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencyLockTimeout: getDurationEnv("IDEMPOTENCY_LOCK_TIMEOUT", 30*time.Second),
+
+		RollupRefreshInterval:   getDurationEnv("ROLLUP_REFRESH_INTERVAL", 30*time.Second),
+		RollupReconcileInterval: getDurationEnv("ROLLUP_RECONCILE_INTERVAL", 24*time.Hour),
+		RollupReconcileDays:     getIntEnv("ROLLUP_RECONCILE_DAYS", 3),
+
+		AppBaseURL:     getEnv("APP_BASE_URL", "http://localhost:8080"),
+		DigestInterval: getDurationEnv("DIGEST_INTERVAL", 15*time.Minute),
+		DigestHour:     getIntEnv("DIGEST_HOUR", 8),
+
+		ExportSyncRows: getIntEnv("EXPORT_SYNC_ROWS", 10000),
+		ExportInterval: getDurationEnv("EXPORT_INTERVAL", 10*time.Second),
+		ExportTTL:      getDurationEnv("EXPORT_TTL", 24*time.Hour),
+
+		EventBus:       getEnv("EVENT_BUS", "memory"),
+		EventHeartbeat: getDurationEnv("EVENT_HEARTBEAT", 15*time.Second),
+		EventTokenTTL:  getDurationEnv("EVENT_TOKEN_TTL", time.Minute),
 	}
 }
 
@@ -75,3 +142,12 @@ func getIntEnv(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
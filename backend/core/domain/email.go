@@ -1,6 +1,11 @@
 package domain
 
+import "time"
+
 type EmailService interface {
 	SendWelcomeEmail(email, name string) error
 	SendPasswordResetEmail(email, resetToken string) error
+	SendInvoiceEmail(email, title, number, html string, pdf []byte) error
+	SendTrialReminderEmail(email, planName string, endsAt time.Time, amount float64, currency string, willCharge bool) error
+	SendDigestEmail(email, subject, html string) error
 }
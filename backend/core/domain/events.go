@@ -0,0 +1,21 @@
+package domain
+
+// Event is a domain event of a tenant, such as a purchase being made,
+// delivered to the tenant's subscribers
+type Event struct {
+	ID       int // increases as events happen, so subscribers can resume after it
+	TenantID int
+	Type     string
+	Data     []byte // JSON
+}
+
+// EventBus fans events out to the subscribers of their tenant. Delivery is
+// best effort: a subscriber that falls behind has its channel closed and is
+// expected to resume from the last event it got.
+type EventBus interface {
+	Publish(event Event) error
+
+	// Subscribe returns the channel of the tenant's events published from
+	// now on and a function ending the subscription
+	Subscribe(tenantID int) (<-chan Event, func())
+}
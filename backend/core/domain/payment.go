@@ -0,0 +1,68 @@
+package domain
+
+// Payment intent statuses reported by a PaymentGateway
+const (
+	PaymentStatusRequiresConfirmation = "requires_confirmation"
+	PaymentStatusRequiresAction       = "requires_action" // e.g. 3-D Secure authentication
+	PaymentStatusSucceeded            = "succeeded"
+	PaymentStatusDeclined             = "declined"
+)
+
+// Webhook event types reported by a PaymentGateway
+const (
+	PaymentEventSucceeded = "payment.succeeded"
+	PaymentEventFailed    = "payment.failed"
+	PaymentEventRefunded  = "payment.refunded"
+	PaymentEventDisputed  = "payment.disputed"
+)
+
+// PaymentIntentRequest describes an amount to be collected
+type PaymentIntentRequest struct {
+	Amount        float64
+	Currency      string
+	PaymentMethod string
+	Description   string
+	Metadata      map[string]string
+}
+
+// PaymentIntent tracks the collection of a single payment at the provider
+type PaymentIntent struct {
+	ID            string
+	Status        string
+	Amount        float64
+	Currency      string
+	PaymentMethod string
+	NextActionURL string // where the customer completes a requires_action step
+	DeclineReason string
+}
+
+// PaymentRefund is a (partial) refund of a succeeded payment intent
+type PaymentRefund struct {
+	ID       string
+	IntentID string
+	Status   string
+	Amount   float64
+}
+
+// PaymentWebhookEvent is a verified notification sent by the provider
+type PaymentWebhookEvent struct {
+	ID       string
+	Type     string
+	IntentID string
+	RefundID string
+	Amount   float64
+	Reason   string
+}
+
+// PaymentGateway is implemented by each payment provider adapter
+type PaymentGateway interface {
+	// Name identifies the provider, e.g. in webhook URLs and stored purchases
+	Name() string
+	CreateIntent(req PaymentIntentRequest) (*PaymentIntent, error)
+	// ConfirmIntent attempts the payment. An empty payment method re-confirms
+	// an intent after its requires_action step was completed.
+	ConfirmIntent(intentID, paymentMethod string) (*PaymentIntent, error)
+	Refund(intentID string, amount float64) (*PaymentRefund, error)
+	// VerifyWebhook authenticates a raw webhook request and parses its event
+	VerifyWebhook(payload []byte, headers map[string][]string) (*PaymentWebhookEvent, error)
+}
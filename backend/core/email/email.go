@@ -4,9 +4,18 @@ import (
 	"backend/core"
 	"fmt"
 	"gopkg.in/gomail.v2"
+	"io"
 	"log"
+	"strings"
+	"time"
 )
 
+// Attachment is a file attached to an email
+type Attachment struct {
+	Filename string
+	Content  []byte
+}
+
 type Service struct {
 	cfg *core.Config
 }
@@ -42,10 +51,42 @@ func (s *Service) SendPasswordResetEmail(email, resetToken string) error {
 	return s.sendEmail(email, subject, body)
 }
 
-func (s *Service) sendEmail(to, subject, body string) error {
+// SendInvoiceEmail sends a finalized invoice or credit note, titled e.g.
+// "Invoice", as HTML with its PDF attached
+func (s *Service) SendInvoiceEmail(email, title, number, html string, pdf []byte) error {
+	subject := fmt.Sprintf("%s %s", title, number)
+	filename := fmt.Sprintf("%s-%s.pdf", strings.ReplaceAll(strings.ToLower(title), " ", "-"), number)
+	return s.sendEmail(email, subject, html, Attachment{Filename: filename, Content: pdf})
+}
+
+// SendTrialReminderEmail tells a customer their free trial is about to end,
+// and whether they'll be charged for the plan or need to add a payment method
+func (s *Service) SendTrialReminderEmail(email, planName string, endsAt time.Time, amount float64, currency string, willCharge bool) error {
+	subject := fmt.Sprintf("Your %s trial ends soon", planName)
+	next := fmt.Sprintf("<p>Add a payment method before then to keep using %s.</p>", planName)
+	if willCharge {
+		next = fmt.Sprintf("<p>Your payment method will then be charged %.2f %s for your first period.</p>", amount, currency)
+	}
+	body := fmt.Sprintf(`
+		<h1>Your trial ends soon</h1>
+		<p>Your free trial of %s ends on %s.</p>
+		%s
+		<p>You can cancel any time before the trial ends.</p>
+		<p>Best regards,<br>The SaaS Platform Team</p>
+	`, planName, endsAt.Format("January 2, 2006"), next)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendDigestEmail sends a rendered analytics digest
+func (s *Service) SendDigestEmail(email, subject, html string) error {
+	return s.sendEmail(email, subject, html)
+}
+
+func (s *Service) sendEmail(to, subject, body string, attachments ...Attachment) error {
 	if !s.cfg.SendRealEmail {
 		// Log email instead of sending
-		log.Printf("EMAIL LOG - To: %s, Subject: %s, Body: %s", to, subject, body)
+		log.Printf("EMAIL LOG - To: %s, Subject: %s, Body: %s, Attachments: %d", to, subject, body, len(attachments))
 		return nil
 	}
 
@@ -55,6 +96,13 @@ func (s *Service) sendEmail(to, subject, body string) error {
 	m.SetHeader("To", to)
 	m.SetHeader("Subject", subject)
 	m.SetBody("text/html", body)
+	for _, attachment := range attachments {
+		content := attachment.Content
+		m.Attach(attachment.Filename, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		}))
+	}
 
 	d := gomail.NewDialer(s.cfg.SMTPHost, s.cfg.SMTPPort, s.cfg.SMTPUser, s.cfg.SMTPPass)
 
@@ -0,0 +1,99 @@
+package i18n
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var localePattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{2}))?$`)
+
+// Normalize converts a locale tag such as "en_us" to its canonical form
+// "en-US". It reports false for tags that aren't a language with an optional
+// region.
+func Normalize(tag string) (string, bool) {
+	matches := localePattern.FindStringSubmatch(strings.TrimSpace(tag))
+	if matches == nil {
+		return "", false
+	}
+
+	locale := strings.ToLower(matches[1])
+	if matches[2] != "" {
+		locale += "-" + strings.ToUpper(matches[2])
+	}
+	return locale, true
+}
+
+// Language returns the language part of a normalized locale ("de-CH" -> "de")
+func Language(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return language
+}
+
+// ParseAcceptLanguage returns the normalized locales of an Accept-Language
+// header ordered by preference. Wildcards and invalid tags are skipped.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		locale string
+		q      float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		locale, ok := Normalize(tag)
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if value, found := strings.CutPrefix(strings.TrimSpace(params), "q="); found {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		tags = append(tags, weighted{locale: locale, q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	locales := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		locales = append(locales, tag.locale)
+	}
+	return locales
+}
+
+// Candidates expands preferred locales with their base languages and cuts the
+// list at the default locale, whose content lives in the untranslated fields.
+// For preferences [de-CH, en] and default "en" it returns [de-CH, de].
+func Candidates(preferred []string, defaultLocale string) []string {
+	var candidates []string
+	seen := make(map[string]bool)
+
+	add := func(locale string) bool {
+		if locale == defaultLocale {
+			return false
+		}
+		if !seen[locale] {
+			seen[locale] = true
+			candidates = append(candidates, locale)
+		}
+		return true
+	}
+
+	for _, locale := range preferred {
+		if !add(locale) {
+			break
+		}
+		if language := Language(locale); language != locale && !add(language) {
+			break
+		}
+	}
+
+	return candidates
+}
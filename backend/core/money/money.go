@@ -0,0 +1,10 @@
+// Package money holds helpers for the monetary amounts stored as float64
+// throughout the backend.
+package money
+
+import "math"
+
+// Round rounds an amount to whole cents
+func Round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
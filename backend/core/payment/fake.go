@@ -0,0 +1,187 @@
+package payment
+
+import (
+	"backend/core"
+	"backend/core/domain"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const FakeProviderName = "fake"
+
+// FakeSignatureHeader carries the hex HMAC-SHA256 of fake webhook payloads
+const FakeSignatureHeader = "X-Fake-Signature"
+
+// Payment methods with special behaviour in the fake gateway. Any other
+// non-empty payment method succeeds.
+const (
+	FakeMethodDeclined = "fake_declined"
+	FakeMethodThreeDS  = "fake_3ds"
+)
+
+// FakeGateway is an in-process PaymentGateway for local development and
+// tests. Intents live in memory and are lost on restart.
+type FakeGateway struct {
+	cfg     *core.Config
+	mu      sync.Mutex
+	intents map[string]*fakeIntent
+}
+
+type fakeIntent struct {
+	intent   domain.PaymentIntent
+	refunded float64
+}
+
+func NewFakeGateway(cfg *core.Config) *FakeGateway {
+	return &FakeGateway{cfg: cfg, intents: make(map[string]*fakeIntent)}
+}
+
+func (g *FakeGateway) Name() string {
+	return FakeProviderName
+}
+
+func (g *FakeGateway) CreateIntent(req domain.PaymentIntentRequest) (*domain.PaymentIntent, error) {
+	if req.Amount < 0 {
+		return nil, errors.New("amount cannot be negative")
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored := &fakeIntent{intent: domain.PaymentIntent{
+		ID:            newFakeID("pi"),
+		Status:        domain.PaymentStatusRequiresConfirmation,
+		Amount:        req.Amount,
+		Currency:      req.Currency,
+		PaymentMethod: req.PaymentMethod,
+	}}
+	g.intents[stored.intent.ID] = stored
+
+	intent := stored.intent
+	return &intent, nil
+}
+
+func (g *FakeGateway) ConfirmIntent(intentID, paymentMethod string) (*domain.PaymentIntent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("payment intent %s not found", intentID)
+	}
+	intent := &stored.intent
+
+	switch intent.Status {
+	case domain.PaymentStatusRequiresConfirmation:
+		if paymentMethod != "" {
+			intent.PaymentMethod = paymentMethod
+		}
+		switch intent.PaymentMethod {
+		case "":
+			return nil, errors.New("payment method is required")
+		case FakeMethodDeclined:
+			intent.Status = domain.PaymentStatusDeclined
+			intent.DeclineReason = "card_declined"
+		case FakeMethodThreeDS:
+			intent.Status = domain.PaymentStatusRequiresAction
+			intent.NextActionURL = "https://fake-gateway.local/3ds/" + intent.ID
+		default:
+			intent.Status = domain.PaymentStatusSucceeded
+		}
+	case domain.PaymentStatusRequiresAction:
+		// Confirming again simulates the customer completing authentication
+		intent.Status = domain.PaymentStatusSucceeded
+		intent.NextActionURL = ""
+	}
+
+	result := *intent
+	return &result, nil
+}
+
+func (g *FakeGateway) Refund(intentID string, amount float64) (*domain.PaymentRefund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stored, ok := g.intents[intentID]
+	if !ok {
+		return nil, fmt.Errorf("payment intent %s not found", intentID)
+	}
+	if stored.intent.Status != domain.PaymentStatusSucceeded {
+		return nil, errors.New("only succeeded payments can be refunded")
+	}
+	if amount <= 0 || stored.refunded+amount > stored.intent.Amount+0.005 {
+		return nil, errors.New("refund amount exceeds the refundable balance")
+	}
+
+	stored.refunded += amount
+	return &domain.PaymentRefund{
+		ID:       newFakeID("re"),
+		IntentID: intentID,
+		Status:   domain.PaymentStatusSucceeded,
+		Amount:   amount,
+	}, nil
+}
+
+// fakeWebhookPayload is the JSON body of fake webhook requests
+type fakeWebhookPayload struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		IntentID string  `json:"intent_id"`
+		RefundID string  `json:"refund_id"`
+		Amount   float64 `json:"amount"`
+		Reason   string  `json:"reason"`
+	} `json:"data"`
+}
+
+func (g *FakeGateway) VerifyWebhook(payload []byte, headers map[string][]string) (*domain.PaymentWebhookEvent, error) {
+	signature := ""
+	for name, values := range headers {
+		if strings.EqualFold(name, FakeSignatureHeader) && len(values) > 0 {
+			signature = values[0]
+			break
+		}
+	}
+
+	expected := SignFakeWebhook(g.cfg.PaymentWebhookSecret, payload)
+	if signature == "" || !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, errors.New("invalid webhook signature")
+	}
+
+	var body fakeWebhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %v", err)
+	}
+	if body.ID == "" || body.Type == "" {
+		return nil, errors.New("webhook event id and type are required")
+	}
+
+	return &domain.PaymentWebhookEvent{
+		ID:       body.ID,
+		Type:     body.Type,
+		IntentID: body.Data.IntentID,
+		RefundID: body.Data.RefundID,
+		Amount:   body.Data.Amount,
+		Reason:   body.Data.Reason,
+	}, nil
+}
+
+// SignFakeWebhook returns the signature header value for a fake webhook payload
+func SignFakeWebhook(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newFakeID(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return prefix + "_fake_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,24 @@
+package payment
+
+import (
+	"backend/core"
+	"backend/core/domain"
+	"errors"
+	"fmt"
+)
+
+// NewGateway returns the adapter for the configured payment provider. The
+// fake gateway approves any payment method, so it's refused in production;
+// being the only one built in, a production deployment needs an adapter for
+// its provider added here.
+func NewGateway(cfg *core.Config) (domain.PaymentGateway, error) {
+	switch cfg.PaymentProvider {
+	case FakeProviderName:
+		if cfg.Environment == "production" {
+			return nil, errors.New("PAYMENT_PROVIDER must name a real payment provider in production, and the fake one is the only one built in; add an adapter for your provider to core/payment")
+		}
+		return NewFakeGateway(cfg), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment provider '%s'; supported: %s", cfg.PaymentProvider, FakeProviderName)
+	}
+}
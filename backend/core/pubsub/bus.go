@@ -0,0 +1,19 @@
+package pubsub
+
+import (
+	"backend/core"
+	"backend/core/domain"
+	"fmt"
+)
+
+const MemoryBusName = "memory"
+
+// NewBus returns the configured event bus
+func NewBus(cfg *core.Config) (domain.EventBus, error) {
+	switch cfg.EventBus {
+	case MemoryBusName:
+		return NewMemoryBus(), nil
+	default:
+		return nil, fmt.Errorf("unsupported event bus '%s'", cfg.EventBus)
+	}
+}
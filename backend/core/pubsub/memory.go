@@ -0,0 +1,68 @@
+package pubsub
+
+import (
+	"backend/core/domain"
+	"sync"
+)
+
+// subscriberBuffer bounds the events queued for a subscriber before it's
+// deemed to have fallen behind
+const subscriberBuffer = 64
+
+// MemoryBus is an in-process EventBus. Subscribers only get events published
+// by the same instance; fanning out across instances needs a shared bus,
+// e.g. one on Postgres LISTEN/NOTIFY.
+type MemoryBus struct {
+	mu          sync.Mutex
+	subscribers map[int]map[chan domain.Event]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subscribers: make(map[int]map[chan domain.Event]struct{})}
+}
+
+// Publish delivers the event to the subscribers of its tenant without
+// blocking. Subscribers whose queue is full are dropped.
+func (b *MemoryBus) Publish(event domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for events := range b.subscribers[event.TenantID] {
+		select {
+		case events <- event:
+		default:
+			b.remove(event.TenantID, events)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(tenantID int) (<-chan domain.Event, func()) {
+	events := make(chan domain.Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[tenantID] == nil {
+		b.subscribers[tenantID] = make(map[chan domain.Event]struct{})
+	}
+	b.subscribers[tenantID][events] = struct{}{}
+	b.mu.Unlock()
+
+	return events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.remove(tenantID, events)
+	}
+}
+
+// remove closes and forgets a subscriber, if it's still subscribed. b.mu
+// must be held.
+func (b *MemoryBus) remove(tenantID int, events chan domain.Event) {
+	if _, ok := b.subscribers[tenantID][events]; !ok {
+		return
+	}
+	delete(b.subscribers[tenantID], events)
+	if len(b.subscribers[tenantID]) == 0 {
+		delete(b.subscribers, tenantID)
+	}
+	close(events)
+}
@@ -1,48 +1,194 @@
 package init
 
 import (
+	"context"
+	"log"
+	"time"
+
 	"backend/core"
+	"backend/core/payment"
+	"backend/core/pubsub"
+	"backend/core/scheduler"
 	"backend/internal/analytics"
 	"backend/internal/auth"
+	"backend/internal/billing"
+	"backend/internal/catalog"
+	"backend/internal/coupon"
+	"backend/internal/digest"
+	"backend/internal/events"
+	"backend/internal/export"
+	"backend/internal/feature"
+	"backend/internal/idempotency"
+	"backend/internal/invoice"
 	"backend/internal/plan"
 	"backend/internal/product"
 	"backend/internal/purchase"
+	"backend/internal/rollup"
+	"backend/internal/storefront"
+	"backend/internal/tax"
 	handlers2 "backend/internal/tenant"
+	"backend/internal/translation"
+	"backend/internal/webhook"
 	"gorm.io/gorm"
 )
 
 type App struct {
-	AuthHandler      *auth.Controller
-	TenantHandler    *handlers2.Controller
-	ProductHandler   *product.Controller
-	PlanHandler      *plan.Controller
-	PurchaseHandler  *purchase.Controller
-	AnalyticsHandler *analytics.Controller
-	Config           *core.Config
+	AuthHandler        *auth.Controller
+	TenantHandler      *handlers2.Controller
+	ProductHandler     *product.Controller
+	PlanHandler        *plan.Controller
+	FeatureHandler     *feature.Controller
+	PurchaseHandler    *purchase.Controller
+	AnalyticsHandler   *analytics.Controller
+	StorefrontHandler  *storefront.Controller
+	CatalogHandler     *catalog.Controller
+	TranslationHandler *translation.Controller
+	WebhookHandler     *webhook.Controller
+	BillingHandler     *billing.Controller
+	InvoiceHandler     *invoice.Controller
+	TaxHandler         *tax.Controller
+	CouponHandler      *coupon.Controller
+	DigestHandler      *digest.Controller
+	ExportHandler      *export.Controller
+	EventsHandler      *events.Controller
+	Idempotency        *idempotency.Service
+	Config             *core.Config
 }
 
 func InitializeApp(db *gorm.DB, cfg *core.Config) (*App, func(), error) {
 
+	// Payment gateway shared by purchases, renewals and webhooks
+	gateway, err := payment.NewGateway(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Event bus fanning recorded activities out to the tenants' event streams
+	bus, err := pubsub.NewBus(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := events.RegisterPublisher(db, events.NewServiceWire(db, cfg, bus)); err != nil {
+		return nil, nil, err
+	}
+
 	// Initialize handlers
 	authHandler := auth.NewControllerWire(db, cfg)
 	tenantHandler := handlers2.NewControllerWire(db)
 	productHandler := product.NewControllerWire(db)
 	planHandler := plan.NewControllerWire(db)
-	purchaseHandler := purchase.NewControllerWire(db)
+	featureHandler := feature.NewControllerWire(db)
+	purchaseHandler := purchase.NewControllerWire(db, cfg, gateway)
 	analyticsHandler := analytics.NewControllerWire(db)
+	storefrontHandler := storefront.NewControllerWire(db)
+	catalogHandler := catalog.NewControllerWire(db)
+	translationHandler := translation.NewControllerWire(db)
+	webhookHandler := webhook.NewControllerWire(db, cfg, gateway)
+	billingHandler := billing.NewControllerWire(db)
+	invoiceHandler := invoice.NewControllerWire(db, cfg)
+	taxHandler := tax.NewControllerWire(db)
+	couponHandler := coupon.NewControllerWire(db)
+	digestHandler := digest.NewControllerWire(db, cfg)
+	exportHandler := export.NewControllerWire(db, cfg)
+	eventsHandler := events.NewControllerWire(db, cfg, bus)
+	idempotencyService := idempotency.NewServiceWire(db, cfg)
 
 	app := &App{
-		AuthHandler:      authHandler,
-		TenantHandler:    tenantHandler,
-		ProductHandler:   productHandler,
-		PlanHandler:      planHandler,
-		PurchaseHandler:  purchaseHandler,
-		AnalyticsHandler: analyticsHandler,
-		Config:           cfg,
+		AuthHandler:        authHandler,
+		TenantHandler:      tenantHandler,
+		ProductHandler:     productHandler,
+		PlanHandler:        planHandler,
+		FeatureHandler:     featureHandler,
+		PurchaseHandler:    purchaseHandler,
+		AnalyticsHandler:   analyticsHandler,
+		StorefrontHandler:  storefrontHandler,
+		CatalogHandler:     catalogHandler,
+		TranslationHandler: translationHandler,
+		WebhookHandler:     webhookHandler,
+		BillingHandler:     billingHandler,
+		InvoiceHandler:     invoiceHandler,
+		TaxHandler:         taxHandler,
+		CouponHandler:      couponHandler,
+		DigestHandler:      digestHandler,
+		ExportHandler:      exportHandler,
+		EventsHandler:      eventsHandler,
+		Idempotency:        idempotencyService,
+		Config:             cfg,
+	}
+
+	// Background jobs
+	jobs := scheduler.New()
+	if cfg.SchedulerEnabled {
+		purchaseService := purchase.NewServiceWire(db, cfg, gateway)
+		jobs.Every("purchase-lifecycle", cfg.LifecycleInterval, func(ctx context.Context) error {
+			processed, err := purchaseService.ProcessDuePurchases(time.Now())
+			if processed > 0 {
+				log.Printf("Processed %d due purchases", processed)
+			}
+			return err
+		})
+
+		jobs.Every("trial-reminders", cfg.LifecycleInterval, func(ctx context.Context) error {
+			sent, err := purchaseService.ProcessTrialReminders(time.Now())
+			if sent > 0 {
+				log.Printf("Sent %d trial reminders", sent)
+			}
+			return err
+		})
+
+		webhookService := webhook.NewServiceWire(db, cfg, gateway)
+		jobs.Every("payment-webhooks", cfg.WebhookRetryInterval, func(ctx context.Context) error {
+			_, err := webhookService.ProcessDue(time.Now())
+			return err
+		})
+
+		rollupService := rollup.NewServiceWire(db)
+		jobs.Every("analytics-rollups", cfg.RollupRefreshInterval, func(ctx context.Context) error {
+			_, err := rollupService.ProcessStale()
+			return err
+		})
+
+		jobs.Every("analytics-reconciliation", cfg.RollupReconcileInterval, func(ctx context.Context) error {
+			refreshed, err := rollupService.Reconcile(time.Now(), cfg.RollupReconcileDays)
+			if refreshed > 0 {
+				log.Printf("Reconciled %d tenant days of analytics rollups", refreshed)
+			}
+			return err
+		})
+
+		digestService := digest.NewServiceWire(db, cfg)
+		jobs.Every("analytics-digests", cfg.DigestInterval, func(ctx context.Context) error {
+			sent, err := digestService.ProcessDue(time.Now())
+			if sent > 0 {
+				log.Printf("Sent %d analytics digests", sent)
+			}
+			return err
+		})
+
+		exportService := export.NewServiceWire(db, cfg)
+		jobs.Every("exports", cfg.ExportInterval, func(ctx context.Context) error {
+			built, err := exportService.ProcessPending()
+			if built > 0 {
+				log.Printf("Built %d exports", built)
+			}
+			return err
+		})
+
+		jobs.Every("export-cleanup", time.Hour, func(ctx context.Context) error {
+			_, err := exportService.PurgeExpired(time.Now())
+			return err
+		})
+
+		jobs.Every("idempotency-keys", time.Hour, func(ctx context.Context) error {
+			_, err := idempotencyService.PurgeExpired(time.Now())
+			return err
+		})
+
+		jobs.Start()
 	}
 
 	cleanup := func() {
-		// Cleanup resources if needed
+		jobs.Stop()
 	}
 
 	return app, cleanup, nil
@@ -0,0 +1,19 @@
+package activity
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(activity *models.Activity) error {
+	return r.db.Create(activity).Error
+}
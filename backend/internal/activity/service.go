@@ -0,0 +1,24 @@
+package activity
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"log"
+)
+
+// Service records what happens in a tenant for its activity feed
+type Service struct {
+	repo domain.ActivityRepository
+}
+
+func NewService(repo domain.ActivityRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Record stores the activity. Callers record it once the change is saved, so
+// a failure has nothing to undo and is just logged.
+func (s *Service) Record(activity *models.Activity) {
+	if err := s.repo.Create(activity); err != nil {
+		log.Printf("Failed to record %s activity of tenant %d: %v", activity.Type, activity.TenantID, err)
+	}
+}
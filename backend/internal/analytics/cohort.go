@@ -0,0 +1,150 @@
+package analytics
+
+import (
+	"backend/core/money"
+	"backend/models"
+	"time"
+)
+
+// GetCohorts groups users into monthly cohorts by when they registered or
+// first purchased, and tracks how many of each cohort are active, and the
+// revenue they bring, in every month since. Months start in the location of
+// the query's From. Only purchases matching the query's filter count.
+func (s *Service) GetCohorts(tenantID int, query models.CohortQuery) (*models.CohortAnalytics, error) {
+	location := query.From.Location()
+	now := s.clock.Now().In(location)
+
+	members, err := s.repo.GetCohortMembers(tenantID, query.By, query.From, query.To, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.repo.GetMRRMovements(tenantID, query.From, now, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetRevenueEntries(tenantID, query.From, now, query.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CohortAnalytics{
+		By:       query.By,
+		Timezone: location.String(),
+		Currency: query.Currency,
+		Periods:  query.Periods,
+	}
+
+	// One row per month of the range, each with a cell per month elapsed
+	var active [][]map[int]bool
+	for month := query.From; month.Before(query.To); month = month.AddDate(0, 1, 0) {
+		cohort := models.Cohort{Month: month}
+		var users []map[int]bool
+		for period := 0; period < query.Periods; period++ {
+			if month.AddDate(0, period, 0).After(now) {
+				break
+			}
+			cohort.Cells = append(cohort.Cells, models.CohortCell{Period: period})
+			users = append(users, make(map[int]bool))
+		}
+		result.Cohorts = append(result.Cohorts, cohort)
+		active = append(active, users)
+	}
+
+	cohortOf := make(map[int]int, len(members))
+	for _, member := range members {
+		index := monthsBetween(query.From, member.JoinedAt.In(location))
+		if index < 0 || index >= len(result.Cohorts) {
+			continue
+		}
+		cohortOf[member.UserID] = index
+		result.Cohorts[index].Users++
+	}
+
+	// A user is active in every month overlapping one of their paid periods
+	for _, paid := range paidIntervals(movements, now) {
+		index, ok := cohortOf[paid.userID]
+		if !ok {
+			continue
+		}
+		cohort := &result.Cohorts[index]
+		for period := range cohort.Cells {
+			start := cohort.Month.AddDate(0, period, 0)
+			if paid.start.Before(start.AddDate(0, 1, 0)) && paid.end.After(start) {
+				active[index][period][paid.userID] = true
+			}
+		}
+	}
+
+	// and in every month they made a payment in
+	for _, entry := range entries {
+		index, ok := cohortOf[entry.UserID]
+		if !ok {
+			continue
+		}
+		cohort := &result.Cohorts[index]
+		period := monthsBetween(cohort.Month, entry.OccurredAt.In(location))
+		if period < 0 || period >= len(cohort.Cells) {
+			continue
+		}
+		if entry.Amount > 0 {
+			active[index][period][entry.UserID] = true
+		}
+		if entry.Currency == query.Currency {
+			cohort.Cells[period].Revenue += entry.Amount
+		}
+	}
+
+	for i := range result.Cohorts {
+		cohort := &result.Cohorts[i]
+		for period := range cohort.Cells {
+			cell := &cohort.Cells[period]
+			cell.ActiveUsers = len(active[i][period])
+			cell.Revenue = money.Round(cell.Revenue)
+			if cohort.Users > 0 {
+				retention := percent(float64(cell.ActiveUsers), float64(cohort.Users))
+				cell.Retention = &retention
+			}
+			if first := cohort.Cells[0].Revenue; first > 0 {
+				retention := percent(cell.Revenue, first)
+				cell.RevenueRetention = &retention
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// paidInterval is a stretch of time a purchase had MRR
+type paidInterval struct {
+	userID     int
+	start, end time.Time
+}
+
+// paidIntervals replays MRR movements, oldest first, into the intervals each
+// purchase was paid for. Purchases still paying at now end at now.
+func paidIntervals(movements []models.MRRMovement, now time.Time) []paidInterval {
+	var intervals []paidInterval
+	open := make(map[int]paidInterval)
+	for _, movement := range movements {
+		paid, paying := open[movement.PurchaseID]
+		switch {
+		case movement.MRR > 0 && !paying:
+			open[movement.PurchaseID] = paidInterval{userID: movement.UserID, start: movement.OccurredAt}
+		case movement.MRR == 0 && paying:
+			paid.end = movement.OccurredAt
+			intervals = append(intervals, paid)
+			delete(open, movement.PurchaseID)
+		}
+	}
+	for _, paid := range open {
+		paid.end = now
+		intervals = append(intervals, paid)
+	}
+	return intervals
+}
+
+// monthsBetween returns the number of calendar months from the month of from
+// to the month of t
+func monthsBetween(from, t time.Time) int {
+	return (t.Year()-from.Year())*12 + int(t.Month()) - int(from.Month())
+}
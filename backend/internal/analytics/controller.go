@@ -2,9 +2,45 @@ package analytics
 
 import (
 	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 )
 
+// maxRevenuePoints bounds the periods of a revenue series
+const maxRevenuePoints = 1000
+
+// Performance page sizes
+const (
+	defaultPerformancePerPage = 20
+	maxPerformancePerPage     = 100
+)
+
+// Activity page sizes
+const (
+	defaultActivityLimit = 10
+	maxActivityLimit     = 100
+)
+
+// Platform analytics sizes
+const (
+	defaultTopTenants    = 10
+	maxTopTenants        = 100
+	defaultHealthPerPage = 20
+	maxHealthPerPage     = 100
+)
+
+// Cohort matrix bounds
+const (
+	defaultCohortPeriods = 12
+	maxCohortPeriods     = 36
+	maxCohorts           = 60
+)
+
 type Controller struct {
 	service domain.AnalyticsService
 }
@@ -31,11 +67,432 @@ func (c *Controller) GetDashboardMetrics(ctx *fiber.Ctx) error {
 	})
 }
 
-// GetRecentActivity gets recent activity for the current tenant
-func (c *Controller) GetRecentActivity(ctx *fiber.Ctx) error {
+// GetRevenueSeries gets revenue, MRR and churn series for the current tenant.
+// from and to are inclusive dates; the last 30 days by day by default. Days
+// start in the tenant's timezone unless tz names another.
+func (c *Controller) GetRevenueSeries(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	now, err := c.service.GetLocalTime(*tenantID, ctx.Query("tz"))
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "tz must be an IANA timezone like Europe/Berlin",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	query, err := ParseRevenueQuery(ctx, now)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	series, err := c.service.GetRevenueSeries(*tenantID, query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  series,
+	})
+}
+
+// ParseRevenueQuery reads the from, to and interval query parameters. Dates
+// are in now's location.
+func ParseRevenueQuery(ctx *fiber.Ctx, now time.Time) (models.RevenueQuery, error) {
+	query := models.RevenueQuery{Interval: ctx.Query("interval", models.RevenueIntervalDay)}
+	switch query.Interval {
+	case models.RevenueIntervalDay, models.RevenueIntervalWeek, models.RevenueIntervalMonth:
+	default:
+		return query, errors.New("interval must be day, week or month")
+	}
+
+	from, to, err := parseDateRange(ctx, now)
+	if err != nil {
+		return query, err
+	}
+	query.From = from
+	query.To = to
+	points := 0
+	for start := PeriodStart(query.From, query.Interval); start.Before(query.To); start = NextPeriod(start, query.Interval) {
+		if points++; points > maxRevenuePoints {
+			return query, errors.New("date range has too many periods for the interval")
+		}
+	}
+	return query, nil
+}
+
+// parseDateRange reads the inclusive from and to date query parameters, the
+// last 30 days by default, and returns the range with an exclusive end.
+// Dates are in now's location.
+func parseDateRange(ctx *fiber.Ctx, now time.Time) (time.Time, time.Time, error) {
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("to must be a date like 2006-01-02")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -29)
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("from must be a date like 2006-01-02")
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return time.Time{}, time.Time{}, errors.New("from must not be after to")
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+// GetProductPerformance gets per-product performance for the current tenant
+func (c *Controller) GetProductPerformance(ctx *fiber.Ctx) error {
+	return c.getPerformance(ctx, models.PerformanceByProduct)
+}
+
+// GetPlanPerformance gets per-plan performance for the current tenant
+func (c *Controller) GetPlanPerformance(ctx *fiber.Ctx) error {
+	return c.getPerformance(ctx, models.PerformanceByPlan)
+}
+
+func (c *Controller) getPerformance(ctx *fiber.Ctx, by string) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	now, err := c.service.GetLocalTime(*tenantID, ctx.Query("tz"))
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "tz must be an IANA timezone like Europe/Berlin",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	query, err := parsePerformanceQuery(ctx, now)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	page, err := c.service.GetPerformance(*tenantID, by, query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  page,
+	})
+}
+
+// parsePerformanceQuery reads the from, to, currency, sort, order, page and
+// per_page query parameters. Dates are in now's location.
+func parsePerformanceQuery(ctx *fiber.Ctx, now time.Time) (models.PerformanceQuery, error) {
+	query := models.PerformanceQuery{
+		Currency: strings.ToUpper(ctx.Query("currency", "USD")),
+		Sort:     ctx.Query("sort", performanceSortRevenue),
+		Order:    ctx.Query("order", "desc"),
+	}
+
+	valid := false
+	for _, sort := range performanceSorts {
+		valid = valid || query.Sort == sort
+	}
+	if !valid {
+		return query, errors.New("sort must be one of " + strings.Join(performanceSorts, ", "))
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		return query, errors.New("order must be asc or desc")
+	}
+
+	page, err := strconv.Atoi(ctx.Query("page", "1"))
+	if err != nil || page < 1 {
+		return query, errors.New("page must be a positive number")
+	}
+	perPage, err := strconv.Atoi(ctx.Query("per_page", strconv.Itoa(defaultPerformancePerPage)))
+	if err != nil || perPage < 1 || perPage > maxPerformancePerPage {
+		return query, errors.New("per_page must be between 1 and " + strconv.Itoa(maxPerformancePerPage))
+	}
+	query.Page = page
+	query.PerPage = perPage
+
+	query.From, query.To, err = parseDateRange(ctx, now)
+	return query, err
+}
+
+// GetCohorts gets a retention matrix of monthly cohorts for the current
+// tenant. from and to are inclusive months; the last 12 by default.
+func (c *Controller) GetCohorts(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	now, err := c.service.GetLocalTime(*tenantID, ctx.Query("tz"))
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "tz must be an IANA timezone like Europe/Berlin",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	query, err := parseCohortQuery(ctx, now)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	cohorts, err := c.service.GetCohorts(*tenantID, query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  cohorts,
+	})
+}
+
+// parseCohortQuery reads the by, from, to, periods, currency, product_id and
+// plan_id query parameters. Months are in now's location.
+func parseCohortQuery(ctx *fiber.Ctx, now time.Time) (models.CohortQuery, error) {
+	query := models.CohortQuery{
+		By:       ctx.Query("by", models.CohortBySignup),
+		Currency: strings.ToUpper(ctx.Query("currency", "USD")),
+	}
+	switch query.By {
+	case models.CohortBySignup, models.CohortByFirstPurchase:
+	default:
+		return query, errors.New("by must be signup or first_purchase")
+	}
+
+	periods, err := strconv.Atoi(ctx.Query("periods", strconv.Itoa(defaultCohortPeriods)))
+	if err != nil || periods < 1 || periods > maxCohortPeriods {
+		return query, errors.New("periods must be between 1 and " + strconv.Itoa(maxCohortPeriods))
+	}
+	query.Periods = periods
+
+	to := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01", value, now.Location())
+		if err != nil {
+			return query, errors.New("to must be a month like 2006-01")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, -11, 0)
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01", value, now.Location())
+		if err != nil {
+			return query, errors.New("from must be a month like 2006-01")
+		}
+		from = parsed
+	}
+	if from.After(to) {
+		return query, errors.New("from must not be after to")
+	}
+	if monthsBetween(from, to) >= maxCohorts {
+		return query, errors.New("date range has too many cohorts")
+	}
+	query.From = from
+	query.To = to.AddDate(0, 1, 0)
+
+	if value := ctx.Query("product_id"); value != "" {
+		productID, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("invalid product ID")
+		}
+		query.Filter.ProductID = &productID
+	}
+	if value := ctx.Query("plan_id"); value != "" {
+		planID, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("invalid plan ID")
+		}
+		query.Filter.PlanID = &planID
+	}
+	return query, nil
+}
+
+// GetActivities gets a page of the current tenant's activities, newest
+// first. Pass the returned next_cursor as cursor to get the next page.
+func (c *Controller) GetActivities(ctx *fiber.Ctx) error {
 	tenantID := ctx.Locals("tenantID").(*int)
 
-	activities, err := c.service.GetRecentActivity(*tenantID)
+	now, err := c.service.GetLocalTime(*tenantID, ctx.Query("tz"))
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "tz must be an IANA timezone like Europe/Berlin",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	query, err := ParseActivityQuery(ctx, now.Location())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	page, err := c.service.GetActivities(*tenantID, query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	var nextCursor *string
+	if page.NextCursor != "" {
+		nextCursor = &page.NextCursor
+	}
+	return ctx.JSON(fiber.Map{
+		"error":       false,
+		"data":        page.Activities,
+		"next_cursor": nextCursor,
+	})
+}
+
+// ParseActivityQuery reads the type, entity_type, entity_id, user_id, from,
+// to, cursor and limit query parameters. type takes a comma-separated list;
+// from and to are inclusive dates in location.
+func ParseActivityQuery(ctx *fiber.Ctx, location *time.Location) (models.ActivityQuery, error) {
+	query := models.ActivityQuery{EntityType: ctx.Query("entity_type")}
+
+	if value := ctx.Query("type"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			activityType := models.ActivityType(strings.TrimSpace(name))
+			if !activityType.Valid() {
+				return query, errors.New("unknown activity type: " + string(activityType))
+			}
+			query.Types = append(query.Types, activityType)
+		}
+	}
+	if value := ctx.Query("entity_id"); value != "" {
+		entityID, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("invalid entity ID")
+		}
+		query.EntityID = &entityID
+	}
+	if value := ctx.Query("user_id"); value != "" {
+		userID, err := strconv.Atoi(value)
+		if err != nil {
+			return query, errors.New("invalid user ID")
+		}
+		query.UserID = &userID
+	}
+
+	if value := ctx.Query("from"); value != "" {
+		from, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			return query, errors.New("from must be a date like 2006-01-02")
+		}
+		query.From = from
+	}
+	if value := ctx.Query("to"); value != "" {
+		to, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			return query, errors.New("to must be a date like 2006-01-02")
+		}
+		query.To = to.AddDate(0, 0, 1)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, errors.New("from must not be after to")
+	}
+
+	if value := ctx.Query("cursor"); value != "" {
+		before, err := strconv.Atoi(value)
+		if err != nil || before < 1 {
+			return query, errors.New("invalid cursor")
+		}
+		query.Before = before
+	}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", strconv.Itoa(defaultActivityLimit)))
+	if err != nil || limit < 1 || limit > maxActivityLimit {
+		return query, errors.New("limit must be between 1 and " + strconv.Itoa(maxActivityLimit))
+	}
+	query.Limit = limit
+	return query, nil
+}
+
+// GetPlatformOverview gets GMV, MRR, tenants by status and signups across
+// all tenants. from and to are inclusive dates, in each tenant's timezone;
+// the last 30 days by default.
+func (c *Controller) GetPlatformOverview(ctx *fiber.Ctx) error {
+	query, err := parsePlatformQuery(ctx, c.service.GetPlatformTime())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	overview, err := c.service.GetPlatformOverview(query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  overview,
+	})
+}
+
+// GetTopTenants gets the tenants with the most revenue and revenue growth
+func (c *Controller) GetTopTenants(ctx *fiber.Ctx) error {
+	query, err := parsePlatformQuery(ctx, c.service.GetPlatformTime())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	top, err := c.service.GetTopTenants(query)
 	if err != nil {
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
@@ -45,6 +502,110 @@ func (c *Controller) GetRecentActivity(ctx *fiber.Ctx) error {
 
 	return ctx.JSON(fiber.Map{
 		"error": false,
-		"data":  activities,
+		"data":  top,
 	})
 }
+
+// GetSignupTrends gets the tenants and users who signed up per day, week or
+// month
+func (c *Controller) GetSignupTrends(ctx *fiber.Ctx) error {
+	query, err := ParseRevenueQuery(ctx, c.service.GetPlatformTime())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	trends, err := c.service.GetSignupTrends(query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  trends,
+	})
+}
+
+// GetTenantHealth gets a page of tenants with their health indicators
+func (c *Controller) GetTenantHealth(ctx *fiber.Ctx) error {
+	query, err := parseTenantHealthQuery(ctx, c.service.GetPlatformTime())
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	page, err := c.service.GetTenantHealth(query)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  page,
+	})
+}
+
+// parsePlatformQuery reads the from, to, currency and limit query parameters
+func parsePlatformQuery(ctx *fiber.Ctx, now time.Time) (models.PlatformQuery, error) {
+	query := models.PlatformQuery{Currency: strings.ToUpper(ctx.Query("currency", "USD"))}
+
+	limit, err := strconv.Atoi(ctx.Query("limit", strconv.Itoa(defaultTopTenants)))
+	if err != nil || limit < 1 || limit > maxTopTenants {
+		return query, errors.New("limit must be between 1 and " + strconv.Itoa(maxTopTenants))
+	}
+	query.Limit = limit
+
+	query.From, query.To, err = parseDateRange(ctx, now)
+	return query, err
+}
+
+// parseTenantHealthQuery reads the from, to, currency, status, sort, order,
+// page and per_page query parameters
+func parseTenantHealthQuery(ctx *fiber.Ctx, now time.Time) (models.TenantHealthQuery, error) {
+	query := models.TenantHealthQuery{
+		Currency: strings.ToUpper(ctx.Query("currency", "USD")),
+		Status:   ctx.Query("status"),
+		Sort:     ctx.Query("sort", healthSortRevenue),
+		Order:    ctx.Query("order", "desc"),
+	}
+
+	switch query.Status {
+	case "", models.TenantStatusActive, models.TenantStatusInactive:
+	default:
+		return query, errors.New("status must be active or inactive")
+	}
+	valid := false
+	for _, sort := range healthSorts {
+		valid = valid || query.Sort == sort
+	}
+	if !valid {
+		return query, errors.New("sort must be one of " + strings.Join(healthSorts, ", "))
+	}
+	if query.Order != "asc" && query.Order != "desc" {
+		return query, errors.New("order must be asc or desc")
+	}
+
+	page, err := strconv.Atoi(ctx.Query("page", "1"))
+	if err != nil || page < 1 {
+		return query, errors.New("page must be a positive number")
+	}
+	perPage, err := strconv.Atoi(ctx.Query("per_page", strconv.Itoa(defaultHealthPerPage)))
+	if err != nil || perPage < 1 || perPage > maxHealthPerPage {
+		return query, errors.New("per_page must be between 1 and " + strconv.Itoa(maxHealthPerPage))
+	}
+	query.Page = page
+	query.PerPage = perPage
+
+	query.From, query.To, err = parseDateRange(ctx, now)
+	return query, err
+}
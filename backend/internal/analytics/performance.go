@@ -0,0 +1,194 @@
+package analytics
+
+import (
+	"backend/core/money"
+	"backend/models"
+	"sort"
+	"strings"
+)
+
+// Performance sort keys
+const (
+	performanceSortName                = "name"
+	performanceSortRevenue             = "revenue"
+	performanceSortActiveSubscribers   = "active_subscribers"
+	performanceSortPayingUsers         = "paying_users"
+	performanceSortARPU                = "arpu"
+	performanceSortTrialConversionRate = "trial_conversion_rate"
+	performanceSortChurnRate           = "churn_rate"
+)
+
+// performanceSorts lists the sort keys a performance query accepts
+var performanceSorts = []string{
+	performanceSortName,
+	performanceSortRevenue,
+	performanceSortActiveSubscribers,
+	performanceSortPayingUsers,
+	performanceSortARPU,
+	performanceSortTrialConversionRate,
+	performanceSortChurnRate,
+}
+
+// GetPerformance returns a page of the tenant's products or plans with
+// their subscribers, revenue, trial conversion, churn and ARPU over the
+// query's range. Deleted items are included as long as they have purchases.
+func (s *Service) GetPerformance(tenantID int, by string, query models.PerformanceQuery) (*models.PerformancePage, error) {
+	items, err := s.repo.GetPerformanceItems(tenantID, by)
+	if err != nil {
+		return nil, err
+	}
+	index := make(map[int]*models.ItemPerformance, len(items))
+	for i := range items {
+		index[items[i].ID] = &items[i]
+	}
+
+	revenue, err := s.repo.SumItemRevenue(tenantID, by, query.From, query.To, query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range revenue {
+		if item := index[total.ID]; item != nil {
+			item.Revenue = money.Round(total.Amount)
+			item.PayingUsers = total.Count
+		}
+	}
+
+	subscribers, err := s.repo.CountItemSubscribers(tenantID, by, query.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range subscribers {
+		if item := index[total.ID]; item != nil {
+			item.ActiveSubscribers = total.Count
+		}
+	}
+
+	opening, err := s.repo.CountItemSubscribers(tenantID, by, query.From)
+	if err != nil {
+		return nil, err
+	}
+	churned, err := s.repo.CountItemChurn(tenantID, by, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	openingCounts := make(map[int]int, len(opening))
+	for _, total := range opening {
+		openingCounts[total.ID] = total.Count
+	}
+	for _, total := range churned {
+		if item := index[total.ID]; item != nil {
+			item.ChurnedSubscribers = total.Count
+		}
+	}
+
+	trials, err := s.repo.CountItemTrials(tenantID, by, query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range trials {
+		if item := index[total.ID]; item != nil {
+			item.TrialsEnded = total.Ended
+			item.TrialsConverted = total.Converted
+		}
+	}
+
+	for i := range items {
+		item := &items[i]
+		if item.PayingUsers > 0 {
+			arpu := money.Round(item.Revenue / float64(item.PayingUsers))
+			item.ARPU = &arpu
+		}
+		if item.TrialsEnded > 0 {
+			rate := percent(float64(item.TrialsConverted), float64(item.TrialsEnded))
+			item.TrialConversionRate = &rate
+		}
+		if count := openingCounts[item.ID]; count > 0 {
+			rate := percent(float64(item.ChurnedSubscribers), float64(count))
+			item.ChurnRate = &rate
+		}
+	}
+
+	sortPerformance(items, query.Sort, query.Order == "asc")
+
+	page := &models.PerformancePage{
+		From:     query.From,
+		To:       query.To,
+		Timezone: query.From.Location().String(),
+		Currency: query.Currency,
+		Sort:     query.Sort,
+		Order:    query.Order,
+		Page:     query.Page,
+		PerPage:  query.PerPage,
+		Total:    len(items),
+		Items:    []models.ItemPerformance{},
+	}
+	start := (query.Page - 1) * query.PerPage
+	if start < len(items) {
+		end := start + query.PerPage
+		if end > len(items) {
+			end = len(items)
+		}
+		page.Items = items[start:end]
+	}
+	return page, nil
+}
+
+// sortPerformance orders items by the sort key. Items without a rate come
+// last either way; ties are broken by ID.
+func sortPerformance(items []models.ItemPerformance, key string, ascending bool) {
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := &items[i], &items[j]
+		var cmp int
+		switch key {
+		case performanceSortName:
+			cmp = strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+		case performanceSortActiveSubscribers:
+			cmp = compareFloats(float64(a.ActiveSubscribers), float64(b.ActiveSubscribers))
+		case performanceSortPayingUsers:
+			cmp = compareFloats(float64(a.PayingUsers), float64(b.PayingUsers))
+		case performanceSortARPU:
+			cmp = compareRates(a.ARPU, b.ARPU, ascending)
+		case performanceSortTrialConversionRate:
+			cmp = compareRates(a.TrialConversionRate, b.TrialConversionRate, ascending)
+		case performanceSortChurnRate:
+			cmp = compareRates(a.ChurnRate, b.ChurnRate, ascending)
+		default:
+			cmp = compareFloats(a.Revenue, b.Revenue)
+		}
+		if cmp == 0 {
+			return a.ID < b.ID
+		}
+		if ascending {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareRates compares rates that may be missing, ranking a missing one
+// after any other in the given order
+func compareRates(a, b *float64, ascending bool) int {
+	last := -1
+	if ascending {
+		last = 1
+	}
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return last
+	case b == nil:
+		return -last
+	}
+	return compareFloats(*a, *b)
+}
@@ -0,0 +1,387 @@
+package analytics
+
+import (
+	"backend/core/money"
+	"backend/models"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Tenant health sort keys
+const (
+	healthSortName              = "name"
+	healthSortLastActivity      = "last_activity"
+	healthSortRevenue           = "revenue"
+	healthSortMRR               = "mrr"
+	healthSortActiveUsers       = "active_users"
+	healthSortFailedPayments    = "failed_payments"
+	healthSortFailedPaymentRate = "failed_payment_rate"
+)
+
+// healthSorts lists the sort keys a tenant health query accepts
+var healthSorts = []string{
+	healthSortName,
+	healthSortLastActivity,
+	healthSortRevenue,
+	healthSortMRR,
+	healthSortActiveUsers,
+	healthSortFailedPayments,
+	healthSortFailedPaymentRate,
+}
+
+// GetPlatformTime returns the current time in UTC, the timezone of platform
+// analytics
+func (s *Service) GetPlatformTime() time.Time {
+	return s.clock.Now().UTC()
+}
+
+// GetPlatformOverview sums every tenant's rollups of the dates in the
+// query's range, each tenant's dates starting in its own timezone. MRR,
+// active users and tenant statuses are as of each tenant's latest rollup.
+func (s *Service) GetPlatformOverview(query models.PlatformQuery) (*models.PlatformOverview, error) {
+	revenue, err := s.repo.SumPlatformRevenue(query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+	mrr, err := s.repo.GetLatestMRR()
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := s.repo.GetPlatformTenants()
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.latestTenantRollups()
+	if err != nil {
+		return nil, err
+	}
+	signups, err := s.repo.CountUserSignups(query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &models.PlatformOverview{
+		From:            query.From,
+		To:              query.To,
+		Revenue:         []models.CurrencyRevenue{},
+		TenantsByStatus: map[string]int{models.TenantStatusActive: 0, models.TenantStatusInactive: 0, models.TenantStatusDeleted: 0},
+	}
+
+	index := make(map[string]int, len(revenue))
+	for _, total := range revenue {
+		index[total.Currency] = len(overview.Revenue)
+		overview.Revenue = append(overview.Revenue, total)
+	}
+	deleted := make(map[int]bool)
+	for _, tenant := range tenants {
+		status := s.tenantStatus(&tenant, latest[tenant.ID])
+		overview.TenantsByStatus[status]++
+		if status == models.TenantStatusDeleted {
+			deleted[tenant.ID] = true
+		} else {
+			overview.TotalTenants++
+			if rollup := latest[tenant.ID]; rollup != nil {
+				overview.ActiveUsers += rollup.ActiveUsers
+			}
+		}
+		if !tenant.CreatedAt.Before(query.From) && tenant.CreatedAt.Before(query.To) {
+			overview.NewTenants++
+		}
+	}
+	for _, amount := range mrr {
+		if deleted[amount.TenantID] || amount.Amount == 0 {
+			continue
+		}
+		i, ok := index[amount.Currency]
+		if !ok {
+			i = len(overview.Revenue)
+			index[amount.Currency] = i
+			overview.Revenue = append(overview.Revenue, models.CurrencyRevenue{Currency: amount.Currency})
+		}
+		overview.Revenue[i].MRR += amount.Amount
+	}
+	sort.Slice(overview.Revenue, func(i, j int) bool {
+		return overview.Revenue[i].Currency < overview.Revenue[j].Currency
+	})
+	for i := range overview.Revenue {
+		total := &overview.Revenue[i]
+		total.GMV = money.Round(total.GMV)
+		total.Refunds = money.Round(total.Refunds)
+		total.NetRevenue = money.Round(total.GMV - total.Refunds)
+		total.MRR = money.Round(total.MRR)
+		total.ARR = money.Round(total.MRR * 12)
+		overview.FailedPayments += total.FailedPayments
+	}
+	for _, count := range signups {
+		overview.NewUsers += count.Count
+	}
+	return overview, nil
+}
+
+// GetTopTenants ranks the tenants by their revenue in the query's currency
+// over its range, and by the growth of that revenue over the range of the
+// same length before it. Tenants without previous revenue have no growth
+// and aren't ranked by it; deleted tenants aren't ranked.
+func (s *Service) GetTopTenants(query models.PlatformQuery) (*models.TopTenants, error) {
+	days := int(query.To.Sub(query.From).Hours() / 24)
+	previousFrom := query.From.AddDate(0, 0, -days)
+	current, err := s.repo.SumTenantRevenue(query.From, query.To, query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.SumTenantRevenue(previousFrom, query.From, query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	tenants, err := s.repo.GetPlatformTenants()
+	if err != nil {
+		return nil, err
+	}
+
+	revenue := make(map[int]*models.TenantRevenue)
+	var ranked []*models.TenantRevenue
+	for _, tenant := range tenants {
+		if tenant.DeletedAt.Valid {
+			continue
+		}
+		item := &models.TenantRevenue{TenantID: tenant.ID, TenantName: tenant.TenantName}
+		revenue[tenant.ID] = item
+		ranked = append(ranked, item)
+	}
+	for _, total := range current {
+		if item := revenue[total.TenantID]; item != nil {
+			item.Revenue = money.Round(total.Revenue)
+		}
+	}
+	for _, total := range previous {
+		if item := revenue[total.TenantID]; item != nil {
+			item.PreviousRevenue = money.Round(total.Revenue)
+		}
+	}
+
+	top := &models.TopTenants{
+		From:      query.From,
+		To:        query.To,
+		Currency:  query.Currency,
+		ByRevenue: []models.TenantRevenue{},
+		ByGrowth:  []models.TenantRevenue{},
+	}
+	for _, item := range ranked {
+		if item.PreviousRevenue > 0 {
+			growth := percent(item.Revenue-item.PreviousRevenue, item.PreviousRevenue)
+			item.Growth = &growth
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Revenue > ranked[j].Revenue
+	})
+	for _, item := range ranked {
+		if len(top.ByRevenue) == query.Limit || item.Revenue <= 0 {
+			break
+		}
+		top.ByRevenue = append(top.ByRevenue, *item)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return compareRates(ranked[i].Growth, ranked[j].Growth, false) > 0
+	})
+	for _, item := range ranked {
+		if len(top.ByGrowth) == query.Limit || item.Growth == nil {
+			break
+		}
+		top.ByGrowth = append(top.ByGrowth, *item)
+	}
+	return top, nil
+}
+
+// GetSignupTrends counts the tenants created, by UTC date, and the users
+// who signed up, by their tenant's date, in every period of the query's
+// range
+func (s *Service) GetSignupTrends(query models.RevenueQuery) (*models.SignupTrends, error) {
+	tenants, err := s.repo.GetPlatformTenants()
+	if err != nil {
+		return nil, err
+	}
+	signups, err := s.repo.CountUserSignups(query.From, query.To)
+	if err != nil {
+		return nil, err
+	}
+
+	trends := &models.SignupTrends{Interval: query.Interval, Points: []models.SignupPoint{}}
+	index := make(map[time.Time]int)
+	for start := PeriodStart(query.From, query.Interval); start.Before(query.To); start = NextPeriod(start, query.Interval) {
+		index[start] = len(trends.Points)
+		trends.Points = append(trends.Points, models.SignupPoint{Start: start})
+	}
+
+	for _, tenant := range tenants {
+		created := tenant.CreatedAt.UTC()
+		if created.Before(query.From) || !created.Before(query.To) {
+			continue
+		}
+		if i, ok := index[PeriodStart(created, query.Interval)]; ok {
+			trends.Points[i].NewTenants++
+		}
+	}
+	for _, count := range signups {
+		date := time.Date(count.Date.Year(), count.Date.Month(), count.Date.Day(), 0, 0, 0, 0, time.UTC)
+		if i, ok := index[PeriodStart(date, query.Interval)]; ok {
+			trends.Points[i].NewUsers += count.Count
+		}
+	}
+	return trends, nil
+}
+
+// GetTenantHealth returns a page of the tenants that aren't deleted with
+// their status, last activity, active users and MRR as of their latest
+// rollup, and their revenue and payments over the query's range
+func (s *Service) GetTenantHealth(query models.TenantHealthQuery) (*models.TenantHealthPage, error) {
+	tenants, err := s.repo.GetPlatformTenants()
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.latestTenantRollups()
+	if err != nil {
+		return nil, err
+	}
+	totals, err := s.repo.SumTenantRevenue(query.From, query.To, query.Currency)
+	if err != nil {
+		return nil, err
+	}
+	mrr, err := s.repo.GetLatestMRR()
+	if err != nil {
+		return nil, err
+	}
+
+	health := make([]models.TenantHealth, 0, len(tenants))
+	index := make(map[int]int, len(tenants))
+	for _, tenant := range tenants {
+		status := s.tenantStatus(&tenant, latest[tenant.ID])
+		if status == models.TenantStatusDeleted || (query.Status != "" && status != query.Status) {
+			continue
+		}
+		item := models.TenantHealth{
+			TenantID:     tenant.ID,
+			TenantName:   tenant.TenantName,
+			TenantDomain: tenant.TenantDomain,
+			Status:       status,
+			CreatedAt:    tenant.CreatedAt,
+		}
+		if rollup := latest[tenant.ID]; rollup != nil {
+			item.LastActivityAt = rollup.LastActivityAt
+			item.ActiveUsers = rollup.ActiveUsers
+		}
+		index[tenant.ID] = len(health)
+		health = append(health, item)
+	}
+	for _, total := range totals {
+		if i, ok := index[total.TenantID]; ok {
+			item := &health[i]
+			item.Revenue = money.Round(total.Revenue)
+			item.Payments = total.Payments + total.FailedPayments
+			item.FailedPayments = total.FailedPayments
+			if item.Payments > 0 {
+				rate := percent(float64(item.FailedPayments), float64(item.Payments))
+				item.FailedPaymentRate = &rate
+			}
+		}
+	}
+	for _, amount := range mrr {
+		if i, ok := index[amount.TenantID]; ok && amount.Currency == query.Currency {
+			health[i].MRR = money.Round(amount.Amount)
+		}
+	}
+
+	sortHealth(health, query.Sort, query.Order == "asc")
+
+	page := &models.TenantHealthPage{
+		From:     query.From,
+		To:       query.To,
+		Currency: query.Currency,
+		Sort:     query.Sort,
+		Order:    query.Order,
+		Page:     query.Page,
+		PerPage:  query.PerPage,
+		Total:    len(health),
+		Tenants:  []models.TenantHealth{},
+	}
+	start := (query.Page - 1) * query.PerPage
+	if start < len(health) {
+		end := start + query.PerPage
+		if end > len(health) {
+			end = len(health)
+		}
+		page.Tenants = health[start:end]
+	}
+	return page, nil
+}
+
+// latestTenantRollups returns each tenant's latest tenant rollup by tenant
+func (s *Service) latestTenantRollups() (map[int]*models.DailyTenantRollup, error) {
+	rollups, err := s.repo.GetLatestTenantRollups()
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[int]*models.DailyTenantRollup, len(rollups))
+	for i := range rollups {
+		latest[rollups[i].TenantID] = &rollups[i]
+	}
+	return latest, nil
+}
+
+// tenantStatus tells whether the tenant is deleted, or active by the last
+// activity of its latest rollup
+func (s *Service) tenantStatus(tenant *models.Tenant, latest *models.DailyTenantRollup) string {
+	if tenant.DeletedAt.Valid {
+		return models.TenantStatusDeleted
+	}
+	activeSince := s.clock.Now().AddDate(0, 0, -models.TenantActiveDays)
+	if latest != nil && latest.LastActivityAt != nil && latest.LastActivityAt.After(activeSince) {
+		return models.TenantStatusActive
+	}
+	return models.TenantStatusInactive
+}
+
+// sortHealth orders tenants by the sort key. Tenants without a last
+// activity or failed payment rate come last either way; ties are broken by
+// ID.
+func sortHealth(health []models.TenantHealth, key string, ascending bool) {
+	sort.SliceStable(health, func(i, j int) bool {
+		a, b := &health[i], &health[j]
+		var cmp int
+		switch key {
+		case healthSortName:
+			cmp = strings.Compare(strings.ToLower(a.TenantName), strings.ToLower(b.TenantName))
+		case healthSortLastActivity:
+			cmp = compareRates(unixSeconds(a.LastActivityAt), unixSeconds(b.LastActivityAt), ascending)
+		case healthSortMRR:
+			cmp = compareFloats(a.MRR, b.MRR)
+		case healthSortActiveUsers:
+			cmp = compareFloats(float64(a.ActiveUsers), float64(b.ActiveUsers))
+		case healthSortFailedPayments:
+			cmp = compareFloats(float64(a.FailedPayments), float64(b.FailedPayments))
+		case healthSortFailedPaymentRate:
+			cmp = compareRates(a.FailedPaymentRate, b.FailedPaymentRate, ascending)
+		default:
+			cmp = compareFloats(a.Revenue, b.Revenue)
+		}
+		if cmp == 0 {
+			return a.TenantID < b.TenantID
+		}
+		if ascending {
+			return cmp < 0
+		}
+		return cmp > 0
+	})
+}
+
+// unixSeconds returns t as seconds since the epoch, nil if t is
+func unixSeconds(t *time.Time) *float64 {
+	if t == nil {
+		return nil
+	}
+	seconds := float64(t.Unix())
+	return &seconds
+}
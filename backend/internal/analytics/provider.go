@@ -1,7 +1,9 @@
 package analytics
 
 import (
+	"backend/core/clock"
 	"backend/internal/domain"
+	"backend/internal/rollup"
 	"github.com/google/wire"
 )
 
@@ -10,6 +12,8 @@ var ProviderSet = wire.NewSet(
 	NewController,
 	NewService,
 	NewRepository,
+	rollup.ProviderSet,
+	clock.New,
 
 	wire.Bind(new(domain.AnalyticsControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.AnalyticsService), new(*Service)),
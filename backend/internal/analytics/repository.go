@@ -2,6 +2,8 @@ package analytics
 
 import (
 	"backend/models"
+	"fmt"
+	"sort"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,50 +17,399 @@ func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{db: db}
 }
 
-func (r *Repository) GetProductCount(tenantID int) (int, error) {
-	var count int64
-	err := r.db.Model(&models.Product{}).Where("tenant_id = ? AND active = ?", tenantID, true).Count(&count).Error
-	return int(count), err
+func (r *Repository) GetTenant(tenantID int) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.First(&tenant, tenantID).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
 }
 
-func (r *Repository) GetActivePlanCount(tenantID int) (int, error) {
-	var count int64
-	err := r.db.Model(&models.Plan{}).
-		Joins("JOIN products ON plans.product_id = products.id").
-		Where("plans.tenant_id = ? AND products.active = ?", tenantID, true).
-		Count(&count).Error
-	return int(count), err
+// planScope restricts a query to rows whose plan, in column, is the filter's
+// plan or one of the filter's product's plans
+func planScope(filter models.AnalyticsFilter, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.PlanID != nil {
+			db = db.Where(column+" = ?", *filter.PlanID)
+		}
+		if filter.ProductID != nil {
+			db = db.Where(column+" IN (SELECT id FROM plans WHERE product_id = ?)", *filter.ProductID)
+		}
+		return db
+	}
 }
 
-func (r *Repository) GetTeamMemberCount(tenantID int) (int, error) {
-	var count int64
-	err := r.db.Model(&models.User{}).Where("tenant_id = ?", tenantID).Count(&count).Error
-	return int(count), err
+// purchaseScope restricts a query to rows whose purchase, in column, is on
+// the filter's plan or product
+func purchaseScope(filter models.AnalyticsFilter, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if filter.PlanID != nil {
+			db = db.Where(column+" IN (SELECT id FROM purchases WHERE plan_id = ?)", *filter.PlanID)
+		}
+		if filter.ProductID != nil {
+			db = db.Where(column+" IN (SELECT purchases.id FROM purchases JOIN plans ON plans.id = purchases.plan_id WHERE plans.product_id = ?)", *filter.ProductID)
+		}
+		return db
+	}
 }
 
-func (r *Repository) GetRevenueForPeriod(tenantID int, start, end time.Time) (float64, error) {
-	var totalRevenue float64
-	err := r.db.Model(&models.Purchase{}).
-		Where("tenant_id = ? AND status = ? AND purchased_at BETWEEN ? AND ?", tenantID, "active", start, end).
-		Select("COALESCE(SUM(amount), 0)").
-		Scan(&totalRevenue).Error
-	return totalRevenue, err
+// SumRevenueRollups totals the tenant's daily revenue rollups of the days
+// starting in [start, end)
+func (r *Repository) SumRevenueRollups(tenantID int, start, end time.Time) (*models.RevenueTotals, error) {
+	var totals models.RevenueTotals
+	err := r.db.Model(&models.DailyRevenueRollup{}).
+		Where("tenant_id = ? AND day >= ? AND day < ?", tenantID, start, end).
+		Select("COALESCE(SUM(revenue), 0) AS revenue, COALESCE(SUM(refunds), 0) AS refunds, COALESCE(SUM(tax), 0) AS tax").
+		Scan(&totals).Error
+	return &totals, err
 }
 
-func (r *Repository) GetActivePurchaseCount(tenantID int) (int, error) {
-	var count int64
+// GetRollupMRR sums the MRR of the tenant's revenue rollups of the day
+func (r *Repository) GetRollupMRR(tenantID int, day time.Time) (float64, error) {
+	var mrr float64
+	err := r.db.Model(&models.DailyRevenueRollup{}).
+		Where("tenant_id = ? AND day = ?", tenantID, day).
+		Select("COALESCE(SUM(mrr), 0)").
+		Scan(&mrr).Error
+	return mrr, err
+}
+
+// GetRevenueEntries returns the net amounts collected and refunded in the
+// period, oldest first, refunds as negative amounts
+func (r *Repository) GetRevenueEntries(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.RevenueEntry, error) {
+	var collected, refunded []models.RevenueEntry
+	err := r.db.Model(&models.Payment{}).
+		Scopes(purchaseScope(filter, "payments.purchase_id")).
+		Where("payments.tenant_id = ? AND payments.status IN ? AND payments.created_at >= ? AND payments.created_at < ?",
+			tenantID, models.CollectedPaymentStatuses, start, end).
+		Select("payments.user_id, payments.currency, COALESCE(payments.net_amount, payments.amount) AS amount, payments.created_at AS occurred_at").
+		Scan(&collected).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Refund{}).
+		Scopes(purchaseScope(filter, "refunds.purchase_id")).
+		Where("refunds.tenant_id = ? AND refunds.created_at >= ? AND refunds.created_at < ?", tenantID, start, end).
+		Select("refunds.user_id, refunds.currency, -refunds.net_amount AS amount, refunds.created_at AS occurred_at").
+		Scan(&refunded).Error
+	if err != nil {
+		return nil, err
+	}
+
+	entries := append(collected, refunded...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].OccurredAt.Before(entries[j].OccurredAt)
+	})
+	return entries, nil
+}
+
+// GetMRRAt returns the latest MRR movement of each purchase before at, which
+// holds the purchase's MRR at that time
+func (r *Repository) GetMRRAt(tenantID int, at time.Time) ([]models.MRRMovement, error) {
+	var movements []models.MRRMovement
+	err := r.db.Select("DISTINCT ON (purchase_id) *").
+		Where("tenant_id = ? AND occurred_at < ?", tenantID, at).
+		Order("purchase_id, occurred_at DESC, id DESC").
+		Find(&movements).Error
+	return movements, err
+}
+
+// GetMRRMovements returns the MRR movements in the period, oldest first
+func (r *Repository) GetMRRMovements(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.MRRMovement, error) {
+	var movements []models.MRRMovement
+	err := r.db.Scopes(planScope(filter, "plan_id")).
+		Where("tenant_id = ? AND occurred_at >= ? AND occurred_at < ?", tenantID, start, end).
+		Order("occurred_at, id").
+		Find(&movements).Error
+	return movements, err
+}
+
+// GetCohortMembers returns the users who joined a cohort in the period: by
+// registering, or by making their first completed purchase matching the
+// filter. Deleted users still count towards the cohort they joined.
+func (r *Repository) GetCohortMembers(tenantID int, by string, start, end time.Time, filter models.AnalyticsFilter) ([]models.CohortMember, error) {
+	var members []models.CohortMember
+	if by == models.CohortBySignup {
+		err := r.db.Unscoped().Model(&models.User{}).
+			Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, start, end).
+			Select("id AS user_id, created_at AS joined_at").
+			Scan(&members).Error
+		return members, err
+	}
+
 	err := r.db.Model(&models.Purchase{}).
-		Where("tenant_id = ? AND status = ?", tenantID, "active").
-		Count(&count).Error
-	return int(count), err
+		Scopes(planScope(filter, "plan_id")).
+		Where("tenant_id = ? AND status <> ?", tenantID, models.PurchaseStatusIncomplete).
+		Select("user_id, MIN(created_at) AS joined_at").
+		Group("user_id").
+		Having("MIN(created_at) >= ? AND MIN(created_at) < ?", start, end).
+		Scan(&members).Error
+	return members, err
+}
+
+// itemKey is the column of the joined plans row that identifies a product
+// or plan
+func itemKey(by string) string {
+	if by == models.PerformanceByProduct {
+		return "plans.product_id"
+	}
+	return "plans.id"
+}
+
+// GetPerformanceItems returns the tenant's products or plans, including
+// deleted ones that still have purchases
+func (r *Repository) GetPerformanceItems(tenantID int, by string) ([]models.ItemPerformance, error) {
+	var items []models.ItemPerformance
+	if by == models.PerformanceByProduct {
+		err := r.db.Unscoped().Model(&models.Product{}).
+			Where("tenant_id = ?", tenantID).
+			Where("deleted_at IS NULL OR EXISTS (SELECT 1 FROM purchases JOIN plans ON plans.id = purchases.plan_id WHERE plans.product_id = products.id)").
+			Select("id, name, archived_at IS NOT NULL AS archived, deleted_at IS NOT NULL AS deleted").
+			Order("id").
+			Scan(&items).Error
+		return items, err
+	}
+
+	err := r.db.Unscoped().Model(&models.Plan{}).
+		Where("tenant_id = ?", tenantID).
+		Where("deleted_at IS NULL OR EXISTS (SELECT 1 FROM purchases WHERE purchases.plan_id = plans.id)").
+		Select("id, name, product_id, archived_at IS NOT NULL AS archived, deleted_at IS NOT NULL AS deleted").
+		Order("id").
+		Scan(&items).Error
+	return items, err
 }
 
-func (r *Repository) GetRecentActivity(tenantID int, limit int) ([]models.Activity, error) {
+// SumItemRevenue totals the net payments less refunds in the currency in the
+// period per product or plan, with the number of users who paid
+func (r *Repository) SumItemRevenue(tenantID int, by string, start, end time.Time, currency string) ([]models.ItemTotal, error) {
+	var collected, refunded []models.ItemTotal
+	err := r.db.Model(&models.Payment{}).
+		Joins("JOIN purchases ON purchases.id = payments.purchase_id").
+		Joins("JOIN plans ON plans.id = purchases.plan_id").
+		Where("payments.tenant_id = ? AND payments.status IN ? AND payments.currency = ? AND payments.created_at >= ? AND payments.created_at < ?",
+			tenantID, models.CollectedPaymentStatuses, currency, start, end).
+		Select(itemKey(by) + " AS id, COUNT(DISTINCT payments.user_id) AS count, COALESCE(SUM(COALESCE(payments.net_amount, payments.amount)), 0) AS amount").
+		Group(itemKey(by)).
+		Scan(&collected).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Refund{}).
+		Joins("JOIN purchases ON purchases.id = refunds.purchase_id").
+		Joins("JOIN plans ON plans.id = purchases.plan_id").
+		Where("refunds.tenant_id = ? AND refunds.currency = ? AND refunds.created_at >= ? AND refunds.created_at < ?",
+			tenantID, currency, start, end).
+		Select(itemKey(by) + " AS id, COALESCE(SUM(refunds.net_amount), 0) AS amount").
+		Group(itemKey(by)).
+		Scan(&refunded).Error
+	if err != nil {
+		return nil, err
+	}
+
+	index := make(map[int]int, len(collected))
+	for i, total := range collected {
+		index[total.ID] = i
+	}
+	for _, refund := range refunded {
+		if i, ok := index[refund.ID]; ok {
+			collected[i].Amount -= refund.Amount
+			continue
+		}
+		collected = append(collected, models.ItemTotal{ID: refund.ID, Amount: -refund.Amount})
+	}
+	return collected, nil
+}
+
+// CountItemSubscribers counts the purchases paying for a recurring plan at
+// the time per product or plan
+func (r *Repository) CountItemSubscribers(tenantID int, by string, at time.Time) ([]models.ItemTotal, error) {
+	latest := r.db.Model(&models.MRRMovement{}).
+		Select("DISTINCT ON (purchase_id) plan_id, mrr").
+		Where("tenant_id = ? AND occurred_at < ?", tenantID, at).
+		Order("purchase_id, occurred_at DESC, id DESC")
+
+	var totals []models.ItemTotal
+	err := r.db.Table("(?) AS latest", latest).
+		Joins("JOIN plans ON plans.id = latest.plan_id").
+		Where("latest.mrr > 0").
+		Select(itemKey(by) + " AS id, COUNT(*) AS count").
+		Group(itemKey(by)).
+		Scan(&totals).Error
+	return totals, err
+}
+
+// CountItemChurn counts the purchases that stopped paying in the period per
+// product or plan
+func (r *Repository) CountItemChurn(tenantID int, by string, start, end time.Time) ([]models.ItemTotal, error) {
+	var totals []models.ItemTotal
+	err := r.db.Model(&models.MRRMovement{}).
+		Joins("JOIN plans ON plans.id = mrr_movements.plan_id").
+		Where("mrr_movements.tenant_id = ? AND mrr_movements.type = ? AND mrr_movements.occurred_at >= ? AND mrr_movements.occurred_at < ?",
+			tenantID, models.MRRMovementChurn, start, end).
+		Select(itemKey(by) + " AS id, COUNT(DISTINCT mrr_movements.purchase_id) AS count").
+		Group(itemKey(by)).
+		Scan(&totals).Error
+	return totals, err
+}
+
+// CountItemTrials counts the trials that ended in the period per product or
+// plan, and how many of them went on to a paid period
+func (r *Repository) CountItemTrials(tenantID int, by string, start, end time.Time) ([]models.ItemTrials, error) {
+	var trials []models.ItemTrials
+	err := r.db.Model(&models.PurchaseTransition{}).
+		Joins("JOIN purchases ON purchases.id = purchase_transitions.purchase_id").
+		Joins("JOIN plans ON plans.id = purchases.plan_id").
+		Where("purchase_transitions.tenant_id = ? AND purchase_transitions.from_status = ? AND purchase_transitions.to_status <> ?",
+			tenantID, models.PurchaseStatusTrialing, models.PurchaseStatusTrialing).
+		Where("purchase_transitions.created_at >= ? AND purchase_transitions.created_at < ?", start, end).
+		Select(itemKey(by)+" AS id, COUNT(*) AS ended, SUM(CASE WHEN purchase_transitions.to_status = ? THEN 1 ELSE 0 END) AS converted",
+			models.PurchaseStatusActive).
+		Group(itemKey(by)).
+		Scan(&trials).Error
+	return trials, err
+}
+
+// ListActivities returns the tenant's activities matching the query, newest
+// first, with their users
+func (r *Repository) ListActivities(tenantID int, query models.ActivityQuery) ([]models.Activity, error) {
+	db := r.db.Scopes(activityScope(tenantID, query))
+	if query.Before > 0 {
+		db = db.Where("id < ?", query.Before)
+	}
+
 	var activities []models.Activity
-	err := r.db.Where("tenant_id = ?", tenantID).
-		Preload("User").
-		Order("created_at DESC").
-		Limit(limit).
+	err := db.Preload("User").
+		Order("id DESC").
+		Limit(query.Limit).
 		Find(&activities).Error
 	return activities, err
 }
+
+// CountActivities counts the tenant's activities matching the query's
+// filters, ignoring its cursor and limit
+func (r *Repository) CountActivities(tenantID int, query models.ActivityQuery) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Activity{}).Scopes(activityScope(tenantID, query)).Count(&count).Error
+	return count, err
+}
+
+// activityScope restricts a query to the tenant's activities matching the
+// query's filters
+func activityScope(tenantID int, query models.ActivityQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("tenant_id = ?", tenantID)
+		if len(query.Types) > 0 {
+			db = db.Where("type IN ?", query.Types)
+		}
+		if query.EntityType != "" {
+			db = db.Where("entity_type = ?", query.EntityType)
+		}
+		if query.EntityID != nil {
+			db = db.Where("entity_id = ?", *query.EntityID)
+		}
+		if query.UserID != nil {
+			db = db.Where("user_id = ?", *query.UserID)
+		}
+		if !query.From.IsZero() {
+			db = db.Where("created_at >= ?", query.From)
+		}
+		if !query.To.IsZero() {
+			db = db.Where("created_at < ?", query.To)
+		}
+		return db
+	}
+}
+
+// rollupDate is the calendar date, in its tenant's timezone, of the day a
+// rollup joined with its tenant covers
+const rollupDate = "(%s.day AT TIME ZONE COALESCE(NULLIF(tenants.timezone, ''), 'UTC'))::date"
+
+// rollupDateScope restricts a query of table, a rollup table joined with
+// tenants, to the rollups of the dates in [start, end). Tenants' days start
+// at most a day away from UTC, which bounds day for its index.
+func rollupDateScope(table string, start, end time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		date := fmt.Sprintf(rollupDate, table)
+		return db.Where(table+".day >= ? AND "+table+".day < ?", start.AddDate(0, 0, -1), end.AddDate(0, 0, 1)).
+			Where(date+" >= ? AND "+date+" < ?", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+}
+
+// GetPlatformTenants returns all tenants, deleted ones too
+func (r *Repository) GetPlatformTenants() ([]models.Tenant, error) {
+	var tenants []models.Tenant
+	err := r.db.Unscoped().Order("id").Find(&tenants).Error
+	return tenants, err
+}
+
+// SumPlatformRevenue totals every tenant's revenue rollups of the dates in
+// [start, end) by currency
+func (r *Repository) SumPlatformRevenue(start, end time.Time) ([]models.CurrencyRevenue, error) {
+	var totals []models.CurrencyRevenue
+	err := r.db.Model(&models.DailyRevenueRollup{}).
+		Joins("JOIN tenants ON tenants.id = daily_revenue_rollups.tenant_id").
+		Scopes(rollupDateScope("daily_revenue_rollups", start, end)).
+		Select("daily_revenue_rollups.currency, COALESCE(SUM(payments), 0) AS payments, " +
+			"COALESCE(SUM(failed_payments), 0) AS failed_payments, COALESCE(SUM(revenue), 0) AS gmv, COALESCE(SUM(refunds), 0) AS refunds").
+		Group("daily_revenue_rollups.currency").
+		Order("daily_revenue_rollups.currency").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// SumTenantRevenue totals each tenant's revenue rollups of the dates in
+// [start, end): revenue less refunds in currency, and payments in any
+func (r *Repository) SumTenantRevenue(start, end time.Time, currency string) ([]models.TenantTotal, error) {
+	var totals []models.TenantTotal
+	err := r.db.Model(&models.DailyRevenueRollup{}).
+		Joins("JOIN tenants ON tenants.id = daily_revenue_rollups.tenant_id").
+		Scopes(rollupDateScope("daily_revenue_rollups", start, end)).
+		Select("daily_revenue_rollups.tenant_id, "+
+			"COALESCE(SUM(CASE WHEN daily_revenue_rollups.currency = ? THEN revenue - refunds ELSE 0 END), 0) AS revenue, "+
+			"COALESCE(SUM(payments), 0) AS payments, COALESCE(SUM(failed_payments), 0) AS failed_payments", currency).
+		Group("daily_revenue_rollups.tenant_id").
+		Scan(&totals).Error
+	return totals, err
+}
+
+// GetLatestMRR returns each tenant's MRR by currency as of its latest
+// revenue rollup
+func (r *Repository) GetLatestMRR() ([]models.TenantAmount, error) {
+	latest := r.db.Model(&models.DailyRevenueRollup{}).
+		Select("tenant_id, MAX(day) AS day").
+		Group("tenant_id")
+	var amounts []models.TenantAmount
+	err := r.db.Model(&models.DailyRevenueRollup{}).
+		Joins("JOIN (?) AS latest ON latest.tenant_id = daily_revenue_rollups.tenant_id AND latest.day = daily_revenue_rollups.day", latest).
+		Select("daily_revenue_rollups.tenant_id, daily_revenue_rollups.currency, COALESCE(SUM(mrr), 0) AS amount").
+		Group("daily_revenue_rollups.tenant_id, daily_revenue_rollups.currency").
+		Scan(&amounts).Error
+	return amounts, err
+}
+
+// GetLatestTenantRollups returns each tenant's latest tenant rollup
+func (r *Repository) GetLatestTenantRollups() ([]models.DailyTenantRollup, error) {
+	var rollups []models.DailyTenantRollup
+	err := r.db.Model(&models.DailyTenantRollup{}).
+		Select("DISTINCT ON (tenant_id) *").
+		Order("tenant_id, day DESC").
+		Find(&rollups).Error
+	return rollups, err
+}
+
+// CountUserSignups sums the new users of every tenant's rollups of the dates
+// in [start, end) by date
+func (r *Repository) CountUserSignups(start, end time.Time) ([]models.DateCount, error) {
+	date := fmt.Sprintf(rollupDate, "daily_tenant_rollups")
+	var counts []models.DateCount
+	err := r.db.Model(&models.DailyTenantRollup{}).
+		Joins("JOIN tenants ON tenants.id = daily_tenant_rollups.tenant_id").
+		Scopes(rollupDateScope("daily_tenant_rollups", start, end)).
+		Select(date + " AS date, COALESCE(SUM(new_users), 0) AS count").
+		Group(date).
+		Order(date).
+		Scan(&counts).Error
+	return counts, err
+}
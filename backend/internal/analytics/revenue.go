@@ -0,0 +1,200 @@
+package analytics
+
+import (
+	"backend/core/money"
+	"backend/models"
+	"math"
+	"sort"
+	"time"
+)
+
+// GetRevenueSeries returns revenue, MRR and churn per period of the query's
+// range, one series per currency. Periods start in the location of the
+// query's From. MRR comes from replaying the MRR ledger from the start of the
+// range.
+func (s *Service) GetRevenueSeries(tenantID int, query models.RevenueQuery) (*models.RevenueAnalytics, error) {
+	opening, err := s.repo.GetMRRAt(tenantID, query.From)
+	if err != nil {
+		return nil, err
+	}
+	movements, err := s.repo.GetMRRMovements(tenantID, query.From, query.To, models.AnalyticsFilter{})
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.GetRevenueEntries(tenantID, query.From, query.To, models.AnalyticsFilter{})
+	if err != nil {
+		return nil, err
+	}
+
+	ledgers := make(map[string]*mrrLedger)
+	ledger := func(currency string) *mrrLedger {
+		if ledgers[currency] == nil {
+			ledgers[currency] = newMRRLedger()
+		}
+		return ledgers[currency]
+	}
+	for _, movement := range opening {
+		ledger(movement.Currency).apply(movement)
+	}
+	for _, movement := range movements {
+		ledger(movement.Currency)
+	}
+	for _, entry := range entries {
+		ledger(entry.Currency)
+	}
+
+	currencies := make([]string, 0, len(ledgers))
+	for currency := range ledgers {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	result := &models.RevenueAnalytics{
+		From:     query.From,
+		To:       query.To,
+		Interval: query.Interval,
+		Timezone: query.From.Location().String(),
+		Series:   make([]models.RevenueSeries, len(currencies)),
+	}
+	for i, currency := range currencies {
+		result.Series[i].Currency = currency
+	}
+
+	nextMovement, nextEntry := 0, 0
+	for start := query.From; start.Before(query.To); {
+		end := NextPeriod(PeriodStart(start, query.Interval), query.Interval)
+		if end.After(query.To) {
+			end = query.To
+		}
+
+		points := make(map[string]*models.RevenuePoint, len(ledgers))
+		openings := make(map[string]ledgerSnapshot, len(ledgers))
+		for currency, l := range ledgers {
+			points[currency] = &models.RevenuePoint{PeriodStart: start, PeriodEnd: end}
+			openings[currency] = l.snapshot()
+		}
+
+		for ; nextMovement < len(movements) && movements[nextMovement].OccurredAt.Before(end); nextMovement++ {
+			movement := movements[nextMovement]
+			point := points[movement.Currency]
+			switch movement.Type {
+			case models.MRRMovementNew:
+				point.NewMRR += movement.Amount
+			case models.MRRMovementExpansion:
+				point.ExpansionMRR += movement.Amount
+			case models.MRRMovementContraction:
+				point.ContractionMRR -= movement.Amount
+			case models.MRRMovementChurn:
+				point.ChurnedMRR -= movement.Amount
+			}
+			ledgers[movement.Currency].apply(movement)
+		}
+		for ; nextEntry < len(entries) && entries[nextEntry].OccurredAt.Before(end); nextEntry++ {
+			points[entries[nextEntry].Currency].Revenue += entries[nextEntry].Amount
+		}
+
+		for i, currency := range currencies {
+			point := points[currency]
+			closePoint(point, openings[currency], ledgers[currency])
+			result.Series[i].Points = append(result.Series[i].Points, *point)
+		}
+		start = end
+	}
+
+	return result, nil
+}
+
+// closePoint fills in the point's totals as at the end of its period and the
+// churn relative to its start
+func closePoint(point *models.RevenuePoint, opening ledgerSnapshot, l *mrrLedger) {
+	closing := l.snapshot()
+	for userID := range closing.payingUsers {
+		if !opening.payingUsers[userID] {
+			point.NewCustomers++
+		}
+	}
+	for userID := range opening.payingUsers {
+		if !closing.payingUsers[userID] {
+			point.ChurnedCustomers++
+		}
+	}
+
+	point.Revenue = money.Round(point.Revenue)
+	point.NewMRR = money.Round(point.NewMRR)
+	point.ExpansionMRR = money.Round(point.ExpansionMRR)
+	point.ContractionMRR = money.Round(point.ContractionMRR)
+	point.ChurnedMRR = money.Round(point.ChurnedMRR)
+	point.NetNewMRR = money.Round(point.NewMRR + point.ExpansionMRR - point.ContractionMRR - point.ChurnedMRR)
+	point.MRR = money.Round(closing.mrr)
+	point.ARR = money.Round(closing.mrr * 12)
+	point.Customers = len(closing.payingUsers)
+
+	if len(opening.payingUsers) > 0 {
+		rate := percent(float64(point.ChurnedCustomers), float64(len(opening.payingUsers)))
+		point.CustomerChurnRate = &rate
+	}
+	if opening.mrr > 0 {
+		rate := percent(point.ChurnedMRR+point.ContractionMRR, opening.mrr)
+		point.RevenueChurnRate = &rate
+	}
+}
+
+// mrrLedger tracks the MRR of each purchase in one currency
+type mrrLedger struct {
+	purchases map[int]models.MRRMovement // latest movement per purchase
+}
+
+// ledgerSnapshot is a ledger's MRR and paying users at a point in time
+type ledgerSnapshot struct {
+	mrr         float64
+	payingUsers map[int]bool
+}
+
+func newMRRLedger() *mrrLedger {
+	return &mrrLedger{purchases: make(map[int]models.MRRMovement)}
+}
+
+func (l *mrrLedger) apply(movement models.MRRMovement) {
+	if movement.MRR == 0 {
+		delete(l.purchases, movement.PurchaseID)
+		return
+	}
+	l.purchases[movement.PurchaseID] = movement
+}
+
+func (l *mrrLedger) snapshot() ledgerSnapshot {
+	snapshot := ledgerSnapshot{payingUsers: make(map[int]bool)}
+	for _, movement := range l.purchases {
+		snapshot.mrr += movement.MRR
+		snapshot.payingUsers[movement.UserID] = true
+	}
+	return snapshot
+}
+
+// PeriodStart returns the start of the day, ISO week or month containing t
+func PeriodStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch interval {
+	case models.RevenueIntervalWeek:
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case models.RevenueIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	}
+	return day
+}
+
+// NextPeriod returns the start of the period after the one starting at start
+func NextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case models.RevenueIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case models.RevenueIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// percent returns part as a percentage of whole, to two decimals
+func percent(part, whole float64) float64 {
+	return math.Round(part/whole*10000) / 100
+}
@@ -1,78 +1,128 @@
 package analytics
 
 import (
+	"backend/core/clock"
+	"backend/core/money"
 	"backend/internal/domain"
 	"backend/models"
+	"strconv"
 	"time"
 )
 
 type Service struct {
-	repo domain.AnalyticsRepository
+	repo    domain.AnalyticsRepository
+	rollups domain.RollupService
+	clock   clock.Clock
 }
 
-func NewService(repo domain.AnalyticsRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo domain.AnalyticsRepository, rollups domain.RollupService, clock clock.Clock) *Service {
+	return &Service{repo: repo, rollups: rollups, clock: clock}
 }
 
-func (s *Service) GetDashboardMetrics(tenantID int) (*models.DashboardMetrics, error) {
-	// Get current month metrics
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	startOfLastMonth := startOfMonth.AddDate(0, -1, 0)
-
-	// Total products
-	totalProducts, err := s.repo.GetProductCount(tenantID)
-	if err != nil {
-		return nil, err
+// GetLocalTime returns the current time in timezone, an IANA name, or in the
+// tenant's timezone when it's empty
+func (s *Service) GetLocalTime(tenantID int, timezone string) (time.Time, error) {
+	var location *time.Location
+	if timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil || timezone == "Local" {
+			return time.Time{}, domain.ErrInvalidTimezone
+		}
+		location = loaded
+	} else {
+		tenant, err := s.repo.GetTenant(tenantID)
+		if err != nil {
+			return time.Time{}, err
+		}
+		location = tenant.Location()
 	}
+	return s.clock.Now().In(location), nil
+}
 
-	// Active plans
-	activePlans, err := s.repo.GetActivePlanCount(tenantID)
+// GetDashboardMetrics reads this month's metrics from the daily rollups,
+// compared with last month's. Months start in the tenant's timezone.
+func (s *Service) GetDashboardMetrics(tenantID int) (*models.DashboardMetrics, error) {
+	now, err := s.GetLocalTime(tenantID, "")
 	if err != nil {
 		return nil, err
 	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return s.GetPeriodMetrics(tenantID, startOfMonth, today.AddDate(0, 0, 1), startOfMonth.AddDate(0, -1, 0))
+}
 
-	// Team members
-	teamMembers, err := s.repo.GetTeamMemberCount(tenantID)
+// GetPeriodMetrics reads the metrics of the days from start until end from
+// the daily rollups, with the revenue growth over the days from
+// previousStart until start. Counts and MRR are as of the rollup of the last
+// day, which is computed on first use. Days start in the location of start
+// and end, which should be the tenant's timezone.
+func (s *Service) GetPeriodMetrics(tenantID int, start, end, previousStart time.Time) (*models.DashboardMetrics, error) {
+	lastDay, err := s.rollups.GetTenantDay(tenantID, end.AddDate(0, 0, -1))
 	if err != nil {
 		return nil, err
 	}
 
-	// Revenue metrics
-	currentRevenue, err := s.repo.GetRevenueForPeriod(tenantID, startOfMonth, now)
+	current, err := s.repo.SumRevenueRollups(tenantID, start, end)
 	if err != nil {
 		return nil, err
 	}
-
-	lastMonthRevenue, err := s.repo.GetRevenueForPeriod(tenantID, startOfLastMonth, startOfMonth)
+	previous, err := s.repo.SumRevenueRollups(tenantID, previousStart, start)
 	if err != nil {
 		return nil, err
 	}
+	currentRevenue := current.Revenue - current.Refunds
+	previousRevenue := previous.Revenue - previous.Refunds
 
-	// Calculate revenue growth
-	var revenueGrowth float64
-	if lastMonthRevenue > 0 {
-		revenueGrowth = ((currentRevenue - lastMonthRevenue) / lastMonthRevenue) * 100
-	} else if currentRevenue > 0 {
-		revenueGrowth = 100 // 100% growth from 0
+	// Growth from nothing isn't a percentage, so it's left out
+	var revenueGrowth *float64
+	if previousRevenue > 0 {
+		growth := percent(currentRevenue-previousRevenue, previousRevenue)
+		revenueGrowth = &growth
 	}
 
-	// Active purchases
-	activePurchases, err := s.repo.GetActivePurchaseCount(tenantID)
+	// Recurring revenue, with yearly plans normalized to a month
+	mrr, err := s.repo.GetRollupMRR(tenantID, lastDay.Day)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.DashboardMetrics{
-		TotalProducts:   totalProducts,
-		ActivePlans:     activePlans,
-		TeamMembers:     teamMembers,
+		TotalProducts:   lastDay.Products,
+		ActivePlans:     lastDay.ActivePlans,
+		TeamMembers:     lastDay.TeamMembers,
 		RevenueGrowth:   revenueGrowth,
-		TotalRevenue:    currentRevenue,
-		ActivePurchases: activePurchases,
+		TotalRevenue:    money.Round(currentRevenue),
+		TaxCollected:    money.Round(current.Tax),
+		RefundedAmount:  money.Round(current.Refunds),
+		ActivePurchases: lastDay.ActivePurchases,
+		ActiveUsers:     lastDay.ActiveUsers,
+		MRR:             money.Round(mrr),
+		ARR:             money.Round(mrr * 12),
 	}, nil
 }
 
-func (s *Service) GetRecentActivity(tenantID int) ([]models.Activity, error) {
-	return s.repo.GetRecentActivity(tenantID, 10) // Get last 10 activities
+// GetActivities returns a page of the tenant's activities, newest first.
+// One more activity than the page holds is loaded to tell whether there's a
+// next page.
+func (s *Service) GetActivities(tenantID int, query models.ActivityQuery) (*models.ActivityPage, error) {
+	limit := query.Limit
+	query.Limit++
+	activities, err := s.repo.ListActivities(tenantID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.ActivityPage{Activities: activities}
+	if len(activities) > limit {
+		page.Activities = activities[:limit]
+		page.NextCursor = strconv.Itoa(page.Activities[limit-1].ID)
+	}
+	return page, nil
+}
+
+// CountActivities counts the tenant's activities matching the query's
+// filters
+func (s *Service) CountActivities(tenantID int, query models.ActivityQuery) (int, error) {
+	count, err := s.repo.CountActivities(tenantID, query)
+	return int(count), err
 }
@@ -0,0 +1,248 @@
+package analytics
+
+import (
+	"backend/core/clock"
+	"backend/internal/domain"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return location
+}
+
+// metricsRepository records the ranges the dashboard metrics are read over
+type metricsRepository struct {
+	domain.AnalyticsRepository
+	tenant  models.Tenant
+	ranges  [][2]time.Time
+	mrrDay  time.Time
+	entries []models.RevenueEntry
+}
+
+func (r *metricsRepository) GetTenant(tenantID int) (*models.Tenant, error) {
+	return &r.tenant, nil
+}
+
+func (r *metricsRepository) SumRevenueRollups(tenantID int, start, end time.Time) (*models.RevenueTotals, error) {
+	r.ranges = append(r.ranges, [2]time.Time{start, end})
+	return &models.RevenueTotals{Revenue: 100}, nil
+}
+
+func (r *metricsRepository) GetRollupMRR(tenantID int, day time.Time) (float64, error) {
+	r.mrrDay = day
+	return 10, nil
+}
+
+func (r *metricsRepository) GetMRRAt(tenantID int, at time.Time) ([]models.MRRMovement, error) {
+	return nil, nil
+}
+
+func (r *metricsRepository) GetMRRMovements(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.MRRMovement, error) {
+	return nil, nil
+}
+
+func (r *metricsRepository) GetRevenueEntries(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.RevenueEntry, error) {
+	return r.entries, nil
+}
+
+// dayRollups returns the rollup of the tenant's day containing at
+type dayRollups struct {
+	domain.RollupService
+	location *time.Location
+}
+
+func (r dayRollups) GetTenantDay(tenantID int, at time.Time) (*models.DailyTenantRollup, error) {
+	at = at.In(r.location)
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, r.location)
+	return &models.DailyTenantRollup{TenantID: tenantID, Day: day}, nil
+}
+
+// Months and days start at midnight in the tenant's timezone, however long
+// DST makes them
+func TestGetDashboardMetricsTenantBoundaries(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	sydney := mustLoad(t, "Australia/Sydney")
+
+	tests := []struct {
+		name      string
+		timezone  string
+		now       time.Time
+		today     time.Time
+		month     [2]time.Time
+		lastMonth [2]time.Time
+	}{
+		{
+			"new york 23h day", "America/New_York", time.Date(2026, 3, 8, 17, 0, 0, 0, time.UTC),
+			time.Date(2026, 3, 8, 0, 0, 0, 0, newYork),
+			[2]time.Time{time.Date(2026, 3, 1, 5, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 2, 1, 5, 0, 0, 0, time.UTC), time.Date(2026, 3, 1, 5, 0, 0, 0, time.UTC)},
+		},
+		{
+			"new york 25h day after the repeated hour", "America/New_York", time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC),
+			time.Date(2026, 11, 1, 0, 0, 0, 0, newYork),
+			[2]time.Time{time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 11, 2, 5, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 10, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC)},
+		},
+		{
+			"new york last evening of the month, past midnight utc", "America/New_York", time.Date(2026, 11, 1, 3, 30, 0, 0, time.UTC),
+			time.Date(2026, 10, 31, 0, 0, 0, 0, newYork),
+			[2]time.Time{time.Date(2026, 10, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 9, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 4, 0, 0, 0, time.UTC)},
+		},
+		{
+			"sydney first day of the month, before midnight utc", "Australia/Sydney", time.Date(2026, 3, 31, 13, 30, 0, 0, time.UTC),
+			time.Date(2026, 4, 1, 0, 0, 0, 0, sydney),
+			[2]time.Time{time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 13, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 2, 28, 13, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC)},
+		},
+		{
+			"sydney 25h day", "Australia/Sydney", time.Date(2026, 4, 5, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 4, 5, 0, 0, 0, 0, sydney),
+			[2]time.Time{time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC), time.Date(2026, 4, 5, 14, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 2, 28, 13, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC)},
+		},
+		{
+			"sydney 23h day", "Australia/Sydney", time.Date(2026, 10, 4, 2, 0, 0, 0, time.UTC),
+			time.Date(2026, 10, 4, 0, 0, 0, 0, sydney),
+			[2]time.Time{time.Date(2026, 9, 30, 14, 0, 0, 0, time.UTC), time.Date(2026, 10, 4, 13, 0, 0, 0, time.UTC)},
+			[2]time.Time{time.Date(2026, 8, 31, 14, 0, 0, 0, time.UTC), time.Date(2026, 9, 30, 14, 0, 0, 0, time.UTC)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &metricsRepository{tenant: models.Tenant{ID: 1, Timezone: tt.timezone}}
+			location := mustLoad(t, tt.timezone)
+			service := NewService(repo, dayRollups{location: location}, clock.Fixed(tt.now))
+
+			metrics, err := service.GetDashboardMetrics(1)
+			if err != nil {
+				t.Fatalf("GetDashboardMetrics: %v", err)
+			}
+			if len(repo.ranges) != 2 {
+				t.Fatalf("read %d revenue ranges, want 2", len(repo.ranges))
+			}
+			for i, want := range [][2]time.Time{tt.month, tt.lastMonth} {
+				got := repo.ranges[i]
+				if !got[0].Equal(want[0]) || !got[1].Equal(want[1]) {
+					t.Errorf("range %d is [%s, %s), want [%s, %s)", i, got[0].UTC(), got[1].UTC(), want[0], want[1])
+				}
+			}
+			if !repo.mrrDay.Equal(tt.today) {
+				t.Errorf("MRR read as of %s, want %s", repo.mrrDay, tt.today)
+			}
+			if metrics.MRR != 10 || metrics.ARR != 120 {
+				t.Errorf("MRR %v and ARR %v, want 10 and 120", metrics.MRR, metrics.ARR)
+			}
+		})
+	}
+}
+
+// Revenue falls in the period of its local day, even when the day is 23 or
+// 25 hours long
+func TestGetRevenueSeriesBucketsByLocalPeriod(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	sydney := mustLoad(t, "Australia/Sydney")
+
+	type period struct {
+		start, end time.Time
+		revenue    float64
+	}
+	tests := []struct {
+		name    string
+		query   models.RevenueQuery
+		entries []time.Time
+		want    []period
+	}{
+		{
+			"new york days across spring forward",
+			models.RevenueQuery{From: time.Date(2026, 3, 7, 0, 0, 0, 0, newYork), To: time.Date(2026, 3, 10, 0, 0, 0, 0, newYork), Interval: models.RevenueIntervalDay},
+			[]time.Time{
+				time.Date(2026, 3, 8, 4, 59, 0, 0, time.UTC), // 23:59 EST on the 7th
+				time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC),  // midnight EST on the 8th
+				time.Date(2026, 3, 9, 3, 59, 0, 0, time.UTC), // 23:59 EDT on the 8th
+				time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC),  // midnight EDT on the 9th
+			},
+			[]period{
+				{time.Date(2026, 3, 7, 0, 0, 0, 0, newYork), time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), 1},
+				{time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), time.Date(2026, 3, 9, 0, 0, 0, 0, newYork), 2},
+				{time.Date(2026, 3, 9, 0, 0, 0, 0, newYork), time.Date(2026, 3, 10, 0, 0, 0, 0, newYork), 1},
+			},
+		},
+		{
+			"new york days across fall back",
+			models.RevenueQuery{From: time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), To: time.Date(2026, 11, 3, 0, 0, 0, 0, newYork), Interval: models.RevenueIntervalDay},
+			[]time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // first 1:30
+				time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), // second 1:30
+				time.Date(2026, 11, 2, 4, 59, 0, 0, time.UTC), // 23:59 EST on the 1st
+				time.Date(2026, 11, 2, 5, 0, 0, 0, time.UTC),  // midnight EST on the 2nd
+			},
+			[]period{
+				{time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), time.Date(2026, 11, 2, 0, 0, 0, 0, newYork), 3},
+				{time.Date(2026, 11, 2, 0, 0, 0, 0, newYork), time.Date(2026, 11, 3, 0, 0, 0, 0, newYork), 1},
+			},
+		},
+		{
+			"sydney months across fall back",
+			models.RevenueQuery{From: time.Date(2026, 3, 1, 0, 0, 0, 0, sydney), To: time.Date(2026, 5, 1, 0, 0, 0, 0, sydney), Interval: models.RevenueIntervalMonth},
+			[]time.Time{
+				time.Date(2026, 3, 31, 12, 59, 0, 0, time.UTC), // 23:59 AEDT on 31 March
+				time.Date(2026, 3, 31, 13, 0, 0, 0, time.UTC),  // midnight AEDT on 1 April
+				time.Date(2026, 4, 30, 13, 59, 0, 0, time.UTC), // 23:59 AEST on 30 April
+			},
+			[]period{
+				{time.Date(2026, 3, 1, 0, 0, 0, 0, sydney), time.Date(2026, 4, 1, 0, 0, 0, 0, sydney), 1},
+				{time.Date(2026, 4, 1, 0, 0, 0, 0, sydney), time.Date(2026, 5, 1, 0, 0, 0, 0, sydney), 2},
+			},
+		},
+		{
+			"sydney weeks across spring forward",
+			models.RevenueQuery{From: time.Date(2026, 9, 28, 0, 0, 0, 0, sydney), To: time.Date(2026, 10, 12, 0, 0, 0, 0, sydney), Interval: models.RevenueIntervalWeek},
+			[]time.Time{
+				time.Date(2026, 10, 4, 12, 59, 0, 0, time.UTC), // 23:59 AEDT on Sunday 4 October
+				time.Date(2026, 10, 4, 13, 0, 0, 0, time.UTC),  // midnight AEDT on Monday 5 October
+			},
+			[]period{
+				{time.Date(2026, 9, 28, 0, 0, 0, 0, sydney), time.Date(2026, 10, 5, 0, 0, 0, 0, sydney), 1},
+				{time.Date(2026, 10, 5, 0, 0, 0, 0, sydney), time.Date(2026, 10, 12, 0, 0, 0, 0, sydney), 1},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &metricsRepository{}
+			for _, at := range tt.entries {
+				repo.entries = append(repo.entries, models.RevenueEntry{UserID: 1, Currency: "USD", Amount: 1, OccurredAt: at})
+			}
+			service := NewService(repo, nil, clock.Fixed(tt.query.To))
+
+			result, err := service.GetRevenueSeries(1, tt.query)
+			if err != nil {
+				t.Fatalf("GetRevenueSeries: %v", err)
+			}
+			if len(result.Series) != 1 {
+				t.Fatalf("got %d series, want 1", len(result.Series))
+			}
+			points := result.Series[0].Points
+			if len(points) != len(tt.want) {
+				t.Fatalf("got %d periods, want %d", len(points), len(tt.want))
+			}
+			for i, want := range tt.want {
+				point := points[i]
+				if !point.PeriodStart.Equal(want.start) || !point.PeriodEnd.Equal(want.end) {
+					t.Errorf("period %d is [%s, %s), want [%s, %s)", i, point.PeriodStart, point.PeriodEnd, want.start, want.end)
+				}
+				if point.Revenue != want.revenue {
+					t.Errorf("period %d has revenue %v, want %v", i, point.Revenue, want.revenue)
+				}
+			}
+		})
+	}
+}
@@ -7,6 +7,8 @@
 package analytics
 
 import (
+	"backend/core/clock"
+	"backend/internal/rollup"
 	"gorm.io/gorm"
 )
 
@@ -14,7 +16,10 @@ import (
 
 func NewControllerWire(db *gorm.DB) *Controller {
 	repository := NewRepository(db)
-	service := NewService(repository)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	clockClock := clock.New()
+	service := NewService(repository, rollupService, clockClock)
 	controller := NewController(service)
 	return controller
 }
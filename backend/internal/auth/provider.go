@@ -3,7 +3,9 @@ package auth
 import (
 	coreDomain "backend/core/domain"
 	"backend/core/email"
+	"backend/internal/activity"
 	"backend/internal/domain"
+	"backend/internal/rollup"
 	"github.com/google/wire"
 )
 
@@ -13,6 +15,8 @@ var ProviderSet = wire.NewSet(
 	NewAuthService,
 	NewUserRepository,
 	email.NewEmailService,
+	rollup.ProviderSet,
+	activity.ProviderSet,
 
 	wire.Bind(new(domain.AuthControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.AuthService), new(*Service)),
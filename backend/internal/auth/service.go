@@ -7,21 +7,26 @@ import (
 	"backend/internal/middleware"
 	"backend/models"
 	"errors"
+	"fmt"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 type Service struct {
-	userRepo domain.UserRepository
-	cfg      *core.Config
-	email    coreDomain.EmailService
+	userRepo   domain.UserRepository
+	cfg        *core.Config
+	email      coreDomain.EmailService
+	rollups    domain.RollupService
+	activities domain.ActivityService
 }
 
-func NewAuthService(userRepo domain.UserRepository, cfg *core.Config, email coreDomain.EmailService) *Service {
+func NewAuthService(userRepo domain.UserRepository, cfg *core.Config, email coreDomain.EmailService, rollups domain.RollupService, activities domain.ActivityService) *Service {
 	return &Service{
-		userRepo: userRepo,
-		cfg:      cfg,
-		email:    email,
+		userRepo:   userRepo,
+		cfg:        cfg,
+		email:      email,
+		rollups:    rollups,
+		activities: activities,
 	}
 }
 
@@ -99,6 +104,18 @@ func (s *Service) Register(req models.RegisterRequest, tenantID *int) (*models.A
 	if err := s.userRepo.Create(user); err != nil {
 		return nil, errors.New("failed to create user")
 	}
+	if tenantID != nil {
+		s.rollups.Touch(*tenantID, user.CreatedAt)
+		s.activities.Record(&models.Activity{
+			UserID:      user.ID,
+			TenantID:    *tenantID,
+			Type:        models.ActivityUserRegistered,
+			Description: fmt.Sprintf("%s registered", user.Email),
+			EntityType:  models.ActivityEntityUser,
+			EntityID:    &user.ID,
+			Payload:     models.JSONB{"email": user.Email, "role": user.Role},
+		})
+	}
 
 	// Send welcome email
 	go s.email.SendWelcomeEmail(user.Email, req.FirstName)
@@ -9,6 +9,8 @@ package auth
 import (
 	"backend/core"
 	"backend/core/email"
+	"backend/internal/activity"
+	"backend/internal/rollup"
 	"gorm.io/gorm"
 )
 
@@ -17,7 +19,11 @@ import (
 func NewControllerWire(db *gorm.DB, cfg *core.Config) *Controller {
 	userRepository := NewUserRepository(db)
 	service := email.NewEmailService(cfg)
-	authService := NewAuthService(userRepository, cfg, service)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	activityRepository := activity.NewRepository(db)
+	activityService := activity.NewService(activityRepository)
+	authService := NewAuthService(userRepository, cfg, service, rollupService, activityService)
 	controller := NewAuthController(authService)
 	return controller
 }
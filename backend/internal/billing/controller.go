@@ -0,0 +1,106 @@
+package billing
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.BillingService
+}
+
+func NewController(service domain.BillingService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetTenantSettings gets the tenant's seller details
+func (c *Controller) GetTenantSettings(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	settings, err := c.service.GetTenantSettings(*tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  settings,
+	})
+}
+
+// UpdateTenantSettings replaces the tenant's seller details
+func (c *Controller) UpdateTenantSettings(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var req models.UpdateTenantSettingsRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	settings, err := c.service.UpdateTenantSettings(*tenantID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  settings,
+	})
+}
+
+// GetBillingProfile gets the current user's buyer details
+func (c *Controller) GetBillingProfile(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	profile, err := c.service.GetBillingProfile(userID, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  profile,
+	})
+}
+
+// UpdateBillingProfile replaces the current user's buyer details
+func (c *Controller) UpdateBillingProfile(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var req models.UpdateBillingProfileRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	profile, err := c.service.UpdateBillingProfile(userID, *tenantID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  profile,
+	})
+}
@@ -0,0 +1,17 @@
+package billing
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+
+	wire.Bind(new(domain.BillingControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.BillingService), new(*Service)),
+	wire.Bind(new(domain.BillingRepository), new(*Repository)),
+)
@@ -0,0 +1,39 @@
+package billing
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetTenantSettings(tenantID int) (*models.TenantSettings, error) {
+	var settings models.TenantSettings
+	if err := r.db.Where("tenant_id = ?", tenantID).First(&settings).Error; err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+func (r *Repository) SaveTenantSettings(settings *models.TenantSettings) error {
+	return r.db.Save(settings).Error
+}
+
+func (r *Repository) GetBillingProfile(userID, tenantID int) (*models.BillingProfile, error) {
+	var profile models.BillingProfile
+	if err := r.db.Where("user_id = ? AND tenant_id = ?", userID, tenantID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *Repository) SaveBillingProfile(profile *models.BillingProfile) error {
+	return r.db.Save(profile).Error
+}
@@ -0,0 +1,123 @@
+package billing
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+const defaultInvoicePrefix = "INV-"
+
+var (
+	countryPattern       = regexp.MustCompile(`^[A-Z]{2}$`)
+	invoicePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/_-]{0,12}$`)
+)
+
+type Service struct {
+	repo domain.BillingRepository
+}
+
+func NewService(repo domain.BillingRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// GetTenantSettings returns the tenant's seller details, or empty defaults
+// when none were saved yet
+func (s *Service) GetTenantSettings(tenantID int) (*models.TenantSettings, error) {
+	settings, err := s.repo.GetTenantSettings(tenantID)
+	if err != nil {
+		return &models.TenantSettings{TenantID: tenantID, InvoicePrefix: defaultInvoicePrefix}, nil
+	}
+	return settings, nil
+}
+
+func (s *Service) UpdateTenantSettings(tenantID int, req models.UpdateTenantSettingsRequest) (*models.TenantSettings, error) {
+	country, err := normalizeCountry(req.Country)
+	if err != nil {
+		return nil, err
+	}
+	if req.InvoicePrefix == "" {
+		req.InvoicePrefix = defaultInvoicePrefix
+	}
+	if !invoicePrefixPattern.MatchString(req.InvoicePrefix) {
+		return nil, errors.New("invoice prefix must be up to 12 letters, digits, '-', '_' or '/'")
+	}
+
+	settings, err := s.GetTenantSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	settings.LegalName = strings.TrimSpace(req.LegalName)
+	settings.Email = strings.TrimSpace(req.Email)
+	settings.AddressLine1 = strings.TrimSpace(req.AddressLine1)
+	settings.AddressLine2 = strings.TrimSpace(req.AddressLine2)
+	settings.City = strings.TrimSpace(req.City)
+	settings.PostalCode = strings.TrimSpace(req.PostalCode)
+	settings.Region = strings.TrimSpace(req.Region)
+	settings.Country = country
+	settings.VATID = normalizeVATID(req.VATID)
+	settings.PricesIncludeTax = req.PricesIncludeTax
+	settings.InvoicePrefix = req.InvoicePrefix
+	settings.InvoiceFooter = strings.TrimSpace(req.InvoiceFooter)
+
+	if err := s.repo.SaveTenantSettings(settings); err != nil {
+		return nil, errors.New("failed to save tenant settings")
+	}
+
+	return settings, nil
+}
+
+// GetBillingProfile returns the user's buyer details, or an empty profile
+// when none was saved yet
+func (s *Service) GetBillingProfile(userID, tenantID int) (*models.BillingProfile, error) {
+	profile, err := s.repo.GetBillingProfile(userID, tenantID)
+	if err != nil {
+		return &models.BillingProfile{UserID: userID, TenantID: tenantID}, nil
+	}
+	return profile, nil
+}
+
+func (s *Service) UpdateBillingProfile(userID, tenantID int, req models.UpdateBillingProfileRequest) (*models.BillingProfile, error) {
+	country, err := normalizeCountry(req.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.GetBillingProfile(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Name = strings.TrimSpace(req.Name)
+	profile.Company = strings.TrimSpace(req.Company)
+	profile.AddressLine1 = strings.TrimSpace(req.AddressLine1)
+	profile.AddressLine2 = strings.TrimSpace(req.AddressLine2)
+	profile.City = strings.TrimSpace(req.City)
+	profile.PostalCode = strings.TrimSpace(req.PostalCode)
+	profile.Region = strings.TrimSpace(req.Region)
+	profile.Country = country
+	profile.VATID = normalizeVATID(req.VATID)
+
+	if err := s.repo.SaveBillingProfile(profile); err != nil {
+		return nil, errors.New("failed to save billing profile")
+	}
+
+	return profile, nil
+}
+
+func normalizeCountry(country string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country != "" && !countryPattern.MatchString(country) {
+		return "", errors.New("country must be a 2-letter ISO code")
+	}
+	return country, nil
+}
+
+// normalizeVATID strips the spaces and dots VAT IDs are often written with
+func normalizeVATID(vatID string) string {
+	vatID = strings.ToUpper(vatID)
+	return strings.NewReplacer(" ", "", ".", "", "-", "").Replace(vatID)
+}
@@ -0,0 +1,16 @@
+//go:build wireinject
+// +build wireinject
+
+package billing
+
+import (
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewControllerWire(db *gorm.DB) *Controller {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Controller{}
+}
@@ -0,0 +1,124 @@
+package catalog
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.CatalogService
+}
+
+func NewController(service domain.CatalogService) *Controller {
+	return &Controller{service: service}
+}
+
+// ImportCatalog imports products and plans from a CSV or JSON body or an
+// uploaded "file" form field. Pass dry_run=true to only validate.
+func (c *Controller) ImportCatalog(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	data, format, err := readImport(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	result, err := c.service.Import(*tenantID, userID, format, data, ctx.QueryBool("dry_run"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	if len(result.Errors) > 0 {
+		return ctx.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":   true,
+			"message": "Import contains invalid rows; nothing was applied",
+			"data":    result,
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  result,
+	})
+}
+
+// ExportCatalog exports all products and plans as CSV or JSON (format query parameter)
+func (c *Controller) ExportCatalog(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+	format := strings.ToLower(ctx.Query("format", models.CatalogFormatJSON))
+
+	data, err := c.service.Export(*tenantID, format)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	contentType := fiber.MIMEApplicationJSON
+	if format == models.CatalogFormatCSV {
+		contentType = "text/csv"
+	}
+	ctx.Set(fiber.HeaderContentType, contentType)
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="catalog-%s.%s"`, time.Now().Format("20060102"), format))
+	return ctx.Send(data)
+}
+
+// readImport returns the import payload and its format, taken from the format
+// query parameter, the uploaded file extension or the request content type
+func readImport(ctx *fiber.Ctx) ([]byte, string, error) {
+	format := strings.ToLower(ctx.Query("format"))
+	data := ctx.Body()
+
+	if strings.HasPrefix(ctx.Get(fiber.HeaderContentType), fiber.MIMEMultipartForm) {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			return nil, "", fmt.Errorf("missing import file")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read import file")
+		}
+		defer file.Close()
+
+		data, err = io.ReadAll(file)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read import file")
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+	}
+
+	if format == "" {
+		contentType := ctx.Get(fiber.HeaderContentType)
+		switch {
+		case strings.HasPrefix(contentType, "text/csv"):
+			format = models.CatalogFormatCSV
+		case strings.HasPrefix(contentType, fiber.MIMEApplicationJSON):
+			format = models.CatalogFormatJSON
+		default:
+			return nil, "", fmt.Errorf("unable to detect import format; pass format=csv or format=json")
+		}
+	}
+
+	if len(data) == 0 {
+		return nil, "", fmt.Errorf("import file is empty")
+	}
+
+	return data, format, nil
+}
@@ -0,0 +1,243 @@
+package catalog
+
+import (
+	"backend/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvColumns is the column layout of CSV imports and exports. Each row holds
+// one plan; product columns repeat on every row of the product, and a row
+// without a plan_key describes a product with no plans. product_features
+// holds the product's feature catalogue as a JSON array.
+var csvColumns = []string{
+	"product_key",
+	"product_name",
+	"product_description",
+	"product_url",
+	"product_active",
+	"product_features",
+	"plan_key",
+	"plan_name",
+	"plan_description",
+	"plan_price",
+	"plan_currency",
+	"plan_interval",
+	"plan_features",
+}
+
+// importEntry is one normalized input record: a product, optionally with one of its plans
+type importEntry struct {
+	row     int
+	product models.CatalogProduct
+	plan    *models.CatalogPlan
+}
+
+// parseJSON flattens a CatalogDocument into entries, numbered by product position
+func parseJSON(data []byte) ([]importEntry, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var document models.CatalogDocument
+	if err := decoder.Decode(&document); err != nil {
+		return nil, fmt.Errorf("invalid JSON document: %v", err)
+	}
+
+	var entries []importEntry
+	for i, product := range document.Products {
+		plans := product.Plans
+		product.Plans = nil
+
+		entries = append(entries, importEntry{row: i + 1, product: product})
+		for j := range plans {
+			entries = append(entries, importEntry{row: i + 1, product: product, plan: &plans[j]})
+		}
+	}
+	return entries, nil
+}
+
+// parseCSV reads CSV rows into entries. Malformed files fail as a whole while
+// unparseable cell values are reported per row.
+func parseCSV(data []byte) ([]importEntry, []models.CatalogImportError, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, errors.New("CSV file must start with a header row")
+	}
+
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isCSVColumn(name) {
+			return nil, nil, fmt.Errorf("unknown CSV column '%s'", name)
+		}
+		index[name] = i
+	}
+	if _, ok := index["product_key"]; !ok {
+		return nil, nil, errors.New("CSV header must include product_key")
+	}
+
+	var entries []importEntry
+	var rowErrors []models.CatalogImportError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid CSV: %v", err)
+		}
+		row, _ := reader.FieldPos(0)
+
+		value := func(column string) string {
+			if i, ok := index[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		fail := func(message string) {
+			rowErrors = append(rowErrors, models.CatalogImportError{
+				Row:        row,
+				ProductKey: value("product_key"),
+				PlanKey:    value("plan_key"),
+				Message:    message,
+			})
+		}
+
+		entry := importEntry{
+			row: row,
+			product: models.CatalogProduct{
+				ExternalKey: value("product_key"),
+				Name:        value("product_name"),
+				Description: value("product_description"),
+				URL:         value("product_url"),
+			},
+		}
+
+		if active := value("product_active"); active != "" {
+			parsed, err := strconv.ParseBool(active)
+			if err != nil {
+				fail("product_active must be true or false")
+				continue
+			}
+			entry.product.Active = &parsed
+		}
+
+		if features := value("product_features"); features != "" {
+			if err := json.Unmarshal([]byte(features), &entry.product.Features); err != nil {
+				fail("product_features must be a JSON array of features")
+				continue
+			}
+		}
+
+		if planKey := value("plan_key"); planKey != "" {
+			plan := &models.CatalogPlan{
+				ExternalKey: planKey,
+				Name:        value("plan_name"),
+				Description: value("plan_description"),
+				Currency:    value("plan_currency"),
+				Interval:    value("plan_interval"),
+			}
+
+			price, err := strconv.ParseFloat(value("plan_price"), 64)
+			if err != nil {
+				fail("plan_price must be a number")
+				continue
+			}
+			plan.Price = price
+
+			if features := value("plan_features"); features != "" {
+				if err := json.Unmarshal([]byte(features), &plan.Features); err != nil {
+					fail("plan_features must be a JSON object")
+					continue
+				}
+			}
+
+			entry.plan = plan
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, rowErrors, nil
+}
+
+func isCSVColumn(name string) bool {
+	for _, column := range csvColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeJSON(document models.CatalogDocument) ([]byte, error) {
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func encodeCSV(document models.CatalogDocument) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(csvColumns); err != nil {
+		return nil, err
+	}
+
+	for _, product := range document.Products {
+		active := ""
+		if product.Active != nil {
+			active = strconv.FormatBool(*product.Active)
+		}
+		productFeatures := ""
+		if len(product.Features) > 0 {
+			encoded, err := json.Marshal(product.Features)
+			if err != nil {
+				return nil, err
+			}
+			productFeatures = string(encoded)
+		}
+		productColumns := []string{product.ExternalKey, product.Name, product.Description, product.URL, active, productFeatures}
+
+		if len(product.Plans) == 0 {
+			if err := writer.Write(append(productColumns, "", "", "", "", "", "", "")); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		for _, plan := range product.Plans {
+			features := ""
+			if len(plan.Features) > 0 {
+				encoded, err := json.Marshal(plan.Features)
+				if err != nil {
+					return nil, err
+				}
+				features = string(encoded)
+			}
+
+			record := append(append([]string{}, productColumns...),
+				plan.ExternalKey,
+				plan.Name,
+				plan.Description,
+				strconv.FormatFloat(plan.Price, 'f', -1, 64),
+				plan.Currency,
+				plan.Interval,
+				features,
+			)
+			if err := writer.Write(record); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
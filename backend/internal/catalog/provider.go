@@ -0,0 +1,29 @@
+package catalog
+
+import (
+	"backend/internal/activity"
+	"backend/internal/domain"
+	"backend/internal/feature"
+	"backend/internal/product"
+	"backend/internal/rollup"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	feature.NewFeatureService,
+	feature.NewFeatureRepository,
+	product.NewProductRepository,
+	rollup.ProviderSet,
+	activity.ProviderSet,
+
+	wire.Bind(new(domain.CatalogControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.CatalogService), new(*Service)),
+	wire.Bind(new(domain.CatalogRepository), new(*Repository)),
+	wire.Bind(new(domain.FeatureService), new(*feature.Service)),
+	wire.Bind(new(domain.FeatureRepository), new(*feature.Repository)),
+	wire.Bind(new(domain.ProductRepository), new(*product.Repository)),
+)
@@ -0,0 +1,86 @@
+package catalog
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetProductsByExternalKeys(keys []string, tenantID int) ([]models.Product, error) {
+	var products []models.Product
+	if len(keys) == 0 {
+		return products, nil
+	}
+	err := r.db.Where("external_key IN ? AND tenant_id = ?", keys, tenantID).Find(&products).Error
+	return products, err
+}
+
+func (r *Repository) GetPlansByExternalKeys(keys []string, tenantID int) ([]models.Plan, error) {
+	var plans []models.Plan
+	if len(keys) == 0 {
+		return plans, nil
+	}
+	err := r.db.Where("external_key IN ? AND tenant_id = ?", keys, tenantID).Find(&plans).Error
+	return plans, err
+}
+
+// GetFeaturesByProducts returns the feature catalogues of the products
+func (r *Repository) GetFeaturesByProducts(productIDs []int, tenantID int) ([]models.Feature, error) {
+	var features []models.Feature
+	if len(productIDs) == 0 {
+		return features, nil
+	}
+	err := r.db.Where("product_id IN ? AND tenant_id = ?", productIDs, tenantID).Order("id ASC").Find(&features).Error
+	return features, err
+}
+
+// ApplyImport saves all products, features and plans in a single
+// transaction. Features and plans of newly created products take their
+// ProductID from the Product pointer.
+func (r *Repository) ApplyImport(products []*models.Product, features []*models.Feature, plans []*models.Plan) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, product := range products {
+			if err := tx.Omit(clause.Associations).Save(product).Error; err != nil {
+				return err
+			}
+		}
+		for _, feature := range features {
+			if feature.Product != nil {
+				feature.ProductID = feature.Product.ID
+			}
+			if err := tx.Omit(clause.Associations).Save(feature).Error; err != nil {
+				return err
+			}
+		}
+		for _, plan := range plans {
+			if plan.Product != nil {
+				plan.ProductID = plan.Product.ID
+			}
+			if err := tx.Omit(clause.Associations).Save(plan).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetCatalog returns every product of the tenant, archived ones included, with their plans
+func (r *Repository) GetCatalog(tenantID int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.Preload("Plans", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).
+		Where("tenant_id = ?", tenantID).
+		Order("id ASC").
+		Find(&products).Error
+	return products, err
+}
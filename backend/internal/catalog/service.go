@@ -0,0 +1,519 @@
+package catalog
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+type Service struct {
+	repo           domain.CatalogRepository
+	featureService domain.FeatureService
+	rollups        domain.RollupService
+	activities     domain.ActivityService
+}
+
+func NewService(repo domain.CatalogRepository, featureService domain.FeatureService, rollups domain.RollupService, activities domain.ActivityService) *Service {
+	return &Service{
+		repo:           repo,
+		featureService: featureService,
+		rollups:        rollups,
+		activities:     activities,
+	}
+}
+
+// productGroup collects the entries of one product key in input order
+type productGroup struct {
+	row     int
+	product models.CatalogProduct
+	plans   []importEntry
+}
+
+// Import validates a CSV or JSON catalogue and, unless dryRun is set or any
+// row is invalid, creates or updates products, their features and plans
+// matched by key in a single transaction. Plans are checked against their
+// product's feature catalogue as it will be after the import, so a new
+// product's features and plans can be imported together. Re-importing the
+// same file changes nothing.
+// Each product and plan created or updated is recorded as an activity of the
+// importing user, like a change made through the API.
+func (s *Service) Import(tenantID, userID int, format string, data []byte, dryRun bool) (*models.CatalogImportResult, error) {
+	var entries []importEntry
+	var rowErrors []models.CatalogImportError
+	var err error
+
+	switch format {
+	case models.CatalogFormatJSON:
+		entries, err = parseJSON(data)
+	case models.CatalogFormatCSV:
+		entries, rowErrors, err = parseCSV(data)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.CatalogImportResult{DryRun: dryRun}
+	fail := func(row int, productKey, planKey, message string) {
+		rowErrors = append(rowErrors, models.CatalogImportError{
+			Row:        row,
+			ProductKey: productKey,
+			PlanKey:    planKey,
+			Message:    message,
+		})
+	}
+
+	// Group entries by product and validate them
+	var groups []*productGroup
+	byProductKey := make(map[string]*productGroup)
+	planRows := make(map[string]int)
+
+	for _, entry := range entries {
+		key := entry.product.ExternalKey
+		if key == "" {
+			fail(entry.row, "", "", "product key is required")
+			continue
+		}
+
+		group, exists := byProductKey[key]
+		if !exists {
+			group = &productGroup{row: entry.row, product: entry.product}
+			byProductKey[key] = group
+			groups = append(groups, group)
+		} else if message := mergeProduct(&group.product, entry.product); message != "" {
+			fail(entry.row, key, "", message)
+		}
+
+		if entry.plan == nil {
+			continue
+		}
+		if entry.plan.ExternalKey == "" {
+			fail(entry.row, key, "", "plan key is required")
+			continue
+		}
+		if previous, duplicate := planRows[entry.plan.ExternalKey]; duplicate {
+			fail(entry.row, key, entry.plan.ExternalKey, fmt.Sprintf("duplicate plan key (first seen on row %d)", previous))
+			continue
+		}
+		planRows[entry.plan.ExternalKey] = entry.row
+
+		if message := normalizePlan(entry.plan); message != "" {
+			fail(entry.row, key, entry.plan.ExternalKey, message)
+			continue
+		}
+		group.plans = append(group.plans, entry)
+	}
+
+	for _, group := range groups {
+		if group.product.Name == "" {
+			fail(group.row, group.product.ExternalKey, "", "product name is required")
+		}
+	}
+
+	// Match existing products and plans by external key
+	productKeys := make([]string, 0, len(groups))
+	for _, group := range groups {
+		productKeys = append(productKeys, group.product.ExternalKey)
+	}
+	planKeys := make([]string, 0, len(planRows))
+	for key := range planRows {
+		planKeys = append(planKeys, key)
+	}
+
+	existingProducts, err := s.repo.GetProductsByExternalKeys(productKeys, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load existing products")
+	}
+	existingPlans, err := s.repo.GetPlansByExternalKeys(planKeys, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load existing plans")
+	}
+
+	productsByKey := make(map[string]*models.Product, len(existingProducts))
+	productIDs := make([]int, 0, len(existingProducts))
+	for i := range existingProducts {
+		productsByKey[existingProducts[i].ExternalKey] = &existingProducts[i]
+		productIDs = append(productIDs, existingProducts[i].ID)
+	}
+	existingFeatures, err := s.repo.GetFeaturesByProducts(productIDs, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load existing features")
+	}
+	featuresByProduct := make(map[int][]models.Feature, len(existingProducts))
+	for _, feature := range existingFeatures {
+		featuresByProduct[feature.ProductID] = append(featuresByProduct[feature.ProductID], feature)
+	}
+	plansByKey := make(map[string]*models.Plan, len(existingPlans))
+	for i := range existingPlans {
+		plansByKey[existingPlans[i].ExternalKey] = &existingPlans[i]
+	}
+
+	var productsToSave []*models.Product
+	var featuresToSave []*models.Feature
+	var plansToSave []*models.Plan
+	// The activity payloads of updated products and plans before the import
+	productsBefore := make(map[*models.Product]models.JSONB)
+	plansBefore := make(map[*models.Plan]models.JSONB)
+
+	for _, group := range groups {
+		product, exists := productsByKey[group.product.ExternalKey]
+		if !exists {
+			product = &models.Product{ExternalKey: group.product.ExternalKey, TenantID: tenantID}
+			applyProduct(product, group.product)
+			result.ProductsCreated++
+			productsToSave = append(productsToSave, product)
+		} else if before := models.ProductPayload(product); applyProduct(product, group.product) {
+			result.ProductsUpdated++
+			productsToSave = append(productsToSave, product)
+			productsBefore[product] = before
+		} else {
+			result.ProductsUnchanged++
+		}
+
+		var existing []models.Feature
+		if exists {
+			existing = featuresByProduct[product.ID]
+		}
+		features, catalogue := s.importFeatures(group, product, existing, result, fail)
+		featuresToSave = append(featuresToSave, features...)
+
+		for _, entry := range group.plans {
+			imported := entry.plan
+
+			if err := s.featureService.ValidatePlanValues(catalogue, imported.Features); err != nil {
+				fail(entry.row, group.product.ExternalKey, imported.ExternalKey, err.Error())
+				continue
+			}
+
+			plan, planExists := plansByKey[imported.ExternalKey]
+			if planExists && (!exists || plan.ProductID != product.ID) {
+				fail(entry.row, group.product.ExternalKey, imported.ExternalKey, "plan key belongs to another product")
+				continue
+			}
+			if !planExists {
+				plan = &models.Plan{ExternalKey: imported.ExternalKey, TenantID: tenantID, Product: product}
+				applyPlan(plan, *imported)
+				result.PlansCreated++
+				plansToSave = append(plansToSave, plan)
+				continue
+			}
+
+			before := models.PlanPayload(plan)
+			if applyPlan(plan, *imported) {
+				result.PlansUpdated++
+				plansToSave = append(plansToSave, plan)
+				plansBefore[plan] = before
+			} else {
+				result.PlansUnchanged++
+			}
+		}
+	}
+
+	result.Errors = rowErrors
+	if result.Errors == nil {
+		result.Errors = []models.CatalogImportError{}
+	}
+	if len(rowErrors) > 0 || dryRun {
+		return result, nil
+	}
+
+	if err := s.repo.ApplyImport(productsToSave, featuresToSave, plansToSave); err != nil {
+		return nil, errors.New("failed to apply import")
+	}
+	result.Applied = true
+
+	if len(productsToSave) > 0 || len(plansToSave) > 0 {
+		s.rollups.Touch(tenantID, time.Now())
+	}
+	s.logImport(tenantID, userID, productsToSave, plansToSave, productsBefore, plansBefore)
+
+	return result, nil
+}
+
+// importFeatures applies the product's imported feature definitions to its
+// existing catalogue. It returns the features to create or update and the
+// catalogue after the import.
+func (s *Service) importFeatures(group *productGroup, product *models.Product, existing []models.Feature, result *models.CatalogImportResult, fail func(row int, productKey, planKey, message string)) ([]*models.Feature, []models.Feature) {
+	catalogue := append([]models.Feature{}, existing...)
+	byKey := make(map[string]int, len(catalogue))
+	for i, feature := range catalogue {
+		byKey[feature.Key] = i
+	}
+
+	var toSave []*models.Feature
+	seen := make(map[string]bool, len(group.product.Features))
+	for _, imported := range group.product.Features {
+		key := group.product.ExternalKey
+		if seen[imported.Key] {
+			fail(group.row, key, "", fmt.Sprintf("duplicate feature key '%s'", imported.Key))
+			continue
+		}
+		seen[imported.Key] = true
+
+		if err := s.featureService.ValidateDefinition(models.CreateFeatureRequest{
+			Key:         imported.Key,
+			Name:        imported.Name,
+			Description: imported.Description,
+			Type:        imported.Type,
+			Options:     imported.Options,
+		}); err != nil {
+			fail(group.row, key, "", fmt.Sprintf("feature '%s': %v", imported.Key, err))
+			continue
+		}
+
+		i, exists := byKey[imported.Key]
+		if !exists {
+			feature := &models.Feature{Key: imported.Key, Type: imported.Type, TenantID: product.TenantID, Product: product}
+			applyFeature(feature, imported)
+			result.FeaturesCreated++
+			toSave = append(toSave, feature)
+			catalogue = append(catalogue, *feature)
+			continue
+		}
+
+		if catalogue[i].Type != imported.Type {
+			fail(group.row, key, "", fmt.Sprintf("feature '%s': feature type cannot be changed", imported.Key))
+			continue
+		}
+		feature := catalogue[i]
+		if applyFeature(&feature, imported) {
+			result.FeaturesUpdated++
+			toSave = append(toSave, &feature)
+			catalogue[i] = feature
+		} else {
+			result.FeaturesUnchanged++
+		}
+	}
+	return toSave, catalogue
+}
+
+// logImport records the imported products and plans, as created unless
+// they have a payload from before the import
+func (s *Service) logImport(tenantID, userID int, products []*models.Product, plans []*models.Plan, productsBefore map[*models.Product]models.JSONB, plansBefore map[*models.Plan]models.JSONB) {
+	for _, product := range products {
+		activity := &models.Activity{
+			UserID:      userID,
+			TenantID:    tenantID,
+			Type:        models.ActivityProductCreated,
+			Description: fmt.Sprintf("Imported product '%s'", product.Name),
+			EntityType:  models.ActivityEntityProduct,
+			EntityID:    &product.ID,
+			Payload:     models.ProductPayload(product),
+		}
+		if before, updated := productsBefore[product]; updated {
+			activity.Type = models.ActivityProductUpdated
+			activity.Description = fmt.Sprintf("Updated product '%s' by import", product.Name)
+			activity.Payload = models.JSONB{"changes": models.ActivityChanges(before, models.ProductPayload(product))}
+		}
+		s.activities.Record(activity)
+	}
+	for _, plan := range plans {
+		activity := &models.Activity{
+			UserID:      userID,
+			TenantID:    tenantID,
+			Type:        models.ActivityPlanCreated,
+			Description: fmt.Sprintf("Imported plan '%s'", plan.Name),
+			EntityType:  models.ActivityEntityPlan,
+			EntityID:    &plan.ID,
+			Payload:     models.PlanPayload(plan),
+		}
+		if before, updated := plansBefore[plan]; updated {
+			activity.Type = models.ActivityPlanUpdated
+			activity.Description = fmt.Sprintf("Updated plan '%s' by import", plan.Name)
+			activity.Payload = models.JSONB{"changes": models.ActivityChanges(before, models.PlanPayload(plan))}
+		}
+		s.activities.Record(activity)
+	}
+}
+
+// Export renders all of the tenant's products, their features and plans in
+// the import format
+func (s *Service) Export(tenantID int, format string) ([]byte, error) {
+	if format != models.CatalogFormatJSON && format != models.CatalogFormatCSV {
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+
+	products, err := s.repo.GetCatalog(tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load catalog")
+	}
+	productIDs := make([]int, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+	features, err := s.repo.GetFeaturesByProducts(productIDs, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load features")
+	}
+	featuresByProduct := make(map[int][]models.CatalogFeature, len(products))
+	for _, feature := range features {
+		featuresByProduct[feature.ProductID] = append(featuresByProduct[feature.ProductID], models.CatalogFeature{
+			Key:         feature.Key,
+			Name:        feature.Name,
+			Description: feature.Description,
+			Type:        feature.Type,
+			Options:     feature.Options,
+		})
+	}
+
+	document := models.CatalogDocument{Products: make([]models.CatalogProduct, 0, len(products))}
+	for _, product := range products {
+		exported := models.CatalogProduct{
+			ExternalKey: product.ExternalKey,
+			Name:        product.Name,
+			Description: product.Description,
+			URL:         product.URL,
+			Active:      product.Active,
+			Features:    featuresByProduct[product.ID],
+			Plans:       make([]models.CatalogPlan, 0, len(product.Plans)),
+		}
+		for _, plan := range product.Plans {
+			exported.Plans = append(exported.Plans, models.CatalogPlan{
+				ExternalKey: plan.ExternalKey,
+				Name:        plan.Name,
+				Description: plan.Description,
+				Price:       plan.Price,
+				Currency:    plan.Currency,
+				Interval:    plan.Interval,
+				Features:    plan.Features,
+			})
+		}
+		document.Products = append(document.Products, exported)
+	}
+
+	if format == models.CatalogFormatCSV {
+		return encodeCSV(document)
+	}
+	return encodeJSON(document)
+}
+
+// mergeProduct folds product details repeated on later rows into the first
+// occurrence, returning a message when they disagree
+func mergeProduct(target *models.CatalogProduct, source models.CatalogProduct) string {
+	merge := func(current *string, value string) bool {
+		if value == "" {
+			return true
+		}
+		if *current == "" {
+			*current = value
+			return true
+		}
+		return *current == value
+	}
+
+	if !merge(&target.Name, source.Name) ||
+		!merge(&target.Description, source.Description) ||
+		!merge(&target.URL, source.URL) {
+		return "conflicting product details for the same product key"
+	}
+
+	if source.Features != nil {
+		if target.Features == nil {
+			target.Features = source.Features
+		} else if !reflect.DeepEqual(target.Features, source.Features) {
+			return "conflicting product details for the same product key"
+		}
+	}
+
+	if source.Active != nil {
+		if target.Active == nil {
+			target.Active = source.Active
+		} else if *target.Active != *source.Active {
+			return "conflicting product details for the same product key"
+		}
+	}
+
+	return ""
+}
+
+// normalizePlan applies defaults and validates an imported plan
+func normalizePlan(plan *models.CatalogPlan) string {
+	if plan.Name == "" {
+		return "plan name is required"
+	}
+	if plan.Price < 0 {
+		return "plan price cannot be negative"
+	}
+
+	plan.Currency = strings.ToUpper(plan.Currency)
+	if plan.Currency == "" {
+		plan.Currency = "USD"
+	}
+	if !currencyPattern.MatchString(plan.Currency) {
+		return "plan currency must be a 3-letter ISO code"
+	}
+
+	if plan.Interval == "" {
+		plan.Interval = "monthly"
+	}
+	if plan.Interval != "monthly" && plan.Interval != "yearly" {
+		return "plan interval must be monthly or yearly"
+	}
+
+	return ""
+}
+
+// applyProduct copies imported fields onto the product and reports whether anything changed
+func applyProduct(product *models.Product, imported models.CatalogProduct) bool {
+	changed := product.Name != imported.Name ||
+		product.Description != imported.Description ||
+		product.URL != imported.URL
+
+	product.Name = imported.Name
+	product.Description = imported.Description
+	product.URL = imported.URL
+
+	if imported.Active != nil && (product.Active == nil || *product.Active != *imported.Active) {
+		active := *imported.Active
+		product.Active = &active
+		changed = true
+	}
+
+	return changed
+}
+
+// applyFeature copies imported fields onto the feature and reports whether anything changed
+func applyFeature(feature *models.Feature, imported models.CatalogFeature) bool {
+	sameOptions := (len(feature.Options) == 0 && len(imported.Options) == 0) ||
+		reflect.DeepEqual([]string(feature.Options), imported.Options)
+
+	changed := feature.Name != imported.Name ||
+		feature.Description != imported.Description ||
+		!sameOptions
+
+	feature.Name = imported.Name
+	feature.Description = imported.Description
+	feature.Options = models.StringArray(imported.Options)
+
+	return changed
+}
+
+// applyPlan copies imported fields onto the plan and reports whether anything changed
+func applyPlan(plan *models.Plan, imported models.CatalogPlan) bool {
+	sameFeatures := (len(plan.Features) == 0 && len(imported.Features) == 0) ||
+		reflect.DeepEqual(plan.Features, imported.Features)
+
+	changed := plan.Name != imported.Name ||
+		plan.Description != imported.Description ||
+		plan.Price != imported.Price ||
+		plan.Currency != imported.Currency ||
+		plan.Interval != imported.Interval ||
+		!sameFeatures
+
+	plan.Name = imported.Name
+	plan.Description = imported.Description
+	plan.Price = imported.Price
+	plan.Currency = imported.Currency
+	plan.Interval = imported.Interval
+	plan.Features = imported.Features
+
+	return changed
+}
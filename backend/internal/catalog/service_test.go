@@ -0,0 +1,225 @@
+package catalog
+
+import (
+	"backend/internal/domain"
+	"backend/internal/feature"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+// memoryCatalogRepository holds one tenant's catalogue in memory
+type memoryCatalogRepository struct {
+	domain.CatalogRepository
+	products []*models.Product
+	features []*models.Feature
+	plans    []*models.Plan
+	nextID   int
+}
+
+func (r *memoryCatalogRepository) GetProductsByExternalKeys(keys []string, tenantID int) ([]models.Product, error) {
+	var products []models.Product
+	for _, product := range r.products {
+		for _, key := range keys {
+			if product.ExternalKey == key {
+				products = append(products, *product)
+			}
+		}
+	}
+	return products, nil
+}
+
+func (r *memoryCatalogRepository) GetPlansByExternalKeys(keys []string, tenantID int) ([]models.Plan, error) {
+	var plans []models.Plan
+	for _, plan := range r.plans {
+		for _, key := range keys {
+			if plan.ExternalKey == key {
+				plans = append(plans, *plan)
+			}
+		}
+	}
+	return plans, nil
+}
+
+func (r *memoryCatalogRepository) GetFeaturesByProducts(productIDs []int, tenantID int) ([]models.Feature, error) {
+	var features []models.Feature
+	for _, feature := range r.features {
+		for _, id := range productIDs {
+			if feature.ProductID == id {
+				features = append(features, *feature)
+			}
+		}
+	}
+	return features, nil
+}
+
+func (r *memoryCatalogRepository) GetCatalog(tenantID int) ([]models.Product, error) {
+	var products []models.Product
+	for _, product := range r.products {
+		exported := *product
+		for _, plan := range r.plans {
+			if plan.ProductID == product.ID {
+				exported.Plans = append(exported.Plans, *plan)
+			}
+		}
+		products = append(products, exported)
+	}
+	return products, nil
+}
+
+func (r *memoryCatalogRepository) ApplyImport(products []*models.Product, features []*models.Feature, plans []*models.Plan) error {
+	for _, product := range products {
+		if product.ID == 0 {
+			r.nextID++
+			product.ID = r.nextID
+			r.products = append(r.products, product)
+		}
+	}
+	for _, feature := range features {
+		if feature.Product != nil {
+			feature.ProductID = feature.Product.ID
+		}
+		if feature.ID == 0 {
+			r.nextID++
+			feature.ID = r.nextID
+			r.features = append(r.features, feature)
+			continue
+		}
+		for i, existing := range r.features {
+			if existing.ID == feature.ID {
+				r.features[i] = feature
+			}
+		}
+	}
+	for _, plan := range plans {
+		if plan.Product != nil {
+			plan.ProductID = plan.Product.ID
+		}
+		if plan.ID == 0 {
+			r.nextID++
+			plan.ID = r.nextID
+			r.plans = append(r.plans, plan)
+		}
+	}
+	return nil
+}
+
+type stubRollups struct {
+	domain.RollupService
+}
+
+func (stubRollups) Touch(tenantID int, at time.Time) {}
+
+type stubActivities struct{}
+
+func (stubActivities) Record(activity *models.Activity) {}
+
+const newProductDocument = `{"products": [{
+	"external_key": "analytics",
+	"name": "Analytics",
+	"description": "",
+	"url": "",
+	"features": [
+		{"key": "seats", "name": "Seats", "description": "", "type": "limit"},
+		{"key": "support", "name": "Support", "description": "", "type": "enum", "options": ["email", "phone"]}
+	],
+	"plans": [
+		{"external_key": "analytics_pro", "name": "Pro", "description": "", "price": 20, "currency": "USD", "interval": "monthly", "features": {"seats": 10, "support": "phone"}}
+	]
+}]}`
+
+// A new product's features are created with it, so that its plans can use
+// them in the same import, and an export of the result imports as no change
+func TestImportCreatesFeaturesOfNewProduct(t *testing.T) {
+	repo := &memoryCatalogRepository{}
+	service := NewService(repo, feature.NewFeatureService(nil, nil), stubRollups{}, stubActivities{})
+
+	result, err := service.Import(1, 2, models.CatalogFormatJSON, []byte(newProductDocument), false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) > 0 || !result.Applied {
+		t.Fatalf("import was not applied: %+v", result.Errors)
+	}
+	if result.ProductsCreated != 1 || result.FeaturesCreated != 2 || result.PlansCreated != 1 {
+		t.Errorf("created %d products, %d features and %d plans, want 1, 2 and 1", result.ProductsCreated, result.FeaturesCreated, result.PlansCreated)
+	}
+	for _, feature := range repo.features {
+		if feature.ProductID != repo.products[0].ID {
+			t.Errorf("feature %s belongs to product %d, want %d", feature.Key, feature.ProductID, repo.products[0].ID)
+		}
+	}
+
+	for _, format := range []string{models.CatalogFormatJSON, models.CatalogFormatCSV} {
+		exported, err := service.Export(1, format)
+		if err != nil {
+			t.Fatalf("Export %s: %v", format, err)
+		}
+		again, err := service.Import(1, 2, format, exported, true)
+		if err != nil {
+			t.Fatalf("Import of %s export: %v", format, err)
+		}
+		if len(again.Errors) > 0 || again.ProductsUnchanged != 1 || again.FeaturesUnchanged != 2 || again.PlansUnchanged != 1 {
+			t.Errorf("re-importing the %s export changes the catalogue: %+v", format, again)
+		}
+	}
+}
+
+func TestImportRejectsInvalidFeatures(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		message  string
+	}{
+		{"unknown plan feature", `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "seats", "name": "Seats", "description": "", "type": "limit"}], "plans": [{"external_key": "pl", "name": "Pl", "description": "", "price": 1, "currency": "USD", "interval": "monthly", "features": {"api": true}}]}]}`, "unknown feature 'api'"},
+		{"invalid value", `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "seats", "name": "Seats", "description": "", "type": "limit"}], "plans": [{"external_key": "pl", "name": "Pl", "description": "", "price": 1, "currency": "USD", "interval": "monthly", "features": {"seats": "many"}}]}]}`, "feature 'seats' must be a whole number, or -1 for unlimited"},
+		{"invalid definition", `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "Seats", "name": "Seats", "description": "", "type": "limit"}], "plans": []}]}`, "feature 'Seats': feature key must be lowercase snake_case"},
+		{"duplicate key", `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "seats", "name": "Seats", "description": "", "type": "limit"}, {"key": "seats", "name": "Seats", "description": "", "type": "boolean"}], "plans": []}]}`, "duplicate feature key 'seats'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryCatalogRepository{}
+			service := NewService(repo, feature.NewFeatureService(nil, nil), stubRollups{}, stubActivities{})
+
+			result, err := service.Import(1, 2, models.CatalogFormatJSON, []byte(tt.document), false)
+			if err != nil {
+				t.Fatalf("Import: %v", err)
+			}
+			if len(result.Errors) != 1 || result.Errors[0].Message != tt.message {
+				t.Fatalf("errors = %+v, want %q", result.Errors, tt.message)
+			}
+			if result.Applied || len(repo.products) > 0 || len(repo.features) > 0 {
+				t.Error("invalid import was applied")
+			}
+		})
+	}
+}
+
+// Features are updated in place by key, and their type can't change
+func TestImportUpdatesFeatures(t *testing.T) {
+	product := &models.Product{ID: 1, ExternalKey: "p", Name: "P", TenantID: 1}
+	repo := &memoryCatalogRepository{
+		products: []*models.Product{product},
+		features: []*models.Feature{{ID: 2, Key: "seats", Name: "Seats", Type: models.FeatureTypeLimit, ProductID: 1, TenantID: 1}},
+		nextID:   2,
+	}
+	service := NewService(repo, feature.NewFeatureService(nil, nil), stubRollups{}, stubActivities{})
+
+	renamed := `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "seats", "name": "Users", "description": "", "type": "limit"}], "plans": []}]}`
+	result, err := service.Import(1, 2, models.CatalogFormatJSON, []byte(renamed), false)
+	if err != nil || len(result.Errors) > 0 {
+		t.Fatalf("Import = %+v, %v", result, err)
+	}
+	if result.FeaturesUpdated != 1 || len(repo.features) != 1 || repo.features[0].Name != "Users" {
+		t.Errorf("feature was not renamed: %+v", repo.features[0])
+	}
+
+	retyped := `{"products": [{"external_key": "p", "name": "P", "description": "", "url": "", "features": [{"key": "seats", "name": "Users", "description": "", "type": "boolean"}], "plans": []}]}`
+	result, err = service.Import(1, 2, models.CatalogFormatJSON, []byte(retyped), false)
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Message != "feature 'seats': feature type cannot be changed" {
+		t.Errorf("errors = %+v, want a type change error", result.Errors)
+	}
+}
@@ -0,0 +1,180 @@
+package coupon
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.CouponService
+}
+
+func NewController(service domain.CouponService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetCoupons lists the tenant's coupons
+func (c *Controller) GetCoupons(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	coupons, err := c.service.GetCoupons(*tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  coupons,
+	})
+}
+
+// GetCoupon gets a coupon
+func (c *Controller) GetCoupon(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid coupon ID",
+		})
+	}
+
+	coupon, err := c.service.GetCoupon(id, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  coupon,
+	})
+}
+
+// CreateCoupon creates a coupon with its promo code
+func (c *Controller) CreateCoupon(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var req models.CouponRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	coupon, err := c.service.CreateCoupon(*tenantID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"error": false,
+		"data":  coupon,
+	})
+}
+
+// UpdateCoupon replaces a coupon
+func (c *Controller) UpdateCoupon(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid coupon ID",
+		})
+	}
+
+	var req models.CouponRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	coupon, err := c.service.UpdateCoupon(id, *tenantID, req)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if err.Error() == "coupon not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  coupon,
+	})
+}
+
+// DeleteCoupon deletes a coupon; existing discounts keep running
+func (c *Controller) DeleteCoupon(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid coupon ID",
+		})
+	}
+
+	if err := c.service.DeleteCoupon(id, *tenantID); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error":   false,
+		"message": "Coupon deleted successfully",
+	})
+}
+
+// GetCouponReport summarizes a coupon's redemptions
+func (c *Controller) GetCouponReport(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid coupon ID",
+		})
+	}
+
+	report, err := c.service.GetCouponReport(id, *tenantID)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "coupon not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  report,
+	})
+}
@@ -0,0 +1,17 @@
+package coupon
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+
+	wire.Bind(new(domain.CouponControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.CouponService), new(*Service)),
+	wire.Bind(new(domain.CouponRepository), new(*Repository)),
+)
@@ -0,0 +1,165 @@
+package coupon
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// recentRedemptionLimit bounds the redemptions listed in a coupon report
+const recentRedemptionLimit = 50
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetCoupons(tenantID int) ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("created_at DESC").
+		Find(&coupons).Error
+	return coupons, err
+}
+
+func (r *Repository) GetByID(id, tenantID int) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *Repository) GetByCode(code string, tenantID int) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := r.db.Where("code = ? AND tenant_id = ?", code, tenantID).First(&coupon).Error; err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (r *Repository) Create(coupon *models.Coupon) error {
+	return r.db.Create(coupon).Error
+}
+
+func (r *Repository) Update(coupon *models.Coupon) error {
+	return r.db.Save(coupon).Error
+}
+
+func (r *Repository) Delete(id, tenantID int) error {
+	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.Coupon{}).Error
+}
+
+// Reserve locks the coupon, lets check veto the redemption against the
+// coupon's current redemption counts and records it
+func (r *Repository) Reserve(redemption *models.CouponRedemption, check func(coupon *models.Coupon, userRedemptions int64) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var coupon models.Coupon
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", redemption.CouponID, redemption.TenantID).
+			First(&coupon).Error; err != nil {
+			return err
+		}
+
+		var userRedemptions int64
+		if err := tx.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", redemption.CouponID, redemption.UserID).
+			Count(&userRedemptions).Error; err != nil {
+			return err
+		}
+
+		if err := check(&coupon, userRedemptions); err != nil {
+			return err
+		}
+
+		if err := tx.Create(redemption).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Coupon{}).
+			Where("id = ?", coupon.ID).
+			UpdateColumn("times_redeemed", gorm.Expr("times_redeemed + 1")).Error
+	})
+}
+
+// Release removes a reserved redemption and gives it back to the coupon
+func (r *Repository) Release(redemption *models.CouponRedemption) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.CouponRedemption{}, redemption.ID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Coupon{}).
+			Where("id = ? AND times_redeemed > 0", redemption.CouponID).
+			UpdateColumn("times_redeemed", gorm.Expr("times_redeemed - 1")).Error
+	})
+}
+
+func (r *Repository) AttachPurchase(redemptionID, purchaseID int) error {
+	return r.db.Model(&models.CouponRedemption{}).
+		Where("id = ?", redemptionID).
+		Update("purchase_id", purchaseID).Error
+}
+
+func (r *Repository) GetRedemptionByPurchase(purchaseID, tenantID int) (*models.CouponRedemption, error) {
+	var redemption models.CouponRedemption
+	if err := r.db.Where("purchase_id = ? AND tenant_id = ?", purchaseID, tenantID).First(&redemption).Error; err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// GetReport totals the redemptions of a coupon: their number, distinct
+// customers, discounts granted and the net first-period revenue of the
+// redeeming purchases
+func (r *Repository) GetReport(couponID, tenantID int) (*models.CouponReport, error) {
+	var totals struct {
+		Redemptions     int
+		UniqueCustomers int
+		TotalDiscount   float64
+	}
+	err := r.db.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND tenant_id = ?", couponID, tenantID).
+		Select("COUNT(*) AS redemptions, COUNT(DISTINCT user_id) AS unique_customers, COALESCE(SUM(discount_amount), 0) AS total_discount").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	report := models.CouponReport{
+		Redemptions:     totals.Redemptions,
+		UniqueCustomers: totals.UniqueCustomers,
+		TotalDiscount:   totals.TotalDiscount,
+	}
+	err = r.db.Model(&models.CouponRedemption{}).
+		Joins("JOIN purchases ON purchases.id = coupon_redemptions.purchase_id AND purchases.deleted_at IS NULL").
+		Where("coupon_redemptions.coupon_id = ? AND coupon_redemptions.tenant_id = ?", couponID, tenantID).
+		Select("COALESCE(SUM(COALESCE(purchases.net_amount, purchases.amount)), 0)").
+		Scan(&report.Revenue).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (r *Repository) GetRecentRedemptions(couponID, tenantID int) ([]models.CouponRedemption, error) {
+	var redemptions []models.CouponRedemption
+	err := r.db.Where("coupon_id = ? AND tenant_id = ?", couponID, tenantID).
+		Preload("User").
+		Preload("Purchase").
+		Order("created_at DESC").
+		Limit(recentRedemptionLimit).
+		Find(&redemptions).Error
+	return redemptions, err
+}
+
+// CountPlans counts the plans of the tenant among ids
+func (r *Repository) CountPlans(ids []int, tenantID int) (int, error) {
+	var count int64
+	err := r.db.Model(&models.Plan{}).Where("id IN ? AND tenant_id = ?", ids, tenantID).Count(&count).Error
+	return int(count), err
+}
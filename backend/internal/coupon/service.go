@@ -0,0 +1,321 @@
+package coupon
+
+import (
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	codePattern     = regexp.MustCompile(`^[A-Z0-9_-]{3,32}$`)
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type Service struct {
+	repo domain.CouponRepository
+}
+
+func NewService(repo domain.CouponRepository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetCoupons(tenantID int) ([]models.Coupon, error) {
+	return s.repo.GetCoupons(tenantID)
+}
+
+func (s *Service) GetCoupon(id, tenantID int) (*models.Coupon, error) {
+	coupon, err := s.repo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("coupon not found")
+	}
+	return coupon, nil
+}
+
+func (s *Service) CreateCoupon(tenantID int, req models.CouponRequest) (*models.Coupon, error) {
+	if err := s.validate(tenantID, &req); err != nil {
+		return nil, err
+	}
+	if existing, _ := s.repo.GetByCode(req.Code, tenantID); existing != nil {
+		return nil, errors.New("coupon code already exists")
+	}
+
+	coupon := &models.Coupon{TenantID: tenantID, Active: true}
+	applyRequest(coupon, req)
+
+	if err := s.repo.Create(coupon); err != nil {
+		return nil, errors.New("failed to create coupon")
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon replaces a coupon. Once redeemed, its discount terms are
+// fixed since purchases already carry them; limits, validity window, plans
+// and name can still change.
+func (s *Service) UpdateCoupon(id, tenantID int, req models.CouponRequest) (*models.Coupon, error) {
+	coupon, err := s.repo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("coupon not found")
+	}
+	if err := s.validate(tenantID, &req); err != nil {
+		return nil, err
+	}
+	if req.Code != coupon.Code {
+		if existing, _ := s.repo.GetByCode(req.Code, tenantID); existing != nil {
+			return nil, errors.New("coupon code already exists")
+		}
+	}
+	if coupon.TimesRedeemed > 0 && (req.Type != coupon.Type ||
+		req.PercentOff != coupon.PercentOff ||
+		req.AmountOff != coupon.AmountOff ||
+		req.Currency != coupon.Currency ||
+		req.Duration != coupon.Duration ||
+		req.DurationPeriods != coupon.DurationPeriods) {
+		return nil, errors.New("discount terms of a redeemed coupon cannot be changed")
+	}
+
+	applyRequest(coupon, req)
+	if err := s.repo.Update(coupon); err != nil {
+		return nil, errors.New("failed to update coupon")
+	}
+	return coupon, nil
+}
+
+func (s *Service) DeleteCoupon(id, tenantID int) error {
+	if _, err := s.repo.GetByID(id, tenantID); err != nil {
+		return errors.New("coupon not found")
+	}
+	return s.repo.Delete(id, tenantID)
+}
+
+// GetCouponReport summarizes a coupon's redemptions with the most recent ones
+func (s *Service) GetCouponReport(id, tenantID int) (*models.CouponReport, error) {
+	coupon, err := s.repo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("coupon not found")
+	}
+
+	report, err := s.repo.GetReport(id, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load redemptions")
+	}
+	report.Coupon = *coupon
+
+	report.Recent, err = s.repo.GetRecentRedemptions(id, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load redemptions")
+	}
+
+	return report, nil
+}
+
+// Apply looks up a promo code for a purchase of the plan and returns the
+// coupon with the discount it takes off the plan price
+func (s *Service) Apply(code string, plan *models.Plan, tenantID int) (*models.Coupon, float64, error) {
+	coupon, err := s.repo.GetByCode(normalizeCode(code), tenantID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: code not found", domain.ErrInvalidCoupon)
+	}
+
+	if err := checkRedeemable(coupon, plan, time.Now()); err != nil {
+		return nil, 0, err
+	}
+	if coupon.MaxRedemptions != nil && coupon.TimesRedeemed >= *coupon.MaxRedemptions {
+		return nil, 0, fmt.Errorf("%w: redemption limit reached", domain.ErrInvalidCoupon)
+	}
+
+	return coupon, discountFor(coupon, plan.Price), nil
+}
+
+// Reserve records a redemption of the coupon before the purchase is paid,
+// enforcing the redemption limits under a lock on the coupon
+func (s *Service) Reserve(coupon *models.Coupon, userID int, discount float64, currency string) (*models.CouponRedemption, error) {
+	redemption := &models.CouponRedemption{
+		CouponID:       coupon.ID,
+		UserID:         userID,
+		DiscountAmount: discount,
+		Currency:       currency,
+		TenantID:       coupon.TenantID,
+	}
+
+	err := s.repo.Reserve(redemption, func(locked *models.Coupon, userRedemptions int64) error {
+		if locked.MaxRedemptions != nil && locked.TimesRedeemed >= *locked.MaxRedemptions {
+			return fmt.Errorf("%w: redemption limit reached", domain.ErrInvalidCoupon)
+		}
+		if locked.MaxRedemptionsPerUser != nil && userRedemptions >= int64(*locked.MaxRedemptionsPerUser) {
+			return fmt.Errorf("%w: already redeemed", domain.ErrInvalidCoupon)
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCoupon) {
+			return nil, err
+		}
+		return nil, errors.New("failed to redeem coupon")
+	}
+
+	return redemption, nil
+}
+
+// AttachPurchase links a reserved redemption to the purchase it paid for
+func (s *Service) AttachPurchase(redemption *models.CouponRedemption, purchaseID int) error {
+	redemption.PurchaseID = &purchaseID
+	return s.repo.AttachPurchase(redemption.ID, purchaseID)
+}
+
+// Release gives a reserved redemption back when the purchase wasn't made
+func (s *Service) Release(redemption *models.CouponRedemption) error {
+	return s.repo.Release(redemption)
+}
+
+// ReleaseForPurchase gives back the redemption of a purchase that was never paid
+func (s *Service) ReleaseForPurchase(purchaseID, tenantID int) error {
+	redemption, err := s.repo.GetRedemptionByPurchase(purchaseID, tenantID)
+	if err != nil {
+		return nil
+	}
+	return s.repo.Release(redemption)
+}
+
+// checkRedeemable checks the coupon's status, validity window and plan and
+// currency restrictions
+func checkRedeemable(coupon *models.Coupon, plan *models.Plan, now time.Time) error {
+	if !coupon.Active {
+		return fmt.Errorf("%w: coupon is inactive", domain.ErrInvalidCoupon)
+	}
+	if coupon.ValidFrom != nil && now.Before(*coupon.ValidFrom) {
+		return fmt.Errorf("%w: coupon is not valid yet", domain.ErrInvalidCoupon)
+	}
+	if coupon.ValidUntil != nil && !now.Before(*coupon.ValidUntil) {
+		return fmt.Errorf("%w: coupon has expired", domain.ErrInvalidCoupon)
+	}
+	if len(coupon.PlanIDs) > 0 && !containsPlan(coupon.PlanIDs, plan.ID) {
+		return fmt.Errorf("%w: coupon does not apply to this plan", domain.ErrInvalidCoupon)
+	}
+	if coupon.Type == models.CouponTypeFixed && coupon.Currency != plan.Currency {
+		return fmt.Errorf("%w: coupon is in %s but the plan is priced in %s", domain.ErrInvalidCoupon, coupon.Currency, plan.Currency)
+	}
+	return nil
+}
+
+// discountFor returns the amount the coupon takes off price, rounded to
+// cents and never more than the price itself
+func discountFor(coupon *models.Coupon, price float64) float64 {
+	discount := coupon.AmountOff
+	if coupon.Type == models.CouponTypePercent {
+		discount = price * coupon.PercentOff / 100
+	}
+	return money.Round(math.Min(discount, price))
+}
+
+func (s *Service) validate(tenantID int, req *models.CouponRequest) error {
+	req.Code = normalizeCode(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+	req.Currency = strings.ToUpper(strings.TrimSpace(req.Currency))
+
+	if !codePattern.MatchString(req.Code) {
+		return errors.New("code must be 3 to 32 letters, digits, '-' or '_'")
+	}
+
+	switch req.Type {
+	case models.CouponTypePercent:
+		if req.PercentOff <= 0 || req.PercentOff > 100 {
+			return errors.New("percent_off must be between 0 and 100")
+		}
+		req.AmountOff = 0
+		req.Currency = ""
+	case models.CouponTypeFixed:
+		if req.AmountOff <= 0 {
+			return errors.New("amount_off must be positive")
+		}
+		if !currencyPattern.MatchString(req.Currency) {
+			return errors.New("fixed discounts require a 3-letter ISO currency")
+		}
+		req.PercentOff = 0
+	default:
+		return fmt.Errorf("invalid coupon type '%s'", req.Type)
+	}
+
+	switch req.Duration {
+	case models.CouponDurationOnce, models.CouponDurationForever:
+		req.DurationPeriods = 0
+	case models.CouponDurationRepeating:
+		if req.DurationPeriods < 1 {
+			return errors.New("repeating coupons require duration_periods of at least 1")
+		}
+	default:
+		return fmt.Errorf("invalid coupon duration '%s'", req.Duration)
+	}
+
+	if req.MaxRedemptions != nil && *req.MaxRedemptions < 1 {
+		return errors.New("max_redemptions must be at least 1")
+	}
+	if req.MaxRedemptionsPerUser != nil && *req.MaxRedemptionsPerUser < 1 {
+		return errors.New("max_redemptions_per_user must be at least 1")
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidUntil.After(*req.ValidFrom) {
+		return errors.New("valid_until must be after valid_from")
+	}
+
+	req.PlanIDs = uniquePlanIDs(req.PlanIDs)
+	if len(req.PlanIDs) > 0 {
+		count, err := s.repo.CountPlans(req.PlanIDs, tenantID)
+		if err != nil {
+			return errors.New("failed to check plans")
+		}
+		if count != len(req.PlanIDs) {
+			return errors.New("plan not found")
+		}
+	}
+
+	return nil
+}
+
+func applyRequest(coupon *models.Coupon, req models.CouponRequest) {
+	coupon.Code = req.Code
+	coupon.Name = req.Name
+	coupon.Type = req.Type
+	coupon.PercentOff = req.PercentOff
+	coupon.AmountOff = req.AmountOff
+	coupon.Currency = req.Currency
+	coupon.PlanIDs = models.IntArray(req.PlanIDs)
+	coupon.Duration = req.Duration
+	coupon.DurationPeriods = req.DurationPeriods
+	coupon.MaxRedemptions = req.MaxRedemptions
+	coupon.MaxRedemptionsPerUser = req.MaxRedemptionsPerUser
+	coupon.ValidFrom = req.ValidFrom
+	coupon.ValidUntil = req.ValidUntil
+	if req.Active != nil {
+		coupon.Active = *req.Active
+	}
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func uniquePlanIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+func containsPlan(ids models.IntArray, planID int) bool {
+	for _, id := range ids {
+		if id == planID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package coupon
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryCouponRepository holds one coupon and its redemptions. Reserve runs
+// under a mutex, standing in for the row lock of the database repository.
+type memoryCouponRepository struct {
+	domain.CouponRepository
+	mu          sync.Mutex
+	coupon      models.Coupon
+	redemptions []models.CouponRedemption
+}
+
+func (r *memoryCouponRepository) GetByCode(code string, tenantID int) (*models.Coupon, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.coupon.Code != code || r.coupon.TenantID != tenantID {
+		return nil, errors.New("coupon not found")
+	}
+	coupon := r.coupon
+	return &coupon, nil
+}
+
+func (r *memoryCouponRepository) Reserve(redemption *models.CouponRedemption, check func(coupon *models.Coupon, userRedemptions int64) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var userRedemptions int64
+	for _, existing := range r.redemptions {
+		if existing.UserID == redemption.UserID {
+			userRedemptions++
+		}
+	}
+	locked := r.coupon
+	if err := check(&locked, userRedemptions); err != nil {
+		return err
+	}
+	redemption.ID = len(r.redemptions) + 1
+	r.redemptions = append(r.redemptions, *redemption)
+	r.coupon.TimesRedeemed++
+	return nil
+}
+
+var testPlan = &models.Plan{ID: 1, Name: "Pro", Price: 29.99, Currency: "USD", Interval: "monthly"}
+
+// Percent discounts are taken off the plan price and rounded to the cent,
+// fixed ones in the plan's currency only; neither goes below zero
+func TestApplyDiscount(t *testing.T) {
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		want    float64
+		wantErr bool
+	}{
+		{"percent", models.Coupon{Type: models.CouponTypePercent, PercentOff: 20}, 6, false},
+		{"percent rounded", models.Coupon{Type: models.CouponTypePercent, PercentOff: 15}, 4.5, false},
+		{"full percent", models.Coupon{Type: models.CouponTypePercent, PercentOff: 100}, 29.99, false},
+		{"fixed", models.Coupon{Type: models.CouponTypeFixed, AmountOff: 5, Currency: "USD"}, 5, false},
+		{"fixed above the price", models.Coupon{Type: models.CouponTypeFixed, AmountOff: 50, Currency: "USD"}, 29.99, false},
+		{"fixed in another currency", models.Coupon{Type: models.CouponTypeFixed, AmountOff: 5, Currency: "EUR"}, 0, true},
+		{"other plan", models.Coupon{Type: models.CouponTypePercent, PercentOff: 20, PlanIDs: models.IntArray{2}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.coupon.Code, tt.coupon.Active, tt.coupon.TenantID = "SAVE", true, 1
+			service := NewService(&memoryCouponRepository{coupon: tt.coupon})
+
+			_, discount, err := service.Apply(" save ", testPlan, 1)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrInvalidCoupon) {
+					t.Fatalf("Apply error = %v, want ErrInvalidCoupon", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			if discount != tt.want {
+				t.Errorf("discount = %v, want %v", discount, tt.want)
+			}
+		})
+	}
+}
+
+// Customers racing for the last redemptions all pass Apply, but only as many
+// as the coupon allows get one reserved
+func TestReserveEnforcesRedemptionLimits(t *testing.T) {
+	const customers = 20
+	maxRedemptions, perUser := 5, 1
+	tests := []struct {
+		name      string
+		users     func(i int) int
+		wantTaken int
+	}{
+		{"limit", func(i int) int { return i + 1 }, maxRedemptions},
+		{"limit per customer", func(i int) int { return 1 }, perUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryCouponRepository{coupon: models.Coupon{
+				ID: 1, Code: "LAUNCH", Type: models.CouponTypePercent, PercentOff: 50, Duration: models.CouponDurationOnce,
+				MaxRedemptions: &maxRedemptions, MaxRedemptionsPerUser: &perUser, Active: true, TenantID: 1,
+			}}
+			service := NewService(repo)
+
+			var applied sync.WaitGroup
+			var reserved sync.WaitGroup
+			applied.Add(customers)
+			reserved.Add(customers)
+			errs := make(chan error, customers)
+			for i := 0; i < customers; i++ {
+				go func(userID int) {
+					defer reserved.Done()
+					coupon, discount, err := service.Apply("LAUNCH", testPlan, 1)
+					applied.Done()
+					if err != nil {
+						t.Errorf("Apply: %v", err)
+						return
+					}
+					// Reserve only once every customer has seen the coupon
+					// with redemptions left
+					applied.Wait()
+					_, err = service.Reserve(coupon, userID, discount, testPlan.Currency)
+					errs <- err
+				}(tt.users(i))
+			}
+			reserved.Wait()
+			close(errs)
+
+			taken := 0
+			for err := range errs {
+				switch {
+				case err == nil:
+					taken++
+				case !errors.Is(err, domain.ErrInvalidCoupon):
+					t.Errorf("Reserve error = %v, want ErrInvalidCoupon", err)
+				}
+			}
+			if taken != tt.wantTaken || repo.coupon.TimesRedeemed != tt.wantTaken || len(repo.redemptions) != tt.wantTaken {
+				t.Errorf("reserved %d, redeemed %d times with %d redemptions, want %d", taken, repo.coupon.TimesRedeemed, len(repo.redemptions), tt.wantTaken)
+			}
+
+			if _, _, err := service.Apply("LAUNCH", testPlan, 1); tt.wantTaken == maxRedemptions && !errors.Is(err, domain.ErrInvalidCoupon) {
+				t.Errorf("Apply after the limit error = %v, want ErrInvalidCoupon", err)
+			}
+		})
+	}
+}
+
+// Expired and inactive coupons can't be applied
+func TestApplyValidity(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	tests := []struct {
+		name    string
+		active  bool
+		from    *time.Time
+		until   *time.Time
+		wantErr bool
+	}{
+		{"valid", true, &past, &future, false},
+		{"inactive", false, nil, nil, true},
+		{"not valid yet", true, &future, nil, true},
+		{"expired", true, nil, &past, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewService(&memoryCouponRepository{coupon: models.Coupon{
+				Code: "SAVE", Type: models.CouponTypePercent, PercentOff: 10, Active: tt.active, ValidFrom: tt.from, ValidUntil: tt.until, TenantID: 1,
+			}})
+			_, _, err := service.Apply("SAVE", testPlan, 1)
+			if tt.wantErr != errors.Is(err, domain.ErrInvalidCoupon) || (!tt.wantErr && err != nil) {
+				t.Errorf("Apply error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
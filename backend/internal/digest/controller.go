@@ -0,0 +1,94 @@
+package digest
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.DigestService
+}
+
+func NewController(service domain.DigestService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetSubscription gets the current user's digest subscription, null if
+// they have none
+func (c *Controller) GetSubscription(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	subscription, err := c.service.GetSubscription(*tenantID, userID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  subscription,
+	})
+}
+
+// Subscribe subscribes the current user to digests, or changes how often
+// they get them
+func (c *Controller) Subscribe(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var req models.DigestSubscriptionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	subscription, err := c.service.Subscribe(*tenantID, userID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  subscription,
+	})
+}
+
+// Unsubscribe unsubscribes the current user from digests
+func (c *Controller) Unsubscribe(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	if err := c.service.Unsubscribe(*tenantID, userID); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error":   false,
+		"message": "Unsubscribed from digests",
+	})
+}
+
+// UnsubscribeByToken unsubscribes the recipient of a digest through the
+// link in it. It answers with a page as it's opened in a browser.
+func (c *Controller) UnsubscribeByToken(ctx *fiber.Ctx) error {
+	ctx.Type("html", "utf-8")
+	if err := c.service.UnsubscribeByToken(ctx.Query("token")); err != nil {
+		return ctx.Status(fiber.StatusNotFound).SendString(
+			"<!DOCTYPE html><html><body><h1>Link not valid</h1><p>This unsubscribe link is unknown or was already used.</p></body></html>")
+	}
+	return ctx.SendString(
+		"<!DOCTYPE html><html><body><h1>Unsubscribed</h1><p>You won't get analytics digests anymore.</p></body></html>")
+}
@@ -0,0 +1,23 @@
+package digest
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/core/email"
+	"backend/internal/analytics"
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	analytics.ProviderSet,
+	email.NewEmailService,
+
+	wire.Bind(new(domain.DigestControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.DigestService), new(*Service)),
+	wire.Bind(new(domain.DigestRepository), new(*Repository)),
+	wire.Bind(new(coreDomain.EmailService), new(*email.Service)),
+)
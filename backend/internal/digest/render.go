@@ -0,0 +1,138 @@
+package digest
+
+import (
+	"backend/models"
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"money":   formatMoney,
+	"date":    func(t time.Time) string { return t.Format("Jan 2, 2006") },
+	"time":    func(t time.Time) string { return t.Format("Jan 2, 15:04") },
+	"percent": formatPercent,
+	"period":  periodLabel,
+	"previous": func(digest *models.Digest) string {
+		return periodLabel(&models.Digest{
+			Frequency:   digest.Frequency,
+			PeriodStart: previousPeriod(digest.PeriodStart, digest.Frequency),
+			PeriodEnd:   digest.PeriodStart,
+		})
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 640px; margin: 24px auto; }
+  table { width: 100%; border-collapse: collapse; margin-top: 12px; }
+  th, td { padding: 6px 8px; border-bottom: 1px solid #ddd; text-align: left; }
+  .amount { text-align: right; white-space: nowrap; }
+  .footer { margin-top: 32px; color: #666; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<h1>{{.TenantName}}</h1>
+<p>Your {{.Frequency}} digest for {{period .}}.</p>
+
+<h2>Key metrics</h2>
+<table>
+  <thead>
+    <tr><th></th><th class="amount">{{period .}}</th><th class="amount">{{previous .}}</th></tr>
+  </thead>
+  <tr><td>Revenue</td><td class="amount">{{money .Metrics.TotalRevenue ""}}{{with .Metrics.RevenueGrowth}} ({{percent .}}){{end}}</td><td class="amount">{{money .Previous.TotalRevenue ""}}</td></tr>
+  <tr><td>Refunded</td><td class="amount">{{money .Metrics.RefundedAmount ""}}</td><td class="amount">{{money .Previous.RefundedAmount ""}}</td></tr>
+  <tr><td>MRR</td><td class="amount">{{money .Metrics.MRR ""}}</td><td class="amount">{{money .Previous.MRR ""}}</td></tr>
+  <tr><td>ARR</td><td class="amount">{{money .Metrics.ARR ""}}</td><td class="amount">{{money .Previous.ARR ""}}</td></tr>
+  <tr><td>Active purchases</td><td class="amount">{{.Metrics.ActivePurchases}}</td><td class="amount">{{.Previous.ActivePurchases}}</td></tr>
+  <tr><td>Active users</td><td class="amount">{{.Metrics.ActiveUsers}}</td><td class="amount">{{.Previous.ActiveUsers}}</td></tr>
+  <tr><td>Products</td><td class="amount">{{.Metrics.TotalProducts}}</td><td class="amount">{{.Previous.TotalProducts}}</td></tr>
+  <tr><td>Active plans</td><td class="amount">{{.Metrics.ActivePlans}}</td><td class="amount">{{.Previous.ActivePlans}}</td></tr>
+  <tr><td>Team members</td><td class="amount">{{.Metrics.TeamMembers}}</td><td class="amount">{{.Previous.TeamMembers}}</td></tr>
+</table>
+<p>Revenue and refunds are those of each period; the other figures are as of its last day.</p>
+
+<h2>Revenue trend</h2>
+{{range .Trend.Series}}
+{{$currency := .Currency}}
+<table>
+  <thead>
+    <tr><th>{{$currency}}</th><th class="amount">Revenue</th><th class="amount">MRR</th><th class="amount">New customers</th><th class="amount">Churned</th></tr>
+  </thead>
+  <tbody>
+  {{range .Points}}
+    <tr>
+      <td>{{date .PeriodStart}}</td>
+      <td class="amount">{{money .Revenue $currency}}</td>
+      <td class="amount">{{money .MRR $currency}}</td>
+      <td class="amount">{{.NewCustomers}}</td>
+      <td class="amount">{{.ChurnedCustomers}}</td>
+    </tr>
+  {{end}}
+  </tbody>
+</table>
+{{else}}
+<p>No revenue yet.</p>
+{{end}}
+
+<h2>Notable events</h2>
+{{if .Events}}
+<table>
+  {{range .Events}}
+  <tr><td>{{time .CreatedAt}}</td><td>{{.Description}}</td></tr>
+  {{end}}
+</table>
+{{if .MoreEvents}}<p>And more in your dashboard's activity feed.</p>{{end}}
+{{else}}
+<p>Nothing notable happened.</p>
+{{end}}
+
+<p class="footer">You get this email because you subscribed to {{.Frequency}} analytics digests. <a href="{{.UnsubscribeURL}}">Unsubscribe</a></p>
+</body>
+</html>
+`))
+
+func renderDigest(digest *models.Digest) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, digest); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// periodLabel names the period a digest covers, e.g. "Oct 12, 2026 - Oct 18, 2026"
+func periodLabel(digest *models.Digest) string {
+	last := digest.PeriodEnd.AddDate(0, 0, -1)
+	if digest.Frequency == models.DigestDaily {
+		return last.Format("Jan 2, 2006")
+	}
+	if digest.Frequency == models.DigestMonthly {
+		return digest.PeriodStart.Format("January 2006")
+	}
+	return digest.PeriodStart.Format("Jan 2, 2006") + " - " + last.Format("Jan 2, 2006")
+}
+
+// formatMoney renders an amount with thousands separators and the currency
+// code, if any
+func formatMoney(amount float64, currency string) string {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole := fmt.Sprint(cents / 100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	sign := ""
+	if amount < 0 && cents > 0 {
+		sign = "-"
+	}
+	if currency == "" {
+		return fmt.Sprintf("%s%s.%02d", sign, whole, cents%100)
+	}
+	return fmt.Sprintf("%s%s.%02d %s", sign, whole, cents%100, currency)
+}
+
+func formatPercent(value *float64) string {
+	return fmt.Sprintf("%+.2f%%", *value)
+}
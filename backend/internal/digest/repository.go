@@ -0,0 +1,72 @@
+package digest
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Get(tenantID, userID int) (*models.DigestSubscription, error) {
+	var subscription models.DigestSubscription
+	err := r.db.Where("tenant_id = ? AND user_id = ?", tenantID, userID).First(&subscription).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (r *Repository) Save(subscription *models.DigestSubscription) error {
+	return r.db.Save(subscription).Error
+}
+
+// Delete deletes the user's subscription, returning whether there was one
+func (r *Repository) Delete(tenantID, userID int) (bool, error) {
+	result := r.db.Where("tenant_id = ? AND user_id = ?", tenantID, userID).Delete(&models.DigestSubscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// DeleteByToken deletes the subscription with the unsubscribe token,
+// returning whether there was one
+func (r *Repository) DeleteByToken(token string) (bool, error) {
+	result := r.db.Where("unsubscribe_token = ?", token).Delete(&models.DigestSubscription{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetDue returns up to limit subscriptions whose next digest is due at now,
+// the longest overdue first, with their user and tenant
+func (r *Repository) GetDue(now time.Time, limit int) ([]models.DigestSubscription, error) {
+	var subscriptions []models.DigestSubscription
+	err := r.db.Preload("User").Preload("Tenant").
+		Where("next_send_at <= ?", now).
+		Order("next_send_at").
+		Limit(limit).
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Claim moves the subscription's next digest to next, unless another
+// instance did so first. It returns whether this call moved it.
+func (r *Repository) Claim(subscription *models.DigestSubscription, next time.Time) (bool, error) {
+	result := r.db.Model(&models.DigestSubscription{}).
+		Where("id = ? AND next_send_at = ?", subscription.ID, subscription.NextSendAt).
+		Update("next_send_at", next)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	subscription.NextSendAt = next
+	return result.RowsAffected > 0, nil
+}
+
+func (r *Repository) MarkSent(subscription *models.DigestSubscription, at time.Time) error {
+	subscription.LastSentAt = &at
+	return r.db.Model(subscription).UpdateColumn("last_sent_at", at).Error
+}
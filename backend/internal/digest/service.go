@@ -0,0 +1,287 @@
+package digest
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/internal/analytics"
+	"backend/internal/domain"
+	"backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// digestBatchSize bounds the digests sent per run
+const digestBatchSize = 100
+
+// maxDigestEvents bounds the notable events listed in a digest
+const maxDigestEvents = 10
+
+// notableActivities are the activity types a digest lists as events
+var notableActivities = []models.ActivityType{
+	models.ActivityPurchaseMade,
+	models.ActivityPurchaseCancelled,
+	models.ActivityPurchaseExpired,
+	models.ActivityTrialConverted,
+	models.ActivityPlanChanged,
+	models.ActivityRefundIssued,
+	models.ActivityProductCreated,
+	models.ActivityPlanCreated,
+}
+
+type Service struct {
+	repo      domain.DigestRepository
+	analytics domain.AnalyticsService
+	email     coreDomain.EmailService
+	cfg       *core.Config
+}
+
+func NewService(repo domain.DigestRepository, analytics domain.AnalyticsService, email coreDomain.EmailService, cfg *core.Config) *Service {
+	return &Service{repo: repo, analytics: analytics, email: email, cfg: cfg}
+}
+
+// GetSubscription returns the user's digest subscription, nil if they have
+// none
+func (s *Service) GetSubscription(tenantID, userID int) (*models.DigestSubscription, error) {
+	subscription, err := s.repo.Get(tenantID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	return subscription, err
+}
+
+// Subscribe subscribes the user to digests of the frequency, or changes the
+// frequency of their subscription. The first digest is sent at the next
+// digest hour that starts a period, in the tenant's timezone.
+func (s *Service) Subscribe(tenantID, userID int, req models.DigestSubscriptionRequest) (*models.DigestSubscription, error) {
+	switch req.Frequency {
+	case models.DigestDaily, models.DigestWeekly, models.DigestMonthly:
+	default:
+		return nil, errors.New("frequency must be daily, weekly or monthly")
+	}
+
+	now, err := s.analytics.GetLocalTime(tenantID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.repo.Get(tenantID, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		token, err := unsubscribeToken()
+		if err != nil {
+			return nil, err
+		}
+		subscription = &models.DigestSubscription{TenantID: tenantID, UserID: userID, UnsubscribeToken: token}
+	} else if err != nil {
+		return nil, err
+	}
+
+	subscription.Frequency = req.Frequency
+	subscription.NextSendAt = s.nextSendAt(req.Frequency, now)
+	if err := s.repo.Save(subscription); err != nil {
+		return nil, fmt.Errorf("failed to save subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+// Unsubscribe deletes the user's digest subscription
+func (s *Service) Unsubscribe(tenantID, userID int) error {
+	found, err := s.repo.Delete(tenantID, userID)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("not subscribed to digests")
+	}
+	return nil
+}
+
+// UnsubscribeByToken deletes the subscription an unsubscribe link was sent
+// for
+func (s *Service) UnsubscribeByToken(token string) error {
+	found, err := s.repo.DeleteByToken(token)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("unknown or already used unsubscribe link")
+	}
+	return nil
+}
+
+// ProcessDue sends the digests due at now. Each is claimed first by moving
+// its next send time, so that instances running concurrently don't send it
+// twice; a digest that fails to send is skipped until the next period.
+// Subscriptions of users who are gone or no longer admins are deleted. It
+// returns the number of digests sent.
+func (s *Service) ProcessDue(now time.Time) (int, error) {
+	subscriptions, err := s.repo.GetDue(now, digestBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if subscription.User == nil || subscription.User.Role != "admin" || subscription.Tenant == nil {
+			if _, err := s.repo.Delete(subscription.TenantID, subscription.UserID); err != nil {
+				log.Printf("Failed to delete digest subscription %d: %v", subscription.ID, err)
+			}
+			continue
+		}
+
+		local := now.In(subscription.Tenant.Location())
+		claimed, err := s.repo.Claim(subscription, s.nextSendAt(subscription.Frequency, local))
+		if err != nil {
+			log.Printf("Failed to claim digest subscription %d: %v", subscription.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if err := s.send(subscription, local); err != nil {
+			log.Printf("Failed to send digest of subscription %d: %v", subscription.ID, err)
+			continue
+		}
+		if err := s.repo.MarkSent(subscription, now); err != nil {
+			log.Printf("Failed to mark digest of subscription %d as sent: %v", subscription.ID, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// send builds, renders and emails the digest of the last whole period
+// before now
+func (s *Service) send(subscription *models.DigestSubscription, now time.Time) error {
+	digest, err := s.buildDigest(subscription, now)
+	if err != nil {
+		return err
+	}
+	html, err := renderDigest(digest)
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("Your %s %s digest: %s", digest.TenantName, digest.Frequency, periodLabel(digest))
+	return s.email.SendDigestEmail(subscription.User.Email, subject, string(html))
+}
+
+// buildDigest gathers the metrics, revenue trend and notable events of the
+// last whole period before now, in now's location
+func (s *Service) buildDigest(subscription *models.DigestSubscription, now time.Time) (*models.Digest, error) {
+	tenantID := subscription.TenantID
+	end := analytics.PeriodStart(now, intervalOf(subscription.Frequency))
+	start := previousPeriod(end, subscription.Frequency)
+
+	previousStart := previousPeriod(start, subscription.Frequency)
+	metrics, err := s.analytics.GetPeriodMetrics(tenantID, start, end, previousStart)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics: %w", err)
+	}
+	previous, err := s.analytics.GetPeriodMetrics(tenantID, previousStart, start, previousPeriod(previousStart, subscription.Frequency))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metrics of the previous period: %w", err)
+	}
+
+	interval, periods := trendOf(subscription.Frequency)
+	trendStart := end
+	for i := 0; i < periods; i++ {
+		trendStart = previousPeriod(trendStart, subscription.Frequency)
+	}
+	trend, err := s.analytics.GetRevenueSeries(tenantID, models.RevenueQuery{From: trendStart, To: end, Interval: interval})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get revenue trend: %w", err)
+	}
+
+	events, err := s.analytics.GetActivities(tenantID, models.ActivityQuery{
+		Types: notableActivities,
+		From:  start,
+		To:    end,
+		Limit: maxDigestEvents,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+	for i := range events.Activities {
+		events.Activities[i].CreatedAt = events.Activities[i].CreatedAt.In(now.Location())
+	}
+
+	return &models.Digest{
+		TenantName:     subscription.Tenant.TenantName,
+		Frequency:      subscription.Frequency,
+		PeriodStart:    start,
+		PeriodEnd:      end,
+		Metrics:        metrics,
+		Previous:       previous,
+		Trend:          trend,
+		Events:         events.Activities,
+		MoreEvents:     events.NextCursor != "",
+		UnsubscribeURL: s.cfg.AppBaseURL + "/api/v1/digests/unsubscribe?token=" + url.QueryEscape(subscription.UnsubscribeToken),
+	}, nil
+}
+
+// nextSendAt returns when the digest after now is due: the configured hour
+// of the first day of the next period, or of the current one if that hour is
+// still to come. now's location decides where days start.
+func (s *Service) nextSendAt(frequency string, now time.Time) time.Time {
+	start := analytics.PeriodStart(now, intervalOf(frequency))
+	at := time.Date(start.Year(), start.Month(), start.Day(), s.cfg.DigestHour, 0, 0, 0, start.Location())
+	if at.After(now) {
+		return at
+	}
+	next := analytics.NextPeriod(start, intervalOf(frequency))
+	return time.Date(next.Year(), next.Month(), next.Day(), s.cfg.DigestHour, 0, 0, 0, next.Location())
+}
+
+// previousPeriod returns the start of the period before the one starting at
+// start
+func previousPeriod(start time.Time, frequency string) time.Time {
+	switch frequency {
+	case models.DigestWeekly:
+		return start.AddDate(0, 0, -7)
+	case models.DigestMonthly:
+		return start.AddDate(0, -1, 0)
+	}
+	return start.AddDate(0, 0, -1)
+}
+
+// intervalOf returns the revenue series interval matching the period of a
+// digest
+func intervalOf(frequency string) string {
+	switch frequency {
+	case models.DigestWeekly:
+		return models.RevenueIntervalWeek
+	case models.DigestMonthly:
+		return models.RevenueIntervalMonth
+	}
+	return models.RevenueIntervalDay
+}
+
+// trendOf returns the revenue series interval of a digest and how many
+// periods of it its trend covers
+func trendOf(frequency string) (string, int) {
+	periods := 7
+	switch frequency {
+	case models.DigestWeekly:
+		periods = 8
+	case models.DigestMonthly:
+		periods = 6
+	}
+	return intervalOf(frequency), periods
+}
+
+// unsubscribeToken returns a random token for an unsubscribe link
+func unsubscribeToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,85 @@
+package digest
+
+import (
+	"backend/core"
+	"backend/internal/domain"
+	"backend/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+// periodAnalytics records the periods metrics are read for
+type periodAnalytics struct {
+	domain.AnalyticsService
+	periods [][3]time.Time
+}
+
+func (a *periodAnalytics) GetPeriodMetrics(tenantID int, start, end, previousStart time.Time) (*models.DashboardMetrics, error) {
+	a.periods = append(a.periods, [3]time.Time{start, end, previousStart})
+	return &models.DashboardMetrics{TotalRevenue: float64(len(a.periods)) * 100}, nil
+}
+
+func (a *periodAnalytics) GetRevenueSeries(tenantID int, query models.RevenueQuery) (*models.RevenueAnalytics, error) {
+	return &models.RevenueAnalytics{}, nil
+}
+
+func (a *periodAnalytics) GetActivities(tenantID int, query models.ActivityQuery) (*models.ActivityPage, error) {
+	return &models.ActivityPage{}, nil
+}
+
+// A digest's figures are those of its period, compared with the period
+// before, not of the month so far
+func TestBuildDigestReadsItsPeriod(t *testing.T) {
+	sydney, err := time.LoadLocation("Australia/Sydney")
+	if err != nil {
+		t.Fatal(err)
+	}
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, sydney)
+	}
+	now := time.Date(2026, 4, 6, 8, 0, 0, 0, sydney) // a Monday, after the DST change
+
+	tests := []struct {
+		frequency string
+		current   [3]time.Time
+		previous  [3]time.Time
+		label     string
+	}{
+		{models.DigestDaily, [3]time.Time{date(2026, 4, 5), date(2026, 4, 6), date(2026, 4, 4)}, [3]time.Time{date(2026, 4, 4), date(2026, 4, 5), date(2026, 4, 3)}, "Apr 4, 2026"},
+		{models.DigestWeekly, [3]time.Time{date(2026, 3, 30), date(2026, 4, 6), date(2026, 3, 23)}, [3]time.Time{date(2026, 3, 23), date(2026, 3, 30), date(2026, 3, 16)}, "Mar 23, 2026 - Mar 29, 2026"},
+		{models.DigestMonthly, [3]time.Time{date(2026, 3, 1), date(2026, 4, 1), date(2026, 2, 1)}, [3]time.Time{date(2026, 2, 1), date(2026, 3, 1), date(2026, 1, 1)}, "February 2026"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.frequency, func(t *testing.T) {
+			analytics := &periodAnalytics{}
+			service := NewService(nil, analytics, nil, &core.Config{})
+			subscription := &models.DigestSubscription{TenantID: 1, Frequency: tt.frequency, Tenant: &models.Tenant{TenantName: "Acme"}}
+
+			digest, err := service.buildDigest(subscription, now)
+			if err != nil {
+				t.Fatalf("buildDigest: %v", err)
+			}
+			if len(analytics.periods) != 2 {
+				t.Fatalf("read metrics of %d periods, want 2", len(analytics.periods))
+			}
+			for i, want := range [][3]time.Time{tt.current, tt.previous} {
+				got := analytics.periods[i]
+				if !got[0].Equal(want[0]) || !got[1].Equal(want[1]) || !got[2].Equal(want[2]) {
+					t.Errorf("period %d is %v, want %v", i, got, want)
+				}
+			}
+			if digest.Metrics.TotalRevenue != 100 || digest.Previous.TotalRevenue != 200 {
+				t.Errorf("digest has metrics %v and previous %v", digest.Metrics.TotalRevenue, digest.Previous.TotalRevenue)
+			}
+
+			html, err := renderDigest(digest)
+			if err != nil {
+				t.Fatalf("renderDigest: %v", err)
+			}
+			if !strings.Contains(string(html), tt.label) || !strings.Contains(string(html), "200.00") {
+				t.Errorf("digest doesn't show the previous period %s", tt.label)
+			}
+		})
+	}
+}
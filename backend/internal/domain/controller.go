@@ -10,6 +10,8 @@ type ProductControllerInterface interface {
 	GetProduct(c *fiber.Ctx) error
 	UpdateProduct(c *fiber.Ctx) error
 	DeleteProduct(c *fiber.Ctx) error
+	ArchiveProduct(c *fiber.Ctx) error
+	UnarchiveProduct(c *fiber.Ctx) error
 }
 
 type PlanControllerInterface interface {
@@ -18,6 +20,35 @@ type PlanControllerInterface interface {
 	GetPlan(c *fiber.Ctx) error
 	UpdatePlan(c *fiber.Ctx) error
 	DeletePlan(c *fiber.Ctx) error
+	ArchivePlan(c *fiber.Ctx) error
+	UnarchivePlan(c *fiber.Ctx) error
+}
+
+type FeatureControllerInterface interface {
+	GetFeatures(c *fiber.Ctx) error
+	CreateFeature(c *fiber.Ctx) error
+	UpdateFeature(c *fiber.Ctx) error
+	DeleteFeature(c *fiber.Ctx) error
+	GetEntitlements(c *fiber.Ctx) error
+}
+
+type StorefrontControllerInterface interface {
+	GetCatalog(c *fiber.Ctx) error
+	GetProductImage(c *fiber.Ctx) error
+}
+
+type CatalogControllerInterface interface {
+	ImportCatalog(c *fiber.Ctx) error
+	ExportCatalog(c *fiber.Ctx) error
+}
+
+type TranslationControllerInterface interface {
+	GetProductTranslations(c *fiber.Ctx) error
+	UpsertProductTranslation(c *fiber.Ctx) error
+	DeleteProductTranslation(c *fiber.Ctx) error
+	GetPlanTranslations(c *fiber.Ctx) error
+	UpsertPlanTranslation(c *fiber.Ctx) error
+	DeletePlanTranslation(c *fiber.Ctx) error
 }
 
 type TenantControllerInterface interface {
@@ -35,12 +66,24 @@ type AuthControllerInterface interface {
 
 type AnalyticsControllerInterface interface {
 	GetDashboardMetrics(ctx *fiber.Ctx) error
-	GetRecentActivity(ctx *fiber.Ctx) error
+	GetRevenueSeries(ctx *fiber.Ctx) error
+	GetCohorts(ctx *fiber.Ctx) error
+	GetProductPerformance(ctx *fiber.Ctx) error
+	GetPlanPerformance(ctx *fiber.Ctx) error
+	GetActivities(ctx *fiber.Ctx) error
+	GetPlatformOverview(ctx *fiber.Ctx) error
+	GetTopTenants(ctx *fiber.Ctx) error
+	GetSignupTrends(ctx *fiber.Ctx) error
+	GetTenantHealth(ctx *fiber.Ctx) error
 }
 
 type PurchaseControllerInterface interface {
 	// CreatePurchase creates a new purchase
 	CreatePurchase(ctx *fiber.Ctx) error
+
+	// QuotePurchase previews the discount, net, tax and gross price of a plan for the current user
+	QuotePurchase(ctx *fiber.Ctx) error
+
 	// GetUserPurchases gets all purchases for the current user
 	GetUserPurchases(ctx *fiber.Ctx) error
 
@@ -49,4 +92,149 @@ type PurchaseControllerInterface interface {
 
 	// GetActivePurchases gets all active purchases for the current user
 	GetActivePurchases(ctx *fiber.Ctx) error
+
+	// ConfirmPurchase completes the payment of a purchase awaiting customer action
+	ConfirmPurchase(ctx *fiber.Ctx) error
+
+	// CancelPurchase cancels a purchase immediately
+	CancelPurchase(ctx *fiber.Ctx) error
+
+	// CancelPurchaseAtPeriodEnd cancels a purchase when its current period ends
+	CancelPurchaseAtPeriodEnd(ctx *fiber.Ctx) error
+
+	// ResumePurchase withdraws a pending cancellation at period end
+	ResumePurchase(ctx *fiber.Ctx) error
+
+	// GetPurchaseTransitions gets the status history of a purchase
+	GetPurchaseTransitions(ctx *fiber.Ctx) error
+
+	// PreviewPlanChange shows the prorated amounts of moving a purchase to another plan
+	PreviewPlanChange(ctx *fiber.Ctx) error
+
+	// ChangePlan upgrades a purchase immediately or schedules a downgrade at period end
+	ChangePlan(ctx *fiber.Ctx) error
+
+	// UpdatePaymentMethod sets the payment method later periods are charged to
+	UpdatePaymentMethod(ctx *fiber.Ctx) error
+
+	// RefundPurchase refunds all or part of a payment of a purchase (tenant admins)
+	RefundPurchase(ctx *fiber.Ctx) error
+
+	// GetRefunds lists the refunds of a purchase (tenant admins)
+	GetRefunds(ctx *fiber.Ctx) error
+}
+
+type WebhookControllerInterface interface {
+	// ReceivePaymentWebhook verifies, stores and queues a payment provider event
+	ReceivePaymentWebhook(ctx *fiber.Ctx) error
+
+	// GetWebhookEvents lists stored webhook events (super admin)
+	GetWebhookEvents(ctx *fiber.Ctx) error
+
+	// ReplayWebhookEvent processes a stored webhook event again (super admin)
+	ReplayWebhookEvent(ctx *fiber.Ctx) error
+}
+
+type BillingControllerInterface interface {
+	// GetTenantSettings gets the tenant's seller details
+	GetTenantSettings(ctx *fiber.Ctx) error
+
+	// UpdateTenantSettings replaces the tenant's seller details
+	UpdateTenantSettings(ctx *fiber.Ctx) error
+
+	// GetBillingProfile gets the current user's buyer details
+	GetBillingProfile(ctx *fiber.Ctx) error
+
+	// UpdateBillingProfile replaces the current user's buyer details
+	UpdateBillingProfile(ctx *fiber.Ctx) error
+}
+
+type InvoiceControllerInterface interface {
+	// GetInvoices lists invoices of the current user, or of the tenant for admins
+	GetInvoices(ctx *fiber.Ctx) error
+
+	// GetInvoice gets an invoice with its lines
+	GetInvoice(ctx *fiber.Ctx) error
+
+	// DownloadInvoicePDF renders an invoice as PDF
+	DownloadInvoicePDF(ctx *fiber.Ctx) error
+
+	// DownloadInvoiceHTML renders an invoice as HTML
+	DownloadInvoiceHTML(ctx *fiber.Ctx) error
+
+	// SendInvoice emails an invoice to its buyer
+	SendInvoice(ctx *fiber.Ctx) error
+}
+
+type TaxControllerInterface interface {
+	// GetTaxRules lists the tenant's tax rules
+	GetTaxRules(ctx *fiber.Ctx) error
+
+	// CreateTaxRule adds a tax rate for a country or region
+	CreateTaxRule(ctx *fiber.Ctx) error
+
+	// UpdateTaxRule replaces a tax rule
+	UpdateTaxRule(ctx *fiber.Ctx) error
+
+	// DeleteTaxRule removes a tax rule, falling back to the default rate
+	DeleteTaxRule(ctx *fiber.Ctx) error
+}
+
+type CouponControllerInterface interface {
+	// GetCoupons lists the tenant's coupons
+	GetCoupons(ctx *fiber.Ctx) error
+
+	// GetCoupon gets a coupon
+	GetCoupon(ctx *fiber.Ctx) error
+
+	// CreateCoupon creates a coupon with its promo code
+	CreateCoupon(ctx *fiber.Ctx) error
+
+	// UpdateCoupon replaces a coupon
+	UpdateCoupon(ctx *fiber.Ctx) error
+
+	// DeleteCoupon deletes a coupon; existing discounts keep running
+	DeleteCoupon(ctx *fiber.Ctx) error
+
+	// GetCouponReport summarizes a coupon's redemptions
+	GetCouponReport(ctx *fiber.Ctx) error
+}
+
+type DigestControllerInterface interface {
+	// GetSubscription gets the current user's digest subscription
+	GetSubscription(ctx *fiber.Ctx) error
+
+	// Subscribe subscribes the current user to digests or changes their frequency
+	Subscribe(ctx *fiber.Ctx) error
+
+	// Unsubscribe unsubscribes the current user from digests
+	Unsubscribe(ctx *fiber.Ctx) error
+
+	// UnsubscribeByToken unsubscribes the recipient of a digest through its link
+	UnsubscribeByToken(ctx *fiber.Ctx) error
+}
+
+type ExportControllerInterface interface {
+	// ExportPurchases exports the tenant's purchases
+	ExportPurchases(ctx *fiber.Ctx) error
+
+	// ExportRevenue exports the tenant's revenue series
+	ExportRevenue(ctx *fiber.Ctx) error
+
+	// ExportActivity exports the tenant's activity feed
+	ExportActivity(ctx *fiber.Ctx) error
+
+	// GetExport gets the status of an export job
+	GetExport(ctx *fiber.Ctx) error
+
+	// DownloadExport downloads the file of a completed export job
+	DownloadExport(ctx *fiber.Ctx) error
+}
+
+type EventControllerInterface interface {
+	// CreateStreamToken issues a short-lived token opening the event stream
+	CreateStreamToken(ctx *fiber.Ctx) error
+
+	// Stream streams the tenant's events as server-sent events
+	Stream(ctx *fiber.Ctx) error
 }
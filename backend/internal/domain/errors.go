@@ -0,0 +1,40 @@
+package domain
+
+import "errors"
+
+// ErrActivePurchases is returned when an item cannot be deleted because
+// active purchases still reference it
+var ErrActivePurchases = errors.New("item has active purchases; archive it instead")
+
+// ErrInvalidTransition is returned when a purchase can't move to the requested status
+var ErrInvalidTransition = errors.New("invalid purchase status transition")
+
+// ErrConcurrentUpdate is returned when a record changed between read and write
+var ErrConcurrentUpdate = errors.New("record was modified concurrently; retry the request")
+
+// ErrPaymentDeclined is returned when the payment provider declines a payment
+var ErrPaymentDeclined = errors.New("payment declined")
+
+// ErrUnknownProvider is returned for webhooks of a payment provider that isn't configured
+var ErrUnknownProvider = errors.New("unknown payment provider")
+
+// ErrInvalidWebhook is returned when a webhook's signature or payload can't be verified
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// ErrInvalidCoupon is returned when a promo code can't be applied to a purchase
+var ErrInvalidCoupon = errors.New("invalid promo code")
+
+// ErrRefundExceedsBalance is returned when a refund is larger than what is
+// left to refund of a payment
+var ErrRefundExceedsBalance = errors.New("refund amount exceeds the refundable balance")
+
+// ErrIdempotencyKeyReused is returned when an idempotency key is sent again
+// with a different request
+var ErrIdempotencyKeyReused = errors.New("idempotency key was already used for a different request")
+
+// ErrInvalidTimezone is returned for a timezone that isn't a known IANA name
+var ErrInvalidTimezone = errors.New("invalid timezone")
+
+// ErrExportNotReady is returned when downloading an export job that hasn't
+// completed
+var ErrExportNotReady = errors.New("export is not ready")
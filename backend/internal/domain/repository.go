@@ -7,19 +7,57 @@ import (
 
 type PlanRepository interface {
 	Create(plan *models.Plan) error
-	GetByProduct(productID int, tenantID int) ([]models.Plan, error)
-	GetByTenant(tenantID int) ([]models.Plan, error)
+	GetByProduct(productID int, tenantID int, includeArchived bool) ([]models.Plan, error)
+	GetByTenant(tenantID int, includeArchived bool) ([]models.Plan, error)
 	GetByID(id int, tenantID int) (*models.Plan, error)
 	Update(plan *models.Plan) error
 	Delete(id int, tenantID int) error
+	SetArchived(id int, tenantID int, archivedAt *time.Time) error
+	CountActivePurchases(id int, tenantID int) (int, error)
 }
 
 type ProductRepository interface {
 	Create(product *models.Product) error
-	GetByTenant(tenantID int) ([]models.Product, error)
+	GetByTenant(tenantID int, includeArchived bool) ([]models.Product, error)
 	GetByID(id int, tenantID int) (*models.Product, error)
 	Update(product *models.Product) error
 	Delete(id int, tenantID int) error
+	SetArchived(id int, tenantID int, archivedAt *time.Time) error
+	CountActivePurchases(id int, tenantID int) (int, error)
+}
+
+type FeatureRepository interface {
+	Create(feature *models.Feature) error
+	GetByProduct(productID int, tenantID int) ([]models.Feature, error)
+	GetByProducts(productIDs []int, tenantID int) ([]models.Feature, error)
+	GetByID(id int, tenantID int) (*models.Feature, error)
+	GetByKey(productID int, key string, tenantID int) (*models.Feature, error)
+	Update(feature *models.Feature) error
+	Delete(id int, tenantID int) error
+	GetActivePlansForUser(userID, tenantID int) ([]models.Plan, error)
+	GetPlansByProduct(productID int, tenantID int) ([]models.Plan, error)
+}
+
+type StorefrontRepository interface {
+	GetActiveProducts(tenantID int) ([]models.Product, error)
+	GetActiveProduct(id int, tenantID int) (*models.Product, error)
+	GetFeaturesByProducts(productIDs []int, tenantID int) ([]models.Feature, error)
+}
+
+type CatalogRepository interface {
+	GetProductsByExternalKeys(keys []string, tenantID int) ([]models.Product, error)
+	GetPlansByExternalKeys(keys []string, tenantID int) ([]models.Plan, error)
+	GetFeaturesByProducts(productIDs []int, tenantID int) ([]models.Feature, error)
+	ApplyImport(products []*models.Product, features []*models.Feature, plans []*models.Plan) error
+	GetCatalog(tenantID int) ([]models.Product, error)
+}
+
+type TranslationRepository interface {
+	GetByEntity(entityType string, entityID int, tenantID int) ([]models.Translation, error)
+	GetByEntities(entityType string, entityIDs []int, locales []string, tenantID int) ([]models.Translation, error)
+	Upsert(translation *models.Translation) error
+	Delete(entityType string, entityID int, locale string, tenantID int) error
+	EntityExists(entityType string, entityID int, tenantID int) (bool, error)
 }
 
 type TenantRepository interface {
@@ -42,19 +80,160 @@ type UserRepository interface {
 }
 
 type AnalyticsRepository interface {
-	GetProductCount(tenantID int) (int, error)
-	GetActivePlanCount(tenantID int) (int, error)
-	GetTeamMemberCount(tenantID int) (int, error)
-	GetRevenueForPeriod(tenantID int, start, end time.Time) (float64, error)
-	GetActivePurchaseCount(tenantID int) (int, error)
-	GetRecentActivity(tenantID int, limit int) ([]models.Activity, error)
+	GetTenant(tenantID int) (*models.Tenant, error)
+	SumRevenueRollups(tenantID int, start, end time.Time) (*models.RevenueTotals, error)
+	GetRollupMRR(tenantID int, day time.Time) (float64, error)
+	GetRevenueEntries(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.RevenueEntry, error)
+	GetMRRAt(tenantID int, at time.Time) ([]models.MRRMovement, error)
+	GetMRRMovements(tenantID int, start, end time.Time, filter models.AnalyticsFilter) ([]models.MRRMovement, error)
+	GetCohortMembers(tenantID int, by string, start, end time.Time, filter models.AnalyticsFilter) ([]models.CohortMember, error)
+	GetPerformanceItems(tenantID int, by string) ([]models.ItemPerformance, error)
+	SumItemRevenue(tenantID int, by string, start, end time.Time, currency string) ([]models.ItemTotal, error)
+	CountItemSubscribers(tenantID int, by string, at time.Time) ([]models.ItemTotal, error)
+	CountItemChurn(tenantID int, by string, start, end time.Time) ([]models.ItemTotal, error)
+	CountItemTrials(tenantID int, by string, start, end time.Time) ([]models.ItemTrials, error)
+	ListActivities(tenantID int, query models.ActivityQuery) ([]models.Activity, error)
+	CountActivities(tenantID int, query models.ActivityQuery) (int64, error)
+	GetPlatformTenants() ([]models.Tenant, error)
+	SumPlatformRevenue(start, end time.Time) ([]models.CurrencyRevenue, error)
+	SumTenantRevenue(start, end time.Time, currency string) ([]models.TenantTotal, error)
+	GetLatestMRR() ([]models.TenantAmount, error)
+	GetLatestTenantRollups() ([]models.DailyTenantRollup, error)
+	CountUserSignups(start, end time.Time) ([]models.DateCount, error)
 }
 
 type PurchaseRepository interface {
 	CreatePurchase(purchase *models.Purchase) (*models.Purchase, error)
+	HasUsedTrial(userID, productID, tenantID int) (bool, error)
+	CreateTrialPurchase(purchase *models.Purchase, productID int) (bool, error)
 	GetUserPurchases(userID, tenantID int) ([]models.Purchase, error)
 	GetPurchaseByID(id, userID, tenantID int) (*models.Purchase, error)
+	GetPurchase(id, tenantID int) (*models.Purchase, error)
 	GetActivePurchases(userID, tenantID int) ([]models.Purchase, error)
 	GetPlanByID(planID, tenantID int) (*models.Plan, error)
 	CreateActivity(activity *models.Activity) error
+	CreateTransition(transition *models.PurchaseTransition) error
+	TransitionPurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error)
+	RepricePurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error)
+	UpdatePricing(purchase *models.Purchase) error
+	RecordMRRMovement(purchase *models.Purchase, movement *models.MRRMovement) error
+	GetDuePurchases(now, pastDueBefore, incompleteBefore time.Time, limit int) ([]models.Purchase, error)
+	GetTrialsEndingBetween(from, to time.Time, limit int) ([]models.Purchase, error)
+	MarkTrialReminderSent(purchase *models.Purchase) error
+	GetPurchaseTransitions(purchaseID, tenantID int) ([]models.PurchaseTransition, error)
+	CreatePayment(payment *models.Payment) error
+	UpdatePayment(payment *models.Payment) error
+	GetPaymentByIntent(provider, intentID string) (*models.Payment, error)
+	GetPayment(id, tenantID int) (*models.Payment, error)
+	GetLatestPayment(purchaseID, tenantID int) (*models.Payment, error)
+	GetPeriodPayment(purchaseID, tenantID int) (*models.Payment, error)
+	RecordRefund(refund *models.Refund) (bool, error)
+	SetRefundCreditNote(refund *models.Refund) error
+	GetRefunds(purchaseID, tenantID int) ([]models.Refund, error)
+}
+
+type WebhookRepository interface {
+	CreateIfNew(event *models.WebhookEvent) (bool, error)
+	GetByID(id int) (*models.WebhookEvent, error)
+	GetEvents(provider, status string, limit int) ([]models.WebhookEvent, error)
+	GetDue(now, staleBefore time.Time, limit int) ([]models.WebhookEvent, error)
+	Claim(id int, staleBefore time.Time) (bool, error)
+	Update(event *models.WebhookEvent) error
+	ResetForReplay(id int) error
+}
+
+type BillingRepository interface {
+	GetTenantSettings(tenantID int) (*models.TenantSettings, error)
+	SaveTenantSettings(settings *models.TenantSettings) error
+	GetBillingProfile(userID, tenantID int) (*models.BillingProfile, error)
+	SaveBillingProfile(profile *models.BillingProfile) error
+}
+
+type InvoiceRepository interface {
+	CreateFinalized(invoice *models.Invoice, prefix string) error
+	GetByID(id, tenantID int) (*models.Invoice, error)
+	GetByPayment(paymentID int) (*models.Invoice, error)
+	GetByRefund(refundID int) (*models.Invoice, error)
+	GetInvoices(tenantID int, userID *int) ([]models.Invoice, error)
+	GetPurchase(id, tenantID int) (*models.Purchase, error)
+	GetUser(id int) (*models.User, error)
+	GetTenant(id int) (*models.Tenant, error)
+}
+
+type TaxRepository interface {
+	GetRules(tenantID int) ([]models.TaxRule, error)
+	GetRulesForCountry(tenantID int, country string) ([]models.TaxRule, error)
+	GetRule(id, tenantID int) (*models.TaxRule, error)
+	CreateRule(rule *models.TaxRule) error
+	UpdateRule(rule *models.TaxRule) error
+	DeleteRule(id, tenantID int) error
+}
+
+type CouponRepository interface {
+	GetCoupons(tenantID int) ([]models.Coupon, error)
+	GetByID(id, tenantID int) (*models.Coupon, error)
+	GetByCode(code string, tenantID int) (*models.Coupon, error)
+	Create(coupon *models.Coupon) error
+	Update(coupon *models.Coupon) error
+	Delete(id, tenantID int) error
+	CountPlans(ids []int, tenantID int) (int, error)
+	Reserve(redemption *models.CouponRedemption, check func(coupon *models.Coupon, userRedemptions int64) error) error
+	Release(redemption *models.CouponRedemption) error
+	AttachPurchase(redemptionID, purchaseID int) error
+	GetRedemptionByPurchase(purchaseID, tenantID int) (*models.CouponRedemption, error)
+	GetReport(couponID, tenantID int) (*models.CouponReport, error)
+	GetRecentRedemptions(couponID, tenantID int) ([]models.CouponRedemption, error)
+}
+
+type IdempotencyRepository interface {
+	Claim(record *models.IdempotencyKey) (bool, error)
+	Get(tenantID, userID int, key string) (*models.IdempotencyKey, error)
+	TakeOver(record *models.IdempotencyKey, staleBefore time.Time) (bool, error)
+	Complete(record *models.IdempotencyKey) error
+	Delete(record *models.IdempotencyKey) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+type ActivityRepository interface {
+	Create(activity *models.Activity) error
+}
+
+type RollupRepository interface {
+	MarkStale(tenantID int, days ...time.Time) error
+	TakeStale(limit int) ([]models.RollupRefresh, error)
+	GetTenantIDs() ([]int, error)
+	GetTenant(id int) (*models.Tenant, error)
+	GetTenantRollup(tenantID int, day time.Time) (*models.DailyTenantRollup, error)
+	SaveTenantRollup(rollup *models.DailyTenantRollup) error
+	DeleteTenantRollups(tenantID int) error
+	ReplaceRevenueRollups(tenantID int, day time.Time, rollups []models.DailyRevenueRollup) error
+	CountTenant(tenantID int, start, end time.Time) (*models.DailyTenantRollup, error)
+	SumRevenue(tenantID int, start, end time.Time) ([]models.DailyRevenueRollup, error)
+}
+
+type DigestRepository interface {
+	Get(tenantID, userID int) (*models.DigestSubscription, error)
+	Save(subscription *models.DigestSubscription) error
+	Delete(tenantID, userID int) (bool, error)
+	DeleteByToken(token string) (bool, error)
+	GetDue(now time.Time, limit int) ([]models.DigestSubscription, error)
+	Claim(subscription *models.DigestSubscription, next time.Time) (bool, error)
+	MarkSent(subscription *models.DigestSubscription, at time.Time) error
+}
+
+type ExportRepository interface {
+	CountPurchases(tenantID int, query models.PurchaseQuery) (int64, error)
+	ListPurchases(tenantID int, query models.PurchaseQuery, afterID, limit int) ([]models.Purchase, error)
+	Create(job *models.ExportJob) error
+	Get(id, tenantID int) (*models.ExportJob, error)
+	SaveChunk(chunk *models.ExportChunk) error
+	GetChunk(jobID, seq int) (*models.ExportChunk, error)
+	DeleteChunks(jobID int) error
+	ClaimPending() (*models.ExportJob, error)
+	SaveResult(job *models.ExportJob) error
+	PurgeExpired(now, staleBefore, expiresAt time.Time) (int64, error)
+}
+
+type EventRepository interface {
+	ListSince(tenantID, afterID int, types []models.ActivityType, limit int) ([]models.Activity, error)
 }
@@ -1,30 +1,70 @@
 package domain
 
-import "backend/models"
+import (
+	coreDomain "backend/core/domain"
+	"backend/models"
+	"bufio"
+	"io"
+	"time"
+)
 
 type PlanService interface {
-	CreatePlan(req models.CreatePlanRequest, productID int, tenantID int) (*models.Plan, error)
-	GetPlansByProduct(productID int, tenantID int) ([]models.Plan, error)
-	GetPlansByTenant(tenantID int) ([]models.Plan, error)
+	CreatePlan(req models.CreatePlanRequest, productID int, userID, tenantID int) (*models.Plan, error)
+	GetPlansByProduct(productID int, tenantID int, includeArchived bool) ([]models.Plan, error)
+	GetPlansByTenant(tenantID int, includeArchived bool) ([]models.Plan, error)
 	GetPlanByID(id int, tenantID int) (*models.Plan, error)
-	UpdatePlan(id int, req models.CreatePlanRequest, tenantID int) (*models.Plan, error)
-	DeletePlan(id int, tenantID int) error
+	UpdatePlan(id int, req models.CreatePlanRequest, userID, tenantID int) (*models.Plan, error)
+	DeletePlan(id int, userID, tenantID int) error
+	ArchivePlan(id int, userID, tenantID int) (*models.Plan, error)
+	UnarchivePlan(id int, userID, tenantID int) (*models.Plan, error)
 }
 
 type ProductService interface {
-	CreateProduct(req models.CreateProductRequest, tenantID int) (*models.Product, error)
-	GetProductsByTenant(tenantID int) ([]models.Product, error)
+	CreateProduct(req models.CreateProductRequest, userID, tenantID int) (*models.Product, error)
+	GetProductsByTenant(tenantID int, includeArchived bool) ([]models.Product, error)
 	GetProductByID(id int, tenantID int) (*models.Product, error)
-	UpdateProduct(id int, req models.CreateProductRequest, tenantID int) (*models.Product, error)
-	DeleteProduct(id int, tenantID int) error
+	UpdateProduct(id int, req models.CreateProductRequest, userID, tenantID int) (*models.Product, error)
+	DeleteProduct(id int, userID, tenantID int) error
+	ArchiveProduct(id int, userID, tenantID int) (*models.Product, error)
+	UnarchiveProduct(id int, userID, tenantID int) (*models.Product, error)
+}
+
+type FeatureService interface {
+	CreateFeature(req models.CreateFeatureRequest, productID int, tenantID int) (*models.Feature, error)
+	GetFeaturesByProduct(productID int, tenantID int) ([]models.Feature, error)
+	GetFeatureByID(id int, tenantID int) (*models.Feature, error)
+	UpdateFeature(id int, req models.CreateFeatureRequest, tenantID int) (*models.Feature, error)
+	DeleteFeature(id int, tenantID int) error
+	ValidateDefinition(req models.CreateFeatureRequest) error
+	ValidatePlanFeatures(productID int, tenantID int, values models.JSONB) error
+	ValidatePlanValues(features []models.Feature, values models.JSONB) error
+	GetEntitlements(userID, tenantID int) ([]models.Entitlement, error)
+}
+
+type StorefrontService interface {
+	GetCatalog(tenantID int, tenantDomain string, baseURL string, locales []string) (*models.PublicCatalog, error)
+	GetProductImage(productID int, tenantID int) ([]byte, string, error)
+}
+
+type CatalogService interface {
+	Import(tenantID, userID int, format string, data []byte, dryRun bool) (*models.CatalogImportResult, error)
+	Export(tenantID int, format string) ([]byte, error)
+}
+
+type TranslationService interface {
+	GetTranslations(entityType string, entityID int, tenantID int) ([]models.Translation, error)
+	UpsertTranslation(entityType string, entityID int, locale string, req models.TranslationRequest, tenantID int) (*models.Translation, error)
+	DeleteTranslation(entityType string, entityID int, locale string, tenantID int) error
+	LocalizeProducts(products []models.Product, tenantID int, locales []string) error
+	LocalizePlans(plans []models.Plan, tenantID int, locales []string) error
 }
 
 type TenantService interface {
-	CreateTenant(req models.CreateTenantRequest) (*models.Tenant, error)
+	CreateTenant(req models.CreateTenantRequest, userID int) (*models.Tenant, error)
 	GetAllTenants() ([]models.Tenant, error)
 	GetTenantByID(id int) (*models.Tenant, error)
-	UpdateTenant(id int, req models.CreateTenantRequest) (*models.Tenant, error)
-	DeleteTenant(id int) error
+	UpdateTenant(id int, req models.CreateTenantRequest, userID int) (*models.Tenant, error)
+	DeleteTenant(id int, userID int) error
 }
 
 type AuthService interface {
@@ -35,13 +75,136 @@ type AuthService interface {
 }
 
 type AnalyticsService interface {
+	GetLocalTime(tenantID int, timezone string) (time.Time, error)
 	GetDashboardMetrics(tenantID int) (*models.DashboardMetrics, error)
-	GetRecentActivity(tenantID int) ([]models.Activity, error)
+	GetPeriodMetrics(tenantID int, start, end, previousStart time.Time) (*models.DashboardMetrics, error)
+	GetRevenueSeries(tenantID int, query models.RevenueQuery) (*models.RevenueAnalytics, error)
+	GetCohorts(tenantID int, query models.CohortQuery) (*models.CohortAnalytics, error)
+	GetPerformance(tenantID int, by string, query models.PerformanceQuery) (*models.PerformancePage, error)
+	GetActivities(tenantID int, query models.ActivityQuery) (*models.ActivityPage, error)
+	CountActivities(tenantID int, query models.ActivityQuery) (int, error)
+	GetPlatformTime() time.Time
+	GetPlatformOverview(query models.PlatformQuery) (*models.PlatformOverview, error)
+	GetTopTenants(query models.PlatformQuery) (*models.TopTenants, error)
+	GetSignupTrends(query models.RevenueQuery) (*models.SignupTrends, error)
+	GetTenantHealth(query models.TenantHealthQuery) (*models.TenantHealthPage, error)
 }
 
 type PurchaseService interface {
 	CreatePurchase(userID, tenantID int, req models.CreatePurchaseRequest) (*models.Purchase, error)
+	QuotePurchase(userID, tenantID, planID int, promoCode string) (*models.PurchaseQuote, error)
 	GetUserPurchases(userID, tenantID int) ([]models.Purchase, error)
 	GetPurchaseByID(id, userID, tenantID int) (*models.Purchase, error)
 	GetActivePurchases(userID, tenantID int) ([]models.Purchase, error)
+	ConfirmPurchase(id, userID, tenantID int) (*models.Purchase, error)
+	CancelPurchase(id, userID, tenantID int, atPeriodEnd bool) (*models.Purchase, error)
+	ResumePurchase(id, userID, tenantID int) (*models.Purchase, error)
+	GetPurchaseTransitions(id, userID, tenantID int) ([]models.PurchaseTransition, error)
+	PreviewPlanChange(id, userID, tenantID int, req models.ChangePlanRequest) (*models.PlanChangePreview, error)
+	ChangePlan(id, userID, tenantID int, req models.ChangePlanRequest) (*models.Purchase, error)
+	UpdatePaymentMethod(id, userID, tenantID int, req models.UpdatePaymentMethodRequest) (*models.Purchase, error)
+	RefundPurchase(id, adminID, tenantID int, req models.RefundRequest) (*models.Refund, error)
+	GetRefunds(id, tenantID int) ([]models.Refund, error)
+	ProcessDuePurchases(now time.Time) (int, error)
+	ProcessTrialReminders(now time.Time) (int, error)
+	ApplyPaymentEvent(provider string, event coreDomain.PaymentWebhookEvent) error
+}
+
+type WebhookService interface {
+	Ingest(provider string, payload []byte, headers map[string][]string) (*models.WebhookEvent, bool, error)
+	ProcessEvent(id int) error
+	ProcessDue(now time.Time) (int, error)
+	GetEvents(provider, status string, limit int) ([]models.WebhookEvent, error)
+	ReplayEvent(id int) (*models.WebhookEvent, error)
+}
+
+type BillingService interface {
+	GetTenantSettings(tenantID int) (*models.TenantSettings, error)
+	UpdateTenantSettings(tenantID int, req models.UpdateTenantSettingsRequest) (*models.TenantSettings, error)
+	GetBillingProfile(userID, tenantID int) (*models.BillingProfile, error)
+	UpdateBillingProfile(userID, tenantID int, req models.UpdateBillingProfileRequest) (*models.BillingProfile, error)
+}
+
+type InvoiceService interface {
+	IssueForPayment(payment *models.Payment) (*models.Invoice, error)
+	IssueCreditNote(refund *models.Refund) (*models.Invoice, error)
+	GetInvoices(userID, tenantID int, asAdmin bool) ([]models.Invoice, error)
+	GetInvoice(id, userID, tenantID int, asAdmin bool) (*models.Invoice, error)
+	RenderHTML(invoice *models.Invoice) ([]byte, error)
+	RenderPDF(invoice *models.Invoice) ([]byte, error)
+	SendInvoice(id, userID, tenantID int, asAdmin bool) error
+}
+
+// TaxEngine works out the tax due on a sale
+type TaxEngine interface {
+	Calculate(req models.TaxRequest) (*models.TaxQuote, error)
+}
+
+type TaxService interface {
+	GetTaxRules(tenantID int) ([]models.TaxRule, error)
+	CreateTaxRule(tenantID int, req models.TaxRuleRequest) (*models.TaxRule, error)
+	UpdateTaxRule(id, tenantID int, req models.TaxRuleRequest) (*models.TaxRule, error)
+	DeleteTaxRule(id, tenantID int) error
+}
+
+type CouponService interface {
+	GetCoupons(tenantID int) ([]models.Coupon, error)
+	GetCoupon(id, tenantID int) (*models.Coupon, error)
+	CreateCoupon(tenantID int, req models.CouponRequest) (*models.Coupon, error)
+	UpdateCoupon(id, tenantID int, req models.CouponRequest) (*models.Coupon, error)
+	DeleteCoupon(id, tenantID int) error
+	GetCouponReport(id, tenantID int) (*models.CouponReport, error)
+	Apply(code string, plan *models.Plan, tenantID int) (*models.Coupon, float64, error)
+	Reserve(coupon *models.Coupon, userID int, discount float64, currency string) (*models.CouponRedemption, error)
+	AttachPurchase(redemption *models.CouponRedemption, purchaseID int) error
+	Release(redemption *models.CouponRedemption) error
+	ReleaseForPurchase(purchaseID, tenantID int) error
+}
+
+type IdempotencyService interface {
+	Begin(tenantID, userID int, key, fingerprint string) (*models.IdempotencyKey, error)
+	Complete(record *models.IdempotencyKey, status int, contentType string, body []byte) error
+	Release(record *models.IdempotencyKey) error
+	PurgeExpired(now time.Time) (int64, error)
+}
+
+type ActivityService interface {
+	Record(activity *models.Activity)
+}
+
+type RollupService interface {
+	Touch(tenantID int, at time.Time)
+	Refresh(tenantID int, at time.Time) error
+	GetTenantDay(tenantID int, at time.Time) (*models.DailyTenantRollup, error)
+	ProcessStale() (int, error)
+	Reconcile(now time.Time, days int) (int, error)
+	Backfill(tenantID int, from, to time.Time) (int, error)
+	Rebuild(tenantID int) error
+}
+
+type DigestService interface {
+	GetSubscription(tenantID, userID int) (*models.DigestSubscription, error)
+	Subscribe(tenantID, userID int, req models.DigestSubscriptionRequest) (*models.DigestSubscription, error)
+	Unsubscribe(tenantID, userID int) error
+	UnsubscribeByToken(token string) error
+	ProcessDue(now time.Time) (int, error)
+}
+
+type ExportService interface {
+	GetLocalTime(tenantID int, timezone string) (time.Time, error)
+	Prepare(tenantID, userID int, kind, format string, query models.ExportQuery) (*models.ExportJob, error)
+	Write(w io.Writer, tenantID int, kind, format string, query models.ExportQuery) (int, error)
+	GetJob(id, tenantID int) (*models.ExportJob, error)
+	GetFile(id, tenantID int) (*models.ExportJob, error)
+	WriteFile(w io.Writer, job *models.ExportJob) error
+	ProcessPending() (int, error)
+	PurgeExpired(now time.Time) (int64, error)
+}
+
+type EventService interface {
+	Publish(activity *models.Activity)
+	Subscribe(tenantID int) (<-chan coreDomain.Event, func())
+	Replay(tenantID, afterID int, types []models.ActivityType, limit int) ([]coreDomain.Event, error)
+	Stream(w *bufio.Writer, tenantID, lastEventID int, types []models.ActivityType) error
+	CreateStreamToken(userID int, email, role string, tenantID int) (string, time.Time, error)
 }
@@ -0,0 +1,30 @@
+package events
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// RegisterPublisher publishes every activity created through db, whichever
+// service records it. Activities are created outside transactions, so they
+// are committed by the time they're published.
+func RegisterPublisher(db *gorm.DB, service *Service) error {
+	return db.Callback().Create().After("gorm:create").Register("events:publish_activity", func(tx *gorm.DB) {
+		if tx.Error != nil || tx.DryRun || tx.Statement.Schema == nil || tx.Statement.Schema.Table != "activities" {
+			return
+		}
+		switch created := tx.Statement.Dest.(type) {
+		case *models.Activity:
+			service.Publish(created)
+		case []models.Activity:
+			for i := range created {
+				service.Publish(&created[i])
+			}
+		case *[]models.Activity:
+			for i := range *created {
+				service.Publish(&(*created)[i])
+			}
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"bufio"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.EventService
+}
+
+func NewController(service domain.EventService) *Controller {
+	return &Controller{service: service}
+}
+
+// CreateStreamToken issues a short-lived token for opening the current
+// tenant's event stream, to pass as its token query parameter. Unlike the
+// session token it may be logged with the URL without harm.
+func (c *Controller) CreateStreamToken(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+	email, _ := ctx.Locals("email").(string)
+	role, _ := ctx.Locals("role").(string)
+
+	token, expiresAt, err := c.service.CreateStreamToken(userID, email, role, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data": fiber.Map{
+			"token":      token,
+			"expires_at": expiresAt,
+		},
+	})
+}
+
+// Stream streams the current tenant's events as server-sent events, each
+// named after its activity type and carrying the activity. type takes a
+// comma-separated list of activity types to stream, all by default. A
+// client reconnecting with the Last-Event-ID header, or the last_event_id
+// query parameter, first gets the events it missed.
+func (c *Controller) Stream(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var types []models.ActivityType
+	if value := ctx.Query("type"); value != "" {
+		for _, name := range strings.Split(value, ",") {
+			activityType := models.ActivityType(strings.TrimSpace(name))
+			if !activityType.Valid() {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   true,
+					"message": "unknown activity type: " + string(activityType),
+				})
+			}
+			types = append(types, activityType)
+		}
+	}
+
+	lastEventID, err := parseLastEventID(ctx)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	ctx.Set("X-Accel-Buffering", "no")
+	tenant := *tenantID
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := c.service.Stream(w, tenant, lastEventID, types); err != nil {
+			log.Printf("Event stream of tenant %d failed: %v", tenant, err)
+		}
+	})
+	return nil
+}
+
+// parseLastEventID reads the ID of the last event a client got, 0 if it's
+// new
+func parseLastEventID(ctx *fiber.Ctx) (int, error) {
+	value := ctx.Get("Last-Event-ID", ctx.Query("last_event_id"))
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid Last-Event-ID")
+	}
+	return id, nil
+}
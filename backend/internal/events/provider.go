@@ -0,0 +1,17 @@
+package events
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+
+	wire.Bind(new(domain.EventControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.EventService), new(*Service)),
+	wire.Bind(new(domain.EventRepository), new(*Repository)),
+)
@@ -0,0 +1,28 @@
+package events
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// ListSince returns up to limit of the tenant's activities of the types, any
+// if none, with an ID above afterID, oldest first
+func (r *Repository) ListSince(tenantID, afterID int, types []models.ActivityType, limit int) ([]models.Activity, error) {
+	db := r.db.Where("tenant_id = ? AND id > ?", tenantID, afterID)
+	if len(types) > 0 {
+		db = db.Where("type IN ?", types)
+	}
+
+	var activities []models.Activity
+	err := db.Order("id").Limit(limit).Find(&activities).Error
+	return activities, err
+}
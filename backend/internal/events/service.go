@@ -0,0 +1,169 @@
+package events
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/internal/domain"
+	"backend/internal/middleware"
+	"backend/models"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// replayBatchSize bounds the missed events loaded at once on resume
+const replayBatchSize = 500
+
+// reconnectDelay is how long clients wait before reconnecting a closed stream
+const reconnectDelay = 3 * time.Second
+
+// Service publishes the activities recorded in a tenant as events and
+// replays those a subscriber missed
+type Service struct {
+	repo domain.EventRepository
+	bus  coreDomain.EventBus
+	cfg  *core.Config
+}
+
+func NewService(repo domain.EventRepository, bus coreDomain.EventBus, cfg *core.Config) *Service {
+	return &Service{repo: repo, bus: bus, cfg: cfg}
+}
+
+// Publish publishes the activity as an event of its type. The activity is
+// stored by then, so an event that isn't published is missing only from the
+// live stream.
+func (s *Service) Publish(activity *models.Activity) {
+	event, err := toEvent(activity)
+	if err == nil {
+		err = s.bus.Publish(event)
+	}
+	if err != nil {
+		log.Printf("Failed to publish %s event of tenant %d: %v", activity.Type, activity.TenantID, err)
+	}
+}
+
+// CreateStreamToken issues a token opening the tenant's event stream for
+// the user, and returns it with its expiry. The stream only checks it on
+// connecting, so it need not outlive that.
+func (s *Service) CreateStreamToken(userID int, email, role string, tenantID int) (string, time.Time, error) {
+	expiresAt := time.Now().Add(s.cfg.EventTokenTTL)
+	token, err := middleware.GenerateStreamToken(userID, email, role, &tenantID, s.cfg.JWTSecret, s.cfg.EventTokenTTL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to create stream token: %w", err)
+	}
+	return token, expiresAt, nil
+}
+
+// Subscribe returns the channel of the tenant's events from now on and a
+// function ending the subscription
+func (s *Service) Subscribe(tenantID int) (<-chan coreDomain.Event, func()) {
+	return s.bus.Subscribe(tenantID)
+}
+
+// Replay returns up to limit of the tenant's events of the types, any if
+// none, that came after the event afterID, oldest first
+func (s *Service) Replay(tenantID, afterID int, types []models.ActivityType, limit int) ([]coreDomain.Event, error) {
+	activities, err := s.repo.ListSince(tenantID, afterID, types, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]coreDomain.Event, 0, len(activities))
+	for i := range activities {
+		event, err := toEvent(&activities[i])
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// Stream writes the tenant's events of the types, any if none, to w as
+// server-sent events, after replaying those since lastEventID if it's set.
+// A comment is sent every heartbeat interval to keep the connection open and
+// notice clients that left. It returns once the client is gone or has fallen
+// behind; the client then reconnects and resumes from the last event it got.
+func (s *Service) Stream(w *bufio.Writer, tenantID, lastEventID int, types []models.ActivityType) error {
+	// Subscribing before replaying ensures no event falls in between
+	events, unsubscribe := s.Subscribe(tenantID)
+	defer unsubscribe()
+
+	fmt.Fprintf(w, "retry: %d\n\n", reconnectDelay.Milliseconds())
+	if err := w.Flush(); err != nil {
+		return nil // client is gone
+	}
+
+	replayed := lastEventID
+	for lastEventID > 0 {
+		missed, err := s.Replay(tenantID, replayed, types, replayBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, event := range missed {
+			writeEvent(w, event)
+			replayed = event.ID
+		}
+		if err := w.Flush(); err != nil {
+			return nil // client is gone
+		}
+		if len(missed) < replayBatchSize {
+			break
+		}
+	}
+
+	heartbeat := time.NewTicker(s.cfg.EventHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if event.ID <= replayed || !matches(event, types) {
+				continue
+			}
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			w.WriteString(": heartbeat\n\n")
+		}
+		if err := w.Flush(); err != nil {
+			return nil // client is gone
+		}
+	}
+}
+
+// writeEvent writes the event in the server-sent events format
+func writeEvent(w *bufio.Writer, event coreDomain.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// matches returns whether the event is of one of the types, or any if none
+func matches(event coreDomain.Event, types []models.ActivityType) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, activityType := range types {
+		if string(activityType) == event.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// toEvent turns an activity into the event announcing it, identified by the
+// activity's ID
+func toEvent(activity *models.Activity) (coreDomain.Event, error) {
+	data, err := json.Marshal(activity)
+	if err != nil {
+		return coreDomain.Event{}, err
+	}
+	return coreDomain.Event{
+		ID:       activity.ID,
+		TenantID: activity.TenantID,
+		Type:     string(activity.Type),
+		Data:     data,
+	}, nil
+}
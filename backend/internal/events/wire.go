@@ -0,0 +1,26 @@
+//go:build wireinject
+// +build wireinject
+
+package events
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewControllerWire(db *gorm.DB, cfg *core.Config, bus coreDomain.EventBus) *Controller {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Controller{}
+}
+
+// NewServiceWire builds the event service publishing recorded activities
+func NewServiceWire(db *gorm.DB, cfg *core.Config, bus coreDomain.EventBus) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
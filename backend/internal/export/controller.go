@@ -0,0 +1,228 @@
+package export
+
+import (
+	"backend/internal/analytics"
+	"backend/internal/domain"
+	"backend/models"
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.ExportService
+}
+
+func NewController(service domain.ExportService) *Controller {
+	return &Controller{service: service}
+}
+
+// ExportPurchases exports the tenant's purchases, filtered by the status,
+// user_id, product_id, plan_id, from and to query parameters
+func (c *Controller) ExportPurchases(ctx *fiber.Ctx) error {
+	return c.export(ctx, models.ExportPurchases, func(now time.Time, query *models.ExportQuery) error {
+		purchases, err := parsePurchaseQuery(ctx, now.Location())
+		query.Purchases = &purchases
+		return err
+	})
+}
+
+// ExportRevenue exports the tenant's revenue series, taking the query
+// parameters of the revenue analytics
+func (c *Controller) ExportRevenue(ctx *fiber.Ctx) error {
+	return c.export(ctx, models.ExportRevenue, func(now time.Time, query *models.ExportQuery) error {
+		revenue, err := analytics.ParseRevenueQuery(ctx, now)
+		query.Revenue = &revenue
+		return err
+	})
+}
+
+// ExportActivity exports the tenant's activity feed, taking the query
+// parameters of the feed. It covers every matching activity, not a page.
+func (c *Controller) ExportActivity(ctx *fiber.Ctx) error {
+	return c.export(ctx, models.ExportActivity, func(now time.Time, query *models.ExportQuery) error {
+		activity, err := analytics.ParseActivityQuery(ctx, now.Location())
+		query.Activity = &activity
+		return err
+	})
+}
+
+// export streams the export in the format query parameter, csv by default,
+// or answers 202 with the job building it if it's too large to stream
+func (c *Controller) export(ctx *fiber.Ctx, kind string, parse func(now time.Time, query *models.ExportQuery) error) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+	format := strings.ToLower(ctx.Query("format", models.ExportFormatCSV))
+
+	now, err := c.service.GetLocalTime(*tenantID, ctx.Query("tz"))
+	if errors.Is(err, domain.ErrInvalidTimezone) {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "tz must be an IANA timezone like Europe/Berlin",
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	query := models.ExportQuery{Timezone: now.Location().String()}
+	if err := parse(now, &query); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	job, err := c.service.Prepare(*tenantID, userID, kind, format, query)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if job != nil {
+		return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+			"error":   false,
+			"message": "Export is too large to stream and is being prepared",
+			"data":    job,
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, contentType(format))
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename(kind, format, now)))
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if _, err := c.service.Write(w, *tenantID, kind, format, query); err != nil {
+			log.Printf("Failed to stream %s export of tenant %d: %v", kind, *tenantID, err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// GetExport gets the status of an export job, with its download URL once
+// completed
+func (c *Controller) GetExport(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid export ID",
+		})
+	}
+
+	job, err := c.service.GetJob(id, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  job,
+	})
+}
+
+// DownloadExport streams the file of a completed export job
+func (c *Controller) DownloadExport(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid export ID",
+		})
+	}
+
+	job, err := c.service.GetFile(id, *tenantID)
+	if errors.Is(err, domain.ErrExportNotReady) {
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, contentType(job.Format))
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, job.Filename))
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := c.service.WriteFile(w, job); err != nil {
+			log.Printf("Failed to download export %d: %v", job.ID, err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// parsePurchaseQuery reads the status (comma-separated), user_id,
+// product_id, plan_id and inclusive from and to date query parameters.
+// Dates are in location; without them all purchases are exported.
+func parsePurchaseQuery(ctx *fiber.Ctx, location *time.Location) (models.PurchaseQuery, error) {
+	var query models.PurchaseQuery
+
+	if value := ctx.Query("status"); value != "" {
+		for _, status := range strings.Split(value, ",") {
+			status = strings.TrimSpace(status)
+			switch status {
+			case models.PurchaseStatusIncomplete, models.PurchaseStatusTrialing, models.PurchaseStatusActive,
+				models.PurchaseStatusPastDue, models.PurchaseStatusCancelled, models.PurchaseStatusExpired:
+			default:
+				return query, errors.New("unknown purchase status: " + status)
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+
+	ids := []struct {
+		name   string
+		target **int
+	}{
+		{"user_id", &query.UserID},
+		{"product_id", &query.ProductID},
+		{"plan_id", &query.PlanID},
+	}
+	for _, param := range ids {
+		if value := ctx.Query(param.name); value != "" {
+			id, err := strconv.Atoi(value)
+			if err != nil {
+				return query, errors.New("invalid " + param.name)
+			}
+			*param.target = &id
+		}
+	}
+
+	if value := ctx.Query("from"); value != "" {
+		from, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			return query, errors.New("from must be a date like 2006-01-02")
+		}
+		query.From = from
+	}
+	if value := ctx.Query("to"); value != "" {
+		to, err := time.ParseInLocation("2006-01-02", value, location)
+		if err != nil {
+			return query, errors.New("to must be a date like 2006-01-02")
+		}
+		query.To = to.AddDate(0, 0, 1)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return query, errors.New("from must not be after to")
+	}
+	return query, nil
+}
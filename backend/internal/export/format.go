@@ -0,0 +1,193 @@
+package export
+
+import (
+	"archive/zip"
+	"backend/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rowWriter writes a table row by row. Cells are strings, ints, float64s,
+// times, nil pointers or nil, which is an empty cell.
+type rowWriter interface {
+	WriteRow(cells []interface{}) error
+	Close() error
+}
+
+// newRowWriter returns a writer of the format writing to w, times shown in
+// location
+func newRowWriter(format string, w io.Writer, location *time.Location) (rowWriter, error) {
+	switch format {
+	case models.ExportFormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), location: location}, nil
+	case models.ExportFormatXLSX:
+		return newXLSXWriter(w, location)
+	}
+	return nil, fmt.Errorf("unsupported format '%s'", format)
+}
+
+// contentType returns the MIME type of files of the format
+func contentType(format string) string {
+	if format == models.ExportFormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+// cellText renders a cell as text. Numbers keep the precision they have;
+// times are RFC 3339 in location. Text that a spreadsheet would take for a
+// formula, such as a customer name starting with =, is escaped.
+func cellText(cell interface{}, location *time.Location) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return escapeFormula(v)
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.In(location).Format(time.RFC3339)
+	case *int:
+		if v == nil {
+			return ""
+		}
+		return strconv.Itoa(*v)
+	case *float64:
+		if v == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*v, 'f', -1, 64)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.In(location).Format(time.RFC3339)
+	}
+	return escapeFormula(fmt.Sprint(cell))
+}
+
+// escapeFormula prefixes text starting like a formula with ', so that
+// spreadsheets show it as text instead of evaluating it
+func escapeFormula(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+type csvWriter struct {
+	w        *csv.Writer
+	location *time.Location
+	record   []string
+}
+
+func (c *csvWriter) WriteRow(cells []interface{}) error {
+	c.record = c.record[:0]
+	for _, cell := range cells {
+		c.record = append(c.record, cellText(cell, c.location))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// xlsxWriter streams a workbook of one worksheet. Strings are written inline
+// so that rows need not be kept for a shared strings table.
+type xlsxWriter struct {
+	zip      *zip.Writer
+	sheet    *bufio.Writer
+	location *time.Location
+	rows     int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+
+const xlsxRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Export" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+
+func newXLSXWriter(w io.Writer, location *time.Location) (*xlsxWriter, error) {
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(file)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &xlsxWriter{zip: archive, sheet: sheet, location: location}, nil
+}
+
+func (x *xlsxWriter) WriteRow(cells []interface{}) error {
+	x.rows++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.rows)
+	for i, cell := range cells {
+		ref := columnName(i) + strconv.Itoa(x.rows)
+		switch v := cell.(type) {
+		case int, float64, *int, *float64:
+			if text := cellText(v, x.location); text != "" {
+				fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, text)
+			}
+		default:
+			if text := cellText(v, x.location); text != "" {
+				fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+				xml.EscapeText(x.sheet, []byte(text))
+				x.sheet.WriteString(`</t></is></c>`)
+			}
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+func (x *xlsxWriter) Close() error {
+	x.sheet.WriteString(`</sheetData></worksheet>`)
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Close()
+}
+
+// columnName returns the letters of the zero-based column, e.g. 27 is AB
+func columnName(column int) string {
+	name := ""
+	for column++; column > 0; column = (column - 1) / 26 {
+		name = string(rune('A'+(column-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,28 @@
+package export
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCSVEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newRowWriter("csv", &buf, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount := -5
+	cells := []interface{}{"=HYPERLINK(\"http://example.com\")", "+1", "-1", "@SUM(A1)", "\tx", "\rx", "plain", -5, &amount, -2.5}
+	if err := writer.WriteRow(cells); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "\"'=HYPERLINK(\"\"http://example.com\"\")\",'+1,'-1,'@SUM(A1),'\tx,\"'\rx\",plain,-5,-5,-2.5\n"
+	if buf.String() != want {
+		t.Fatalf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,19 @@
+package export
+
+import (
+	"backend/internal/analytics"
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	analytics.ProviderSet,
+
+	wire.Bind(new(domain.ExportControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.ExportService), new(*Service)),
+	wire.Bind(new(domain.ExportRepository), new(*Repository)),
+)
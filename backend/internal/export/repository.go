@@ -0,0 +1,166 @@
+package export
+
+import (
+	"backend/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CountPurchases counts the tenant's purchases matching the query
+func (r *Repository) CountPurchases(tenantID int, query models.PurchaseQuery) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Purchase{}).Scopes(purchaseScope(tenantID, query)).Count(&count).Error
+	return count, err
+}
+
+// ListPurchases returns up to limit of the tenant's purchases matching the
+// query with an ID above afterID, in ID order, with their user, plan and
+// product, even if since deleted
+func (r *Repository) ListPurchases(tenantID int, query models.PurchaseQuery, afterID, limit int) ([]models.Purchase, error) {
+	var purchases []models.Purchase
+	err := r.db.Scopes(purchaseScope(tenantID, query)).
+		Where("purchases.id > ?", afterID).
+		Preload("User", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Plan", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Plan.Product", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Order("purchases.id").
+		Limit(limit).
+		Find(&purchases).Error
+	return purchases, err
+}
+
+// purchaseScope restricts a query to the tenant's purchases matching the
+// query's filters
+func purchaseScope(tenantID int, query models.PurchaseQuery) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		db = db.Where("purchases.tenant_id = ?", tenantID)
+		if len(query.Statuses) > 0 {
+			db = db.Where("purchases.status IN ?", query.Statuses)
+		}
+		if query.UserID != nil {
+			db = db.Where("purchases.user_id = ?", *query.UserID)
+		}
+		if query.PlanID != nil {
+			db = db.Where("purchases.plan_id = ?", *query.PlanID)
+		}
+		if query.ProductID != nil {
+			db = db.Where("purchases.plan_id IN (?)",
+				db.Session(&gorm.Session{NewDB: true}).Unscoped().Model(&models.Plan{}).Select("id").Where("product_id = ?", *query.ProductID))
+		}
+		if !query.From.IsZero() {
+			db = db.Where("purchases.created_at >= ?", query.From)
+		}
+		if !query.To.IsZero() {
+			db = db.Where("purchases.created_at < ?", query.To)
+		}
+		return db
+	}
+}
+
+func (r *Repository) Create(job *models.ExportJob) error {
+	return r.db.Create(job).Error
+}
+
+// Get returns the tenant's export job
+func (r *Repository) Get(id, tenantID int) (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&job).Error
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *Repository) SaveChunk(chunk *models.ExportChunk) error {
+	return r.db.Create(chunk).Error
+}
+
+// GetChunk returns the seq-th chunk of the job's file
+func (r *Repository) GetChunk(jobID, seq int) (*models.ExportChunk, error) {
+	var chunk models.ExportChunk
+	err := r.db.Where("job_id = ? AND seq = ?", jobID, seq).First(&chunk).Error
+	if err != nil {
+		return nil, err
+	}
+	return &chunk, nil
+}
+
+// DeleteChunks deletes the job's file
+func (r *Repository) DeleteChunks(jobID int) error {
+	return r.db.Where("job_id = ?", jobID).Delete(&models.ExportChunk{}).Error
+}
+
+// ClaimPending marks the oldest pending export job running and returns it,
+// nil if there is none. Jobs claimed by other instances are skipped.
+func (r *Repository) ClaimPending() (*models.ExportJob, error) {
+	var job models.ExportJob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", models.ExportStatusPending).
+			Order("id").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+		job.Status = models.ExportStatusRunning
+		return tx.Model(&job).Update("status", job.Status).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// SaveResult stores the outcome of a running export job
+func (r *Repository) SaveResult(job *models.ExportJob) error {
+	return r.db.Model(job).Select("status", "rows", "size", "chunks", "error", "completed_at", "expires_at").Updates(job).Error
+}
+
+// PurgeExpired deletes export jobs that expired before now with their files,
+// and fails jobs left running since before staleBefore by an instance that
+// stopped, to expire at expiresAt, dropping the part of the file written. It
+// returns the number of jobs deleted.
+func (r *Repository) PurgeExpired(now, staleBefore, expiresAt time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		stale := tx.Model(&models.ExportJob{}).Select("id").
+			Where("status = ? AND updated_at < ?", models.ExportStatusRunning, staleBefore)
+		if err := tx.Where("job_id IN (?)", stale).Delete(&models.ExportChunk{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.ExportJob{}).
+			Where("status = ? AND updated_at < ?", models.ExportStatusRunning, staleBefore).
+			Updates(map[string]interface{}{
+				"status":       models.ExportStatusFailed,
+				"error":        "export was interrupted",
+				"completed_at": now,
+				"expires_at":   expiresAt,
+			}).Error
+		if err != nil {
+			return err
+		}
+
+		expired := tx.Model(&models.ExportJob{}).Select("id").Where("expires_at < ?", now)
+		if err := tx.Where("job_id IN (?)", expired).Delete(&models.ExportChunk{}).Error; err != nil {
+			return err
+		}
+		result := tx.Where("expires_at < ?", now).Delete(&models.ExportJob{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
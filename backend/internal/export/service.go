@@ -0,0 +1,437 @@
+package export
+
+import (
+	"backend/core"
+	"backend/internal/domain"
+	"backend/models"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportBatchSize bounds the rows loaded at once while writing an export
+const exportBatchSize = 500
+
+// exportJobsPerRun bounds the export jobs built per run
+const exportJobsPerRun = 10
+
+// staleExportAfter is how long a job may run before it's deemed abandoned
+const staleExportAfter = time.Hour
+
+// exportChunkSize is the size of the chunks files of export jobs are stored in
+const exportChunkSize = 1 << 20
+
+type Service struct {
+	repo      domain.ExportRepository
+	analytics domain.AnalyticsService
+	cfg       *core.Config
+}
+
+func NewService(repo domain.ExportRepository, analytics domain.AnalyticsService, cfg *core.Config) *Service {
+	return &Service{repo: repo, analytics: analytics, cfg: cfg}
+}
+
+// GetLocalTime returns the current time in the timezone, the tenant's if
+// empty
+func (s *Service) GetLocalTime(tenantID int, timezone string) (time.Time, error) {
+	return s.analytics.GetLocalTime(tenantID, timezone)
+}
+
+// Prepare checks an export and returns nil if it's small enough to stream
+// right away. Otherwise it queues a job building it and returns the job.
+// Revenue series are bounded in length and always streamed.
+func (s *Service) Prepare(tenantID, userID int, kind, format string, query models.ExportQuery) (*models.ExportJob, error) {
+	if format != models.ExportFormatCSV && format != models.ExportFormatXLSX {
+		return nil, errors.New("format must be csv or xlsx")
+	}
+
+	rows := 0
+	switch kind {
+	case models.ExportPurchases:
+		count, err := s.repo.CountPurchases(tenantID, *query.Purchases)
+		if err != nil {
+			return nil, err
+		}
+		rows = int(count)
+	case models.ExportActivity:
+		count, err := s.analytics.CountActivities(tenantID, *query.Activity)
+		if err != nil {
+			return nil, err
+		}
+		rows = count
+	case models.ExportRevenue:
+	default:
+		return nil, fmt.Errorf("unknown export '%s'", kind)
+	}
+	if rows <= s.cfg.ExportSyncRows {
+		return nil, nil
+	}
+
+	encoded, err := encodeQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	job := &models.ExportJob{
+		TenantID: tenantID,
+		UserID:   userID,
+		Kind:     kind,
+		Format:   format,
+		Query:    encoded,
+		Status:   models.ExportStatusPending,
+		Rows:     rows,
+		Filename: filename(kind, format, time.Now()),
+	}
+	if err := s.repo.Create(job); err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	return job, nil
+}
+
+// Write writes the export to w and returns the number of rows written,
+// besides the header
+func (s *Service) Write(w io.Writer, tenantID int, kind, format string, query models.ExportQuery) (int, error) {
+	location, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	writer, err := newRowWriter(format, w, location)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int
+	switch kind {
+	case models.ExportPurchases:
+		rows, err = s.writePurchases(writer, tenantID, *query.Purchases)
+	case models.ExportRevenue:
+		rows, err = s.writeRevenue(writer, tenantID, *query.Revenue)
+	case models.ExportActivity:
+		rows, err = s.writeActivity(writer, tenantID, *query.Activity)
+	default:
+		err = fmt.Errorf("unknown export '%s'", kind)
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, writer.Close()
+}
+
+func (s *Service) writePurchases(w rowWriter, tenantID int, query models.PurchaseQuery) (int, error) {
+	err := w.WriteRow([]interface{}{
+		"ID", "Created at", "Status", "User ID", "User email", "User name", "Product ID", "Product", "Plan ID", "Plan",
+		"Currency", "Amount", "Net amount", "Tax amount", "Tax rate", "Tax name", "List price", "Discount amount",
+		"Refunded amount", "MRR", "Payment provider", "Transaction ID", "Current period end", "Cancel at period end",
+		"Cancelled at", "Trial ends at",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows, afterID := 0, 0
+	for {
+		purchases, err := s.repo.ListPurchases(tenantID, query, afterID, exportBatchSize)
+		if err != nil {
+			return rows, err
+		}
+		for _, purchase := range purchases {
+			var email, name, product, plan string
+			var productID *int
+			if purchase.User != nil {
+				email = purchase.User.Email
+				name = strings.TrimSpace(purchase.User.FirstName + " " + purchase.User.LastName)
+			}
+			if purchase.Plan != nil {
+				plan = purchase.Plan.Name
+				productID = &purchase.Plan.ProductID
+				if purchase.Plan.Product != nil {
+					product = purchase.Plan.Product.Name
+				}
+			}
+			err := w.WriteRow([]interface{}{
+				purchase.ID, purchase.CreatedAt, purchase.Status, purchase.UserID, email, name, productID, product, purchase.PlanID, plan,
+				purchase.Currency, purchase.Amount, purchase.NetAmount, purchase.TaxAmount, purchase.TaxRate, purchase.TaxName, purchase.ListPrice, purchase.DiscountAmount,
+				purchase.RefundedAmount, purchase.MRR, purchase.PaymentProvider, purchase.TransactionID, purchase.ExpiresAt, purchase.CancelAtPeriodEnd,
+				purchase.CancelledAt, purchase.TrialEndsAt,
+			})
+			if err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if len(purchases) < exportBatchSize {
+			return rows, nil
+		}
+		afterID = purchases[len(purchases)-1].ID
+	}
+}
+
+func (s *Service) writeRevenue(w rowWriter, tenantID int, query models.RevenueQuery) (int, error) {
+	analytics, err := s.analytics.GetRevenueSeries(tenantID, query)
+	if err != nil {
+		return 0, err
+	}
+
+	err = w.WriteRow([]interface{}{
+		"Currency", "Period start", "Period end", "Revenue", "New MRR", "Expansion MRR", "Contraction MRR", "Churned MRR",
+		"Net new MRR", "MRR", "ARR", "Customers", "New customers", "Churned customers", "Customer churn rate", "Revenue churn rate",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	for _, series := range analytics.Series {
+		for _, point := range series.Points {
+			err := w.WriteRow([]interface{}{
+				series.Currency, point.PeriodStart.Format("2006-01-02"), point.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"),
+				point.Revenue, point.NewMRR, point.ExpansionMRR, point.ContractionMRR, point.ChurnedMRR,
+				point.NetNewMRR, point.MRR, point.ARR, point.Customers, point.NewCustomers, point.ChurnedCustomers,
+				point.CustomerChurnRate, point.RevenueChurnRate,
+			})
+			if err != nil {
+				return rows, err
+			}
+			rows++
+		}
+	}
+	return rows, nil
+}
+
+// writeActivity writes the activities, newest first
+func (s *Service) writeActivity(w rowWriter, tenantID int, query models.ActivityQuery) (int, error) {
+	err := w.WriteRow([]interface{}{
+		"ID", "Created at", "Type", "Description", "Entity type", "Entity ID", "User ID", "User email", "Payload",
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	query.Limit = exportBatchSize
+	for {
+		page, err := s.analytics.GetActivities(tenantID, query)
+		if err != nil {
+			return rows, err
+		}
+		for _, activity := range page.Activities {
+			var email, payload string
+			if activity.User != nil {
+				email = activity.User.Email
+			}
+			if activity.Payload != nil {
+				encoded, err := json.Marshal(activity.Payload)
+				if err != nil {
+					return rows, err
+				}
+				payload = string(encoded)
+			}
+			err := w.WriteRow([]interface{}{
+				activity.ID, activity.CreatedAt, string(activity.Type), activity.Description, activity.EntityType, activity.EntityID,
+				activity.UserID, email, payload,
+			})
+			if err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if page.NextCursor == "" {
+			return rows, nil
+		}
+		query.Before, _ = strconv.Atoi(page.NextCursor)
+	}
+}
+
+// GetJob returns the tenant's export job
+func (s *Service) GetJob(id, tenantID int) (*models.ExportJob, error) {
+	job, err := s.repo.Get(id, tenantID)
+	if err != nil {
+		return nil, errors.New("export not found")
+	}
+	s.setDownloadURL(job)
+	return job, nil
+}
+
+// GetFile returns the tenant's export job if its file is ready to download
+// with WriteFile
+func (s *Service) GetFile(id, tenantID int) (*models.ExportJob, error) {
+	job, err := s.repo.Get(id, tenantID)
+	if err != nil {
+		return nil, errors.New("export not found")
+	}
+	if job.Status != models.ExportStatusCompleted {
+		return nil, domain.ErrExportNotReady
+	}
+	return job, nil
+}
+
+// WriteFile writes the file of a completed export job to w, one chunk at a
+// time
+func (s *Service) WriteFile(w io.Writer, job *models.ExportJob) error {
+	for seq := 0; seq < job.Chunks; seq++ {
+		chunk, err := s.repo.GetChunk(job.ID, seq)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %d: %w", seq, err)
+		}
+		if _, err := w.Write(chunk.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessPending builds pending export jobs, storing their files in chunks
+// as they're written. Each is claimed first, so that instances running
+// concurrently don't build it twice. It returns the number of jobs built.
+func (s *Service) ProcessPending() (int, error) {
+	built := 0
+	for built < exportJobsPerRun {
+		job, err := s.repo.ClaimPending()
+		if err != nil {
+			return built, err
+		}
+		if job == nil {
+			return built, nil
+		}
+
+		file := &chunkWriter{repo: s.repo, jobID: job.ID}
+		query, err := decodeQuery(job.Query)
+		if err == nil {
+			job.Rows, err = s.Write(file, job.TenantID, job.Kind, job.Format, query)
+		}
+		if err == nil {
+			err = file.Close()
+		}
+		now := time.Now()
+		expiresAt := now.Add(s.cfg.ExportTTL)
+		job.CompletedAt = &now
+		job.ExpiresAt = &expiresAt
+		if err != nil {
+			log.Printf("Failed to build export %d: %v", job.ID, err)
+			job.Status = models.ExportStatusFailed
+			job.Error = err.Error()
+			if err := s.repo.DeleteChunks(job.ID); err != nil {
+				log.Printf("Failed to delete the file of export %d: %v", job.ID, err)
+			}
+		} else {
+			job.Status = models.ExportStatusCompleted
+			job.Size = file.size
+			job.Chunks = file.seq
+		}
+		if err := s.repo.SaveResult(job); err != nil {
+			return built, fmt.Errorf("failed to save export %d: %w", job.ID, err)
+		}
+		built++
+	}
+	return built, nil
+}
+
+// PurgeExpired deletes expired export jobs and fails abandoned ones. It
+// returns the number of jobs deleted.
+func (s *Service) PurgeExpired(now time.Time) (int64, error) {
+	return s.repo.PurgeExpired(now, now.Add(-staleExportAfter), now.Add(s.cfg.ExportTTL))
+}
+
+func (s *Service) setDownloadURL(job *models.ExportJob) {
+	if job.Status == models.ExportStatusCompleted {
+		job.DownloadURL = fmt.Sprintf("%s/api/v1/exports/%d/download", s.cfg.AppBaseURL, job.ID)
+	}
+}
+
+// chunkWriter stores what's written to it as the chunks of a job's file
+type chunkWriter struct {
+	repo  domain.ExportRepository
+	jobID int
+	buf   []byte
+	seq   int   // chunks stored
+	size  int64 // bytes written
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := exportChunkSize - len(c.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		c.buf = append(c.buf, p[:n]...)
+		p = p[n:]
+		written += n
+		c.size += int64(n)
+		if len(c.buf) == exportChunkSize {
+			if err := c.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close stores the last, partial chunk
+func (c *chunkWriter) Close() error {
+	if len(c.buf) == 0 {
+		return nil
+	}
+	return c.flush()
+}
+
+func (c *chunkWriter) flush() error {
+	err := c.repo.SaveChunk(&models.ExportChunk{JobID: c.jobID, Seq: c.seq, Data: c.buf})
+	if err != nil {
+		return fmt.Errorf("failed to store chunk %d: %w", c.seq, err)
+	}
+	c.seq++
+	c.buf = c.buf[:0]
+	return nil
+}
+
+// filename returns the name of a file of the export made at now, e.g.
+// purchases-20261019.csv
+func filename(kind, format string, now time.Time) string {
+	return fmt.Sprintf("%s-%s.%s", kind, now.Format("20060102"), format)
+}
+
+// encodeQuery stores an export query in a job
+func encodeQuery(query models.ExportQuery) (models.JSONB, error) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return nil, err
+	}
+	var encoded models.JSONB
+	err = json.Unmarshal(data, &encoded)
+	return encoded, err
+}
+
+// decodeQuery reads the export query of a job back, its times in its
+// timezone as periods and days start there
+func decodeQuery(encoded models.JSONB) (models.ExportQuery, error) {
+	var query models.ExportQuery
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		return query, err
+	}
+	if err := json.Unmarshal(data, &query); err != nil {
+		return query, err
+	}
+	location, err := time.LoadLocation(query.Timezone)
+	if err != nil {
+		return query, err
+	}
+	if query.Purchases != nil {
+		query.Purchases.From = query.Purchases.From.In(location)
+		query.Purchases.To = query.Purchases.To.In(location)
+	}
+	if query.Revenue != nil {
+		query.Revenue.From = query.Revenue.From.In(location)
+		query.Revenue.To = query.Revenue.To.In(location)
+	}
+	if query.Activity != nil {
+		query.Activity.From = query.Activity.From.In(location)
+		query.Activity.To = query.Activity.To.In(location)
+	}
+	return query, nil
+}
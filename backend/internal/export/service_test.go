@@ -0,0 +1,71 @@
+package export
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"bytes"
+	"testing"
+)
+
+// chunkRepository stores the chunks of export files in memory
+type chunkRepository struct {
+	domain.ExportRepository
+	chunks []models.ExportChunk
+}
+
+func (r *chunkRepository) SaveChunk(chunk *models.ExportChunk) error {
+	stored := *chunk
+	stored.Data = append([]byte(nil), chunk.Data...)
+	r.chunks = append(r.chunks, stored)
+	return nil
+}
+
+func (r *chunkRepository) GetChunk(jobID, seq int) (*models.ExportChunk, error) {
+	for i := range r.chunks {
+		if r.chunks[i].JobID == jobID && r.chunks[i].Seq == seq {
+			return &r.chunks[i], nil
+		}
+	}
+	return nil, domain.ErrExportNotReady
+}
+
+func TestFileRoundTripsThroughChunks(t *testing.T) {
+	sizes := []int{0, 1, exportChunkSize - 1, exportChunkSize, exportChunkSize + 1, 2*exportChunkSize + 7}
+	for _, size := range sizes {
+		content := make([]byte, size)
+		for i := range content {
+			content[i] = byte(i % 251)
+		}
+
+		repo := &chunkRepository{}
+		file := &chunkWriter{repo: repo, jobID: 1}
+		// Written in pieces that don't line up with chunks
+		for rest := content; len(rest) > 0; {
+			n := 4093
+			if n > len(rest) {
+				n = len(rest)
+			}
+			if _, err := file.Write(rest[:n]); err != nil {
+				t.Fatal(err)
+			}
+			rest = rest[n:]
+		}
+		if err := file.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		wantChunks := (size + exportChunkSize - 1) / exportChunkSize
+		if file.seq != wantChunks || file.size != int64(size) {
+			t.Fatalf("%d bytes stored as %d chunks of %d bytes, want %d chunks", size, file.seq, file.size, wantChunks)
+		}
+
+		var read bytes.Buffer
+		job := &models.ExportJob{ID: 1, Chunks: file.seq}
+		if err := NewService(repo, nil, nil).WriteFile(&read, job); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read.Bytes(), content) {
+			t.Fatalf("%d bytes read back differently", size)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+//go:build wireinject
+// +build wireinject
+
+package export
+
+import (
+	"backend/core"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewControllerWire(db *gorm.DB, cfg *core.Config) *Controller {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Controller{}
+}
+
+// NewServiceWire builds the export service for background jobs
+func NewServiceWire(db *gorm.DB, cfg *core.Config) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
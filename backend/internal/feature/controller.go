@@ -0,0 +1,152 @@
+package feature
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	featureService domain.FeatureService
+}
+
+func NewFeatureController(featureService domain.FeatureService) *Controller {
+	return &Controller{featureService: featureService}
+}
+
+func (h *Controller) GetFeatures(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+
+	productID, err := strconv.Atoi(c.Params("product_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid product ID",
+		})
+	}
+
+	features, err := h.featureService.GetFeaturesByProduct(productID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  features,
+	})
+}
+
+func (h *Controller) CreateFeature(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+
+	productID, err := strconv.Atoi(c.Params("product_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid product ID",
+		})
+	}
+
+	var req models.CreateFeatureRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	feature, err := h.featureService.CreateFeature(req, productID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"error": false,
+		"data":  feature,
+	})
+}
+
+func (h *Controller) UpdateFeature(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid feature ID",
+		})
+	}
+
+	var req models.CreateFeatureRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	feature, err := h.featureService.UpdateFeature(id, req, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  feature,
+	})
+}
+
+func (h *Controller) DeleteFeature(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid feature ID",
+		})
+	}
+
+	err = h.featureService.DeleteFeature(id, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error":   false,
+		"message": "Feature deleted successfully",
+	})
+}
+
+// GetEntitlements returns the merged feature entitlements of the current user's active purchases
+func (h *Controller) GetEntitlements(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(int)
+	tenantID := c.Locals("tenantID").(*int)
+
+	entitlements, err := h.featureService.GetEntitlements(userID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  entitlements,
+	})
+}
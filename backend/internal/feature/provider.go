@@ -0,0 +1,20 @@
+package feature
+
+import (
+	"backend/internal/domain"
+	"backend/internal/product"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewFeatureController,
+	NewFeatureService,
+	NewFeatureRepository,
+	product.NewProductRepository,
+
+	wire.Bind(new(domain.FeatureControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.FeatureService), new(*Service)),
+	wire.Bind(new(domain.FeatureRepository), new(*Repository)),
+	wire.Bind(new(domain.ProductRepository), new(*product.Repository)),
+)
@@ -0,0 +1,87 @@
+package feature
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewFeatureRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Create(feature *models.Feature) error {
+	return r.db.Create(feature).Error
+}
+
+func (r *Repository) GetByProduct(productID int, tenantID int) ([]models.Feature, error) {
+	var features []models.Feature
+	err := r.db.Where("product_id = ? AND tenant_id = ?", productID, tenantID).
+		Order("id ASC").
+		Find(&features).Error
+	return features, err
+}
+
+func (r *Repository) GetByProducts(productIDs []int, tenantID int) ([]models.Feature, error) {
+	var features []models.Feature
+	if len(productIDs) == 0 {
+		return features, nil
+	}
+	err := r.db.Where("product_id IN ? AND tenant_id = ?", productIDs, tenantID).
+		Order("id ASC").
+		Find(&features).Error
+	return features, err
+}
+
+func (r *Repository) GetByID(id int, tenantID int) (*models.Feature, error) {
+	var feature models.Feature
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&feature).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feature, nil
+}
+
+func (r *Repository) GetByKey(productID int, key string, tenantID int) (*models.Feature, error) {
+	var feature models.Feature
+	err := r.db.Where("product_id = ? AND key = ? AND tenant_id = ?", productID, key, tenantID).First(&feature).Error
+	if err != nil {
+		return nil, err
+	}
+	return &feature, nil
+}
+
+func (r *Repository) Update(feature *models.Feature) error {
+	return r.db.Save(feature).Error
+}
+
+func (r *Repository) Delete(id int, tenantID int) error {
+	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.Feature{}).Error
+}
+
+// GetActivePlansForUser returns the plans behind the user's active, unexpired purchases
+func (r *Repository) GetActivePlansForUser(userID, tenantID int) ([]models.Plan, error) {
+	var plans []models.Plan
+	err := r.db.Preload("Product").
+		Joins("JOIN purchases ON purchases.plan_id = plans.id AND purchases.deleted_at IS NULL").
+		Where("purchases.user_id = ? AND purchases.tenant_id = ? AND purchases.status IN ?", userID, tenantID, models.EntitledPurchaseStatuses).
+		Where("(purchases.expires_at IS NULL OR purchases.expires_at > ?)", time.Now()).
+		Distinct().
+		Find(&plans).Error
+	return plans, err
+}
+
+// GetPlansByProduct returns the product's plans, archived ones included since
+// purchases may still be on them
+func (r *Repository) GetPlansByProduct(productID int, tenantID int) ([]models.Plan, error) {
+	var plans []models.Plan
+	err := r.db.Where("product_id = ? AND tenant_id = ?", productID, tenantID).
+		Order("id ASC").
+		Find(&plans).Error
+	return plans, err
+}
@@ -0,0 +1,351 @@
+package feature
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+)
+
+var featureKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+type Service struct {
+	featureRepo domain.FeatureRepository
+	productRepo domain.ProductRepository
+}
+
+func NewFeatureService(featureRepo domain.FeatureRepository, productRepo domain.ProductRepository) *Service {
+	return &Service{
+		featureRepo: featureRepo,
+		productRepo: productRepo,
+	}
+}
+
+// ValidateDefinition checks the key, type and enum options of a feature request
+func (s *Service) ValidateDefinition(req models.CreateFeatureRequest) error {
+	if !featureKeyPattern.MatchString(req.Key) {
+		return errors.New("feature key must be lowercase snake_case")
+	}
+	if req.Name == "" {
+		return errors.New("feature name is required")
+	}
+
+	switch req.Type {
+	case models.FeatureTypeBoolean, models.FeatureTypeLimit:
+		if len(req.Options) > 0 {
+			return fmt.Errorf("options are only allowed for %s features", models.FeatureTypeEnum)
+		}
+	case models.FeatureTypeEnum:
+		if len(req.Options) == 0 {
+			return errors.New("enum features require at least one option")
+		}
+		seen := make(map[string]bool, len(req.Options))
+		for _, option := range req.Options {
+			if option == "" {
+				return errors.New("enum options cannot be empty")
+			}
+			if seen[option] {
+				return fmt.Errorf("duplicate enum option '%s'", option)
+			}
+			seen[option] = true
+		}
+	default:
+		return fmt.Errorf("invalid feature type '%s'", req.Type)
+	}
+
+	return nil
+}
+
+func (s *Service) CreateFeature(req models.CreateFeatureRequest, productID int, tenantID int) (*models.Feature, error) {
+	// Verify product exists and belongs to tenant
+	if _, err := s.productRepo.GetByID(productID, tenantID); err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	if err := s.ValidateDefinition(req); err != nil {
+		return nil, err
+	}
+
+	if existing, _ := s.featureRepo.GetByKey(productID, req.Key, tenantID); existing != nil {
+		return nil, errors.New("feature key already exists for this product")
+	}
+
+	feature := &models.Feature{
+		Key:         req.Key,
+		Name:        req.Name,
+		Description: req.Description,
+		Type:        req.Type,
+		Options:     models.StringArray(req.Options),
+		ProductID:   productID,
+		TenantID:    tenantID,
+	}
+
+	if err := s.featureRepo.Create(feature); err != nil {
+		return nil, errors.New("failed to create feature")
+	}
+
+	return feature, nil
+}
+
+func (s *Service) GetFeaturesByProduct(productID int, tenantID int) ([]models.Feature, error) {
+	// Verify product exists and belongs to tenant
+	if _, err := s.productRepo.GetByID(productID, tenantID); err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	return s.featureRepo.GetByProduct(productID, tenantID)
+}
+
+func (s *Service) GetFeatureByID(id int, tenantID int) (*models.Feature, error) {
+	feature, err := s.featureRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("feature not found")
+	}
+	return feature, nil
+}
+
+// UpdateFeature updates the descriptive fields and enum options of a feature.
+// Key and type are fixed once created since plans already reference them.
+// Enum options are ranked lowest to highest tier, so options plans use can
+// neither be removed nor reordered among themselves; new ones can be added
+// anywhere.
+func (s *Service) UpdateFeature(id int, req models.CreateFeatureRequest, tenantID int) (*models.Feature, error) {
+	feature, err := s.featureRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("feature not found")
+	}
+
+	if req.Key != "" && req.Key != feature.Key {
+		return nil, errors.New("feature key cannot be changed")
+	}
+	if req.Type != "" && req.Type != feature.Type {
+		return nil, errors.New("feature type cannot be changed")
+	}
+
+	req.Key = feature.Key
+	req.Type = feature.Type
+	if err := s.ValidateDefinition(req); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkPlanOptions(feature, models.StringArray(req.Options)); err != nil {
+		return nil, err
+	}
+
+	feature.Name = req.Name
+	feature.Description = req.Description
+	feature.Options = models.StringArray(req.Options)
+
+	if err := s.featureRepo.Update(feature); err != nil {
+		return nil, errors.New("failed to update feature")
+	}
+
+	return feature, nil
+}
+
+// checkPlanOptions checks that the plans of an enum feature's product keep
+// valid values with the new options, and that the options they use keep the
+// tier order GetEntitlements merges them by
+func (s *Service) checkPlanOptions(feature *models.Feature, options models.StringArray) error {
+	if feature.Type != models.FeatureTypeEnum {
+		return nil
+	}
+
+	plans, err := s.featureRepo.GetPlansByProduct(feature.ProductID, feature.TenantID)
+	if err != nil {
+		return errors.New("failed to load plans")
+	}
+
+	updated := *feature
+	updated.Options = options
+	used := make(map[string]bool)
+	for _, plan := range plans {
+		value, ok := plan.Features[feature.Key]
+		if !ok || validateValue(*feature, value) != nil {
+			// Values already invalid don't depend on the options
+			continue
+		}
+		if err := s.ValidatePlanValues([]models.Feature{updated}, models.JSONB{feature.Key: value}); err != nil {
+			return fmt.Errorf("plan '%s' uses option '%v': %w", plan.Name, value, err)
+		}
+		used[value.(string)] = true
+	}
+
+	last := -1
+	for _, option := range feature.Options {
+		if !used[option] {
+			continue
+		}
+		index := optionIndex(options, option)
+		if index < last {
+			return errors.New("enum options used by plans cannot be reordered since their order ranks the tiers")
+		}
+		last = index
+	}
+
+	return nil
+}
+
+func (s *Service) DeleteFeature(id int, tenantID int) error {
+	// Check if feature exists
+	if _, err := s.featureRepo.GetByID(id, tenantID); err != nil {
+		return errors.New("feature not found")
+	}
+
+	return s.featureRepo.Delete(id, tenantID)
+}
+
+// ValidatePlanFeatures checks plan feature values against the product's catalogue
+func (s *Service) ValidatePlanFeatures(productID int, tenantID int, values models.JSONB) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	features, err := s.featureRepo.GetByProduct(productID, tenantID)
+	if err != nil {
+		return errors.New("failed to load feature catalogue")
+	}
+	return s.ValidatePlanValues(features, values)
+}
+
+// ValidatePlanValues checks plan feature values against the given catalogue
+// of the plan's product
+func (s *Service) ValidatePlanValues(features []models.Feature, values models.JSONB) error {
+	byKey := make(map[string]models.Feature, len(features))
+	for _, feature := range features {
+		byKey[feature.Key] = feature
+	}
+
+	for key, value := range values {
+		feature, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("unknown feature '%s'", key)
+		}
+		if err := validateValue(feature, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateValue checks a single plan value against its feature definition
+func validateValue(feature models.Feature, value interface{}) error {
+	switch feature.Type {
+	case models.FeatureTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("feature '%s' must be a boolean", feature.Key)
+		}
+	case models.FeatureTypeLimit:
+		number, ok := value.(float64)
+		if !ok || number != math.Trunc(number) || number < -1 {
+			return fmt.Errorf("feature '%s' must be a whole number, or -1 for unlimited", feature.Key)
+		}
+	case models.FeatureTypeEnum:
+		option, ok := value.(string)
+		if !ok || optionIndex(feature.Options, option) < 0 {
+			return fmt.Errorf("feature '%s' must be one of %v", feature.Key, []string(feature.Options))
+		}
+	default:
+		return fmt.Errorf("feature '%s' has unsupported type '%s'", feature.Key, feature.Type)
+	}
+	return nil
+}
+
+// GetEntitlements merges the plan features of the user's active purchases per product.
+// Booleans are OR-ed, limits take the highest value (-1 wins) and enums take the highest tier.
+func (s *Service) GetEntitlements(userID, tenantID int) ([]models.Entitlement, error) {
+	plans, err := s.featureRepo.GetActivePlansForUser(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int, 0, len(plans))
+	entitlements := make(map[int]*models.Entitlement)
+	for _, plan := range plans {
+		if _, ok := entitlements[plan.ProductID]; ok {
+			continue
+		}
+		productIDs = append(productIDs, plan.ProductID)
+		entitlement := &models.Entitlement{
+			ProductID: plan.ProductID,
+			PlanIDs:   []int{},
+			Features:  models.JSONB{},
+		}
+		if plan.Product != nil {
+			entitlement.ProductName = plan.Product.Name
+		}
+		entitlements[plan.ProductID] = entitlement
+	}
+
+	features, err := s.featureRepo.GetByProducts(productIDs, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	catalogue := make(map[int]map[string]models.Feature, len(productIDs))
+	for _, feature := range features {
+		if catalogue[feature.ProductID] == nil {
+			catalogue[feature.ProductID] = make(map[string]models.Feature)
+		}
+		catalogue[feature.ProductID][feature.Key] = feature
+	}
+
+	for _, plan := range plans {
+		entitlement := entitlements[plan.ProductID]
+		entitlement.PlanIDs = append(entitlement.PlanIDs, plan.ID)
+
+		for key, value := range plan.Features {
+			feature, ok := catalogue[plan.ProductID][key]
+			if !ok || validateValue(feature, value) != nil {
+				// Skip values left behind by deleted or changed catalogue entries
+				continue
+			}
+			current, exists := entitlement.Features[key]
+			if !exists {
+				entitlement.Features[key] = value
+				continue
+			}
+			entitlement.Features[key] = mergeValue(feature, current, value)
+		}
+	}
+
+	result := make([]models.Entitlement, 0, len(productIDs))
+	for _, productID := range productIDs {
+		result = append(result, *entitlements[productID])
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ProductID < result[j].ProductID })
+
+	return result, nil
+}
+
+// mergeValue combines two valid values of the same feature, keeping the more generous one
+func mergeValue(feature models.Feature, current, value interface{}) interface{} {
+	switch feature.Type {
+	case models.FeatureTypeBoolean:
+		return current.(bool) || value.(bool)
+	case models.FeatureTypeLimit:
+		a, b := current.(float64), value.(float64)
+		if a == -1 || b == -1 {
+			return float64(-1)
+		}
+		return math.Max(a, b)
+	case models.FeatureTypeEnum:
+		if optionIndex(feature.Options, value.(string)) > optionIndex(feature.Options, current.(string)) {
+			return value
+		}
+	}
+	return current
+}
+
+func optionIndex(options models.StringArray, option string) int {
+	for i, candidate := range options {
+		if candidate == option {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,68 @@
+package feature
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"testing"
+)
+
+// memoryFeatureRepository holds one feature and the plans of its product
+type memoryFeatureRepository struct {
+	domain.FeatureRepository
+	feature models.Feature
+	plans   []models.Plan
+	updated bool
+}
+
+func (r *memoryFeatureRepository) GetByID(id int, tenantID int) (*models.Feature, error) {
+	feature := r.feature
+	return &feature, nil
+}
+
+func (r *memoryFeatureRepository) GetPlansByProduct(productID int, tenantID int) ([]models.Plan, error) {
+	return r.plans, nil
+}
+
+func (r *memoryFeatureRepository) Update(feature *models.Feature) error {
+	r.updated = true
+	return nil
+}
+
+// Options plans use can't be removed or change tier order; unused ones can
+func TestUpdateFeatureOptions(t *testing.T) {
+	plans := []models.Plan{
+		{ID: 1, Name: "Basic", Features: models.JSONB{"support": "email"}},
+		{ID: 2, Name: "Pro", Features: models.JSONB{"support": "priority"}},
+		{ID: 3, Name: "Legacy", Features: models.JSONB{"support": "fax"}},
+		{ID: 4, Name: "Free"},
+	}
+	tests := []struct {
+		name    string
+		options []string
+		wantErr bool
+	}{
+		{"unchanged", []string{"email", "chat", "priority"}, false},
+		{"unused option removed", []string{"email", "priority"}, false},
+		{"option added", []string{"community", "email", "chat", "priority", "dedicated"}, false},
+		{"unused option moved", []string{"chat", "email", "priority"}, false},
+		{"used option removed", []string{"email", "chat"}, true},
+		{"used options reordered", []string{"priority", "chat", "email"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryFeatureRepository{
+				feature: models.Feature{ID: 1, Key: "support", Name: "Support", Type: models.FeatureTypeEnum, Options: models.StringArray{"email", "chat", "priority"}, ProductID: 1, TenantID: 1},
+				plans:   plans,
+			}
+			service := NewFeatureService(repo, nil)
+
+			_, err := service.UpdateFeature(1, models.CreateFeatureRequest{Name: "Support", Options: tt.options}, 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateFeature error = %v, want error %v", err, tt.wantErr)
+			}
+			if repo.updated == tt.wantErr {
+				t.Errorf("updated = %v", repo.updated)
+			}
+		})
+	}
+}
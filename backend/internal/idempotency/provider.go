@@ -0,0 +1,15 @@
+package idempotency
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewService,
+	NewRepository,
+
+	wire.Bind(new(domain.IdempotencyService), new(*Service)),
+	wire.Bind(new(domain.IdempotencyRepository), new(*Repository)),
+)
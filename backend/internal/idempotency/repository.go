@@ -0,0 +1,88 @@
+package idempotency
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// Claim stores the key as in progress unless the user already holds it. An
+// expired key that wasn't purged yet is replaced. It reports whether the key
+// was claimed.
+func (r *Repository) Claim(record *models.IdempotencyKey) (bool, error) {
+	claimed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND user_id = ? AND key = ? AND expires_at <= ?",
+			record.TenantID, record.UserID, record.Key, time.Now()).
+			Delete(&models.IdempotencyKey{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+		if result.Error != nil {
+			return result.Error
+		}
+		claimed = result.RowsAffected > 0
+		return nil
+	})
+	return claimed, err
+}
+
+func (r *Repository) Get(tenantID, userID int, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	err := r.db.Where("tenant_id = ? AND user_id = ? AND key = ?", tenantID, userID, key).First(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// TakeOver locks an in-progress key whose request started before
+// staleBefore, i.e. was abandoned, for a new request. It reports false when
+// the key changed first.
+func (r *Repository) TakeOver(record *models.IdempotencyKey, staleBefore time.Time) (bool, error) {
+	now := time.Now()
+	result := r.db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status = ? AND locked_at < ?", record.ID, models.IdempotencyStatusInProgress, staleBefore).
+		Update("locked_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	record.LockedAt = &now
+	return true, nil
+}
+
+// Complete stores the response of the request holding the key
+func (r *Repository) Complete(record *models.IdempotencyKey) error {
+	return r.db.Model(&models.IdempotencyKey{}).
+		Where("id = ?", record.ID).
+		Updates(map[string]interface{}{
+			"status":                record.Status,
+			"response_status":       record.ResponseStatus,
+			"response_content_type": record.ResponseContentType,
+			"response_body":         record.ResponseBody,
+		}).Error
+}
+
+func (r *Repository) Delete(record *models.IdempotencyKey) error {
+	return r.db.Delete(&models.IdempotencyKey{}, record.ID).Error
+}
+
+// DeleteExpired removes keys that expired before now
+func (r *Repository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,96 @@
+package idempotency
+
+import (
+	"backend/core"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// pollInterval is how often a duplicate request checks whether the request
+// holding its key finished
+const pollInterval = 100 * time.Millisecond
+
+type Service struct {
+	repo domain.IdempotencyRepository
+	cfg  *core.Config
+}
+
+func NewService(repo domain.IdempotencyRepository, cfg *core.Config) *Service {
+	return &Service{repo: repo, cfg: cfg}
+}
+
+// Begin claims the key for a request with the given fingerprint. It returns
+// the in-progress record when the caller should run the request, or the
+// completed record whose response it should replay. A duplicate of a request
+// that is still running waits for it to finish; one that was abandoned for
+// longer than the lock timeout is taken over.
+func (s *Service) Begin(tenantID, userID int, key, fingerprint string) (*models.IdempotencyKey, error) {
+	for {
+		now := time.Now()
+		record := &models.IdempotencyKey{
+			TenantID:    tenantID,
+			UserID:      userID,
+			Key:         key,
+			Fingerprint: fingerprint,
+			Status:      models.IdempotencyStatusInProgress,
+			LockedAt:    &now,
+			ExpiresAt:   now.Add(s.cfg.IdempotencyKeyTTL),
+		}
+		claimed, err := s.repo.Claim(record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim idempotency key: %w", err)
+		}
+		if claimed {
+			return record, nil
+		}
+
+		existing, err := s.repo.Get(tenantID, userID, key)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Released or expired in the meantime
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load idempotency key: %w", err)
+		}
+		if existing.Fingerprint != fingerprint {
+			return nil, domain.ErrIdempotencyKeyReused
+		}
+		if existing.Status == models.IdempotencyStatusCompleted {
+			return existing, nil
+		}
+
+		tookOver, err := s.repo.TakeOver(existing, now.Add(-s.cfg.IdempotencyLockTimeout))
+		if err != nil {
+			return nil, fmt.Errorf("failed to take over idempotency key: %w", err)
+		}
+		if tookOver {
+			return existing, nil
+		}
+
+		time.Sleep(pollInterval)
+	}
+}
+
+// Complete stores the response of the request that claimed the record
+func (s *Service) Complete(record *models.IdempotencyKey, status int, contentType string, body []byte) error {
+	record.Status = models.IdempotencyStatusCompleted
+	record.ResponseStatus = status
+	record.ResponseContentType = contentType
+	record.ResponseBody = body
+	return s.repo.Complete(record)
+}
+
+// Release gives the key up without a response, so a retry runs the request again
+func (s *Service) Release(record *models.IdempotencyKey) error {
+	return s.repo.Delete(record)
+}
+
+// PurgeExpired deletes keys past their expiry
+func (s *Service) PurgeExpired(now time.Time) (int64, error) {
+	return s.repo.DeleteExpired(now)
+}
@@ -0,0 +1,17 @@
+//go:build wireinject
+// +build wireinject
+
+package idempotency
+
+import (
+	"backend/core"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewServiceWire(db *gorm.DB, cfg *core.Config) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
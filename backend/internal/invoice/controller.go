@@ -0,0 +1,153 @@
+package invoice
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.InvoiceService
+}
+
+func NewController(service domain.InvoiceService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetInvoices lists invoices of the current user, or of the tenant for admins
+func (c *Controller) GetInvoices(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	invoices, err := c.service.GetInvoices(userID, *tenantID, isAdmin(ctx))
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  invoices,
+	})
+}
+
+// GetInvoice gets an invoice with its lines
+func (c *Controller) GetInvoice(ctx *fiber.Ctx) error {
+	invoice, err := c.loadInvoice(ctx)
+	if err != nil {
+		return err
+	}
+	if invoice == nil {
+		return nil
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  invoice,
+	})
+}
+
+// DownloadInvoicePDF renders an invoice as PDF
+func (c *Controller) DownloadInvoicePDF(ctx *fiber.Ctx) error {
+	invoice, err := c.loadInvoice(ctx)
+	if err != nil || invoice == nil {
+		return err
+	}
+
+	pdf, err := c.service.RenderPDF(invoice)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to render invoice",
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/pdf")
+	ctx.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="invoice-%s.pdf"`, *invoice.Number))
+	return ctx.Send(pdf)
+}
+
+// DownloadInvoiceHTML renders an invoice as HTML
+func (c *Controller) DownloadInvoiceHTML(ctx *fiber.Ctx) error {
+	invoice, err := c.loadInvoice(ctx)
+	if err != nil || invoice == nil {
+		return err
+	}
+
+	html, err := c.service.RenderHTML(invoice)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": "Failed to render invoice",
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.Send(html)
+}
+
+// SendInvoice emails an invoice to its buyer
+func (c *Controller) SendInvoice(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid invoice ID",
+		})
+	}
+
+	if err := c.service.SendInvoice(id, userID, *tenantID, isAdmin(ctx)); err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "invoice not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error":   false,
+		"message": "Invoice sent successfully",
+	})
+}
+
+// loadInvoice resolves the :id invoice, writing the error response and
+// returning nil when it can't be accessed
+func (c *Controller) loadInvoice(ctx *fiber.Ctx) (*models.Invoice, error) {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return nil, ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid invoice ID",
+		})
+	}
+
+	invoice, err := c.service.GetInvoice(id, userID, *tenantID, isAdmin(ctx))
+	if err != nil {
+		return nil, ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return invoice, nil
+}
+
+// isAdmin reports whether the current user manages the whole tenant
+func isAdmin(ctx *fiber.Ctx) bool {
+	role, _ := ctx.Locals("role").(string)
+	return role == "admin"
+}
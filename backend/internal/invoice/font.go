@@ -0,0 +1,366 @@
+package invoice
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+//go:embed fonts/DejaVuSans.ttf
+var dejaVuSans []byte
+
+//go:embed fonts/DejaVuSans-Bold.ttf
+var dejaVuSansBold []byte
+
+// The fonts invoices are set in, covering Latin, Greek, Cyrillic and most
+// other alphabetic scripts
+var (
+	regularFont = mustParseTrueType("DejaVuSans", dejaVuSans)
+	boldFont    = mustParseTrueType("DejaVuSans-Bold", dejaVuSansBold)
+)
+
+// trueTypeFont is the part of a TrueType font needed to set text in it and
+// embed a subset of it in a PDF
+type trueTypeFont struct {
+	name       string
+	tables     map[string][]byte
+	unitsPerEm int
+	ascent     int
+	descent    int
+	capHeight  int
+	bbox       [4]int
+	glyphs     map[rune]uint16
+	advances   []int
+	offsets    []int
+}
+
+func mustParseTrueType(name string, data []byte) *trueTypeFont {
+	font, err := parseTrueType(name, data)
+	if err != nil {
+		panic(fmt.Sprintf("invoice: font %s: %v", name, err))
+	}
+	return font
+}
+
+func parseTrueType(name string, data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errors.New("not a TrueType font")
+	}
+	font := &trueTypeFont{name: name, tables: make(map[string][]byte)}
+	count := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(data) {
+			return nil, errors.New("truncated table directory")
+		}
+		offset := int(binary.BigEndian.Uint32(data[record+8:]))
+		length := int(binary.BigEndian.Uint32(data[record+12:]))
+		if offset+length > len(data) {
+			return nil, fmt.Errorf("table %q is out of bounds", data[record:record+4])
+		}
+		font.tables[string(data[record:record+4])] = data[offset : offset+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "loca", "glyf", "cmap"} {
+		if _, ok := font.tables[tag]; !ok {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	head := font.tables["head"]
+	font.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	for i := range font.bbox {
+		font.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+2*i:])))
+	}
+	longOffsets := binary.BigEndian.Uint16(head[50:]) == 1
+
+	hhea := font.tables["hhea"]
+	font.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	font.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	font.capHeight = font.ascent
+	if os2 := font.tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		font.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	numGlyphs := int(binary.BigEndian.Uint16(font.tables["maxp"][4:]))
+	metrics := int(binary.BigEndian.Uint16(hhea[34:]))
+	hmtx := font.tables["hmtx"]
+	if metrics == 0 || len(hmtx) < 4*metrics {
+		return nil, errors.New("truncated hmtx table")
+	}
+	font.advances = make([]int, numGlyphs)
+	for i := range font.advances {
+		font.advances[i] = int(binary.BigEndian.Uint16(hmtx[4*min(i, metrics-1):]))
+	}
+
+	loca := font.tables["loca"]
+	font.offsets = make([]int, numGlyphs+1)
+	for i := range font.offsets {
+		if longOffsets {
+			if 4*i+4 > len(loca) {
+				return nil, errors.New("truncated loca table")
+			}
+			font.offsets[i] = int(binary.BigEndian.Uint32(loca[4*i:]))
+		} else {
+			if 2*i+2 > len(loca) {
+				return nil, errors.New("truncated loca table")
+			}
+			font.offsets[i] = 2 * int(binary.BigEndian.Uint16(loca[2*i:]))
+		}
+	}
+
+	glyphs, err := parseCmap(font.tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	font.glyphs = glyphs
+
+	// Fonts without the cap height in their OS/2 table have it as the top of
+	// the H
+	if font.capHeight == font.ascent {
+		if glyph, ok := glyphs['H']; ok {
+			if data := font.glyphData(glyph); len(data) >= 10 {
+				font.capHeight = int(int16(binary.BigEndian.Uint16(data[8:])))
+			}
+		}
+	}
+	return font, nil
+}
+
+// parseCmap reads the Unicode mapping of the font, the full repertoire
+// (format 12) if it has one, otherwise the Basic Multilingual Plane
+// (format 4)
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	var bmp, full []byte
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count; i++ {
+		record := 4 + 8*i
+		if record+8 > len(cmap) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[record:])
+		encoding := binary.BigEndian.Uint16(cmap[record+2:])
+		offset := int(binary.BigEndian.Uint32(cmap[record+4:]))
+		if offset+4 > len(cmap) {
+			continue
+		}
+		subtable := cmap[offset:]
+		switch format := binary.BigEndian.Uint16(subtable); {
+		case format == 12 && (platform == 0 || platform == 3 && encoding == 10):
+			full = subtable
+		case format == 4 && (platform == 0 || platform == 3 && encoding == 1):
+			bmp = subtable
+		}
+	}
+
+	glyphs := make(map[rune]uint16)
+	switch {
+	case full != nil:
+		groups := int(binary.BigEndian.Uint32(full[12:]))
+		if 16+12*groups > len(full) {
+			return nil, errors.New("truncated cmap subtable")
+		}
+		for i := 0; i < groups; i++ {
+			group := full[16+12*i:]
+			start := rune(binary.BigEndian.Uint32(group))
+			end := rune(binary.BigEndian.Uint32(group[4:]))
+			glyph := binary.BigEndian.Uint32(group[8:])
+			for r := start; r <= end; r++ {
+				glyphs[r] = uint16(glyph + uint32(r-start))
+			}
+		}
+	case bmp != nil:
+		segments := int(binary.BigEndian.Uint16(bmp[6:])) / 2
+		ends, starts, deltas, ranges := 14, 16+2*segments, 16+4*segments, 16+6*segments
+		if ranges+2*segments > len(bmp) {
+			return nil, errors.New("truncated cmap subtable")
+		}
+		for i := 0; i < segments; i++ {
+			start := int(binary.BigEndian.Uint16(bmp[starts+2*i:]))
+			end := int(binary.BigEndian.Uint16(bmp[ends+2*i:]))
+			delta := binary.BigEndian.Uint16(bmp[deltas+2*i:])
+			rangeOffset := int(binary.BigEndian.Uint16(bmp[ranges+2*i:]))
+			for c := start; c <= end && c != 0xffff; c++ {
+				glyph := uint16(c) + delta
+				if rangeOffset != 0 {
+					at := ranges + 2*i + rangeOffset + 2*(c-start)
+					if at+2 > len(bmp) {
+						return nil, errors.New("truncated cmap subtable")
+					}
+					if glyph = binary.BigEndian.Uint16(bmp[at:]); glyph != 0 {
+						glyph += delta
+					}
+				}
+				if glyph != 0 {
+					glyphs[rune(c)] = glyph
+				}
+			}
+		}
+	default:
+		return nil, errors.New("no Unicode cmap subtable")
+	}
+	return glyphs, nil
+}
+
+// glyph returns the glyph of r, or that of '?' for characters the font
+// doesn't have
+func (f *trueTypeFont) glyph(r rune) (uint16, rune) {
+	if glyph, ok := f.glyphs[r]; ok {
+		return glyph, r
+	}
+	return f.glyphs['?'], '?'
+}
+
+// width is the advance width of glyph in thousandths of the font size
+func (f *trueTypeFont) width(glyph uint16) int {
+	return f.scale(f.advances[glyph])
+}
+
+// scale converts font units to thousandths of the font size
+func (f *trueTypeFont) scale(units int) int {
+	return units * 1000 / f.unitsPerEm
+}
+
+func (f *trueTypeFont) glyphData(glyph uint16) []byte {
+	return f.tables["glyf"][f.offsets[glyph]:f.offsets[glyph+1]]
+}
+
+// subset builds a font with the outlines of only the given glyphs and the
+// components they are composed of. Glyph IDs are kept, so that the others
+// are empty rather than renumbered.
+func (f *trueTypeFont) subset(used map[uint16]rune) []byte {
+	keep := make(map[uint16]bool)
+	var add func(glyph uint16)
+	add = func(glyph uint16) {
+		if int(glyph) >= len(f.advances) || keep[glyph] {
+			return
+		}
+		keep[glyph] = true
+		for _, component := range compositeComponents(f.glyphData(glyph)) {
+			add(component)
+		}
+	}
+	add(0)
+	for glyph := range used {
+		add(glyph)
+	}
+
+	var glyf bytes.Buffer
+	loca := make([]byte, 4*(len(f.advances)+1))
+	for glyph := range f.advances {
+		binary.BigEndian.PutUint32(loca[4*glyph:], uint32(glyf.Len()))
+		if keep[uint16(glyph)] {
+			glyf.Write(f.glyphData(uint16(glyph)))
+			for glyf.Len()%4 != 0 {
+				glyf.WriteByte(0)
+			}
+		}
+	}
+	binary.BigEndian.PutUint32(loca[4*len(f.advances):], uint32(glyf.Len()))
+
+	// Long loca offsets, and no checksum adjustment since readers of an
+	// embedded font don't check it
+	head := append([]byte(nil), f.tables["head"]...)
+	binary.BigEndian.PutUint32(head[8:], 0)
+	binary.BigEndian.PutUint16(head[50:], 1)
+
+	tables := map[string][]byte{
+		"cmap": f.tables["cmap"],
+		"head": head,
+		"hhea": f.tables["hhea"],
+		"maxp": f.tables["maxp"],
+		"hmtx": f.tables["hmtx"],
+		"loca": loca,
+		"glyf": glyf.Bytes(),
+	}
+	// The hinting programs, for legible text at small sizes on screen
+	for _, tag := range []string{"cvt ", "fpgm", "prep"} {
+		if table, ok := f.tables[tag]; ok {
+			tables[tag] = table
+		}
+	}
+	return writeTrueType(tables)
+}
+
+// compositeComponents lists the glyphs a composite glyph is built from
+func compositeComponents(data []byte) []uint16 {
+	if len(data) < 10 || int16(binary.BigEndian.Uint16(data)) >= 0 {
+		return nil
+	}
+	var components []uint16
+	for at := 10; at+4 <= len(data); {
+		flags := binary.BigEndian.Uint16(data[at:])
+		components = append(components, binary.BigEndian.Uint16(data[at+2:]))
+		at += 4
+		if flags&0x0001 != 0 { // arguments are words
+			at += 4
+		} else {
+			at += 2
+		}
+		switch {
+		case flags&0x0008 != 0: // a scale
+			at += 2
+		case flags&0x0040 != 0: // an x and a y scale
+			at += 4
+		case flags&0x0080 != 0: // a 2x2 transformation
+			at += 8
+		}
+		if flags&0x0020 == 0 { // no more components
+			break
+		}
+	}
+	return components
+}
+
+// writeTrueType serializes tables as a TrueType font file
+func writeTrueType(tables map[string][]byte) []byte {
+	tags := make([]string, 0, len(tables))
+	for tag := range tables {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	searchRange, selector := 1, 0
+	for searchRange*2 <= len(tags) {
+		searchRange *= 2
+		selector++
+	}
+
+	var out bytes.Buffer
+	header := make([]byte, 12+16*len(tags))
+	binary.BigEndian.PutUint32(header, 0x00010000)
+	binary.BigEndian.PutUint16(header[4:], uint16(len(tags)))
+	binary.BigEndian.PutUint16(header[6:], uint16(16*searchRange))
+	binary.BigEndian.PutUint16(header[8:], uint16(selector))
+	binary.BigEndian.PutUint16(header[10:], uint16(16*(len(tags)-searchRange)))
+
+	offset := len(header)
+	var body bytes.Buffer
+	for i, tag := range tags {
+		table := tables[tag]
+		record := header[12+16*i:]
+		copy(record, tag)
+		binary.BigEndian.PutUint32(record[4:], tableChecksum(table))
+		binary.BigEndian.PutUint32(record[8:], uint32(offset+body.Len()))
+		binary.BigEndian.PutUint32(record[12:], uint32(len(table)))
+		body.Write(table)
+		for body.Len()%4 != 0 {
+			body.WriteByte(0)
+		}
+	}
+	out.Write(header)
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func tableChecksum(table []byte) uint32 {
+	var sum uint32
+	for i := 0; i < len(table); i += 4 {
+		var word [4]byte
+		copy(word[:], table[i:])
+		sum += binary.BigEndian.Uint32(word[:])
+	}
+	return sum
+}
@@ -0,0 +1,211 @@
+package invoice
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfDocument is a minimal PDF writer for text-only documents. Text is set in
+// the embedded DejaVu Sans fonts, of which each document carries the glyphs
+// it uses, with a ToUnicode map so that it can be searched and copied.
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	fonts [2]*pdfFont
+}
+
+// pdfFont is a font of a document and the glyphs set in it, with the
+// character each stands for
+type pdfFont struct {
+	*trueTypeFont
+	used map[uint16]rune
+}
+
+func newPDFDocument() *pdfDocument {
+	doc := &pdfDocument{fonts: [2]*pdfFont{
+		{trueTypeFont: regularFont, used: make(map[uint16]rune)},
+		{trueTypeFont: boldFont, used: make(map[uint16]rune)},
+	}}
+	doc.newPage()
+	return doc
+}
+
+func (d *pdfDocument) newPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+}
+
+func (d *pdfDocument) current() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+func (d *pdfDocument) font(bold bool) (string, *pdfFont) {
+	if bold {
+		return "F2", d.fonts[1]
+	}
+	return "F1", d.fonts[0]
+}
+
+func (d *pdfDocument) text(x, y, size float64, bold bool, s string) {
+	name, font := d.font(bold)
+	fmt.Fprintf(d.current(), "BT /%s %.1f Tf %.2f %.2f Td <%s> Tj ET\n", name, size, x, y, font.encode(s))
+}
+
+// textRight draws text ending at x
+func (d *pdfDocument) textRight(x, y, size float64, bold bool, s string) {
+	d.text(x-d.textWidth(s, size, bold), y, size, bold, s)
+}
+
+// textWidth is the width of s set in the font
+func (d *pdfDocument) textWidth(s string, size float64, bold bool) float64 {
+	_, font := d.font(bold)
+	units := 0
+	for _, r := range s {
+		glyph, _ := font.glyph(r)
+		units += font.width(glyph)
+	}
+	return float64(units) * size / 1000
+}
+
+func (d *pdfDocument) line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(d.current(), "0.5 w %.2f %.2f m %.2f %.2f l S\n", x1, y1, x2, y2)
+}
+
+// encode returns s as the hex string of its glyph IDs, the two-byte codes of
+// the Identity-H encoding, and records the glyphs as used. Characters the
+// font doesn't have are set as '?'.
+func (f *pdfFont) encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		glyph, shown := f.glyph(r)
+		if _, ok := f.used[glyph]; !ok {
+			f.used[glyph] = shown
+		}
+		fmt.Fprintf(&b, "%04X", glyph)
+	}
+	return b.String()
+}
+
+// bytes serializes the document: catalog, page tree, five objects per font
+// and one page plus content stream per page, followed by the
+// cross-reference table
+func (d *pdfDocument) bytes() []byte {
+	var out bytes.Buffer
+	var offsets []int
+
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-2 are fixed, font i uses objects 3+5i to 7+5i and page i
+	// objects first+2i and first+1+2i
+	first := 3 + 5*len(d.fonts)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", first+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	for i, font := range d.fonts {
+		n := 3 + 5*i
+		name := font.subsetTag() + "+" + font.name
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>", name, n+1, n+4))
+		object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /CIDToGIDMap /Identity /W [%s] >>", name, n+2, font.widths()))
+		object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+			name, font.scale(font.bbox[0]), font.scale(font.bbox[1]), font.scale(font.bbox[2]), font.scale(font.bbox[3]),
+			font.scale(font.ascent), font.scale(font.descent), font.scale(font.capHeight), n+3))
+		program := font.subset(font.used)
+		object(pdfStream(program, fmt.Sprintf(" /Length1 %d", len(program))))
+		object(pdfStream(font.toUnicode(), ""))
+	}
+
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 3 0 R /F2 %d 0 R >> >> /Contents %d 0 R >>", 3+5, first+1+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return out.Bytes()
+}
+
+// pdfStream is a stream object of the compressed data, with the extra
+// dictionary entries
+func pdfStream(data []byte, entries string) string {
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(data)
+	writer.Close()
+	return fmt.Sprintf("<< /Filter /FlateDecode /Length %d%s >>\nstream\n%s\nendstream", compressed.Len(), entries, compressed.String())
+}
+
+func (f *pdfFont) glyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(f.used))
+	for glyph := range f.used {
+		glyphs = append(glyphs, glyph)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// widths lists the advance widths of the used glyphs for the W entry
+func (f *pdfFont) widths() string {
+	var b strings.Builder
+	for _, glyph := range f.glyphs() {
+		fmt.Fprintf(&b, "%d [%d] ", glyph, f.width(glyph))
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// subsetTag names the subset after the glyphs in it, six capital letters as
+// PDF requires
+func (f *pdfFont) subsetTag() string {
+	hash := fnv.New32a()
+	for _, glyph := range f.glyphs() {
+		hash.Write([]byte{byte(glyph >> 8), byte(glyph)})
+	}
+	sum := hash.Sum32()
+	tag := make([]byte, 6)
+	for i := range tag {
+		tag[i] = 'A' + byte(sum%26)
+		sum /= 26
+	}
+	return string(tag)
+}
+
+// toUnicode is the CMap from the used glyphs to the characters they stand for
+func (f *pdfFont) toUnicode() []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	glyphs := f.glyphs()
+	// At most 100 mappings per block
+	for start := 0; start < len(glyphs); start += 100 {
+		block := glyphs[start:min(start+100, len(glyphs))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(block))
+		for _, glyph := range block {
+			fmt.Fprintf(&b, "<%04X> <", glyph)
+			for _, unit := range utf16.Encode([]rune{f.used[glyph]}) {
+				fmt.Fprintf(&b, "%04X", unit)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CIDInit /ProcSet findresource /defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
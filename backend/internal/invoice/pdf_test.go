@@ -0,0 +1,156 @@
+package invoice
+
+import (
+	"backend/models"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	objectPattern = regexp.MustCompile(`(?s)(\d+) 0 obj\n(<<.*?>>)\nstream\n`)
+	xrefPattern   = regexp.MustCompile(`(?s)xref\n0 (\d+)\n(.*?)trailer`)
+	bfcharPattern = regexp.MustCompile(`<([0-9A-F]{4})> <([0-9A-F]+)>`)
+)
+
+// pdfStreams decompresses the streams of a rendered document with the
+// dictionary each belongs to
+func pdfStreams(t *testing.T, pdf []byte) map[string][]byte {
+	t.Helper()
+	streams := make(map[string][]byte)
+	for _, match := range objectPattern.FindAllSubmatchIndex(pdf, -1) {
+		dictionary := string(pdf[match[4]:match[5]])
+		start := match[1]
+		if !strings.Contains(dictionary, "/FlateDecode") {
+			continue
+		}
+		length, err := strconv.Atoi(regexp.MustCompile(`/Length (\d+)`).FindStringSubmatch(dictionary)[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := zlib.NewReader(bytes.NewReader(pdf[start : start+length]))
+		if err != nil {
+			t.Fatalf("stream of %s: %v", dictionary, err)
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("stream of %s: %v", dictionary, err)
+		}
+		streams[dictionary] = data
+	}
+	return streams
+}
+
+// Text in any script the font covers is embedded as glyphs of a font subset,
+// with the characters they stand for, rather than replaced with '?'
+func TestRenderPDFUnicode(t *testing.T) {
+	number := "INV-2026-0001"
+	invoice := &models.Invoice{
+		Number:        &number,
+		Currency:      "EUR",
+		SellerName:    "Société Générale d'Électricité",
+		SellerAddress: "Łódź, Piotrkowska 1\nΑθήνα",
+		BuyerName:     "Фёдор Достоевский",
+		BuyerEmail:    "fedor@example.com",
+		Lines:         []models.InvoiceLine{{Description: "Überweisung – Straße ✓", Amount: 12.5}},
+		Subtotal:      12.5,
+		Total:         12.5,
+		Footer:        "東京",
+	}
+	pdf := renderPDF(invoice)
+
+	// Every cross-reference entry points at its object
+	xref := xrefPattern.FindSubmatch(pdf)
+	if xref == nil {
+		t.Fatal("no cross-reference table")
+	}
+	entries := strings.Split(strings.TrimSpace(string(xref[2])), "\n")[1:]
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(entry[:10])
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Fatalf("cross-reference entry %d points at %q", i+1, pdf[offset:offset+10])
+		}
+	}
+
+	text := make(map[rune]bool)
+	fonts, maps := 0, 0
+	for dictionary, data := range pdfStreams(t, pdf) {
+		switch {
+		case strings.Contains(dictionary, "/Length1"):
+			if _, err := parseTrueType("subset", data); err != nil {
+				t.Fatalf("embedded font: %v", err)
+			}
+			if len(data) > 100<<10 {
+				t.Errorf("embedded font is %d bytes", len(data))
+			}
+			fonts++
+		case bytes.Contains(data, []byte("beginbfchar")):
+			for _, mapping := range bfcharPattern.FindAllSubmatch(data, -1) {
+				r, _ := strconv.ParseUint(string(mapping[2]), 16, 32)
+				text[rune(r)] = true
+			}
+			maps++
+		}
+	}
+	if fonts != 2 || maps != 2 {
+		t.Fatalf("found %d fonts and %d ToUnicode maps, want 2 of each", fonts, maps)
+	}
+
+	for _, r := range "éÉŁźΑθήФёдостевй–ßÜ✓?" {
+		if !text[r] {
+			t.Errorf("%q is not set in the document", r)
+		}
+	}
+	for _, r := range "東京" {
+		if text[r] {
+			t.Errorf("%q, which the font doesn't have, is mapped", r)
+		}
+	}
+}
+
+// A subset keeps the outlines of the glyphs used and of the components of
+// composite ones, under the same glyph IDs
+func TestFontSubset(t *testing.T) {
+	used := make(map[uint16]rune)
+	for _, r := range "Ωǻ" {
+		glyph, _ := regularFont.glyph(r)
+		used[glyph] = r
+	}
+	composite, _ := regularFont.glyph('ǻ')
+	components := compositeComponents(regularFont.glyphData(composite))
+	if len(components) == 0 {
+		t.Fatal("ǻ is not a composite glyph")
+	}
+
+	subset, err := parseTrueType("subset", regularFont.subset(used))
+	if err != nil {
+		t.Fatalf("parseTrueType: %v", err)
+	}
+	if len(subset.advances) != len(regularFont.advances) {
+		t.Fatalf("subset has %d glyphs, want %d", len(subset.advances), len(regularFont.advances))
+	}
+	keep := map[uint16]bool{0: true}
+	for glyph := range used {
+		keep[glyph] = true
+	}
+	for _, glyph := range components {
+		keep[glyph] = true
+		for _, nested := range compositeComponents(regularFont.glyphData(glyph)) {
+			keep[nested] = true
+		}
+	}
+	for glyph := range regularFont.advances {
+		got, want := subset.glyphData(uint16(glyph)), regularFont.glyphData(uint16(glyph))
+		if keep[uint16(glyph)] && !bytes.Equal(got[:len(want)], want) {
+			t.Errorf("glyph %d lost its outline", glyph)
+		}
+		if !keep[uint16(glyph)] && len(got) != 0 {
+			t.Errorf("glyph %d wasn't used but has an outline", glyph)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package invoice
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/core/email"
+	"backend/internal/billing"
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	billing.ProviderSet,
+	email.NewEmailService,
+
+	wire.Bind(new(domain.InvoiceControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.InvoiceService), new(*Service)),
+	wire.Bind(new(domain.InvoiceRepository), new(*Repository)),
+	wire.Bind(new(coreDomain.EmailService), new(*email.Service)),
+)
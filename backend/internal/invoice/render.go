@@ -0,0 +1,199 @@
+package invoice
+
+import (
+	"backend/models"
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+)
+
+var htmlTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"money": formatMoney,
+	"date":  formatDate,
+	"lines": func(s string) []string { return strings.Split(s, "\n") },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} {{.Number}}</title>
+<style>
+  body { font-family: Helvetica, Arial, sans-serif; color: #222; max-width: 760px; margin: 40px auto; }
+  table { width: 100%; border-collapse: collapse; margin-top: 24px; }
+  th, td { padding: 8px; border-bottom: 1px solid #ddd; text-align: left; }
+  .amount { text-align: right; white-space: nowrap; }
+  .parties { display: flex; justify-content: space-between; margin-top: 24px; }
+  .totals td { border: none; }
+  .footer { margin-top: 40px; color: #666; font-size: 0.9em; white-space: pre-line; }
+</style>
+</head>
+<body>
+<h1>{{.Title}} {{.Number}}</h1>
+<p>Issued {{date .IssuedAt}}</p>
+{{if .CreditedInvoiceNumber}}<p>Credits invoice {{.CreditedInvoiceNumber}}</p>{{end}}
+<div class="parties">
+  <div>
+    <strong>{{.SellerName}}</strong><br>
+    {{range lines .SellerAddress}}{{.}}<br>{{end}}
+    {{if .SellerEmail}}{{.SellerEmail}}<br>{{end}}
+    {{if .SellerVATID}}VAT ID: {{.SellerVATID}}{{end}}
+  </div>
+  <div>
+    <strong>Bill to</strong><br>
+    {{.BuyerName}}<br>
+    {{range lines .BuyerAddress}}{{.}}<br>{{end}}
+    {{.BuyerEmail}}<br>
+    {{if .BuyerVATID}}VAT ID: {{.BuyerVATID}}{{end}}
+  </div>
+</div>
+<table>
+  <thead>
+    <tr><th>Description</th><th>Period</th><th class="amount">Qty</th><th class="amount">Unit price</th><th class="amount">Amount</th></tr>
+  </thead>
+  <tbody>
+  {{$currency := .Currency}}
+  {{range .Lines}}
+    <tr>
+      <td>{{.Description}}</td>
+      <td>{{if .PeriodStart}}{{date .PeriodStart}} - {{date .PeriodEnd}}{{end}}</td>
+      <td class="amount">{{.Quantity}}</td>
+      <td class="amount">{{money .UnitAmount $currency}}</td>
+      <td class="amount">{{money .Amount $currency}}</td>
+    </tr>
+  {{end}}
+  </tbody>
+</table>
+<table class="totals">
+  <tr><td>Subtotal</td><td class="amount">{{money .Subtotal .Currency}}</td></tr>
+  {{range .TaxLines}}
+  <tr><td>{{.Name}} ({{printf "%.2f" .Rate}}% of {{money .TaxableAmount $currency}})</td><td class="amount">{{money .Amount $currency}}</td></tr>
+  {{end}}
+  <tr><td><strong>Total</strong></td><td class="amount"><strong>{{money .Total .Currency}}</strong></td></tr>
+</table>
+{{if .Footer}}<p class="footer">{{.Footer}}</p>{{end}}
+</body>
+</html>
+`))
+
+func renderHTML(invoice *models.Invoice) ([]byte, error) {
+	var buffer bytes.Buffer
+	if err := htmlTemplate.Execute(&buffer, invoice); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// renderPDF lays the invoice or credit note out on A4 pages
+func renderPDF(invoice *models.Invoice) []byte {
+	doc := newPDFDocument()
+	const left, right = 50.0, 545.0
+	y := 790.0
+
+	number := ""
+	if invoice.Number != nil {
+		number = *invoice.Number
+	}
+	doc.text(left, y, 20, true, invoice.Title()+" "+number)
+	y -= 20
+	doc.text(left, y, 10, false, "Issued "+formatDate(invoice.IssuedAt))
+	if invoice.CreditedInvoiceNumber != "" {
+		y -= 14
+		doc.text(left, y, 10, false, "Credits invoice "+invoice.CreditedInvoiceNumber)
+	}
+	y -= 36
+
+	// Seller on the left, buyer on the right
+	seller := append([]string{invoice.SellerName}, splitLines(invoice.SellerAddress)...)
+	if invoice.SellerEmail != "" {
+		seller = append(seller, invoice.SellerEmail)
+	}
+	if invoice.SellerVATID != "" {
+		seller = append(seller, "VAT ID: "+invoice.SellerVATID)
+	}
+	buyer := append([]string{"Bill to", invoice.BuyerName}, splitLines(invoice.BuyerAddress)...)
+	buyer = append(buyer, invoice.BuyerEmail)
+	if invoice.BuyerVATID != "" {
+		buyer = append(buyer, "VAT ID: "+invoice.BuyerVATID)
+	}
+	partiesTop := y
+	for i, line := range seller {
+		doc.text(left, partiesTop-float64(i)*14, 10, i == 0, line)
+	}
+	for i, line := range buyer {
+		doc.text(320, partiesTop-float64(i)*14, 10, i == 0, line)
+	}
+	y = partiesTop - float64(max(len(seller), len(buyer)))*14 - 24
+
+	// Line items
+	doc.text(left, y, 10, true, "Description")
+	doc.text(300, y, 10, true, "Period")
+	doc.textRight(right, y, 10, true, "Amount")
+	y -= 6
+	doc.line(left, y, right, y)
+	y -= 16
+	for _, line := range invoice.Lines {
+		if y < 120 {
+			doc.newPage()
+			y = 790
+		}
+		doc.text(left, y, 10, false, line.Description)
+		if line.PeriodStart != nil {
+			doc.text(300, y, 10, false, formatDate(line.PeriodStart)+" - "+formatDate(line.PeriodEnd))
+		}
+		doc.textRight(right, y, 10, false, formatMoney(line.Amount, invoice.Currency))
+		y -= 18
+	}
+	doc.line(left, y+8, right, y+8)
+	y -= 10
+
+	// Totals
+	doc.text(330, y, 10, false, "Subtotal")
+	doc.textRight(right, y, 10, false, formatMoney(invoice.Subtotal, invoice.Currency))
+	y -= 16
+	for _, tax := range invoice.TaxLines {
+		doc.text(330, y, 10, false, fmt.Sprintf("%s (%.2f%%)", tax.Name, tax.Rate))
+		doc.textRight(right, y, 10, false, formatMoney(tax.Amount, invoice.Currency))
+		y -= 16
+	}
+	doc.text(330, y, 11, true, "Total")
+	doc.textRight(right, y, 11, true, formatMoney(invoice.Total, invoice.Currency))
+
+	if invoice.Footer != "" {
+		lines := strings.Split(invoice.Footer, "\n")
+		for i, line := range lines {
+			doc.text(left, float64(50+12*(len(lines)-1-i)), 9, false, line)
+		}
+	}
+
+	return doc.bytes()
+}
+
+// formatMoney renders an amount with thousands separators and the currency code
+func formatMoney(amount float64, currency string) string {
+	cents := int64(math.Round(math.Abs(amount) * 100))
+	whole := fmt.Sprint(cents / 100)
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	sign := ""
+	if amount < 0 && cents > 0 {
+		sign = "-"
+	}
+	return fmt.Sprintf("%s%s.%02d %s", sign, whole, cents%100, currency)
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,122 @@
+package invoice
+
+import (
+	"backend/models"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateFinalized numbers the invoice from the tenant's sequence and stores
+// it with its lines in one transaction, so a failed insert never consumes a
+// number
+func (r *Repository) CreateFinalized(invoice *models.Invoice, prefix string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.InvoiceSequence{TenantID: invoice.TenantID}).Error; err != nil {
+			return err
+		}
+
+		var sequence models.InvoiceSequence
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tenant_id = ?", invoice.TenantID).
+			First(&sequence).Error; err != nil {
+			return err
+		}
+
+		sequence.LastNumber++
+		if err := tx.Model(&models.InvoiceSequence{}).
+			Where("tenant_id = ?", invoice.TenantID).
+			Update("last_number", sequence.LastNumber).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		number := fmt.Sprintf("%s%06d", prefix, sequence.LastNumber)
+		invoice.Number = &number
+		invoice.Sequence = sequence.LastNumber
+		invoice.Status = models.InvoiceStatusFinalized
+		invoice.IssuedAt = &now
+
+		return tx.Create(invoice).Error
+	})
+}
+
+func (r *Repository) GetByID(id, tenantID int) (*models.Invoice, error) {
+	var invoice models.Invoice
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("TaxLines", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		First(&invoice).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *Repository) GetByPayment(paymentID int) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Where("payment_id = ?", paymentID).Preload("TaxLines").First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+func (r *Repository) GetByRefund(refundID int) (*models.Invoice, error) {
+	var invoice models.Invoice
+	if err := r.db.Where("refund_id = ?", refundID).First(&invoice).Error; err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetInvoices lists the tenant's invoices, only those of userID when set
+func (r *Repository) GetInvoices(tenantID int, userID *int) ([]models.Invoice, error) {
+	var invoices []models.Invoice
+	query := r.db.Where("tenant_id = ?", tenantID)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	err := query.Preload("Lines").
+		Preload("TaxLines").
+		Order("sequence DESC").
+		Find(&invoices).Error
+	return invoices, err
+}
+
+func (r *Repository) GetPurchase(id, tenantID int) (*models.Purchase, error) {
+	var purchase models.Purchase
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).
+		Preload("Plan.Product").
+		First(&purchase).Error
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
+func (r *Repository) GetUser(id int) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *Repository) GetTenant(id int) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
@@ -0,0 +1,293 @@
+package invoice
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// reverseChargeNote is printed on invoices of reverse charged sales
+const reverseChargeNote = "Reverse charge: VAT to be accounted for by the recipient (Article 196, Council Directive 2006/112/EC)."
+
+// creditNotePrefix is put before the invoice prefix of credit note numbers,
+// which share the tenant's invoice sequence
+const creditNotePrefix = "CN-"
+
+type Service struct {
+	repo           domain.InvoiceRepository
+	billingService domain.BillingService
+	emailService   coreDomain.EmailService
+}
+
+func NewService(repo domain.InvoiceRepository, billingService domain.BillingService, emailService coreDomain.EmailService) *Service {
+	return &Service{
+		repo:           repo,
+		billingService: billingService,
+		emailService:   emailService,
+	}
+}
+
+// IssueForPayment issues and emails the invoice of a succeeded payment,
+// covering the purchase's current period. Issuing twice returns the existing
+// invoice.
+func (s *Service) IssueForPayment(payment *models.Payment) (*models.Invoice, error) {
+	if payment.Status != coreDomain.PaymentStatusSucceeded {
+		return nil, errors.New("only succeeded payments can be invoiced")
+	}
+	if existing, err := s.repo.GetByPayment(payment.ID); err == nil {
+		return existing, nil
+	}
+
+	purchase, err := s.repo.GetPurchase(payment.PurchaseID, payment.TenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	tenant, err := s.repo.GetTenant(payment.TenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+	buyer, err := s.repo.GetUser(payment.UserID)
+	if err != nil {
+		return nil, errors.New("buyer not found")
+	}
+	seller, err := s.billingService.GetTenantSettings(payment.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := s.billingService.GetBillingProfile(payment.UserID, payment.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Payments made before tax was tracked were charged net
+	net := payment.Amount
+	if payment.NetAmount != nil {
+		net = *payment.NetAmount
+	}
+
+	footer := seller.InvoiceFooter
+	if purchase.ReverseCharge {
+		footer = strings.TrimSpace(reverseChargeNote + "\n" + footer)
+	}
+
+	paymentID := payment.ID
+	invoice := &models.Invoice{
+		PurchaseID:    purchase.ID,
+		PaymentID:     &paymentID,
+		UserID:        payment.UserID,
+		Currency:      payment.Currency,
+		Subtotal:      net,
+		TaxTotal:      payment.TaxAmount,
+		Total:         payment.Amount,
+		SellerName:    firstNonEmpty(seller.LegalName, tenant.TenantName),
+		SellerAddress: formatAddress(seller.AddressLine1, seller.AddressLine2, seller.PostalCode, seller.City, seller.Region, seller.Country),
+		SellerEmail:   seller.Email,
+		SellerVATID:   seller.VATID,
+		BuyerName:     firstNonEmpty(profile.Company, profile.Name, strings.TrimSpace(buyer.FirstName+" "+buyer.LastName), buyer.Email),
+		BuyerEmail:    buyer.Email,
+		BuyerAddress:  formatAddress(profile.AddressLine1, profile.AddressLine2, profile.PostalCode, profile.City, profile.Region, profile.Country),
+		BuyerVATID:    profile.VATID,
+		Footer:        footer,
+		Type:          models.InvoiceTypeInvoice,
+		TenantID:      payment.TenantID,
+		Lines: []models.InvoiceLine{{
+			Description: lineDescription(purchase, payment.Kind),
+			Quantity:    1,
+			UnitAmount:  net,
+			Amount:      net,
+			PeriodStart: purchase.CurrentPeriodStart,
+			PeriodEnd:   purchase.ExpiresAt,
+		}},
+	}
+	if purchase.TaxName != "" {
+		invoice.TaxLines = []models.InvoiceTaxLine{{
+			Name:          purchase.TaxName,
+			Rate:          purchase.TaxRate,
+			TaxableAmount: net,
+			Amount:        payment.TaxAmount,
+		}}
+	}
+
+	if err := s.repo.CreateFinalized(invoice, seller.InvoicePrefix); err != nil {
+		// A concurrent issue for the same payment wins the unique index
+		if existing, lookupErr := s.repo.GetByPayment(payment.ID); lookupErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to issue invoice: %w", err)
+	}
+
+	go func() {
+		if err := s.send(invoice); err != nil {
+			log.Printf("Failed to email invoice %s: %v", *invoice.Number, err)
+		}
+	}()
+
+	return invoice, nil
+}
+
+// IssueCreditNote issues and emails a credit note for the refund against the
+// invoice of the refunded payment. The credit note copies the invoice's
+// parties and tax treatment, with negative amounts. Issuing twice returns
+// the existing credit note.
+func (s *Service) IssueCreditNote(refund *models.Refund) (*models.Invoice, error) {
+	if existing, err := s.repo.GetByRefund(refund.ID); err == nil {
+		return existing, nil
+	}
+
+	original, err := s.repo.GetByPayment(refund.PaymentID)
+	if err != nil {
+		return nil, errors.New("refunded payment has no invoice to credit")
+	}
+	seller, err := s.billingService.GetTenantSettings(refund.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	refundID := refund.ID
+	description := fmt.Sprintf("Refund of invoice %s", *original.Number)
+	if refund.Reason != "" {
+		description += ": " + refund.Reason
+	}
+	creditNote := &models.Invoice{
+		Type:                  models.InvoiceTypeCreditNote,
+		PurchaseID:            original.PurchaseID,
+		RefundID:              &refundID,
+		CreditedInvoiceID:     &original.ID,
+		CreditedInvoiceNumber: *original.Number,
+		UserID:                original.UserID,
+		Currency:              refund.Currency,
+		Subtotal:              -refund.NetAmount,
+		TaxTotal:              -refund.TaxAmount,
+		Total:                 -refund.Amount,
+		SellerName:            original.SellerName,
+		SellerAddress:         original.SellerAddress,
+		SellerEmail:           original.SellerEmail,
+		SellerVATID:           original.SellerVATID,
+		BuyerName:             original.BuyerName,
+		BuyerEmail:            original.BuyerEmail,
+		BuyerAddress:          original.BuyerAddress,
+		BuyerVATID:            original.BuyerVATID,
+		Footer:                original.Footer,
+		TenantID:              refund.TenantID,
+		Lines: []models.InvoiceLine{{
+			Description: description,
+			Quantity:    1,
+			UnitAmount:  -refund.NetAmount,
+			Amount:      -refund.NetAmount,
+		}},
+	}
+	if len(original.TaxLines) > 0 {
+		creditNote.TaxLines = []models.InvoiceTaxLine{{
+			Name:          original.TaxLines[0].Name,
+			Rate:          original.TaxLines[0].Rate,
+			TaxableAmount: -refund.NetAmount,
+			Amount:        -refund.TaxAmount,
+		}}
+	}
+
+	if err := s.repo.CreateFinalized(creditNote, creditNotePrefix+seller.InvoicePrefix); err != nil {
+		if existing, lookupErr := s.repo.GetByRefund(refund.ID); lookupErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to issue credit note: %w", err)
+	}
+
+	go func() {
+		if err := s.send(creditNote); err != nil {
+			log.Printf("Failed to email credit note %s: %v", *creditNote.Number, err)
+		}
+	}()
+
+	return creditNote, nil
+}
+
+// GetInvoices lists the user's invoices, or all of the tenant's for admins
+func (s *Service) GetInvoices(userID, tenantID int, asAdmin bool) ([]models.Invoice, error) {
+	if asAdmin {
+		return s.repo.GetInvoices(tenantID, nil)
+	}
+	return s.repo.GetInvoices(tenantID, &userID)
+}
+
+func (s *Service) GetInvoice(id, userID, tenantID int, asAdmin bool) (*models.Invoice, error) {
+	invoice, err := s.repo.GetByID(id, tenantID)
+	if err != nil || (!asAdmin && invoice.UserID != userID) {
+		return nil, errors.New("invoice not found")
+	}
+	return invoice, nil
+}
+
+func (s *Service) RenderHTML(invoice *models.Invoice) ([]byte, error) {
+	return renderHTML(invoice)
+}
+
+func (s *Service) RenderPDF(invoice *models.Invoice) ([]byte, error) {
+	return renderPDF(invoice), nil
+}
+
+// SendInvoice emails the invoice to its buyer again
+func (s *Service) SendInvoice(id, userID, tenantID int, asAdmin bool) error {
+	invoice, err := s.GetInvoice(id, userID, tenantID, asAdmin)
+	if err != nil {
+		return err
+	}
+	if err := s.send(invoice); err != nil {
+		return errors.New("failed to send invoice")
+	}
+	return nil
+}
+
+func (s *Service) send(invoice *models.Invoice) error {
+	html, err := renderHTML(invoice)
+	if err != nil {
+		return err
+	}
+	return s.emailService.SendInvoiceEmail(invoice.BuyerEmail, invoice.Title(), *invoice.Number, string(html), renderPDF(invoice))
+}
+
+func lineDescription(purchase *models.Purchase, kind string) string {
+	description := fmt.Sprintf("Purchase #%d", purchase.ID)
+	if purchase.Plan != nil {
+		description = purchase.Plan.Name
+		if purchase.Plan.Product != nil {
+			description = purchase.Plan.Product.Name + " - " + purchase.Plan.Name
+		}
+	}
+	switch kind {
+	case models.PaymentKindRenewal:
+		description += " (renewal)"
+	case models.PaymentKindProration:
+		description += " (plan change, prorated)"
+	}
+	return description
+}
+
+// formatAddress joins the non-empty address parts into lines
+func formatAddress(line1, line2, postalCode, city, region, country string) string {
+	var lines []string
+	for _, line := range []string{
+		line1,
+		line2,
+		strings.TrimSpace(postalCode + " " + city),
+		region,
+		country,
+	} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
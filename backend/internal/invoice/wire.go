@@ -0,0 +1,17 @@
+//go:build wireinject
+// +build wireinject
+
+package invoice
+
+import (
+	"backend/core"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewControllerWire(db *gorm.DB, cfg *core.Config) *Controller {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Controller{}
+}
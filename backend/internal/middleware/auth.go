@@ -9,6 +9,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// StreamTokenAudience marks the tokens issued for opening event streams
+const StreamTokenAudience = "event-stream"
+
 type JWTClaims struct {
 	UserID   int    `json:"user_id"`
 	Email    string `json:"email"`
@@ -40,7 +43,8 @@ func JWTAuth(cfg *core.Config) fiber.Handler {
 			})
 		}
 
-		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+		// Stream tokens travel in URLs and only open event streams
+		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid && !isStreamToken(claims) {
 			c.Locals("userID", claims.UserID)
 			c.Locals("email", claims.Email)
 			c.Locals("role", claims.Role)
@@ -55,6 +59,39 @@ func JWTAuth(cfg *core.Config) fiber.Handler {
 	}
 }
 
+// StreamTokenAuth authenticates a request by the stream token in the token
+// query parameter, for clients such as browsers' EventSource that can't set
+// headers. Stream tokens are short-lived and only valid here, so that a URL
+// in a log grants nothing for long.
+func StreamTokenAuth(cfg *core.Config) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   true,
+				"message": "Missing stream token",
+			})
+		}
+
+		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return []byte(cfg.JWTSecret), nil
+		}, jwt.WithAudience(StreamTokenAudience))
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error":   true,
+				"message": "Invalid or expired stream token",
+			})
+		}
+
+		claims := token.Claims.(*JWTClaims)
+		c.Locals("userID", claims.UserID)
+		c.Locals("email", claims.Email)
+		c.Locals("role", claims.Role)
+		c.Locals("tenantID", claims.TenantID)
+		return c.Next()
+	}
+}
+
 func SuperAdminAuth(cfg *core.Config) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
@@ -132,3 +169,32 @@ func GenerateJWT(userID int, email, role string, tenantID *int, secret string) (
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
 }
+
+// GenerateStreamToken issues a token opening the user's event stream for ttl
+func GenerateStreamToken(userID int, email, role string, tenantID *int, secret string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := JWTClaims{
+		UserID:   userID,
+		Email:    email,
+		Role:     role,
+		TenantID: tenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{StreamTokenAudience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secret))
+}
+
+func isStreamToken(claims *JWTClaims) bool {
+	for _, audience := range claims.Audience {
+		if audience == StreamTokenAudience {
+			return true
+		}
+	}
+	return false
+}
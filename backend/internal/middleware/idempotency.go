@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// IdempotencyKeyHeader is the request header carrying a client-chosen key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLength bounds the keys clients may send
+const maxIdempotencyKeyLength = 255
+
+// Idempotency makes mutating requests sent with an Idempotency-Key header
+// safe to retry: the first response is stored per tenant, user and key and
+// replayed for later requests with the same key. Must run after JWTAuth.
+func Idempotency(service domain.IdempotencyService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		key := c.Get(IdempotencyKeyHeader)
+		if key == "" || !isMutating(c.Method()) {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   true,
+				"message": "Idempotency-Key is too long",
+			})
+		}
+
+		tenantID, _ := c.Locals("tenantID").(*int)
+		userID, ok := c.Locals("userID").(int)
+		if tenantID == nil || !ok {
+			return c.Next()
+		}
+
+		record, err := service.Begin(*tenantID, userID, key, requestFingerprint(c))
+		if err != nil {
+			if errors.Is(err, domain.ErrIdempotencyKeyReused) {
+				return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+					"error":   true,
+					"message": err.Error(),
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   true,
+				"message": "Failed to process Idempotency-Key",
+			})
+		}
+
+		if record.Status == models.IdempotencyStatusCompleted {
+			c.Set("Idempotent-Replayed", "true")
+			c.Set(fiber.HeaderContentType, record.ResponseContentType)
+			return c.Status(record.ResponseStatus).Send(record.ResponseBody)
+		}
+
+		// Errors and server failures aren't stored, so the request can be retried
+		if err := c.Next(); err != nil {
+			release(service, record)
+			return err
+		}
+		status := c.Response().StatusCode()
+		if status >= fiber.StatusInternalServerError {
+			release(service, record)
+			return nil
+		}
+
+		body := append([]byte(nil), c.Response().Body()...)
+		contentType := string(c.Response().Header.ContentType())
+		if err := service.Complete(record, status, contentType, body); err != nil {
+			log.Printf("Failed to store response for idempotency key %d: %v", record.ID, err)
+		}
+		return nil
+	}
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint identifies a request by its method, URL and body
+func requestFingerprint(c *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method() + " " + c.OriginalURL() + "\n"))
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// release frees the key of a request whose response isn't stored
+func release(service domain.IdempotencyService, record *models.IdempotencyKey) {
+	if err := service.Release(record); err != nil {
+		log.Printf("Failed to release idempotency key %d: %v", record.ID, err)
+	}
+}
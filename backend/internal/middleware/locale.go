@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"backend/core/i18n"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Locale resolves the requested content locales from the locale query
+// parameter or the Accept-Language header. The resulting candidates, stored
+// as "locales", exclude the tenant's default locale and anything after it,
+// since untranslated fields already hold default-locale content.
+func Locale() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		defaultLocale, _ := c.Locals("tenantLocale").(string)
+
+		var preferred []string
+		if locale, ok := i18n.Normalize(c.Query("locale")); ok {
+			preferred = []string{locale}
+		} else {
+			preferred = i18n.ParseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage))
+		}
+
+		c.Locals("locales", i18n.Candidates(preferred, defaultLocale))
+		c.Vary(fiber.HeaderAcceptLanguage)
+		return c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"backend/core"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+)
+
+// PublicRateLimit limits unauthenticated requests per client IP and tenant
+func PublicRateLimit(cfg *core.Config) fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        cfg.PublicRateLimit,
+		Expiration: time.Minute,
+		KeyGenerator: func(c *fiber.Ctx) string {
+			return fmt.Sprintf("%s|%v", c.IP(), c.Locals("tenantID"))
+		},
+		LimitReached: func(c *fiber.Ctx) error {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":   true,
+				"message": "Too many requests",
+			})
+		},
+	})
+}
+
+// CacheControl marks successful responses as publicly cacheable for maxAge seconds
+func CacheControl(maxAge int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if status == fiber.StatusOK || status == fiber.StatusNotModified {
+			c.Set(fiber.HeaderCacheControl, fmt.Sprintf("public, max-age=%d", maxAge))
+		}
+		return nil
+	}
+}
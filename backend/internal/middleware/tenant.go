@@ -12,6 +12,10 @@ func TenantMiddleware(db *gorm.DB) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get tenant domain from x-tenant-id header
 		tenantDomain := c.Get("x-tenant-id")
+		if tenantDomain == "" {
+			// Allow ?tenant= for requests that can't set headers, such as <img> tags
+			tenantDomain = c.Query("tenant")
+		}
 		if tenantDomain == "" {
 			tenantDomain = "amaix"
 			//return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -44,6 +48,7 @@ func TenantMiddleware(db *gorm.DB) fiber.Handler {
 		c.Locals("tenantID", tenant.ID)
 		c.Locals("tenantDomain", tenant.TenantDomain)
 		c.Locals("tenantName", tenant.TenantName)
+		c.Locals("tenantLocale", tenant.DefaultLocale)
 
 		return c.Next()
 	}
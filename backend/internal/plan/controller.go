@@ -3,23 +3,28 @@ package plan
 import (
 	"backend/internal/domain"
 	"backend/models"
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Controller struct {
-	planService domain.PlanService
+	planService        domain.PlanService
+	translationService domain.TranslationService
 }
 
-func NewPlanController(planService domain.PlanService) *Controller {
-	return &Controller{planService: planService}
+func NewPlanController(planService domain.PlanService, translationService domain.TranslationService) *Controller {
+	return &Controller{
+		planService:        planService,
+		translationService: translationService,
+	}
 }
 
 func (h *Controller) GetPlans(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
 
-	plans, err := h.planService.GetPlansByTenant(*tenantID)
+	plans, err := h.planService.GetPlansByTenant(*tenantID, c.QueryBool("include_archived"))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
@@ -27,6 +32,14 @@ func (h *Controller) GetPlans(c *fiber.Ctx) error {
 		})
 	}
 
+	locales, _ := c.Locals("locales").([]string)
+	if err := h.translationService.LocalizePlans(plans, *tenantID, locales); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"error": false,
 		"data":  plans,
@@ -44,7 +57,7 @@ func (h *Controller) GetPlansByProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	plans, err := h.planService.GetPlansByProduct(productID, *tenantID)
+	plans, err := h.planService.GetPlansByProduct(productID, *tenantID, c.QueryBool("include_archived"))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
@@ -52,6 +65,14 @@ func (h *Controller) GetPlansByProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	locales, _ := c.Locals("locales").([]string)
+	if err := h.translationService.LocalizePlans(plans, *tenantID, locales); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"error": false,
 		"data":  plans,
@@ -60,6 +81,7 @@ func (h *Controller) GetPlansByProduct(c *fiber.Ctx) error {
 
 func (h *Controller) CreatePlan(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	productID, err := strconv.Atoi(c.Params("product_id"))
 	if err != nil {
@@ -77,7 +99,7 @@ func (h *Controller) CreatePlan(c *fiber.Ctx) error {
 		})
 	}
 
-	plan, err := h.planService.CreatePlan(req, productID, *tenantID)
+	plan, err := h.planService.CreatePlan(req, productID, userID, *tenantID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -110,6 +132,16 @@ func (h *Controller) GetPlan(c *fiber.Ctx) error {
 		})
 	}
 
+	locales, _ := c.Locals("locales").([]string)
+	localized := []models.Plan{*plan}
+	if err := h.translationService.LocalizePlans(localized, *tenantID, locales); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	plan = &localized[0]
+
 	return c.JSON(fiber.Map{
 		"error": false,
 		"data":  plan,
@@ -118,6 +150,7 @@ func (h *Controller) GetPlan(c *fiber.Ctx) error {
 
 func (h *Controller) UpdatePlan(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -135,7 +168,7 @@ func (h *Controller) UpdatePlan(c *fiber.Ctx) error {
 		})
 	}
 
-	plan, err := h.planService.UpdatePlan(id, req, *tenantID)
+	plan, err := h.planService.UpdatePlan(id, req, userID, *tenantID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -151,6 +184,7 @@ func (h *Controller) UpdatePlan(c *fiber.Ctx) error {
 
 func (h *Controller) DeletePlan(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -160,9 +194,13 @@ func (h *Controller) DeletePlan(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.planService.DeletePlan(id, *tenantID)
+	err = h.planService.DeletePlan(id, userID, *tenantID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		status := fiber.StatusBadRequest
+		if errors.Is(err, domain.ErrActivePurchases) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -173,3 +211,55 @@ func (h *Controller) DeletePlan(c *fiber.Ctx) error {
 		"message": "Plan deleted successfully",
 	})
 }
+
+func (h *Controller) ArchivePlan(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid plan ID",
+		})
+	}
+
+	plan, err := h.planService.ArchivePlan(id, userID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  plan,
+	})
+}
+
+func (h *Controller) UnarchivePlan(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid plan ID",
+		})
+	}
+
+	plan, err := h.planService.UnarchivePlan(id, userID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  plan,
+	})
+}
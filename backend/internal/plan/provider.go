@@ -1,8 +1,12 @@
 package plan
 
 import (
+	"backend/internal/activity"
 	"backend/internal/domain"
+	"backend/internal/feature"
 	"backend/internal/product"
+	"backend/internal/rollup"
+	"backend/internal/translation"
 	"github.com/google/wire"
 )
 
@@ -12,9 +16,19 @@ var ProviderSet = wire.NewSet(
 	NewPlanService,
 	NewPlanRepository,
 	product.NewProductRepository,
+	feature.NewFeatureService,
+	feature.NewFeatureRepository,
+	translation.NewService,
+	translation.NewRepository,
+	rollup.ProviderSet,
+	activity.ProviderSet,
 
 	wire.Bind(new(domain.PlanControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.PlanService), new(*Service)),
 	wire.Bind(new(domain.PlanRepository), new(*Repository)),
 	wire.Bind(new(domain.ProductRepository), new(*product.Repository)),
+	wire.Bind(new(domain.FeatureService), new(*feature.Service)),
+	wire.Bind(new(domain.FeatureRepository), new(*feature.Repository)),
+	wire.Bind(new(domain.TranslationService), new(*translation.Service)),
+	wire.Bind(new(domain.TranslationRepository), new(*translation.Repository)),
 )
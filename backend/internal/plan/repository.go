@@ -2,6 +2,7 @@ package plan
 
 import (
 	"backend/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -18,15 +19,23 @@ func (r *Repository) Create(plan *models.Plan) error {
 	return r.db.Create(plan).Error
 }
 
-func (r *Repository) GetByProduct(productID int, tenantID int) ([]models.Plan, error) {
+func (r *Repository) GetByProduct(productID int, tenantID int, includeArchived bool) ([]models.Plan, error) {
 	var plans []models.Plan
-	err := r.db.Preload("Product").Where("product_id = ? AND tenant_id = ?", productID, tenantID).Find(&plans).Error
+	query := r.db.Preload("Product").Where("product_id = ? AND tenant_id = ?", productID, tenantID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.Find(&plans).Error
 	return plans, err
 }
 
-func (r *Repository) GetByTenant(tenantID int) ([]models.Plan, error) {
+func (r *Repository) GetByTenant(tenantID int, includeArchived bool) ([]models.Plan, error) {
 	var plans []models.Plan
-	err := r.db.Preload("Product").Where("tenant_id = ?", tenantID).Find(&plans).Error
+	query := r.db.Preload("Product").Where("tenant_id = ?", tenantID)
+	if !includeArchived {
+		query = query.Where("archived_at IS NULL")
+	}
+	err := query.Find(&plans).Error
 	return plans, err
 }
 
@@ -46,3 +55,19 @@ func (r *Repository) Update(plan *models.Plan) error {
 func (r *Repository) Delete(id int, tenantID int) error {
 	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.Plan{}).Error
 }
+
+func (r *Repository) SetArchived(id int, tenantID int, archivedAt *time.Time) error {
+	return r.db.Model(&models.Plan{}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Update("archived_at", archivedAt).Error
+}
+
+// CountActivePurchases counts active purchases of the plan, or scheduled to
+// switch to it at their next renewal
+func (r *Repository) CountActivePurchases(id int, tenantID int) (int, error) {
+	var count int64
+	err := r.db.Model(&models.Purchase{}).
+		Where("(plan_id = ? OR scheduled_plan_id = ?) AND tenant_id = ? AND status IN ?", id, id, tenantID, models.EntitledPurchaseStatuses).
+		Count(&count).Error
+	return int(count), err
+}
@@ -4,27 +4,43 @@ import (
 	"backend/internal/domain"
 	"backend/models"
 	"errors"
+	"fmt"
+	"time"
 )
 
 type Service struct {
-	planRepo    domain.PlanRepository
-	productRepo domain.ProductRepository
+	planRepo       domain.PlanRepository
+	productRepo    domain.ProductRepository
+	featureService domain.FeatureService
+	rollups        domain.RollupService
+	activities     domain.ActivityService
 }
 
-func NewPlanService(planRepo domain.PlanRepository, productRepo domain.ProductRepository) *Service {
+func NewPlanService(planRepo domain.PlanRepository, productRepo domain.ProductRepository, featureService domain.FeatureService, rollups domain.RollupService, activities domain.ActivityService) *Service {
 	return &Service{
-		planRepo:    planRepo,
-		productRepo: productRepo,
+		planRepo:       planRepo,
+		productRepo:    productRepo,
+		featureService: featureService,
+		rollups:        rollups,
+		activities:     activities,
 	}
 }
 
-func (s *Service) CreatePlan(req models.CreatePlanRequest, productID int, tenantID int) (*models.Plan, error) {
+func (s *Service) CreatePlan(req models.CreatePlanRequest, productID int, userID, tenantID int) (*models.Plan, error) {
 	// Verify product exists and belongs to tenant
 	_, err := s.productRepo.GetByID(productID, tenantID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
 
+	// Validate feature values against the product's feature catalogue
+	if err := s.featureService.ValidatePlanFeatures(productID, tenantID, req.Features); err != nil {
+		return nil, err
+	}
+	if err := validateTrial(req); err != nil {
+		return nil, err
+	}
+
 	plan := &models.Plan{
 		ProductID:   productID,
 		Name:        req.Name,
@@ -32,29 +48,34 @@ func (s *Service) CreatePlan(req models.CreatePlanRequest, productID int, tenant
 		Price:       req.Price,
 		Currency:    req.Currency,
 		Interval:    req.Interval,
+		TrialDays:   req.TrialDays,
 		Features:    models.JSONB(req.Features),
 		TenantID:    tenantID,
+
+		TrialRequiresPaymentMethod: req.TrialRequiresPaymentMethod,
 	}
 
 	if err := s.planRepo.Create(plan); err != nil {
 		return nil, errors.New("failed to create plan")
 	}
+	s.rollups.Touch(tenantID, time.Now())
+	s.logActivity(plan, models.ActivityPlanCreated, fmt.Sprintf("Created plan '%s'", plan.Name), userID, models.PlanPayload(plan))
 
 	return plan, nil
 }
 
-func (s *Service) GetPlansByProduct(productID int, tenantID int) ([]models.Plan, error) {
+func (s *Service) GetPlansByProduct(productID int, tenantID int, includeArchived bool) ([]models.Plan, error) {
 	// Verify product exists and belongs to tenant
 	_, err := s.productRepo.GetByID(productID, tenantID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
 
-	return s.planRepo.GetByProduct(productID, tenantID)
+	return s.planRepo.GetByProduct(productID, tenantID, includeArchived)
 }
 
-func (s *Service) GetPlansByTenant(tenantID int) ([]models.Plan, error) {
-	return s.planRepo.GetByTenant(tenantID)
+func (s *Service) GetPlansByTenant(tenantID int, includeArchived bool) ([]models.Plan, error) {
+	return s.planRepo.GetByTenant(tenantID, includeArchived)
 }
 
 func (s *Service) GetPlanByID(id int, tenantID int) (*models.Plan, error) {
@@ -65,32 +86,122 @@ func (s *Service) GetPlanByID(id int, tenantID int) (*models.Plan, error) {
 	return plan, nil
 }
 
-func (s *Service) UpdatePlan(id int, req models.CreatePlanRequest, tenantID int) (*models.Plan, error) {
+func (s *Service) UpdatePlan(id int, req models.CreatePlanRequest, userID, tenantID int) (*models.Plan, error) {
 	plan, err := s.planRepo.GetByID(id, tenantID)
 	if err != nil {
 		return nil, errors.New("plan not found")
 	}
+	before := models.PlanPayload(plan)
+
+	// Validate feature values against the product's feature catalogue
+	if err := s.featureService.ValidatePlanFeatures(plan.ProductID, tenantID, req.Features); err != nil {
+		return nil, err
+	}
+	if err := validateTrial(req); err != nil {
+		return nil, err
+	}
 
 	plan.Name = req.Name
 	plan.Description = req.Description
 	plan.Price = req.Price
 	plan.Currency = req.Currency
 	plan.Interval = req.Interval
+	plan.TrialDays = req.TrialDays
+	plan.TrialRequiresPaymentMethod = req.TrialRequiresPaymentMethod
 	plan.Features = models.JSONB(req.Features)
 
 	if err := s.planRepo.Update(plan); err != nil {
 		return nil, errors.New("failed to update plan")
 	}
+	s.logActivity(plan, models.ActivityPlanUpdated, fmt.Sprintf("Updated plan '%s'", plan.Name), userID,
+		models.JSONB{"changes": models.ActivityChanges(before, models.PlanPayload(plan))})
 
 	return plan, nil
 }
 
-func (s *Service) DeletePlan(id int, tenantID int) error {
+func (s *Service) DeletePlan(id int, userID, tenantID int) error {
 	// Check if plan exists
-	_, err := s.planRepo.GetByID(id, tenantID)
+	plan, err := s.planRepo.GetByID(id, tenantID)
 	if err != nil {
 		return errors.New("plan not found")
 	}
 
-	return s.planRepo.Delete(id, tenantID)
+	// Refuse to delete while customers still hold active purchases
+	activePurchases, err := s.planRepo.CountActivePurchases(id, tenantID)
+	if err != nil {
+		return errors.New("failed to check active purchases")
+	}
+	if activePurchases > 0 {
+		return domain.ErrActivePurchases
+	}
+
+	if err := s.planRepo.Delete(id, tenantID); err != nil {
+		return err
+	}
+	s.rollups.Touch(tenantID, time.Now())
+	s.logActivity(plan, models.ActivityPlanDeleted, fmt.Sprintf("Deleted plan '%s'", plan.Name), userID, models.PlanPayload(plan))
+	return nil
+}
+
+// ArchivePlan hides the plan from catalogue listings and new purchases while
+// keeping it resolvable for existing purchases
+func (s *Service) ArchivePlan(id int, userID, tenantID int) (*models.Plan, error) {
+	plan, err := s.planRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("plan not found")
+	}
+
+	if plan.ArchivedAt == nil {
+		now := time.Now()
+		if err := s.planRepo.SetArchived(id, tenantID, &now); err != nil {
+			return nil, errors.New("failed to archive plan")
+		}
+		plan.ArchivedAt = &now
+		s.rollups.Touch(tenantID, now)
+		s.logActivity(plan, models.ActivityPlanArchived, fmt.Sprintf("Archived plan '%s'", plan.Name), userID, nil)
+	}
+
+	return plan, nil
+}
+
+func (s *Service) UnarchivePlan(id int, userID, tenantID int) (*models.Plan, error) {
+	plan, err := s.planRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("plan not found")
+	}
+
+	if plan.ArchivedAt != nil {
+		if err := s.planRepo.SetArchived(id, tenantID, nil); err != nil {
+			return nil, errors.New("failed to unarchive plan")
+		}
+		plan.ArchivedAt = nil
+		s.rollups.Touch(tenantID, time.Now())
+		s.logActivity(plan, models.ActivityPlanUnarchived, fmt.Sprintf("Unarchived plan '%s'", plan.Name), userID, nil)
+	}
+
+	return plan, nil
+}
+
+func (s *Service) logActivity(plan *models.Plan, activityType models.ActivityType, description string, userID int, payload models.JSONB) {
+	s.activities.Record(&models.Activity{
+		UserID:      userID,
+		TenantID:    plan.TenantID,
+		Type:        activityType,
+		Description: description,
+		EntityType:  models.ActivityEntityPlan,
+		EntityID:    &plan.ID,
+		Payload:     payload,
+	})
+}
+
+// validateTrial checks a plan's trial settings. Only recurring plans have a
+// later period to convert the trial into.
+func validateTrial(req models.CreatePlanRequest) error {
+	if req.TrialDays < 0 {
+		return errors.New("trial days can't be negative")
+	}
+	if req.TrialDays > 0 && req.Interval != "" && req.Interval != "monthly" && req.Interval != "yearly" {
+		return errors.New("trials are only available on monthly or yearly plans")
+	}
+	return nil
 }
@@ -7,7 +7,11 @@
 package plan
 
 import (
+	"backend/internal/activity"
+	"backend/internal/feature"
 	"backend/internal/product"
+	"backend/internal/rollup"
+	"backend/internal/translation"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +20,15 @@ import (
 func NewControllerWire(db *gorm.DB) *Controller {
 	repository := NewPlanRepository(db)
 	productRepository := product.NewProductRepository(db)
-	service := NewPlanService(repository, productRepository)
-	controller := NewPlanController(service)
+	featureRepository := feature.NewFeatureRepository(db)
+	service := feature.NewFeatureService(featureRepository, productRepository)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	activityRepository := activity.NewRepository(db)
+	activityService := activity.NewService(activityRepository)
+	planService := NewPlanService(repository, productRepository, service, rollupService, activityService)
+	translationRepository := translation.NewRepository(db)
+	translationService := translation.NewService(translationRepository)
+	controller := NewPlanController(planService, translationService)
 	return controller
 }
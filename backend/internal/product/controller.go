@@ -3,22 +3,27 @@ package product
 import (
 	"backend/internal/domain"
 	"backend/models"
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
 )
 
 type Controller struct {
-	productService domain.ProductService
+	productService     domain.ProductService
+	translationService domain.TranslationService
 }
 
-func NewProductController(productService domain.ProductService) *Controller {
-	return &Controller{productService: productService}
+func NewProductController(productService domain.ProductService, translationService domain.TranslationService) *Controller {
+	return &Controller{
+		productService:     productService,
+		translationService: translationService,
+	}
 }
 
 func (h *Controller) GetProducts(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
-	products, err := h.productService.GetProductsByTenant(*tenantID)
+	products, err := h.productService.GetProductsByTenant(*tenantID, c.QueryBool("include_archived"))
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   true,
@@ -26,6 +31,14 @@ func (h *Controller) GetProducts(c *fiber.Ctx) error {
 		})
 	}
 
+	locales, _ := c.Locals("locales").([]string)
+	if err := h.translationService.LocalizeProducts(products, *tenantID, locales); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"error": false,
 		"data":  products,
@@ -34,6 +47,7 @@ func (h *Controller) GetProducts(c *fiber.Ctx) error {
 
 func (h *Controller) CreateProduct(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	var req models.CreateProductRequest
 	if err := c.BodyParser(&req); err != nil {
@@ -43,7 +57,7 @@ func (h *Controller) CreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	product, err := h.productService.CreateProduct(req, *tenantID)
+	product, err := h.productService.CreateProduct(req, userID, *tenantID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -76,6 +90,16 @@ func (h *Controller) GetProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	locales, _ := c.Locals("locales").([]string)
+	localized := []models.Product{*product}
+	if err := h.translationService.LocalizeProducts(localized, *tenantID, locales); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+	product = &localized[0]
+
 	return c.JSON(fiber.Map{
 		"error": false,
 		"data":  product,
@@ -84,6 +108,7 @@ func (h *Controller) GetProduct(c *fiber.Ctx) error {
 
 func (h *Controller) UpdateProduct(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -101,7 +126,7 @@ func (h *Controller) UpdateProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	product, err := h.productService.UpdateProduct(id, req, *tenantID)
+	product, err := h.productService.UpdateProduct(id, req, userID, *tenantID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -117,6 +142,7 @@ func (h *Controller) UpdateProduct(c *fiber.Ctx) error {
 
 func (h *Controller) DeleteProduct(c *fiber.Ctx) error {
 	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
 
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
@@ -126,9 +152,13 @@ func (h *Controller) DeleteProduct(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.productService.DeleteProduct(id, *tenantID)
+	err = h.productService.DeleteProduct(id, userID, *tenantID)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		status := fiber.StatusBadRequest
+		if errors.Is(err, domain.ErrActivePurchases) {
+			status = fiber.StatusConflict
+		}
+		return c.Status(status).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -139,3 +169,55 @@ func (h *Controller) DeleteProduct(c *fiber.Ctx) error {
 		"message": "Product deleted successfully",
 	})
 }
+
+func (h *Controller) ArchiveProduct(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid product ID",
+		})
+	}
+
+	product, err := h.productService.ArchiveProduct(id, userID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  product,
+	})
+}
+
+func (h *Controller) UnarchiveProduct(c *fiber.Ctx) error {
+	tenantID := c.Locals("tenantID").(*int)
+	userID := c.Locals("userID").(int)
+
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid product ID",
+		})
+	}
+
+	product, err := h.productService.UnarchiveProduct(id, userID, *tenantID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"error": false,
+		"data":  product,
+	})
+}
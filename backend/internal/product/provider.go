@@ -1,7 +1,10 @@
 package product
 
 import (
+	"backend/internal/activity"
 	"backend/internal/domain"
+	"backend/internal/rollup"
+	"backend/internal/translation"
 	"github.com/google/wire"
 )
 
@@ -10,8 +13,14 @@ var ProviderSet = wire.NewSet(
 	NewProductController,
 	NewProductService,
 	NewProductRepository,
+	translation.NewService,
+	translation.NewRepository,
+	rollup.ProviderSet,
+	activity.ProviderSet,
 
 	wire.Bind(new(domain.ProductControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.ProductService), new(*Service)),
 	wire.Bind(new(domain.ProductRepository), new(*Repository)),
+	wire.Bind(new(domain.TranslationService), new(*translation.Service)),
+	wire.Bind(new(domain.TranslationRepository), new(*translation.Repository)),
 )
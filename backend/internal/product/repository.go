@@ -2,6 +2,7 @@ package product
 
 import (
 	"backend/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -18,9 +19,15 @@ func (r *Repository) Create(product *models.Product) error {
 	return r.db.Create(product).Error
 }
 
-func (r *Repository) GetByTenant(tenantID int) ([]models.Product, error) {
+func (r *Repository) GetByTenant(tenantID int, includeArchived bool) ([]models.Product, error) {
 	var products []models.Product
-	err := r.db.Preload("Plans").Where("tenant_id = ?", tenantID).Find(&products).Error
+	query := r.db.Where("tenant_id = ?", tenantID)
+	if includeArchived {
+		query = query.Preload("Plans")
+	} else {
+		query = query.Preload("Plans", "archived_at IS NULL").Where("archived_at IS NULL")
+	}
+	err := query.Find(&products).Error
 	return products, err
 }
 
@@ -37,6 +44,32 @@ func (r *Repository) Update(product *models.Product) error {
 	return r.db.Save(product).Error
 }
 
+// Delete soft-deletes the product and archives its plans so they stay
+// resolvable for past purchases
 func (r *Repository) Delete(id int, tenantID int) error {
-	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.Product{}).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Plan{}).
+			Where("product_id = ? AND tenant_id = ? AND archived_at IS NULL", id, tenantID).
+			Update("archived_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.Product{}).Error
+	})
+}
+
+func (r *Repository) SetArchived(id int, tenantID int, archivedAt *time.Time) error {
+	return r.db.Model(&models.Product{}).
+		Where("id = ? AND tenant_id = ?", id, tenantID).
+		Update("archived_at", archivedAt).Error
+}
+
+// CountActivePurchases counts active purchases of any of the product's plans,
+// or scheduled to switch to one at their next renewal
+func (r *Repository) CountActivePurchases(id int, tenantID int) (int, error) {
+	var count int64
+	err := r.db.Model(&models.Purchase{}).
+		Joins("JOIN plans ON plans.id = purchases.plan_id OR plans.id = purchases.scheduled_plan_id").
+		Where("plans.product_id = ? AND purchases.tenant_id = ? AND purchases.status IN ?", id, tenantID, models.EntitledPurchaseStatuses).
+		Count(&count).Error
+	return int(count), err
 }
@@ -7,15 +7,20 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 type Service struct {
 	productRepo domain.ProductRepository
+	rollups     domain.RollupService
+	activities  domain.ActivityService
 }
 
-func NewProductService(productRepo domain.ProductRepository) *Service {
+func NewProductService(productRepo domain.ProductRepository, rollups domain.RollupService, activities domain.ActivityService) *Service {
 	return &Service{
 		productRepo: productRepo,
+		rollups:     rollups,
+		activities:  activities,
 	}
 }
 
@@ -51,7 +56,7 @@ func (s *Service) validateAndEncodeImage(imageData string) (string, error) {
 	return fmt.Sprintf("data:image/jpeg;base64,%s", imageData), nil
 }
 
-func (s *Service) CreateProduct(req models.CreateProductRequest, tenantID int) (*models.Product, error) {
+func (s *Service) CreateProduct(req models.CreateProductRequest, userID, tenantID int) (*models.Product, error) {
 	// Validate and encode image if provided
 	encodedImage, err := s.validateAndEncodeImage(req.Image)
 	if err != nil {
@@ -70,12 +75,14 @@ func (s *Service) CreateProduct(req models.CreateProductRequest, tenantID int) (
 	if err := s.productRepo.Create(product); err != nil {
 		return nil, errors.New("failed to create product")
 	}
+	s.rollups.Touch(tenantID, time.Now())
+	s.logActivity(product, models.ActivityProductCreated, fmt.Sprintf("Created product '%s'", product.Name), userID, models.ProductPayload(product))
 
 	return product, nil
 }
 
-func (s *Service) GetProductsByTenant(tenantID int) ([]models.Product, error) {
-	return s.productRepo.GetByTenant(tenantID)
+func (s *Service) GetProductsByTenant(tenantID int, includeArchived bool) ([]models.Product, error) {
+	return s.productRepo.GetByTenant(tenantID, includeArchived)
 }
 
 func (s *Service) GetProductByID(id int, tenantID int) (*models.Product, error) {
@@ -86,11 +93,12 @@ func (s *Service) GetProductByID(id int, tenantID int) (*models.Product, error)
 	return product, nil
 }
 
-func (s *Service) UpdateProduct(id int, req models.CreateProductRequest, tenantID int) (*models.Product, error) {
+func (s *Service) UpdateProduct(id int, req models.CreateProductRequest, userID, tenantID int) (*models.Product, error) {
 	product, err := s.productRepo.GetByID(id, tenantID)
 	if err != nil {
 		return nil, errors.New("product not found")
 	}
+	before := models.ProductPayload(product)
 
 	// Validate and encode image if provided
 	if req.Image != "" {
@@ -111,16 +119,84 @@ func (s *Service) UpdateProduct(id int, req models.CreateProductRequest, tenantI
 	if err := s.productRepo.Update(product); err != nil {
 		return nil, errors.New("failed to update product")
 	}
+	s.rollups.Touch(tenantID, time.Now())
+	s.logActivity(product, models.ActivityProductUpdated, fmt.Sprintf("Updated product '%s'", product.Name), userID,
+		models.JSONB{"changes": models.ActivityChanges(before, models.ProductPayload(product))})
 
 	return product, nil
 }
 
-func (s *Service) DeleteProduct(id int, tenantID int) error {
+func (s *Service) DeleteProduct(id int, userID, tenantID int) error {
 	// Check if product exists
-	_, err := s.productRepo.GetByID(id, tenantID)
+	product, err := s.productRepo.GetByID(id, tenantID)
 	if err != nil {
 		return errors.New("product not found")
 	}
 
-	return s.productRepo.Delete(id, tenantID)
+	// Refuse to delete while customers still hold active purchases
+	activePurchases, err := s.productRepo.CountActivePurchases(id, tenantID)
+	if err != nil {
+		return errors.New("failed to check active purchases")
+	}
+	if activePurchases > 0 {
+		return domain.ErrActivePurchases
+	}
+
+	if err := s.productRepo.Delete(id, tenantID); err != nil {
+		return err
+	}
+	s.rollups.Touch(tenantID, time.Now())
+	s.logActivity(product, models.ActivityProductDeleted, fmt.Sprintf("Deleted product '%s'", product.Name), userID, models.ProductPayload(product))
+	return nil
+}
+
+// ArchiveProduct hides the product from catalogue listings while keeping it
+// resolvable for existing purchases
+func (s *Service) ArchiveProduct(id int, userID, tenantID int) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	if product.ArchivedAt == nil {
+		now := time.Now()
+		if err := s.productRepo.SetArchived(id, tenantID, &now); err != nil {
+			return nil, errors.New("failed to archive product")
+		}
+		product.ArchivedAt = &now
+		s.rollups.Touch(tenantID, now)
+		s.logActivity(product, models.ActivityProductArchived, fmt.Sprintf("Archived product '%s'", product.Name), userID, nil)
+	}
+
+	return product, nil
+}
+
+func (s *Service) UnarchiveProduct(id int, userID, tenantID int) (*models.Product, error) {
+	product, err := s.productRepo.GetByID(id, tenantID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	if product.ArchivedAt != nil {
+		if err := s.productRepo.SetArchived(id, tenantID, nil); err != nil {
+			return nil, errors.New("failed to unarchive product")
+		}
+		product.ArchivedAt = nil
+		s.rollups.Touch(tenantID, time.Now())
+		s.logActivity(product, models.ActivityProductUnarchived, fmt.Sprintf("Unarchived product '%s'", product.Name), userID, nil)
+	}
+
+	return product, nil
+}
+
+func (s *Service) logActivity(product *models.Product, activityType models.ActivityType, description string, userID int, payload models.JSONB) {
+	s.activities.Record(&models.Activity{
+		UserID:      userID,
+		TenantID:    product.TenantID,
+		Type:        activityType,
+		Description: description,
+		EntityType:  models.ActivityEntityProduct,
+		EntityID:    &product.ID,
+		Payload:     payload,
+	})
 }
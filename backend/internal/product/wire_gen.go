@@ -7,6 +7,9 @@
 package product
 
 import (
+	"backend/internal/activity"
+	"backend/internal/rollup"
+	"backend/internal/translation"
 	"gorm.io/gorm"
 )
 
@@ -14,7 +17,13 @@ import (
 
 func NewControllerWire(db *gorm.DB) *Controller {
 	repository := NewProductRepository(db)
-	service := NewProductService(repository)
-	controller := NewProductController(service)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	activityRepository := activity.NewRepository(db)
+	activityService := activity.NewService(activityRepository)
+	service := NewProductService(repository, rollupService, activityService)
+	translationRepository := translation.NewRepository(db)
+	translationService := translation.NewService(translationRepository)
+	controller := NewProductController(service, translationService)
 	return controller
 }
@@ -3,6 +3,7 @@ package purchase
 import (
 	"backend/internal/domain"
 	"backend/models"
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
@@ -31,7 +32,13 @@ func (c *Controller) CreatePurchase(ctx *fiber.Ctx) error {
 
 	purchase, err := c.service.CreatePurchase(userID, *tenantID, req)
 	if err != nil {
-		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, domain.ErrPaymentDeclined) {
+			status = fiber.StatusPaymentRequired
+		} else if errors.Is(err, domain.ErrInvalidCoupon) {
+			status = fiber.StatusUnprocessableEntity
+		}
+		return ctx.Status(status).JSON(fiber.Map{
 			"error":   true,
 			"message": err.Error(),
 		})
@@ -43,6 +50,33 @@ func (c *Controller) CreatePurchase(ctx *fiber.Ctx) error {
 	})
 }
 
+// QuotePurchase previews the discount, net, tax and gross price of a plan for the current user
+func (c *Controller) QuotePurchase(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	planID, err := strconv.Atoi(ctx.Query("plan_id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid plan ID",
+		})
+	}
+
+	quote, err := c.service.QuotePurchase(userID, *tenantID, planID, ctx.Query("promo_code"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  quote,
+	})
+}
+
 // GetUserPurchases gets all purchases for the current user
 func (c *Controller) GetUserPurchases(ctx *fiber.Ctx) error {
 	userID := ctx.Locals("userID").(int)
@@ -107,3 +141,303 @@ func (c *Controller) GetActivePurchases(ctx *fiber.Ctx) error {
 		"data":  purchases,
 	})
 }
+
+// ConfirmPurchase completes the payment of a purchase awaiting customer action
+func (c *Controller) ConfirmPurchase(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	purchase, err := c.service.ConfirmPurchase(id, userID, *tenantID)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  purchase,
+	})
+}
+
+// CancelPurchase cancels a purchase immediately
+func (c *Controller) CancelPurchase(ctx *fiber.Ctx) error {
+	return c.cancel(ctx, false)
+}
+
+// CancelPurchaseAtPeriodEnd schedules a purchase to be cancelled when its current period ends
+func (c *Controller) CancelPurchaseAtPeriodEnd(ctx *fiber.Ctx) error {
+	return c.cancel(ctx, true)
+}
+
+func (c *Controller) cancel(ctx *fiber.Ctx, atPeriodEnd bool) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	purchase, err := c.service.CancelPurchase(id, userID, *tenantID, atPeriodEnd)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  purchase,
+	})
+}
+
+// ResumePurchase withdraws a scheduled cancellation
+func (c *Controller) ResumePurchase(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	purchase, err := c.service.ResumePurchase(id, userID, *tenantID)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  purchase,
+	})
+}
+
+// GetPurchaseTransitions lists the status history of a purchase
+func (c *Controller) GetPurchaseTransitions(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	transitions, err := c.service.GetPurchaseTransitions(id, userID, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  transitions,
+	})
+}
+
+// PreviewPlanChange shows the prorated amounts of moving a purchase to another plan
+func (c *Controller) PreviewPlanChange(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	var req models.ChangePlanRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	preview, err := c.service.PreviewPlanChange(id, userID, *tenantID, req)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  preview,
+	})
+}
+
+// ChangePlan upgrades a purchase immediately or schedules a downgrade at period end
+func (c *Controller) ChangePlan(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	var req models.ChangePlanRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	purchase, err := c.service.ChangePlan(id, userID, *tenantID, req)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  purchase,
+	})
+}
+
+// UpdatePaymentMethod sets the payment method later periods are charged to,
+// e.g. to keep a trial that started without one
+func (c *Controller) UpdatePaymentMethod(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	var req models.UpdatePaymentMethodRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	purchase, err := c.service.UpdatePaymentMethod(id, userID, *tenantID, req)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  purchase,
+	})
+}
+
+// RefundPurchase refunds all or part of a payment of any user's purchase (tenant admins)
+func (c *Controller) RefundPurchase(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(int)
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	var req models.RefundRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	refund, err := c.service.RefundPurchase(id, userID, *tenantID, req)
+	if err != nil {
+		return ctx.Status(lifecycleErrorStatus(err)).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"error": false,
+		"data":  refund,
+	})
+}
+
+// GetRefunds lists the refunds of a purchase with their credit notes (tenant admins)
+func (c *Controller) GetRefunds(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid purchase ID",
+		})
+	}
+
+	refunds, err := c.service.GetRefunds(id, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  refunds,
+	})
+}
+
+// lifecycleErrorStatus maps purchase lifecycle errors to HTTP statuses
+func lifecycleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidTransition), errors.Is(err, domain.ErrConcurrentUpdate):
+		return fiber.StatusConflict
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return fiber.StatusPaymentRequired
+	case errors.Is(err, domain.ErrRefundExceedsBalance):
+		return fiber.StatusUnprocessableEntity
+	case err.Error() == "purchase not found", err.Error() == "payment not found":
+		return fiber.StatusNotFound
+	default:
+		return fiber.StatusBadRequest
+	}
+}
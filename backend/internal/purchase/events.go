@@ -0,0 +1,117 @@
+package purchase
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ApplyPaymentEvent maps a verified payment provider event onto the payment
+// and purchase it concerns. Events that were already applied, or that no
+// longer fit the purchase's state, are ignored. Errors are worth retrying.
+func (s *Service) ApplyPaymentEvent(provider string, event coreDomain.PaymentWebhookEvent) error {
+	if event.IntentID == "" {
+		return nil
+	}
+
+	// The event may arrive before the purchase that created the intent is stored
+	payment, err := s.repo.GetPaymentByIntent(provider, event.IntentID)
+	if err != nil {
+		return fmt.Errorf("no payment found for intent %s", event.IntentID)
+	}
+	purchase, err := s.repo.GetPurchase(payment.PurchaseID, payment.TenantID)
+	if err != nil {
+		return fmt.Errorf("purchase %d not found", payment.PurchaseID)
+	}
+
+	switch event.Type {
+	case coreDomain.PaymentEventSucceeded:
+		if payment.Status == coreDomain.PaymentStatusSucceeded {
+			return nil
+		}
+		payment.Status = coreDomain.PaymentStatusSucceeded
+		payment.DeclineReason = ""
+		if err := s.repo.UpdatePayment(payment); err != nil {
+			return err
+		}
+		s.rollups.Touch(payment.TenantID, payment.CreatedAt)
+
+		switch {
+		case purchase.Status == models.PurchaseStatusIncomplete:
+			err = s.activate(purchase, "payment_confirmed", nil)
+		case purchase.Status == models.PurchaseStatusPastDue && payment.Kind == models.PaymentKindRenewal:
+			if err = s.priceNextPeriod(purchase); err == nil {
+				err = s.startNextPeriod(purchase, time.Now(), "payment_recovered")
+			}
+		}
+		if err == nil {
+			s.issueInvoice(payment)
+		}
+
+	case coreDomain.PaymentEventFailed:
+		if payment.Status == coreDomain.PaymentStatusDeclined {
+			return nil
+		}
+		payment.Status = coreDomain.PaymentStatusDeclined
+		payment.DeclineReason = event.Reason
+		if err := s.repo.UpdatePayment(payment); err != nil {
+			return err
+		}
+		s.rollups.Touch(payment.TenantID, payment.CreatedAt)
+
+		switch {
+		case purchase.Status == models.PurchaseStatusIncomplete:
+			err = s.transition(purchase, models.PurchaseStatusExpired, "payment_failed", nil)
+		case payment.Kind == models.PaymentKindRenewal &&
+			(purchase.Status == models.PurchaseStatusActive || purchase.Status == models.PurchaseStatusTrialing):
+			err = s.transition(purchase, models.PurchaseStatusPastDue, "payment_failed", nil)
+		}
+
+	case coreDomain.PaymentEventRefunded:
+		amount := money.Round(event.Amount)
+		if amount <= 0 {
+			amount = money.Round(payment.Amount - payment.RefundedAmount)
+		}
+		if amount <= 0 {
+			return nil
+		}
+		_, err = s.applyRefund(purchase, payment, event.RefundID, amount, event.Reason, nil)
+
+	case coreDomain.PaymentEventDisputed:
+		if payment.Status == models.PaymentStatusDisputed {
+			return nil
+		}
+		payment.Status = models.PaymentStatusDisputed
+		if err := s.repo.UpdatePayment(payment); err != nil {
+			return err
+		}
+		s.rollups.Touch(payment.TenantID, payment.CreatedAt)
+
+		if isEntitled(purchase.Status) {
+			err = s.transition(purchase, models.PurchaseStatusCancelled, "payment_disputed", nil)
+		}
+
+	default:
+		log.Printf("Ignoring unsupported payment event type '%s'", event.Type)
+		return nil
+	}
+
+	if errors.Is(err, domain.ErrInvalidTransition) || errors.Is(err, domain.ErrRefundExceedsBalance) {
+		return nil
+	}
+	return err
+}
+
+func isEntitled(status string) bool {
+	for _, entitled := range models.EntitledPurchaseStatuses {
+		if status == entitled {
+			return true
+		}
+	}
+	return false
+}
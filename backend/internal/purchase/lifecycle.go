@@ -0,0 +1,70 @@
+package purchase
+
+import (
+	"backend/models"
+	"time"
+)
+
+// allowedTransitions is the purchase status state machine. Cancelled and
+// expired are terminal.
+var allowedTransitions = map[string][]string{
+	models.PurchaseStatusIncomplete: {
+		models.PurchaseStatusActive,
+		models.PurchaseStatusCancelled,
+		models.PurchaseStatusExpired,
+	},
+	models.PurchaseStatusTrialing: {
+		models.PurchaseStatusActive,
+		models.PurchaseStatusPastDue,
+		models.PurchaseStatusCancelled,
+		models.PurchaseStatusExpired,
+	},
+	models.PurchaseStatusActive: {
+		models.PurchaseStatusPastDue,
+		models.PurchaseStatusCancelled,
+		models.PurchaseStatusExpired,
+	},
+	models.PurchaseStatusPastDue: {
+		models.PurchaseStatusActive,
+		models.PurchaseStatusCancelled,
+		models.PurchaseStatusExpired,
+	},
+}
+
+// canTransition reports whether a purchase may move between statuses. Staying
+// in the same non-terminal status (a renewal or a flag change) is allowed.
+func canTransition(from, to string) bool {
+	targets, ok := allowedTransitions[from]
+	if !ok {
+		return false
+	}
+	if from == to {
+		return true
+	}
+	for _, target := range targets {
+		if target == to {
+			return true
+		}
+	}
+	return false
+}
+
+// isRecurring reports whether purchases of the interval renew automatically
+func isRecurring(interval string) bool {
+	return interval == "monthly" || interval == "yearly"
+}
+
+// periodEnd returns the end of a billing period starting at start, or nil for
+// non-recurring intervals
+func periodEnd(start time.Time, interval string) *time.Time {
+	var end time.Time
+	switch interval {
+	case "monthly":
+		end = start.AddDate(0, 1, 0)
+	case "yearly":
+		end = start.AddDate(1, 0, 0)
+	default:
+		return nil
+	}
+	return &end
+}
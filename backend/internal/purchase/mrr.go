@@ -0,0 +1,37 @@
+package purchase
+
+import (
+	"backend/models"
+	"log"
+	"time"
+)
+
+// syncMRR records a change of the purchase's monthly recurring revenue in the
+// MRR ledger, after the purchase itself was saved.
+func (s *Service) syncMRR(purchase *models.Purchase) {
+	priced := *purchase
+	if isPaying(purchase.Status) && (purchase.Plan == nil || purchase.Plan.ID != purchase.PlanID) {
+		plan, err := s.repo.GetPlanByID(purchase.PlanID, purchase.TenantID)
+		if err != nil {
+			log.Printf("Failed to load plan of purchase %d for MRR: %v", purchase.ID, err)
+			return
+		}
+		priced.Plan = plan
+	}
+
+	mrr := priced.MonthlyRecurringRevenue()
+	movement := models.NewMRRMovement(purchase, mrr, time.Now())
+	if movement == nil {
+		return
+	}
+	if err := s.repo.RecordMRRMovement(purchase, movement); err != nil {
+		log.Printf("Failed to record MRR movement of purchase %d: %v", purchase.ID, err)
+		return
+	}
+	purchase.MRR = mrr
+}
+
+// isPaying reports whether purchases in the status count towards MRR
+func isPaying(status string) bool {
+	return status == models.PurchaseStatusActive || status == models.PurchaseStatusPastDue
+}
@@ -0,0 +1,297 @@
+package purchase
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// planChange is a change of a purchase to another plan, priced at a moment
+type planChange struct {
+	preview      models.PlanChangePreview
+	plan         *models.Plan
+	quote        *models.TaxQuote // the new plan's price per period
+	resetsPeriod bool             // an upgrade to another interval starts a new period
+}
+
+// PreviewPlanChange shows the credit, charge and tax of moving the purchase
+// to another plan, without changing anything
+func (s *Service) PreviewPlanChange(id, userID, tenantID int, req models.ChangePlanRequest) (*models.PlanChangePreview, error) {
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+
+	change, err := s.planChange(purchase, req.PlanID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &change.preview, nil
+}
+
+// ChangePlan moves the purchase to another plan of the same product.
+// Upgrades charge the prorated difference and apply immediately; downgrades
+// are scheduled for the end of the current period. Choosing the current plan
+// again withdraws a scheduled downgrade.
+func (s *Service) ChangePlan(id, userID, tenantID int, req models.ChangePlanRequest) (*models.Purchase, error) {
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+
+	if req.PlanID == purchase.PlanID && purchase.ScheduledPlanID != nil {
+		purchase.ScheduledPlanID = nil
+		if err := s.repriceTransition(purchase, purchase.Status, "plan_change_withdrawn", &userID); err != nil {
+			return nil, err
+		}
+		return purchase, nil
+	}
+
+	now := time.Now()
+	change, err := s.planChange(purchase, req.PlanID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if change.preview.Change == models.PlanChangeDowngrade {
+		purchase.ScheduledPlanID = &change.plan.ID
+		if err := s.repriceTransition(purchase, purchase.Status, "plan_change_scheduled", &userID); err != nil {
+			return nil, err
+		}
+		s.logPlanChange(purchase, change)
+		return purchase, nil
+	}
+
+	if req.PaymentMethod != "" {
+		purchase.PaymentMethod = req.PaymentMethod
+	}
+
+	var payment *models.Payment
+	var intent *coreDomain.PaymentIntent
+	if change.preview.TotalDue > 0 {
+		payment, intent, err = s.chargeProration(purchase, change)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	previous := *purchase
+	repriceAt(purchase, change.plan, change.quote)
+	if change.resetsPeriod {
+		purchase.CurrentPeriodStart = &now
+		purchase.ExpiresAt = periodEnd(now, change.plan.Interval)
+	}
+	// The new plan is saved with the transition, so an upgrade that fails
+	// either way leaves the purchase as it was
+	if err := s.repriceTransition(purchase, purchase.Status, "plan_upgraded", &userID); err != nil {
+		if intent != nil {
+			// The upgrade didn't happen, so its charge is given back
+			*purchase = previous
+			return nil, s.refundFailedCharge(intent, purchase, payment, err)
+		}
+		return nil, err
+	}
+
+	s.logPlanChange(purchase, change)
+	s.issueInvoice(payment)
+
+	return purchase, nil
+}
+
+// planChange prices moving the purchase to planID at now. The credit is the
+// unused share of the current period's net price. A more expensive plan on
+// the same interval is charged for the rest of the period; on another
+// interval it starts a new period charged in full. Cheaper plans, and
+// interval changes whose credit would exceed the charge, wait for the
+// period to end.
+func (s *Service) planChange(purchase *models.Purchase, planID int, now time.Time) (*planChange, error) {
+	if purchase.Status != models.PurchaseStatusActive {
+		return nil, fmt.Errorf("%w: only active purchases can change plan", domain.ErrInvalidTransition)
+	}
+	if purchase.Plan == nil || !isRecurring(purchase.Plan.Interval) || purchase.CurrentPeriodStart == nil || purchase.ExpiresAt == nil {
+		return nil, errors.New("only recurring purchases can change plan")
+	}
+	if purchase.CancelAtPeriodEnd {
+		return nil, errors.New("purchase is scheduled for cancellation; resume it first")
+	}
+	if planID == purchase.PlanID {
+		return nil, errors.New("purchase is already on this plan")
+	}
+
+	plan, err := s.getAvailablePlan(planID, purchase.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.ProductID != purchase.Plan.ProductID {
+		return nil, errors.New("plan belongs to another product")
+	}
+	if !isRecurring(plan.Interval) {
+		return nil, errors.New("purchases can only change to recurring plans")
+	}
+	if plan.Currency != purchase.Currency {
+		return nil, fmt.Errorf("plan is priced in %s but the purchase in %s", plan.Currency, purchase.Currency)
+	}
+
+	quote, err := s.quoteTax(plan.Price, purchase.UserID, purchase.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	start, end := *purchase.CurrentPeriodStart, *purchase.ExpiresAt
+	remaining := 0.0
+	if length := end.Sub(start); length > 0 && end.After(now) {
+		remaining = float64(end.Sub(now)) / float64(length)
+	}
+
+	currentNet := purchase.Amount
+	if purchase.NetAmount != nil {
+		currentNet = *purchase.NetAmount
+	}
+	credit := money.Round(currentNet * remaining)
+
+	sameInterval := plan.Interval == purchase.Plan.Interval
+	charge := quote.NetAmount
+	if sameInterval {
+		charge = money.Round(quote.NetAmount * remaining)
+	}
+
+	change := &planChange{
+		plan:  plan,
+		quote: quote,
+		preview: models.PlanChangePreview{
+			PurchaseID:       purchase.ID,
+			CurrentPlanID:    purchase.PlanID,
+			NewPlanID:        plan.ID,
+			NextPeriodAmount: quote.GrossAmount,
+			Currency:         plan.Currency,
+		},
+	}
+	preview := &change.preview
+
+	if monthlyPrice(plan) > monthlyPrice(purchase.Plan) && charge >= credit {
+		preview.Change = models.PlanChangeUpgrade
+		preview.EffectiveAt = now
+		preview.PeriodEnd = end
+		if !sameInterval {
+			change.resetsPeriod = true
+			preview.PeriodEnd = *periodEnd(now, plan.Interval)
+		}
+		preview.CreditAmount = credit
+		preview.ChargeAmount = charge
+		preview.NetAmount = money.Round(charge - credit)
+		preview.TaxAmount = money.Round(preview.NetAmount * quote.Rate / 100)
+		preview.TotalDue = money.Round(preview.NetAmount + preview.TaxAmount)
+	} else {
+		preview.Change = models.PlanChangeDowngrade
+		preview.EffectiveAt = end
+		preview.PeriodEnd = *periodEnd(end, plan.Interval)
+	}
+
+	return change, nil
+}
+
+// chargeProration collects the amount due for an upgrade off-session with
+// the purchase's payment method. It returns the recorded payment, nil if
+// recording it failed, and the succeeded intent.
+func (s *Service) chargeProration(purchase *models.Purchase, change *planChange) (*models.Payment, *coreDomain.PaymentIntent, error) {
+	if purchase.PaymentProvider != s.gateway.Name() || purchase.PaymentMethod == "" {
+		return nil, nil, errors.New("purchase has no reusable payment method")
+	}
+
+	intent, err := s.gateway.CreateIntent(coreDomain.PaymentIntentRequest{
+		Amount:        change.preview.TotalDue,
+		Currency:      purchase.Currency,
+		PaymentMethod: purchase.PaymentMethod,
+		Description:   fmt.Sprintf("Change of purchase #%d to %s", purchase.ID, change.plan.Name),
+		Metadata: map[string]string{
+			"tenant_id":   fmt.Sprint(purchase.TenantID),
+			"purchase_id": fmt.Sprint(purchase.ID),
+			"plan_id":     fmt.Sprint(change.plan.ID),
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+	intent, err = s.gateway.ConfirmIntent(intent.ID, purchase.PaymentMethod)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+
+	net := change.preview.NetAmount
+	payment := s.recordPayment(purchase, intent, models.PaymentKindProration, &net, change.preview.TaxAmount)
+
+	switch intent.Status {
+	case coreDomain.PaymentStatusSucceeded:
+		return payment, intent, nil
+	case coreDomain.PaymentStatusDeclined:
+		return nil, nil, fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, intent.DeclineReason)
+	default:
+		// Plan changes are charged off-session and can't wait for the customer
+		return nil, nil, fmt.Errorf("%w: payment requires authentication", domain.ErrPaymentDeclined)
+	}
+}
+
+// applyScheduledPlan switches the purchase to its scheduled downgrade as the
+// next period starts
+func (s *Service) applyScheduledPlan(purchase *models.Purchase) error {
+	if purchase.ScheduledPlanID == nil {
+		return nil
+	}
+
+	plan, err := s.repo.GetPlanByID(*purchase.ScheduledPlanID, purchase.TenantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// The plan was deleted before plans scheduled to were protected;
+		// drop the change rather than failing every renewal
+		log.Printf("Scheduled plan %d of purchase %d no longer exists; keeping plan %d", *purchase.ScheduledPlanID, purchase.ID, purchase.PlanID)
+		purchase.ScheduledPlanID = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("scheduled plan not found: %w", err)
+	}
+	quote, err := s.quoteTax(plan.Price, purchase.UserID, purchase.TenantID)
+	if err != nil {
+		return err
+	}
+
+	repriceAt(purchase, plan, quote)
+	return nil
+}
+
+func (s *Service) logPlanChange(purchase *models.Purchase, change *planChange) {
+	description := fmt.Sprintf("Upgraded purchase #%d to plan '%s'", purchase.ID, change.plan.Name)
+	if change.preview.Change == models.PlanChangeDowngrade {
+		description = fmt.Sprintf("Scheduled change of purchase #%d to plan '%s' at period end", purchase.ID, change.plan.Name)
+	}
+	s.repo.CreateActivity(&models.Activity{
+		UserID:      purchase.UserID,
+		TenantID:    purchase.TenantID,
+		Type:        models.ActivityPlanChanged,
+		Description: description,
+		EntityType:  models.ActivityEntityPurchase,
+		EntityID:    &purchase.ID,
+		Payload: models.JSONB{
+			"change":       change.preview.Change,
+			"from_plan_id": change.preview.CurrentPlanID,
+			"to_plan_id":   change.preview.NewPlanID,
+			"effective_at": change.preview.EffectiveAt,
+			"total_due":    change.preview.TotalDue,
+			"currency":     change.preview.Currency,
+		},
+	})
+}
+
+// monthlyPrice normalizes a plan's list price to one month for comparing plans
+func monthlyPrice(plan *models.Plan) float64 {
+	if plan.Interval == "yearly" {
+		return plan.Price / 12
+	}
+	return plan.Price
+}
@@ -0,0 +1,353 @@
+package purchase
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/core/payment"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryPurchaseRepository holds one purchase with its plans, payments and
+// refunds
+type memoryPurchaseRepository struct {
+	domain.PurchaseRepository
+	purchase    models.Purchase
+	plans       map[int]*models.Plan
+	payments    []models.Payment
+	refunds     []models.Refund
+	transitions []models.PurchaseTransition
+	// conflict makes writes find the purchase changed by another process
+	conflict bool
+}
+
+func (r *memoryPurchaseRepository) GetPurchaseByID(id, userID, tenantID int) (*models.Purchase, error) {
+	return r.GetPurchase(id, tenantID)
+}
+
+func (r *memoryPurchaseRepository) GetPurchase(id, tenantID int) (*models.Purchase, error) {
+	if r.purchase.ID != id || r.purchase.TenantID != tenantID {
+		return nil, errors.New("purchase not found")
+	}
+	purchase := r.purchase
+	return &purchase, nil
+}
+
+func (r *memoryPurchaseRepository) GetPlanByID(planID, tenantID int) (*models.Plan, error) {
+	plan, ok := r.plans[planID]
+	if !ok {
+		return nil, errors.New("plan not found")
+	}
+	return plan, nil
+}
+
+func (r *memoryPurchaseRepository) TransitionPurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	if r.conflict || r.purchase.Status != fromStatus {
+		return false, nil
+	}
+	r.purchase.Status = purchase.Status
+	r.purchase.ExpiresAt = purchase.ExpiresAt
+	r.purchase.CurrentPeriodStart = purchase.CurrentPeriodStart
+	r.purchase.CancelAtPeriodEnd = purchase.CancelAtPeriodEnd
+	r.purchase.CancelledAt = purchase.CancelledAt
+	r.transitions = append(r.transitions, *transition)
+	return true, nil
+}
+
+func (r *memoryPurchaseRepository) RepricePurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	if r.conflict || r.purchase.Status != fromStatus {
+		return false, nil
+	}
+	r.purchase = *purchase
+	r.transitions = append(r.transitions, *transition)
+	return true, nil
+}
+
+func (r *memoryPurchaseRepository) UpdatePricing(purchase *models.Purchase) error {
+	status := r.purchase.Status
+	r.purchase = *purchase
+	r.purchase.Status = status
+	return nil
+}
+
+func (r *memoryPurchaseRepository) RecordMRRMovement(purchase *models.Purchase, movement *models.MRRMovement) error {
+	return nil
+}
+
+func (r *memoryPurchaseRepository) CreateActivity(activity *models.Activity) error {
+	return nil
+}
+
+func (r *memoryPurchaseRepository) CreatePayment(payment *models.Payment) error {
+	payment.ID = len(r.payments) + 1
+	r.payments = append(r.payments, *payment)
+	return nil
+}
+
+func (r *memoryPurchaseRepository) GetPayment(id, tenantID int) (*models.Payment, error) {
+	for _, payment := range r.payments {
+		if payment.ID == id {
+			return &payment, nil
+		}
+	}
+	return nil, errors.New("payment not found")
+}
+
+func (r *memoryPurchaseRepository) GetPeriodPayment(purchaseID, tenantID int) (*models.Payment, error) {
+	for i := len(r.payments) - 1; i >= 0; i-- {
+		payment := r.payments[i]
+		if payment.Kind != models.PaymentKindProration && payment.Status != coreDomain.PaymentStatusDeclined {
+			return &payment, nil
+		}
+	}
+	return nil, errors.New("payment not found")
+}
+
+func (r *memoryPurchaseRepository) RecordRefund(refund *models.Refund) (bool, error) {
+	for i := range r.payments {
+		payment := &r.payments[i]
+		if payment.ID != refund.PaymentID {
+			continue
+		}
+		payment.RefundedAmount += refund.Amount
+		if payment.RefundedAmount >= payment.Amount-0.005 {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		refunded := *payment
+		refund.Payment = &refunded
+	}
+	refund.ID = len(r.refunds) + 1
+	r.refunds = append(r.refunds, *refund)
+	return true, nil
+}
+
+func (r *memoryPurchaseRepository) SetRefundCreditNote(refund *models.Refund) error {
+	return nil
+}
+
+// flatTax charges 10% on top of every amount
+type flatTax struct{}
+
+func (flatTax) Calculate(req models.TaxRequest) (*models.TaxQuote, error) {
+	tax := req.Amount / 10
+	return &models.TaxQuote{NetAmount: req.Amount, TaxAmount: tax, GrossAmount: req.Amount + tax, Rate: 10, Name: "VAT", Country: "DE"}, nil
+}
+
+type stubBilling struct {
+	domain.BillingService
+}
+
+func (stubBilling) GetTenantSettings(tenantID int) (*models.TenantSettings, error) {
+	return &models.TenantSettings{TenantID: tenantID, Country: "DE"}, nil
+}
+
+func (stubBilling) GetBillingProfile(userID, tenantID int) (*models.BillingProfile, error) {
+	return &models.BillingProfile{UserID: userID, TenantID: tenantID, Country: "DE"}, nil
+}
+
+type stubInvoices struct {
+	domain.InvoiceService
+}
+
+func (stubInvoices) IssueForPayment(payment *models.Payment) (*models.Invoice, error) {
+	return &models.Invoice{ID: 1}, nil
+}
+
+func (stubInvoices) IssueCreditNote(refund *models.Refund) (*models.Invoice, error) {
+	return &models.Invoice{ID: 2}, nil
+}
+
+type stubRollups struct {
+	domain.RollupService
+}
+
+func (stubRollups) Touch(tenantID int, at time.Time) {}
+
+// recordingGateway is the fake gateway, remembering the refunds it made
+type recordingGateway struct {
+	*payment.FakeGateway
+	refunds []coreDomain.PaymentRefund
+}
+
+func (g *recordingGateway) Refund(intentID string, amount float64) (*coreDomain.PaymentRefund, error) {
+	refund, err := g.FakeGateway.Refund(intentID, amount)
+	if err == nil {
+		g.refunds = append(g.refunds, *refund)
+	}
+	return refund, err
+}
+
+// testPlans are the plans of one product
+var testPlans = map[int]*models.Plan{
+	1: {ID: 1, ProductID: 1, Name: "Basic", Price: 10, Currency: "USD", Interval: "monthly"},
+	2: {ID: 2, ProductID: 1, Name: "Pro", Price: 30, Currency: "USD", Interval: "monthly"},
+	3: {ID: 3, ProductID: 1, Name: "Pro Yearly", Price: 300, Currency: "USD", Interval: "yearly"},
+	4: {ID: 4, ProductID: 1, Name: "Free", Price: 0, Currency: "USD", Interval: "monthly"},
+	5: {ID: 5, ProductID: 1, Name: "Team", Price: 10, Currency: "USD", Interval: "monthly"},
+}
+
+// newTestPurchase is an active purchase of the plan in its period from start
+// to end, taxed at 10%
+func newTestPurchase(planID int, start, end time.Time) models.Purchase {
+	plan := testPlans[planID]
+	net := plan.Price
+	return models.Purchase{
+		ID: 1, UserID: 2, PlanID: plan.ID, Plan: plan, TransactionID: "pi_initial", PaymentProvider: payment.FakeProviderName, PaymentMethod: "pm_card",
+		Amount: net * 1.1, NetAmount: &net, TaxAmount: net / 10, TaxRate: 10, ListPrice: plan.Price, Currency: "USD",
+		Status: models.PurchaseStatusActive, CurrentPeriodStart: &start, ExpiresAt: &end, TenantID: 4,
+	}
+}
+
+func newTestService(repo *memoryPurchaseRepository, gateway coreDomain.PaymentGateway) *Service {
+	return NewService(repo, &core.Config{}, gateway, stubInvoices{}, stubBilling{}, flatTax{}, nil, nil, stubRollups{})
+}
+
+// An upgrade whose transition can't be saved leaves the purchase on its plan
+// and gives the proration charge back
+func TestChangePlanRefundsUnsavedUpgrade(t *testing.T) {
+	now := time.Now()
+	repo := &memoryPurchaseRepository{purchase: newTestPurchase(1, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20)), plans: testPlans, conflict: true}
+	gateway := &recordingGateway{FakeGateway: payment.NewFakeGateway(&core.Config{})}
+	service := newTestService(repo, gateway)
+
+	_, err := service.ChangePlan(1, 2, 4, models.ChangePlanRequest{PlanID: 2})
+	if !errors.Is(err, domain.ErrConcurrentUpdate) {
+		t.Fatalf("ChangePlan error = %v, want ErrConcurrentUpdate", err)
+	}
+	if repo.purchase.PlanID != 1 || repo.purchase.Amount != 11 {
+		t.Errorf("purchase moved to plan %d at %v", repo.purchase.PlanID, repo.purchase.Amount)
+	}
+	if len(repo.payments) != 1 || len(gateway.refunds) != 1 || gateway.refunds[0].Amount != repo.payments[0].Amount {
+		t.Fatalf("charged %+v and refunded %+v, want the charge refunded in full", repo.payments, gateway.refunds)
+	}
+	if len(repo.refunds) != 1 || repo.refunds[0].PaymentID != repo.payments[0].ID || repo.refunds[0].Reason != "charge_not_applied" {
+		t.Errorf("recorded refunds %+v, want one of the proration payment", repo.refunds)
+	}
+}
+
+// The credit is the unused share of the current period and the charge the
+// new plan for the rest of it, or for a new period on another interval.
+// Cheaper, equally priced and free plans wait for the period to end.
+func TestPlanChangeProration(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	twoThirds := start.AddDate(0, 0, 20)
+	tests := []struct {
+		name     string
+		from, to int
+		now      time.Time
+		want     models.PlanChangePreview
+		resets   bool
+	}{
+		{"upgrade", 1, 2, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeUpgrade, EffectiveAt: twoThirds, PeriodEnd: end,
+			CreditAmount: 3.33, ChargeAmount: 10, NetAmount: 6.67, TaxAmount: 0.67, TotalDue: 7.34, NextPeriodAmount: 33,
+		}, false},
+		{"upgrade at period start", 1, 2, start, models.PlanChangePreview{
+			Change: models.PlanChangeUpgrade, EffectiveAt: start, PeriodEnd: end,
+			CreditAmount: 10, ChargeAmount: 30, NetAmount: 20, TaxAmount: 2, TotalDue: 22, NextPeriodAmount: 33,
+		}, false},
+		{"upgrade to another interval resets the period", 1, 3, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeUpgrade, EffectiveAt: twoThirds, PeriodEnd: twoThirds.AddDate(1, 0, 0),
+			CreditAmount: 3.33, ChargeAmount: 300, NetAmount: 296.67, TaxAmount: 29.67, TotalDue: 326.34, NextPeriodAmount: 330,
+		}, true},
+		{"upgrade from a free plan", 4, 1, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeUpgrade, EffectiveAt: twoThirds, PeriodEnd: end,
+			ChargeAmount: 3.33, NetAmount: 3.33, TaxAmount: 0.33, TotalDue: 3.66, NextPeriodAmount: 11,
+		}, false},
+		{"upgrade at period end is free", 1, 2, end, models.PlanChangePreview{
+			Change: models.PlanChangeUpgrade, EffectiveAt: end, PeriodEnd: end, NextPeriodAmount: 33,
+		}, false},
+		{"downgrade", 2, 1, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeDowngrade, EffectiveAt: end, PeriodEnd: end.AddDate(0, 1, 0), NextPeriodAmount: 11,
+		}, false},
+		{"downgrade to a free plan", 1, 4, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeDowngrade, EffectiveAt: end, PeriodEnd: end.AddDate(0, 1, 0),
+		}, false},
+		{"same price", 1, 5, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeDowngrade, EffectiveAt: end, PeriodEnd: end.AddDate(0, 1, 0), NextPeriodAmount: 11,
+		}, false},
+		{"credit above the charge on another interval", 3, 2, twoThirds, models.PlanChangePreview{
+			Change: models.PlanChangeDowngrade, EffectiveAt: end, PeriodEnd: end.AddDate(0, 1, 0), NextPeriodAmount: 33,
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchase := newTestPurchase(tt.from, start, end)
+			service := newTestService(&memoryPurchaseRepository{purchase: purchase, plans: testPlans}, payment.NewFakeGateway(&core.Config{}))
+
+			change, err := service.planChange(&purchase, tt.to, tt.now)
+			if err != nil {
+				t.Fatalf("planChange: %v", err)
+			}
+
+			want := tt.want
+			want.PurchaseID, want.CurrentPlanID, want.NewPlanID, want.Currency = 1, tt.from, tt.to, "USD"
+			if change.preview != want {
+				t.Errorf("preview = %+v\nwant      %+v", change.preview, want)
+			}
+			if change.resetsPeriod != tt.resets {
+				t.Errorf("resets period = %v, want %v", change.resetsPeriod, tt.resets)
+			}
+		})
+	}
+}
+
+// An upgrade is charged what its preview said is due and applies at once;
+// a downgrade is charged nothing and applies when the purchase renews
+func TestChangePlanAppliesChange(t *testing.T) {
+	tests := []struct {
+		name        string
+		from, to    int
+		elapsed     float64 // share of the period gone
+		wantPlan    int     // plan right after the change
+		wantCharged []float64
+		wantRenewal float64 // gross price charged at renewal
+	}{
+		{"upgrade", 1, 2, 0.5, 2, []float64{11}, 33},
+		{"upgrade at period end", 1, 2, 1, 2, nil, 33},
+		{"downgrade", 2, 1, 0.5, 2, nil, 11},
+		{"same price", 1, 5, 0.5, 1, nil, 11},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			length := 30 * 24 * time.Hour
+			start := now.Add(-time.Duration(tt.elapsed * float64(length)))
+			repo := &memoryPurchaseRepository{purchase: newTestPurchase(tt.from, start, start.Add(length)), plans: testPlans}
+			service := newTestService(repo, payment.NewFakeGateway(&core.Config{}))
+
+			purchase, err := service.ChangePlan(1, 2, 4, models.ChangePlanRequest{PlanID: tt.to})
+			if err != nil {
+				t.Fatalf("ChangePlan: %v", err)
+			}
+			if purchase.PlanID != tt.wantPlan || repo.purchase.PlanID != tt.wantPlan {
+				t.Errorf("purchase on plan %d, saved on %d, want %d", purchase.PlanID, repo.purchase.PlanID, tt.wantPlan)
+			}
+			scheduled := tt.wantPlan != tt.to
+			if (repo.purchase.ScheduledPlanID != nil && *repo.purchase.ScheduledPlanID == tt.to) != scheduled {
+				t.Errorf("scheduled plan = %v, want scheduled %v", repo.purchase.ScheduledPlanID, scheduled)
+			}
+			if len(repo.payments) != len(tt.wantCharged) {
+				t.Fatalf("recorded %d payments, want %d", len(repo.payments), len(tt.wantCharged))
+			}
+			for i, charged := range tt.wantCharged {
+				if repo.payments[i].Amount != charged || repo.payments[i].Kind != models.PaymentKindProration {
+					t.Errorf("charged %s payment of %v, want proration of %v", repo.payments[i].Kind, repo.payments[i].Amount, charged)
+				}
+			}
+
+			due := repo.purchase
+			if err := service.renew(&due, due.ExpiresAt.Add(time.Hour)); err != nil {
+				t.Fatalf("renew: %v", err)
+			}
+			renewal := repo.payments[len(repo.payments)-1]
+			if repo.purchase.PlanID != tt.to || repo.purchase.ScheduledPlanID != nil || renewal.Amount != tt.wantRenewal {
+				t.Errorf("renewed on plan %d (scheduled %v) for %v, want plan %d for %v", repo.purchase.PlanID, repo.purchase.ScheduledPlanID, renewal.Amount, tt.to, tt.wantRenewal)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package purchase
+
+import "backend/models"
+
+// applyTax copies a tax breakdown onto the purchase's per-period amounts
+func applyTax(purchase *models.Purchase, quote *models.TaxQuote) {
+	net := quote.NetAmount
+	purchase.Amount = quote.GrossAmount
+	purchase.NetAmount = &net
+	purchase.TaxAmount = quote.TaxAmount
+	purchase.TaxRate = quote.Rate
+	purchase.TaxName = quote.Name
+	purchase.TaxCountry = quote.Country
+	purchase.ReverseCharge = quote.ReverseCharge
+}
+
+// discountedRenewals returns how many renewals after the first period the
+// coupon still discounts, or nil when it discounts every period
+func discountedRenewals(coupon *models.Coupon) *int {
+	left := 0
+	switch coupon.Duration {
+	case models.CouponDurationForever:
+		return nil
+	case models.CouponDurationRepeating:
+		left = coupon.DurationPeriods - 1
+	}
+	return &left
+}
+
+// priceNextPeriod prices the purchase for the period after its current one:
+// a scheduled downgrade takes effect and the period counts against the
+// discount. The changes are only made to purchase; they're saved with the
+// transition starting the period, once it's paid for.
+func (s *Service) priceNextPeriod(purchase *models.Purchase) error {
+	if err := s.applyScheduledPlan(purchase); err != nil {
+		return err
+	}
+	// The first paid period after a trial is the one the discount started with
+	if purchase.TrialEndsAt != nil && purchase.ExpiresAt != nil && !purchase.ExpiresAt.After(*purchase.TrialEndsAt) {
+		return nil
+	}
+	return s.advanceDiscount(purchase)
+}
+
+// advanceDiscount counts a renewal against the purchase's discount. Once the
+// discounted periods are used up the purchase is repriced at its list price.
+func (s *Service) advanceDiscount(purchase *models.Purchase) error {
+	if purchase.DiscountAmount == 0 || purchase.DiscountPeriodsLeft == nil {
+		return nil
+	}
+
+	if left := *purchase.DiscountPeriodsLeft; left > 0 {
+		left--
+		purchase.DiscountPeriodsLeft = &left
+	} else {
+		quote, err := s.quoteTax(purchase.ListPrice, purchase.UserID, purchase.TenantID)
+		if err != nil {
+			return err
+		}
+		applyTax(purchase, quote)
+		purchase.DiscountAmount = 0
+		purchase.DiscountPeriodsLeft = nil
+	}
+	return nil
+}
+
+// repriceAt moves the purchase to the plan's list price, ending any discount
+func repriceAt(purchase *models.Purchase, plan *models.Plan, quote *models.TaxQuote) {
+	purchase.PlanID = plan.ID
+	purchase.Plan = plan
+	purchase.ScheduledPlanID = nil
+	purchase.ListPrice = plan.Price
+	purchase.DiscountAmount = 0
+	purchase.DiscountPeriodsLeft = nil
+	applyTax(purchase, quote)
+}
@@ -1,7 +1,11 @@
 package purchase
 
 import (
+	"backend/internal/coupon"
 	"backend/internal/domain"
+	"backend/internal/invoice"
+	"backend/internal/rollup"
+	"backend/internal/tax"
 	"github.com/google/wire"
 )
 
@@ -10,6 +14,10 @@ var ProviderSet = wire.NewSet(
 	NewController,
 	NewService,
 	NewRepository,
+	invoice.ProviderSet,
+	tax.ProviderSet,
+	coupon.ProviderSet,
+	rollup.ProviderSet,
 
 	wire.Bind(new(domain.PurchaseControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.PurchaseService), new(*Service)),
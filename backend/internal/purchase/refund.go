@@ -0,0 +1,195 @@
+package purchase
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// RefundPurchase refunds a payment of the purchase through the payment
+// gateway, by default all that is left of its latest payment. It's used by
+// tenant admins, for any user's purchase.
+func (s *Service) RefundPurchase(id, adminID, tenantID int, req models.RefundRequest) (*models.Refund, error) {
+	purchase, err := s.repo.GetPurchase(id, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+
+	var payment *models.Payment
+	if req.PaymentID != 0 {
+		payment, err = s.repo.GetPayment(req.PaymentID, tenantID)
+		if err != nil || payment.PurchaseID != purchase.ID {
+			return nil, errors.New("payment not found")
+		}
+	} else {
+		payment, err = s.repo.GetLatestPayment(purchase.ID, tenantID)
+		if err != nil {
+			return nil, errors.New("purchase has no payment to refund")
+		}
+	}
+
+	if payment.Status != coreDomain.PaymentStatusSucceeded {
+		return nil, fmt.Errorf("payment with status '%s' can't be refunded", payment.Status)
+	}
+	if payment.Provider != s.gateway.Name() {
+		return nil, fmt.Errorf("payment provider '%s' is not configured", payment.Provider)
+	}
+
+	amount := money.Round(req.Amount)
+	if req.Amount == 0 {
+		amount = money.Round(payment.Amount - payment.RefundedAmount)
+	}
+	if amount <= 0 {
+		return nil, errors.New("refund amount must be positive")
+	}
+	if amount > payment.Amount-payment.RefundedAmount+0.005 {
+		return nil, domain.ErrRefundExceedsBalance
+	}
+
+	result, err := s.gateway.Refund(payment.IntentID, amount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	return s.applyRefund(purchase, payment, result.ID, result.Amount, req.Reason, &adminID)
+}
+
+func (s *Service) GetRefunds(id, tenantID int) ([]models.Refund, error) {
+	if _, err := s.repo.GetPurchase(id, tenantID); err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	return s.repo.GetRefunds(id, tenantID)
+}
+
+// applyRefund records a refund the provider made of the payment, whether an
+// admin asked for it or the provider reported it, and credits it on a credit
+// note. Refunding the payment for the current period in full cancels the
+// purchase; other payments, such as a proration charge or an earlier
+// period's renewal, can be refunded without ending it. Refunds the provider
+// reports again are returned as recorded.
+func (s *Service) applyRefund(purchase *models.Purchase, payment *models.Payment, providerRefundID string, amount float64, reason string, adminID *int) (*models.Refund, error) {
+	// Tax is refunded in the proportion it was charged
+	tax := 0.0
+	if payment.Amount > 0 {
+		tax = money.Round(amount * payment.TaxAmount / payment.Amount)
+	}
+
+	refund := &models.Refund{
+		PaymentID:        payment.ID,
+		PurchaseID:       purchase.ID,
+		UserID:           purchase.UserID,
+		Provider:         payment.Provider,
+		ProviderRefundID: providerRefundID,
+		Amount:           amount,
+		NetAmount:        money.Round(amount - tax),
+		TaxAmount:        tax,
+		Currency:         payment.Currency,
+		Reason:           reason,
+		RefundedByID:     adminID,
+		TenantID:         purchase.TenantID,
+	}
+	created, err := s.repo.RecordRefund(refund)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return refund, nil
+	}
+	purchase.RefundedAmount += refund.Amount
+	s.rollups.Touch(refund.TenantID, refund.CreatedAt)
+
+	if refund.Payment.Status == models.PaymentStatusRefunded && isEntitled(purchase.Status) && s.paidCurrentPeriod(purchase, payment) {
+		if err := s.transition(purchase, models.PurchaseStatusCancelled, "payment_refunded", adminID); err != nil {
+			log.Printf("Failed to cancel refunded purchase %d: %v", purchase.ID, err)
+		}
+	}
+
+	actorID := purchase.UserID
+	if adminID != nil {
+		actorID = *adminID
+	}
+	s.repo.CreateActivity(&models.Activity{
+		UserID:      actorID,
+		TenantID:    purchase.TenantID,
+		Type:        models.ActivityRefundIssued,
+		Description: fmt.Sprintf("Refunded $%.2f of payment #%d for purchase #%d", refund.Amount, payment.ID, purchase.ID),
+		EntityType:  models.ActivityEntityRefund,
+		EntityID:    &refund.ID,
+		Payload: models.JSONB{
+			"purchase_id": purchase.ID,
+			"payment_id":  payment.ID,
+			"amount":      refund.Amount,
+			"currency":    refund.Currency,
+			"reason":      refund.Reason,
+		},
+	})
+
+	// The money is returned at this point, with or without a credit note
+	creditNote, err := s.invoiceService.IssueCreditNote(refund)
+	if err != nil {
+		log.Printf("Failed to issue credit note for refund %d: %v", refund.ID, err)
+		return refund, nil
+	}
+	refund.CreditNoteID = &creditNote.ID
+	refund.CreditNote = creditNote
+	if err := s.repo.SetRefundCreditNote(refund); err != nil {
+		log.Printf("Failed to link credit note %d to refund %d: %v", creditNote.ID, refund.ID, err)
+	}
+
+	return refund, nil
+}
+
+// paidCurrentPeriod reports whether the payment paid for the purchase's
+// current period
+func (s *Service) paidCurrentPeriod(purchase *models.Purchase, payment *models.Payment) bool {
+	current, err := s.repo.GetPeriodPayment(purchase.ID, purchase.TenantID)
+	if err != nil {
+		log.Printf("Failed to find the payment for the current period of purchase %d: %v", purchase.ID, err)
+		return false
+	}
+	return current.ID == payment.ID
+}
+
+// refundFailedCharge refunds in full a charge whose purchase change failed to
+// be saved, so that the customer isn't charged for nothing, and returns
+// cause. The refund is recorded against the charge's payment, if there is
+// one, so that the provider's refund webhook is recognized as already
+// applied. A refund that fails is logged for manual follow-up.
+func (s *Service) refundFailedCharge(intent *coreDomain.PaymentIntent, purchase *models.Purchase, payment *models.Payment, cause error) error {
+	result, err := s.gateway.Refund(intent.ID, intent.Amount)
+	if err != nil {
+		log.Printf("Failed to refund payment %s after %v; refund it manually: %v", intent.ID, cause, err)
+		return cause
+	}
+	if payment == nil {
+		return cause
+	}
+
+	tax := 0.0
+	if payment.Amount > 0 {
+		tax = money.Round(result.Amount * payment.TaxAmount / payment.Amount)
+	}
+	refund := &models.Refund{
+		PaymentID:        payment.ID,
+		PurchaseID:       purchase.ID,
+		UserID:           purchase.UserID,
+		Provider:         payment.Provider,
+		ProviderRefundID: result.ID,
+		Amount:           result.Amount,
+		NetAmount:        money.Round(result.Amount - tax),
+		TaxAmount:        tax,
+		Currency:         payment.Currency,
+		Reason:           "charge_not_applied",
+		TenantID:         purchase.TenantID,
+	}
+	if _, err := s.repo.RecordRefund(refund); err != nil {
+		log.Printf("Failed to record refund %s of payment %d: %v", result.ID, payment.ID, err)
+		return cause
+	}
+	s.rollups.Touch(refund.TenantID, refund.CreatedAt)
+	return cause
+}
@@ -0,0 +1,60 @@
+package purchase
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/core/payment"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+// Refunding the payment for the current period in full ends the purchase;
+// refunding a proration charge or an earlier period's renewal doesn't
+func TestRefundCancelsOnlyForCurrentPeriod(t *testing.T) {
+	tests := []struct {
+		name       string
+		paymentID  int
+		wantStatus string
+	}{
+		{"current period", 3, models.PurchaseStatusCancelled},
+		{"proration", 4, models.PurchaseStatusActive},
+		{"earlier period", 2, models.PurchaseStatusActive},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			gateway := payment.NewFakeGateway(&core.Config{})
+			repo := &memoryPurchaseRepository{purchase: newTestPurchase(2, now.AddDate(0, 0, -10), now.AddDate(0, 0, 20)), plans: testPlans}
+			// The initial and renewal payments of two periods, then an upgrade
+			for _, charge := range []struct {
+				kind        string
+				amount, tax float64
+			}{
+				{models.PaymentKindInitial, 11, 1},
+				{models.PaymentKindRenewal, 11, 1},
+				{models.PaymentKindRenewal, 11, 1},
+				{models.PaymentKindProration, 14.66, 1.33},
+			} {
+				intent, _ := gateway.CreateIntent(coreDomain.PaymentIntentRequest{Amount: charge.amount, Currency: "USD", PaymentMethod: "pm_card"})
+				intent, _ = gateway.ConfirmIntent(intent.ID, "")
+				repo.CreatePayment(&models.Payment{
+					PurchaseID: 1, UserID: 2, Provider: gateway.Name(), IntentID: intent.ID, Kind: charge.kind, Status: intent.Status,
+					Amount: charge.amount, TaxAmount: charge.tax, Currency: "USD", TenantID: 4,
+				})
+			}
+			service := newTestService(repo, gateway)
+
+			refund, err := service.RefundPurchase(1, 9, 4, models.RefundRequest{PaymentID: tt.paymentID})
+			if err != nil {
+				t.Fatalf("RefundPurchase: %v", err)
+			}
+			if refund.Amount != repo.payments[tt.paymentID-1].Amount {
+				t.Errorf("refunded %v of a %v payment", refund.Amount, repo.payments[tt.paymentID-1].Amount)
+			}
+			if repo.purchase.Status != tt.wantStatus {
+				t.Errorf("purchase is %s, want %s", repo.purchase.Status, tt.wantStatus)
+			}
+		})
+	}
+}
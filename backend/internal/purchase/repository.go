@@ -1,10 +1,19 @@
 package purchase
 
 import (
+	coreDomain "backend/core/domain"
+	"backend/internal/domain"
 	"backend/models"
+	"errors"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// errTrialUsed rolls back a trial purchase of a user who already had a trial
+var errTrialUsed = errors.New("trial already used")
+
 type Repository struct {
 	db *gorm.DB
 }
@@ -26,6 +35,48 @@ func (r *Repository) CreatePurchase(purchase *models.Purchase) (*models.Purchase
 	return purchase, nil
 }
 
+// HasUsedTrial reports whether the user already started a trial of the product
+func (r *Repository) HasUsedTrial(userID, productID, tenantID int) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.TrialUsage{}).
+		Where("tenant_id = ? AND user_id = ? AND product_id = ?", tenantID, userID, productID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// CreateTrialPurchase creates a trialing purchase together with the user's
+// trial usage of the product. It reports false, creating nothing, when the
+// user already used their trial of the product.
+func (r *Repository) CreateTrialPurchase(purchase *models.Purchase, productID int) (bool, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(purchase).Error; err != nil {
+			return err
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.TrialUsage{
+			UserID:     purchase.UserID,
+			ProductID:  productID,
+			PurchaseID: purchase.ID,
+			TenantID:   purchase.TenantID,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errTrialUsed
+		}
+		return nil
+	})
+	if errors.Is(err, errTrialUsed) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, r.db.Preload("Plan.Product").Preload("User").First(purchase, purchase.ID).Error
+}
+
 func (r *Repository) GetUserPurchases(userID, tenantID int) ([]models.Purchase, error) {
 	var purchases []models.Purchase
 	err := r.db.Where("user_id = ? AND tenant_id = ?", userID, tenantID).
@@ -45,9 +96,21 @@ func (r *Repository) GetPurchaseByID(id, userID, tenantID int) (*models.Purchase
 	return &purchase, err
 }
 
+// GetPurchase loads a purchase of any user of the tenant
+func (r *Repository) GetPurchase(id, tenantID int) (*models.Purchase, error) {
+	var purchase models.Purchase
+	err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).
+		Preload("Plan.Product").
+		First(&purchase).Error
+	if err != nil {
+		return nil, err
+	}
+	return &purchase, nil
+}
+
 func (r *Repository) GetActivePurchases(userID, tenantID int) ([]models.Purchase, error) {
 	var purchases []models.Purchase
-	err := r.db.Where("user_id = ? AND tenant_id = ? AND status = ?", userID, tenantID, "active").
+	err := r.db.Where("user_id = ? AND tenant_id = ? AND status IN ?", userID, tenantID, models.EntitledPurchaseStatuses).
 		Preload("Plan.Product").
 		Preload("User").
 		Order("created_at DESC").
@@ -66,3 +129,264 @@ func (r *Repository) GetPlanByID(planID, tenantID int) (*models.Plan, error) {
 func (r *Repository) CreateActivity(activity *models.Activity) error {
 	return r.db.Create(activity).Error
 }
+
+func (r *Repository) CreateTransition(transition *models.PurchaseTransition) error {
+	return r.db.Create(transition).Error
+}
+
+// TransitionPurchase saves the lifecycle fields of the purchase only if its
+// status is still fromStatus, and records the transition. It reports false
+// when another process changed the purchase first.
+func (r *Repository) TransitionPurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	return r.transitionPurchase(lifecycleColumns(purchase), purchase, fromStatus, transition)
+}
+
+// RepricePurchase is TransitionPurchase for changes that also reprice the
+// purchase: its pricing fields are saved with the transition, or not at all
+func (r *Repository) RepricePurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	columns := lifecycleColumns(purchase)
+	for column, value := range pricingColumns(purchase) {
+		columns[column] = value
+	}
+	return r.transitionPurchase(columns, purchase, fromStatus, transition)
+}
+
+func (r *Repository) transitionPurchase(columns map[string]interface{}, purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	applied := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Purchase{}).
+			Where("id = ? AND tenant_id = ? AND status = ?", purchase.ID, purchase.TenantID, fromStatus).
+			Updates(columns)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		applied = true
+		return tx.Create(transition).Error
+	})
+	return applied, err
+}
+
+// UpdatePricing saves the plan, payment method, per-period amounts and
+// discount state of the purchase
+func (r *Repository) UpdatePricing(purchase *models.Purchase) error {
+	return r.db.Model(&models.Purchase{}).
+		Where("id = ? AND tenant_id = ?", purchase.ID, purchase.TenantID).
+		Updates(pricingColumns(purchase)).Error
+}
+
+func lifecycleColumns(purchase *models.Purchase) map[string]interface{} {
+	return map[string]interface{}{
+		"status":               purchase.Status,
+		"expires_at":           purchase.ExpiresAt,
+		"current_period_start": purchase.CurrentPeriodStart,
+		"cancel_at_period_end": purchase.CancelAtPeriodEnd,
+		"cancelled_at":         purchase.CancelledAt,
+	}
+}
+
+func pricingColumns(purchase *models.Purchase) map[string]interface{} {
+	return map[string]interface{}{
+		"plan_id":               purchase.PlanID,
+		"scheduled_plan_id":     purchase.ScheduledPlanID,
+		"payment_method":        purchase.PaymentMethod,
+		"list_price":            purchase.ListPrice,
+		"amount":                purchase.Amount,
+		"net_amount":            purchase.NetAmount,
+		"tax_amount":            purchase.TaxAmount,
+		"tax_rate":              purchase.TaxRate,
+		"tax_name":              purchase.TaxName,
+		"tax_country":           purchase.TaxCountry,
+		"reverse_charge":        purchase.ReverseCharge,
+		"discount_amount":       purchase.DiscountAmount,
+		"discount_periods_left": purchase.DiscountPeriodsLeft,
+	}
+}
+
+// RecordMRRMovement saves the purchase's new MRR along with the movement
+// that changed it
+func (r *Repository) RecordMRRMovement(purchase *models.Purchase, movement *models.MRRMovement) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Purchase{}).
+			Where("id = ? AND tenant_id = ?", purchase.ID, purchase.TenantID).
+			Update("mrr", movement.MRR).Error; err != nil {
+			return err
+		}
+		return tx.Create(movement).Error
+	})
+}
+
+// GetDuePurchases returns purchases whose period has ended, past-due
+// purchases whose period ended before pastDueBefore and incomplete purchases
+// created before incompleteBefore, across all tenants
+func (r *Repository) GetDuePurchases(now, pastDueBefore, incompleteBefore time.Time, limit int) ([]models.Purchase, error) {
+	var purchases []models.Purchase
+	err := r.db.Where("(status IN ? AND expires_at <= ?) OR (status = ? AND expires_at <= ?) OR (status = ? AND created_at <= ?)",
+		[]string{models.PurchaseStatusTrialing, models.PurchaseStatusActive}, now,
+		models.PurchaseStatusPastDue, pastDueBefore,
+		models.PurchaseStatusIncomplete, incompleteBefore).
+		Preload("Plan").
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&purchases).Error
+	return purchases, err
+}
+
+// GetTrialsEndingBetween returns trialing purchases, across all tenants, whose
+// trial ends after from and no later than to, and whose reminder hasn't been
+// sent. Trials scheduled for cancellation are left out.
+func (r *Repository) GetTrialsEndingBetween(from, to time.Time, limit int) ([]models.Purchase, error) {
+	var purchases []models.Purchase
+	err := r.db.Where("status = ? AND cancel_at_period_end = ? AND trial_reminder_sent_at IS NULL AND trial_ends_at > ? AND trial_ends_at <= ?",
+		models.PurchaseStatusTrialing, false, from, to).
+		Preload("Plan").
+		Preload("User").
+		Order("trial_ends_at ASC").
+		Limit(limit).
+		Find(&purchases).Error
+	return purchases, err
+}
+
+func (r *Repository) MarkTrialReminderSent(purchase *models.Purchase) error {
+	return r.db.Model(&models.Purchase{}).
+		Where("id = ? AND tenant_id = ?", purchase.ID, purchase.TenantID).
+		Update("trial_reminder_sent_at", purchase.TrialReminderSentAt).Error
+}
+
+func (r *Repository) GetPurchaseTransitions(purchaseID, tenantID int) ([]models.PurchaseTransition, error) {
+	var transitions []models.PurchaseTransition
+	err := r.db.Where("purchase_id = ? AND tenant_id = ?", purchaseID, tenantID).
+		Order("created_at ASC, id ASC").
+		Find(&transitions).Error
+	return transitions, err
+}
+
+func (r *Repository) CreatePayment(payment *models.Payment) error {
+	return r.db.Create(payment).Error
+}
+
+func (r *Repository) UpdatePayment(payment *models.Payment) error {
+	return r.db.Save(payment).Error
+}
+
+func (r *Repository) GetPaymentByIntent(provider, intentID string) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("provider = ? AND intent_id = ?", provider, intentID).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *Repository) GetPayment(id, tenantID int) (*models.Payment, error) {
+	var payment models.Payment
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&payment).Error; err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetLatestPayment returns the most recent payment of the purchase that took
+// money, including ones already (partly) refunded
+func (r *Repository) GetLatestPayment(purchaseID, tenantID int) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("purchase_id = ? AND tenant_id = ? AND status IN ?", purchaseID, tenantID,
+		[]string{coreDomain.PaymentStatusSucceeded, models.PaymentStatusRefunded}).
+		Order("created_at DESC, id DESC").
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// GetPeriodPayment returns the payment that paid for the purchase's current
+// period: its most recent initial or renewal payment that took money
+func (r *Repository) GetPeriodPayment(purchaseID, tenantID int) (*models.Payment, error) {
+	var payment models.Payment
+	err := r.db.Where("purchase_id = ? AND tenant_id = ? AND kind IN ? AND status IN ?", purchaseID, tenantID,
+		[]string{models.PaymentKindInitial, models.PaymentKindRenewal},
+		[]string{coreDomain.PaymentStatusSucceeded, models.PaymentStatusRefunded}).
+		Order("created_at DESC, id DESC").
+		First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// RecordRefund stores the refund and adds it to the refunded amounts of its
+// payment and purchase, all under a lock on the payment. It reports false,
+// loading the stored refund, when the provider's refund was already recorded.
+func (r *Repository) RecordRefund(refund *models.Refund) (bool, error) {
+	created := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND tenant_id = ?", refund.PaymentID, refund.TenantID).
+			First(&payment).Error; err != nil {
+			return err
+		}
+
+		if refund.ProviderRefundID != "" {
+			var existing models.Refund
+			err := tx.Where("provider = ? AND provider_refund_id = ?", refund.Provider, refund.ProviderRefundID).
+				Limit(1).Find(&existing).Error
+			if err != nil {
+				return err
+			}
+			if existing.ID != 0 {
+				*refund = existing
+				refund.Payment = &payment
+				return nil
+			}
+		}
+
+		// Half a cent of slack absorbs rounding of the refund's split
+		if refund.Amount > payment.Amount-payment.RefundedAmount+0.005 {
+			return domain.ErrRefundExceedsBalance
+		}
+
+		payment.RefundedAmount += refund.Amount
+		if payment.RefundedAmount >= payment.Amount-0.005 {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		if err := tx.Model(&models.Payment{}).Where("id = ?", payment.ID).
+			Updates(map[string]interface{}{
+				"refunded_amount": payment.RefundedAmount,
+				"status":          payment.Status,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Purchase{}).Where("id = ?", refund.PurchaseID).
+			Update("refunded_amount", gorm.Expr("refunded_amount + ?", refund.Amount)).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(refund).Error; err != nil {
+			return err
+		}
+		refund.Payment = &payment
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *Repository) SetRefundCreditNote(refund *models.Refund) error {
+	return r.db.Model(&models.Refund{}).
+		Where("id = ?", refund.ID).
+		Update("credit_note_id", refund.CreditNoteID).Error
+}
+
+func (r *Repository) GetRefunds(purchaseID, tenantID int) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.Where("purchase_id = ? AND tenant_id = ?", purchaseID, tenantID).
+		Preload("CreditNote").
+		Order("created_at DESC, id DESC").
+		Find(&refunds).Error
+	return refunds, err
+}
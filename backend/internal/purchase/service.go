@@ -1,70 +1,354 @@
 package purchase
 
 import (
+	"backend/core"
+	coreDomain "backend/core/domain"
 	"backend/internal/domain"
 	"backend/models"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 )
 
+// duePurchaseBatchSize bounds the purchases handled per scheduler run
+const duePurchaseBatchSize = 500
+
 type Service struct {
-	repo domain.PurchaseRepository
+	repo           domain.PurchaseRepository
+	cfg            *core.Config
+	gateway        coreDomain.PaymentGateway
+	invoiceService domain.InvoiceService
+	billingService domain.BillingService
+	taxEngine      domain.TaxEngine
+	couponService  domain.CouponService
+	emailService   coreDomain.EmailService
+	rollups        domain.RollupService
 }
 
-func NewService(repo domain.PurchaseRepository) *Service {
-	return &Service{repo: repo}
+func NewService(repo domain.PurchaseRepository, cfg *core.Config, gateway coreDomain.PaymentGateway, invoiceService domain.InvoiceService, billingService domain.BillingService, taxEngine domain.TaxEngine, couponService domain.CouponService, emailService coreDomain.EmailService, rollups domain.RollupService) *Service {
+	return &Service{
+		repo:           repo,
+		cfg:            cfg,
+		gateway:        gateway,
+		invoiceService: invoiceService,
+		billingService: billingService,
+		taxEngine:      taxEngine,
+		couponService:  couponService,
+		emailService:   emailService,
+		rollups:        rollups,
+	}
 }
 
+// CreatePurchase charges the plan price, less any promo code discount and
+// with tax for the buyer's billing location, through the payment gateway.
+// The purchase is active once the payment succeeds, or incomplete while the
+// customer still has to authenticate it. Plans with a trial the user hasn't
+// used yet start trialing instead, and are first charged when it ends.
 func (s *Service) CreatePurchase(userID, tenantID int, req models.CreatePurchaseRequest) (*models.Purchase, error) {
-	// Get plan details
-	plan, err := s.repo.GetPlanByID(req.PlanID, tenantID)
+	plan, err := s.getAvailablePlan(req.PlanID, tenantID)
 	if err != nil {
-		return nil, fmt.Errorf("plan not found: %w", err)
+		return nil, err
 	}
 
-	// Calculate expiry date based on plan interval
-	var expiresAt *time.Time
-	now := time.Now()
-	switch plan.Interval {
-	case "monthly":
-		expires := now.AddDate(0, 1, 0)
-		expiresAt = &expires
-	case "yearly":
-		expires := now.AddDate(1, 0, 0)
-		expiresAt = &expires
+	trial, err := s.trialAvailable(plan, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if req.PaymentMethod == "" && (!trial || plan.TrialRequiresPaymentMethod) {
+		return nil, errors.New("payment method is required")
 	}
 
-	purchase := &models.Purchase{
-		UserID:        userID,
-		PlanID:        req.PlanID,
-		TransactionID: req.TransactionID,
-		Amount:        plan.Price,
+	quote, coupon, err := s.price(plan, req.PromoCode, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	start := s.startPurchase
+	if trial {
+		start = s.startTrial
+	}
+
+	if coupon == nil {
+		return start(plan, quote, nil, userID, tenantID, req.PaymentMethod)
+	}
+
+	// Hold a redemption while the payment is taken so limits can't be overrun
+	redemption, err := s.couponService.Reserve(coupon, userID, quote.DiscountAmount, plan.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	purchase, err := start(plan, quote, coupon, userID, tenantID, req.PaymentMethod)
+	if err != nil {
+		if releaseErr := s.couponService.Release(redemption); releaseErr != nil {
+			log.Printf("Failed to release redemption %d of coupon %d: %v", redemption.ID, coupon.ID, releaseErr)
+		}
+		return nil, err
+	}
+	if err := s.couponService.AttachPurchase(redemption, purchase.ID); err != nil {
+		log.Printf("Failed to link redemption %d to purchase %d: %v", redemption.ID, purchase.ID, err)
+	}
+
+	return purchase, nil
+}
+
+// startPurchase takes the first payment for the quoted price and records the purchase
+func (s *Service) startPurchase(plan *models.Plan, quote *models.PurchaseQuote, coupon *models.Coupon, userID, tenantID int, paymentMethod string) (*models.Purchase, error) {
+	intent, err := s.gateway.CreateIntent(coreDomain.PaymentIntentRequest{
+		Amount:        quote.GrossAmount,
 		Currency:      plan.Currency,
-		Status:        "active",
-		PurchasedAt:   now,
-		ExpiresAt:     expiresAt,
-		TenantID:      tenantID,
+		PaymentMethod: paymentMethod,
+		Description:   plan.Name,
+		Metadata: map[string]string{
+			"tenant_id": fmt.Sprint(tenantID),
+			"user_id":   fmt.Sprint(userID),
+			"plan_id":   fmt.Sprint(plan.ID),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	intent, err = s.gateway.ConfirmIntent(intent.ID, paymentMethod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+
+	purchase := s.newPurchase(plan, quote, coupon, userID, tenantID, intent.ID, paymentMethod)
+
+	now := time.Now()
+	switch intent.Status {
+	case coreDomain.PaymentStatusSucceeded:
+		purchase.Status = models.PurchaseStatusActive
+		purchase.PurchasedAt = now
+		purchase.CurrentPeriodStart = &now
+		purchase.ExpiresAt = periodEnd(now, plan.Interval)
+	case coreDomain.PaymentStatusRequiresAction:
+		purchase.Status = models.PurchaseStatusIncomplete
+	case coreDomain.PaymentStatusDeclined:
+		return nil, fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, intent.DeclineReason)
+	default:
+		return nil, fmt.Errorf("unexpected payment status '%s'", intent.Status)
 	}
 
 	createdPurchase, err := s.repo.CreatePurchase(purchase)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create purchase: %w", err)
+		err = fmt.Errorf("failed to create purchase: %w", err)
+		// An unsaved purchase must not keep the customer's money. One waiting
+		// for customer action hasn't been charged, and its action URL is
+		// never handed out.
+		if purchase.ID == 0 && intent.Status == coreDomain.PaymentStatusSucceeded {
+			return nil, s.refundFailedCharge(intent, purchase, nil, err)
+		}
+		return nil, err
 	}
+	createdPurchase.NextActionURL = intent.NextActionURL
+	s.rollups.Touch(tenantID, createdPurchase.CreatedAt)
+
+	payment := s.recordPayment(createdPurchase, intent, models.PaymentKindInitial, createdPurchase.NetAmount, createdPurchase.TaxAmount)
 
-	// Create activity log
-	activity := &models.Activity{
-		UserID:      userID,
-		TenantID:    tenantID,
-		Type:        "purchase_made",
-		Description: fmt.Sprintf("Purchased plan '%s' for $%.2f", plan.Name, plan.Price),
-		EntityType:  "purchase",
-		EntityID:    &createdPurchase.ID,
+	s.repo.CreateTransition(&models.PurchaseTransition{
+		PurchaseID: createdPurchase.ID,
+		ToStatus:   createdPurchase.Status,
+		Reason:     "created",
+		UserID:     &userID,
+		TenantID:   tenantID,
+	})
+
+	if createdPurchase.Status == models.PurchaseStatusActive {
+		s.syncMRR(createdPurchase)
+		s.logPurchaseMade(createdPurchase, plan)
+		s.issueInvoice(payment)
 	}
-	s.repo.CreateActivity(activity)
 
 	return createdPurchase, nil
 }
 
+// newPurchase builds a purchase of the plan at the quoted per-period price
+func (s *Service) newPurchase(plan *models.Plan, quote *models.PurchaseQuote, coupon *models.Coupon, userID, tenantID int, transactionID, paymentMethod string) *models.Purchase {
+	purchase := &models.Purchase{
+		UserID:          userID,
+		PlanID:          plan.ID,
+		TransactionID:   transactionID,
+		PaymentProvider: s.gateway.Name(),
+		PaymentMethod:   paymentMethod,
+		ListPrice:       quote.ListPrice,
+		Currency:        plan.Currency,
+		TenantID:        tenantID,
+	}
+	applyTax(purchase, &quote.TaxQuote)
+	if coupon != nil {
+		purchase.CouponID = &coupon.ID
+		purchase.DiscountAmount = quote.DiscountAmount
+		purchase.DiscountPeriodsLeft = discountedRenewals(coupon)
+	}
+	return purchase
+}
+
+// QuotePurchase previews what CreatePurchase would charge for the plan, and
+// how many trial days come before the first charge
+func (s *Service) QuotePurchase(userID, tenantID, planID int, promoCode string) (*models.PurchaseQuote, error) {
+	plan, err := s.getAvailablePlan(planID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	quote, _, err := s.price(plan, promoCode, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	trial, err := s.trialAvailable(plan, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if trial {
+		quote.TrialDays = plan.TrialDays
+	}
+	return quote, nil
+}
+
+func (s *Service) getAvailablePlan(planID, tenantID int) (*models.Plan, error) {
+	plan, err := s.repo.GetPlanByID(planID, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("plan not found: %w", err)
+	}
+
+	// Archived plans and products stay resolvable but can't be bought
+	if plan.ArchivedAt != nil || (plan.Product != nil && plan.Product.ArchivedAt != nil) {
+		return nil, fmt.Errorf("plan is no longer available")
+	}
+
+	return plan, nil
+}
+
+// price works out what the user pays per period for the plan. The promo
+// code's discount comes off the list price before tax.
+func (s *Service) price(plan *models.Plan, promoCode string, userID, tenantID int) (*models.PurchaseQuote, *models.Coupon, error) {
+	quote := &models.PurchaseQuote{PlanID: plan.ID, ListPrice: plan.Price}
+
+	var coupon *models.Coupon
+	if promoCode != "" {
+		var err error
+		coupon, quote.DiscountAmount, err = s.couponService.Apply(promoCode, plan, tenantID)
+		if err != nil {
+			return nil, nil, err
+		}
+		quote.CouponCode = coupon.Code
+	}
+
+	tax, err := s.quoteTax(plan.Price-quote.DiscountAmount, userID, tenantID)
+	if err != nil {
+		return nil, nil, err
+	}
+	quote.TaxQuote = *tax
+
+	return quote, coupon, nil
+}
+
+// quoteTax works out the tax on amount from the tenant's seller settings and
+// the buyer's billing profile
+func (s *Service) quoteTax(amount float64, userID, tenantID int) (*models.TaxQuote, error) {
+	seller, err := s.billingService.GetTenantSettings(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	buyer, err := s.billingService.GetBillingProfile(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	quote, err := s.taxEngine.Calculate(models.TaxRequest{
+		TenantID:         tenantID,
+		Amount:           amount,
+		SellerCountry:    seller.Country,
+		BuyerCountry:     buyer.Country,
+		BuyerRegion:      buyer.Region,
+		BuyerVATID:       buyer.VATID,
+		PricesIncludeTax: seller.PricesIncludeTax,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate tax: %w", err)
+	}
+	return quote, nil
+}
+
+// ConfirmPurchase completes the payment of an incomplete purchase once the
+// customer has finished the provider's authentication step
+func (s *Service) ConfirmPurchase(id, userID, tenantID int) (*models.Purchase, error) {
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	if purchase.Status != models.PurchaseStatusIncomplete {
+		return nil, domain.ErrInvalidTransition
+	}
+	if purchase.PaymentProvider != s.gateway.Name() {
+		return nil, fmt.Errorf("payment provider '%s' is not configured", purchase.PaymentProvider)
+	}
+
+	intent, err := s.gateway.ConfirmIntent(purchase.TransactionID, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+	payment := s.updatePayment(intent)
+
+	switch intent.Status {
+	case coreDomain.PaymentStatusSucceeded:
+		if err := s.activate(purchase, "payment_confirmed", &userID); err != nil {
+			return nil, err
+		}
+		s.issueInvoice(payment)
+	case coreDomain.PaymentStatusDeclined:
+		if err := s.transition(purchase, models.PurchaseStatusExpired, "payment_failed", &userID); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %s", domain.ErrPaymentDeclined, intent.DeclineReason)
+	default:
+		purchase.NextActionURL = intent.NextActionURL
+	}
+
+	return purchase, nil
+}
+
+// activate starts the first period of an incomplete purchase whose payment succeeded
+func (s *Service) activate(purchase *models.Purchase, reason string, userID *int) error {
+	now := time.Now()
+	purchase.PurchasedAt = now
+	purchase.CurrentPeriodStart = &now
+	if purchase.Plan != nil {
+		purchase.ExpiresAt = periodEnd(now, purchase.Plan.Interval)
+	}
+
+	if err := s.transition(purchase, models.PurchaseStatusActive, reason, userID); err != nil {
+		return err
+	}
+
+	s.logPurchaseMade(purchase, purchase.Plan)
+	return nil
+}
+
+func (s *Service) logPurchaseMade(purchase *models.Purchase, plan *models.Plan) {
+	name := ""
+	if plan != nil {
+		name = plan.Name
+	}
+	s.repo.CreateActivity(&models.Activity{
+		UserID:      purchase.UserID,
+		TenantID:    purchase.TenantID,
+		Type:        models.ActivityPurchaseMade,
+		Description: fmt.Sprintf("Purchased plan '%s' for $%.2f", name, purchase.Amount),
+		EntityType:  models.ActivityEntityPurchase,
+		EntityID:    &purchase.ID,
+		Payload: models.JSONB{
+			"plan_id":  purchase.PlanID,
+			"amount":   purchase.Amount,
+			"currency": purchase.Currency,
+			"status":   purchase.Status,
+		},
+	})
+}
+
 func (s *Service) GetUserPurchases(userID, tenantID int) ([]models.Purchase, error) {
 	return s.repo.GetUserPurchases(userID, tenantID)
 }
@@ -76,3 +360,372 @@ func (s *Service) GetPurchaseByID(id, userID, tenantID int) (*models.Purchase, e
 func (s *Service) GetActivePurchases(userID, tenantID int) ([]models.Purchase, error) {
 	return s.repo.GetActivePurchases(userID, tenantID)
 }
+
+// CancelPurchase cancels a purchase immediately, or flags it to be cancelled
+// when the current period ends
+func (s *Service) CancelPurchase(id, userID, tenantID int, atPeriodEnd bool) (*models.Purchase, error) {
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+
+	now := time.Now()
+	purchase.CancelledAt = &now
+
+	if atPeriodEnd {
+		if purchase.ExpiresAt == nil {
+			return nil, errors.New("purchase has no period end; cancel it immediately instead")
+		}
+		if purchase.CancelAtPeriodEnd {
+			return purchase, nil
+		}
+		purchase.CancelAtPeriodEnd = true
+		err = s.transition(purchase, purchase.Status, "cancellation_scheduled", &userID)
+	} else {
+		purchase.ExpiresAt = &now
+		err = s.transition(purchase, models.PurchaseStatusCancelled, "cancelled", &userID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// An immediate cancellation is recorded by transition; a scheduled one is
+	// only requested until the scheduler applies it
+	if atPeriodEnd {
+		s.repo.CreateActivity(&models.Activity{
+			UserID:      userID,
+			TenantID:    tenantID,
+			Type:        models.ActivityCancellationScheduled,
+			Description: fmt.Sprintf("Scheduled cancellation of purchase #%d at period end", purchase.ID),
+			EntityType:  models.ActivityEntityPurchase,
+			EntityID:    &purchase.ID,
+			Payload: models.JSONB{
+				"plan_id":    purchase.PlanID,
+				"expires_at": purchase.ExpiresAt,
+			},
+		})
+	}
+
+	return purchase, nil
+}
+
+// ResumePurchase withdraws a cancellation scheduled for the end of the period
+func (s *Service) ResumePurchase(id, userID, tenantID int) (*models.Purchase, error) {
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+
+	if !purchase.CancelAtPeriodEnd {
+		return nil, errors.New("purchase has no scheduled cancellation")
+	}
+
+	purchase.CancelAtPeriodEnd = false
+	purchase.CancelledAt = nil
+	if err := s.transition(purchase, purchase.Status, "cancellation_withdrawn", &userID); err != nil {
+		return nil, err
+	}
+
+	return purchase, nil
+}
+
+// UpdatePaymentMethod sets the payment method that later periods of a
+// trialing or active purchase are charged to
+func (s *Service) UpdatePaymentMethod(id, userID, tenantID int, req models.UpdatePaymentMethodRequest) (*models.Purchase, error) {
+	if req.PaymentMethod == "" {
+		return nil, errors.New("payment method is required")
+	}
+
+	purchase, err := s.repo.GetPurchaseByID(id, userID, tenantID)
+	if err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	if purchase.Status != models.PurchaseStatusTrialing && purchase.Status != models.PurchaseStatusActive {
+		return nil, domain.ErrInvalidTransition
+	}
+	if purchase.Plan == nil || !isRecurring(purchase.Plan.Interval) {
+		return nil, errors.New("only recurring purchases have a payment method to update")
+	}
+
+	purchase.PaymentMethod = req.PaymentMethod
+	if err := s.repo.UpdatePricing(purchase); err != nil {
+		return nil, fmt.Errorf("failed to update purchase: %w", err)
+	}
+
+	return purchase, nil
+}
+
+func (s *Service) GetPurchaseTransitions(id, userID, tenantID int) ([]models.PurchaseTransition, error) {
+	if _, err := s.repo.GetPurchaseByID(id, userID, tenantID); err != nil {
+		return nil, errors.New("purchase not found")
+	}
+	return s.repo.GetPurchaseTransitions(id, tenantID)
+}
+
+// ProcessDuePurchases moves purchases whose period has ended to their next
+// state: scheduled cancellations are cancelled, ended trials convert,
+// recurring purchases renew,
+// one-off purchases, overdue past-due purchases and abandoned incomplete
+// payments expire. It returns the number of purchases transitioned.
+func (s *Service) ProcessDuePurchases(now time.Time) (int, error) {
+	due, err := s.repo.GetDuePurchases(now, now.Add(-s.cfg.PastDueGracePeriod), now.Add(-s.cfg.PaymentActionTimeout), duePurchaseBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for i := range due {
+		purchase := &due[i]
+
+		var err error
+		switch {
+		case purchase.Status == models.PurchaseStatusIncomplete:
+			err = s.transition(purchase, models.PurchaseStatusExpired, "payment_abandoned", nil)
+		case purchase.Status == models.PurchaseStatusPastDue:
+			err = s.transition(purchase, models.PurchaseStatusExpired, "payment_overdue", nil)
+		case purchase.CancelAtPeriodEnd:
+			err = s.transition(purchase, models.PurchaseStatusCancelled, "cancelled_at_period_end", nil)
+		case purchase.Status == models.PurchaseStatusTrialing && purchase.Plan != nil && isRecurring(purchase.Plan.Interval):
+			err = s.convertTrial(purchase, now)
+		case purchase.Plan != nil && isRecurring(purchase.Plan.Interval):
+			err = s.renew(purchase, now)
+		default:
+			err = s.transition(purchase, models.PurchaseStatusExpired, "expired", nil)
+		}
+
+		if err != nil {
+			log.Printf("Failed to process due purchase %d: %v", purchase.ID, err)
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// renew charges the stored payment method for the purchase's next period and
+// starts it. A failed charge moves the purchase to past_due, keeping its
+// plan and discount until the period is paid for.
+func (s *Service) renew(purchase *models.Purchase, now time.Time) error {
+	previous := *purchase
+	if err := s.priceNextPeriod(purchase); err != nil {
+		return err
+	}
+
+	var payment *models.Payment
+	if purchase.Amount > 0 {
+		var failure string
+		payment, failure = s.chargeRenewal(purchase)
+		if failure != "" {
+			log.Printf("Renewal payment for purchase %d failed: %s", purchase.ID, failure)
+			*purchase = previous
+			return s.transition(purchase, models.PurchaseStatusPastDue, "payment_failed", nil)
+		}
+	}
+
+	reason := "renewed"
+	if purchase.Status == models.PurchaseStatusTrialing {
+		reason = "trial_converted"
+	}
+	if err := s.startNextPeriod(purchase, now, reason); err != nil {
+		return err
+	}
+
+	s.issueInvoice(payment)
+	return nil
+}
+
+// startNextPeriod activates the period following the purchase's current one,
+// skipping periods missed while the scheduler wasn't running. The pricing of
+// the period is saved with it.
+func (s *Service) startNextPeriod(purchase *models.Purchase, now time.Time, reason string) error {
+	start := *purchase.ExpiresAt
+	end := periodEnd(start, purchase.Plan.Interval)
+	for !end.After(now) {
+		start = *end
+		end = periodEnd(start, purchase.Plan.Interval)
+	}
+
+	purchase.CurrentPeriodStart = &start
+	purchase.ExpiresAt = end
+	return s.repriceTransition(purchase, models.PurchaseStatusActive, reason, nil)
+}
+
+// chargeRenewal collects one period of the purchase off-session. It returns
+// the recorded payment and an empty string on success, or why the charge
+// failed.
+func (s *Service) chargeRenewal(purchase *models.Purchase) (*models.Payment, string) {
+	if purchase.PaymentProvider != s.gateway.Name() || purchase.PaymentMethod == "" {
+		return nil, "no reusable payment method"
+	}
+
+	intent, err := s.gateway.CreateIntent(coreDomain.PaymentIntentRequest{
+		Amount:        purchase.Amount,
+		Currency:      purchase.Currency,
+		PaymentMethod: purchase.PaymentMethod,
+		Description:   fmt.Sprintf("Renewal of purchase #%d", purchase.ID),
+		Metadata: map[string]string{
+			"tenant_id":   fmt.Sprint(purchase.TenantID),
+			"purchase_id": fmt.Sprint(purchase.ID),
+		},
+	})
+	if err != nil {
+		return nil, err.Error()
+	}
+	intent, err = s.gateway.ConfirmIntent(intent.ID, purchase.PaymentMethod)
+	if err != nil {
+		return nil, err.Error()
+	}
+
+	payment := s.recordPayment(purchase, intent, models.PaymentKindRenewal, purchase.NetAmount, purchase.TaxAmount)
+
+	switch intent.Status {
+	case coreDomain.PaymentStatusSucceeded:
+		return payment, ""
+	case coreDomain.PaymentStatusDeclined:
+		return payment, intent.DeclineReason
+	default:
+		// Off-session charges can't wait for the customer
+		return payment, intent.Status
+	}
+}
+
+// recordPayment stores the outcome of a payment intent with its net and tax
+// split, returning nil when it couldn't be stored
+func (s *Service) recordPayment(purchase *models.Purchase, intent *coreDomain.PaymentIntent, kind string, net *float64, tax float64) *models.Payment {
+	payment := &models.Payment{
+		PurchaseID:    purchase.ID,
+		UserID:        purchase.UserID,
+		Provider:      s.gateway.Name(),
+		IntentID:      intent.ID,
+		Kind:          kind,
+		Status:        intent.Status,
+		Amount:        intent.Amount,
+		NetAmount:     net,
+		TaxAmount:     tax,
+		Currency:      intent.Currency,
+		PaymentMethod: intent.PaymentMethod,
+		DeclineReason: intent.DeclineReason,
+		TenantID:      purchase.TenantID,
+	}
+	if err := s.repo.CreatePayment(payment); err != nil {
+		log.Printf("Failed to record payment %s: %v", intent.ID, err)
+		return nil
+	}
+	s.rollups.Touch(payment.TenantID, payment.CreatedAt)
+	return payment
+}
+
+func (s *Service) updatePayment(intent *coreDomain.PaymentIntent) *models.Payment {
+	payment, err := s.repo.GetPaymentByIntent(s.gateway.Name(), intent.ID)
+	if err != nil {
+		return nil
+	}
+	payment.Status = intent.Status
+	payment.DeclineReason = intent.DeclineReason
+	if err := s.repo.UpdatePayment(payment); err != nil {
+		log.Printf("Failed to update payment %s: %v", intent.ID, err)
+		return payment
+	}
+	s.rollups.Touch(payment.TenantID, payment.CreatedAt)
+	return payment
+}
+
+// issueInvoice invoices a succeeded payment. The customer has been charged by
+// then, so an invoice that can't be issued mustn't fail the purchase.
+func (s *Service) issueInvoice(payment *models.Payment) {
+	if payment == nil || payment.Status != coreDomain.PaymentStatusSucceeded {
+		return
+	}
+	if _, err := s.invoiceService.IssueForPayment(payment); err != nil {
+		log.Printf("Failed to invoice payment %d: %v", payment.ID, err)
+	}
+}
+
+// transition moves the purchase to status and records the change. The write
+// only succeeds if nobody changed the purchase's status since it was read.
+func (s *Service) transition(purchase *models.Purchase, status, reason string, userID *int) error {
+	return s.applyTransition(s.repo.TransitionPurchase, purchase, status, reason, userID)
+}
+
+// repriceTransition is transition for changes that also reprice the
+// purchase, whose new plan and amounts are saved with the transition
+func (s *Service) repriceTransition(purchase *models.Purchase, status, reason string, userID *int) error {
+	return s.applyTransition(s.repo.RepricePurchase, purchase, status, reason, userID)
+}
+
+func (s *Service) applyTransition(save func(*models.Purchase, string, *models.PurchaseTransition) (bool, error), purchase *models.Purchase, status, reason string, userID *int) error {
+	from := purchase.Status
+	if !canTransition(from, status) {
+		return domain.ErrInvalidTransition
+	}
+
+	purchase.Status = status
+	applied, err := save(purchase, from, &models.PurchaseTransition{
+		PurchaseID: purchase.ID,
+		FromStatus: from,
+		ToStatus:   status,
+		Reason:     reason,
+		UserID:     userID,
+		TenantID:   purchase.TenantID,
+	})
+	if err != nil {
+		purchase.Status = from
+		return fmt.Errorf("failed to update purchase: %w", err)
+	}
+	if !applied {
+		purchase.Status = from
+		return domain.ErrConcurrentUpdate
+	}
+
+	// A purchase that was never paid gives its coupon redemption back
+	if from == models.PurchaseStatusIncomplete && status == models.PurchaseStatusExpired && purchase.CouponID != nil {
+		if err := s.couponService.ReleaseForPurchase(purchase.ID, purchase.TenantID); err != nil {
+			log.Printf("Failed to release coupon redemption of purchase %d: %v", purchase.ID, err)
+		}
+	}
+
+	s.syncMRR(purchase)
+	s.rollups.Touch(purchase.TenantID, time.Now())
+	if from != status {
+		s.logPurchaseEnded(purchase, from, reason, userID)
+	}
+	return nil
+}
+
+// logPurchaseEnded records a purchase being cancelled or expiring, whoever
+// or whatever caused it. Other transitions are recorded where they're made.
+func (s *Service) logPurchaseEnded(purchase *models.Purchase, from, reason string, userID *int) {
+	var activityType models.ActivityType
+	var description string
+	switch purchase.Status {
+	case models.PurchaseStatusCancelled:
+		activityType = models.ActivityPurchaseCancelled
+		description = fmt.Sprintf("Cancelled purchase #%d", purchase.ID)
+	case models.PurchaseStatusExpired:
+		activityType = models.ActivityPurchaseExpired
+		description = fmt.Sprintf("Purchase #%d expired", purchase.ID)
+	default:
+		return
+	}
+
+	// Changes the scheduler or the provider made are the customer's
+	actorID := purchase.UserID
+	if userID != nil {
+		actorID = *userID
+	}
+	s.repo.CreateActivity(&models.Activity{
+		UserID:      actorID,
+		TenantID:    purchase.TenantID,
+		Type:        activityType,
+		Description: description,
+		EntityType:  models.ActivityEntityPurchase,
+		EntityID:    &purchase.ID,
+		Payload: models.JSONB{
+			"plan_id":     purchase.PlanID,
+			"from_status": from,
+			"reason":      reason,
+			"expires_at":  purchase.ExpiresAt,
+		},
+	})
+}
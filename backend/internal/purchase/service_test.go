@@ -0,0 +1,133 @@
+package purchase
+
+import (
+	"backend/core"
+	"backend/core/payment"
+	"backend/models"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// A renewal applies the scheduled downgrade and counts against the discount
+// only once its charge succeeds; a declined one leaves both for the retry
+func TestRenewPricesPeriodOnceCharged(t *testing.T) {
+	tests := []struct {
+		name          string
+		paymentMethod string
+		scheduled     bool // a downgrade to plan 1 is scheduled
+		discountLeft  *int // renewals still discounted by 10
+		wantStatus    string
+		wantPlan      int
+		wantAmount    float64
+		wantLeft      *int
+	}{
+		{"downgrade charged", "pm_card", true, nil, models.PurchaseStatusActive, 1, 11, nil},
+		{"downgrade declined", payment.FakeMethodDeclined, true, nil, models.PurchaseStatusPastDue, 2, 33, nil},
+		{"discount charged", "pm_card", false, intPtr(1), models.PurchaseStatusActive, 2, 22, intPtr(0)},
+		{"discount declined", payment.FakeMethodDeclined, false, intPtr(1), models.PurchaseStatusPastDue, 2, 22, intPtr(1)},
+		{"discount ends", "pm_card", false, intPtr(0), models.PurchaseStatusActive, 2, 33, nil},
+		{"discount ends declined", payment.FakeMethodDeclined, false, intPtr(0), models.PurchaseStatusPastDue, 2, 22, intPtr(0)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			purchase := newTestPurchase(2, now.AddDate(0, -1, 0), now.Add(-time.Hour))
+			purchase.PaymentMethod = tt.paymentMethod
+			if tt.scheduled {
+				scheduled := 1
+				purchase.ScheduledPlanID = &scheduled
+			}
+			if tt.discountLeft != nil {
+				net := 20.0
+				purchase.DiscountAmount = 10
+				purchase.DiscountPeriodsLeft = tt.discountLeft
+				purchase.NetAmount, purchase.TaxAmount, purchase.Amount = &net, 2, 22
+			}
+			repo := &memoryPurchaseRepository{purchase: purchase, plans: testPlans}
+			service := newTestService(repo, payment.NewFakeGateway(&core.Config{}))
+
+			due := repo.purchase
+			if err := service.renew(&due, now); err != nil {
+				t.Fatalf("renew: %v", err)
+			}
+
+			got := repo.purchase
+			if got.Status != tt.wantStatus || got.PlanID != tt.wantPlan || got.Amount != tt.wantAmount {
+				t.Errorf("purchase is %s on plan %d at %v, want %s on plan %d at %v", got.Status, got.PlanID, got.Amount, tt.wantStatus, tt.wantPlan, tt.wantAmount)
+			}
+			if (got.ScheduledPlanID != nil) != (tt.scheduled && tt.wantPlan == 2) {
+				t.Errorf("scheduled plan = %v", got.ScheduledPlanID)
+			}
+			if (got.DiscountPeriodsLeft == nil) != (tt.wantLeft == nil) || (tt.wantLeft != nil && *got.DiscountPeriodsLeft != *tt.wantLeft) {
+				t.Errorf("discount periods left = %s, want %s", periods(got.DiscountPeriodsLeft), periods(tt.wantLeft))
+			}
+			if len(repo.payments) != 1 {
+				t.Fatalf("recorded %d payments, want 1", len(repo.payments))
+			}
+			if tt.wantStatus == models.PurchaseStatusActive && repo.payments[0].Amount != tt.wantAmount {
+				t.Errorf("charged %v, want the new period's %v", repo.payments[0].Amount, tt.wantAmount)
+			}
+			if due.PlanID != got.PlanID || due.Amount != got.Amount || due.Status != got.Status {
+				t.Errorf("renewed purchase is %s on plan %d at %v, but %s on plan %d at %v is saved", due.Status, due.PlanID, due.Amount, got.Status, got.PlanID, got.Amount)
+			}
+		})
+	}
+}
+
+// A coupon discounts the first period, its first DurationPeriods periods or
+// every period; renewals after that are charged the list price
+func TestCouponDurationAcrossRenewals(t *testing.T) {
+	full, discounted := 33.0, 16.5
+	tests := []struct {
+		name    string
+		coupon  models.Coupon
+		charged []float64 // the four renewals after the first period
+	}{
+		{"once", models.Coupon{Duration: models.CouponDurationOnce}, []float64{full, full, full, full}},
+		{"repeating", models.Coupon{Duration: models.CouponDurationRepeating, DurationPeriods: 3}, []float64{discounted, discounted, full, full}},
+		{"forever", models.Coupon{Duration: models.CouponDurationForever}, []float64{discounted, discounted, discounted, discounted}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryPurchaseRepository{plans: testPlans}
+			service := newTestService(repo, payment.NewFakeGateway(&core.Config{}))
+
+			start := time.Now().AddDate(0, -1, 0)
+			end := start.AddDate(0, 1, 0)
+			quote := &models.PurchaseQuote{ListPrice: 30, DiscountAmount: 15, TaxQuote: models.TaxQuote{NetAmount: 15, TaxAmount: 1.5, GrossAmount: discounted, Rate: 10}}
+			purchase := service.newPurchase(testPlans[2], quote, &tt.coupon, 2, 4, "pi_initial", "pm_card")
+			purchase.ID, purchase.Plan, purchase.Status = 1, testPlans[2], models.PurchaseStatusActive
+			purchase.CurrentPeriodStart, purchase.ExpiresAt = &start, &end
+			repo.purchase = *purchase
+
+			for i := range tt.charged {
+				due := repo.purchase
+				if err := service.renew(&due, repo.purchase.ExpiresAt.Add(time.Hour)); err != nil {
+					t.Fatalf("renewal %d: %v", i+1, err)
+				}
+			}
+
+			if len(repo.payments) != len(tt.charged) {
+				t.Fatalf("recorded %d payments, want %d", len(repo.payments), len(tt.charged))
+			}
+			for i, payment := range repo.payments {
+				if payment.Amount != tt.charged[i] {
+					t.Errorf("renewal %d charged %v, want %v", i+1, payment.Amount, tt.charged[i])
+				}
+			}
+		})
+	}
+}
+
+func intPtr(n int) *int {
+	return &n
+}
+
+// periods formats the discount periods left of a purchase
+func periods(left *int) string {
+	if left == nil {
+		return "none"
+	}
+	return strconv.Itoa(*left)
+}
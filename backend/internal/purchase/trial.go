@@ -0,0 +1,154 @@
+package purchase
+
+import (
+	"backend/models"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// trialReminderBatchSize bounds the reminders sent per scheduler run
+const trialReminderBatchSize = 200
+
+// trialAvailable reports whether buying the plan starts a free trial. Each
+// user gets one trial per product.
+func (s *Service) trialAvailable(plan *models.Plan, userID, tenantID int) (bool, error) {
+	if plan.TrialDays <= 0 || !isRecurring(plan.Interval) {
+		return false, nil
+	}
+	used, err := s.repo.HasUsedTrial(userID, plan.ProductID, tenantID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check trial eligibility: %w", err)
+	}
+	return !used, nil
+}
+
+// startTrial records a trialing purchase at the quoted price, charged for
+// the first time when the trial ends. A user who started another trial of
+// the product in the meantime pays straight away instead.
+func (s *Service) startTrial(plan *models.Plan, quote *models.PurchaseQuote, coupon *models.Coupon, userID, tenantID int, paymentMethod string) (*models.Purchase, error) {
+	purchase := s.newPurchase(plan, quote, coupon, userID, tenantID, trialTransactionID(), paymentMethod)
+
+	now := time.Now()
+	trialEnd := now.AddDate(0, 0, plan.TrialDays)
+	purchase.Status = models.PurchaseStatusTrialing
+	purchase.PurchasedAt = now
+	purchase.CurrentPeriodStart = &now
+	purchase.ExpiresAt = &trialEnd
+	purchase.TrialEndsAt = &trialEnd
+
+	created, err := s.repo.CreateTrialPurchase(purchase, plan.ProductID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create purchase: %w", err)
+	}
+	if !created {
+		if paymentMethod == "" {
+			return nil, errors.New("trial of this product was already used; payment method is required")
+		}
+		return s.startPurchase(plan, quote, coupon, userID, tenantID, paymentMethod)
+	}
+	s.rollups.Touch(tenantID, purchase.CreatedAt)
+
+	s.repo.CreateTransition(&models.PurchaseTransition{
+		PurchaseID: purchase.ID,
+		ToStatus:   purchase.Status,
+		Reason:     "trial_started",
+		UserID:     &userID,
+		TenantID:   tenantID,
+	})
+
+	s.repo.CreateActivity(&models.Activity{
+		UserID:      userID,
+		TenantID:    tenantID,
+		Type:        models.ActivityTrialStarted,
+		Description: fmt.Sprintf("Started a %d-day trial of plan '%s'", plan.TrialDays, plan.Name),
+		EntityType:  models.ActivityEntityPurchase,
+		EntityID:    &purchase.ID,
+		Payload: models.JSONB{
+			"plan_id":       plan.ID,
+			"trial_days":    plan.TrialDays,
+			"trial_ends_at": purchase.TrialEndsAt,
+		},
+	})
+
+	return purchase, nil
+}
+
+// convertTrial starts the first paid period of a trial that has ended. A
+// trial without a payment method expires; a failed charge moves the purchase
+// to past_due like a failed renewal.
+func (s *Service) convertTrial(purchase *models.Purchase, now time.Time) error {
+	if purchase.PaymentMethod == "" {
+		return s.transition(purchase, models.PurchaseStatusExpired, "trial_expired", nil)
+	}
+
+	if err := s.renew(purchase, now); err != nil {
+		return err
+	}
+
+	if purchase.Status == models.PurchaseStatusActive {
+		s.repo.CreateActivity(&models.Activity{
+			UserID:      purchase.UserID,
+			TenantID:    purchase.TenantID,
+			Type:        models.ActivityTrialConverted,
+			Description: fmt.Sprintf("Trial of plan '%s' converted for $%.2f", purchase.Plan.Name, purchase.Amount),
+			EntityType:  models.ActivityEntityPurchase,
+			EntityID:    &purchase.ID,
+			Payload: models.JSONB{
+				"plan_id":  purchase.PlanID,
+				"amount":   purchase.Amount,
+				"currency": purchase.Currency,
+			},
+		})
+	}
+	return nil
+}
+
+// ProcessTrialReminders emails customers whose trial ends within the
+// configured number of days, once per trial. It returns the number of
+// reminders sent.
+func (s *Service) ProcessTrialReminders(now time.Time) (int, error) {
+	if s.cfg.TrialReminderDays <= 0 {
+		return 0, nil
+	}
+
+	trials, err := s.repo.GetTrialsEndingBetween(now, now.AddDate(0, 0, s.cfg.TrialReminderDays), trialReminderBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range trials {
+		purchase := &trials[i]
+		if purchase.User == nil || purchase.Plan == nil {
+			continue
+		}
+
+		err := s.emailService.SendTrialReminderEmail(purchase.User.Email, purchase.Plan.Name, *purchase.TrialEndsAt,
+			purchase.Amount, purchase.Currency, purchase.PaymentMethod != "")
+		if err != nil {
+			log.Printf("Failed to send trial reminder for purchase %d: %v", purchase.ID, err)
+			continue
+		}
+
+		purchase.TrialReminderSentAt = &now
+		if err := s.repo.MarkTrialReminderSent(purchase); err != nil {
+			log.Printf("Failed to mark trial reminder of purchase %d as sent: %v", purchase.ID, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// trialTransactionID identifies a trial purchase, which has no payment
+// intent until the trial converts
+func trialTransactionID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return "trial_" + hex.EncodeToString(b)
+}
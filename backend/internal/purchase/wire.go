@@ -4,13 +4,23 @@
 package purchase
 
 import (
+	"backend/core"
+	coreDomain "backend/core/domain"
 	"github.com/google/wire"
 	"gorm.io/gorm"
 )
 
-func NewControllerWire(db *gorm.DB) *Controller {
+func NewControllerWire(db *gorm.DB, cfg *core.Config, gateway coreDomain.PaymentGateway) *Controller {
 	wire.Build(
 		ProviderSet,
 	)
 	return &Controller{}
 }
+
+// NewServiceWire builds the purchase service for background jobs
+func NewServiceWire(db *gorm.DB, cfg *core.Config, gateway coreDomain.PaymentGateway) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
@@ -7,14 +7,53 @@
 package purchase
 
 import (
+	"backend/core"
+	"backend/core/domain"
+	"backend/core/email"
+	"backend/internal/billing"
+	"backend/internal/coupon"
+	"backend/internal/invoice"
+	"backend/internal/rollup"
+	"backend/internal/tax"
 	"gorm.io/gorm"
 )
 
 // Injectors from wire.go:
 
-func NewControllerWire(db *gorm.DB) *Controller {
+func NewControllerWire(db *gorm.DB, cfg *core.Config, gateway domain.PaymentGateway) *Controller {
 	repository := NewRepository(db)
-	service := NewService(repository)
-	controller := NewController(service)
+	invoiceRepository := invoice.NewRepository(db)
+	billingRepository := billing.NewRepository(db)
+	service := billing.NewService(billingRepository)
+	emailService := email.NewEmailService(cfg)
+	invoiceService := invoice.NewService(invoiceRepository, service, emailService)
+	taxRepository := tax.NewRepository(db)
+	formatValidator := tax.NewFormatValidator()
+	ruleEngine := tax.NewRuleEngine(taxRepository, formatValidator)
+	couponRepository := coupon.NewRepository(db)
+	couponService := coupon.NewService(couponRepository)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	purchaseService := NewService(repository, cfg, gateway, invoiceService, service, ruleEngine, couponService, emailService, rollupService)
+	controller := NewController(purchaseService)
 	return controller
-}
\ No newline at end of file
+}
+
+// NewServiceWire builds the purchase service for background jobs
+func NewServiceWire(db *gorm.DB, cfg *core.Config, gateway domain.PaymentGateway) *Service {
+	repository := NewRepository(db)
+	invoiceRepository := invoice.NewRepository(db)
+	billingRepository := billing.NewRepository(db)
+	service := billing.NewService(billingRepository)
+	emailService := email.NewEmailService(cfg)
+	invoiceService := invoice.NewService(invoiceRepository, service, emailService)
+	taxRepository := tax.NewRepository(db)
+	formatValidator := tax.NewFormatValidator()
+	ruleEngine := tax.NewRuleEngine(taxRepository, formatValidator)
+	couponRepository := coupon.NewRepository(db)
+	couponService := coupon.NewService(couponRepository)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	purchaseService := NewService(repository, cfg, gateway, invoiceService, service, ruleEngine, couponService, emailService, rollupService)
+	return purchaseService
+}
@@ -0,0 +1,319 @@
+package rollup
+
+import (
+	coreDomain "backend/core/domain"
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// purchaseStatusAt is a purchase's status at a given time: that of its last
+// transition before then, or its current status if it has none
+const purchaseStatusAt = `COALESCE((SELECT t.to_status FROM purchase_transitions t
+	WHERE t.purchase_id = purchases.id AND t.created_at < ?
+	ORDER BY t.created_at DESC, t.id DESC LIMIT 1), purchases.status)`
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// MarkStale records that the tenant's rollups of the days need a refresh
+func (r *Repository) MarkStale(tenantID int, days ...time.Time) error {
+	if len(days) == 0 {
+		return nil
+	}
+	refreshes := make([]models.RollupRefresh, len(days))
+	for i, day := range days {
+		refreshes[i] = models.RollupRefresh{TenantID: tenantID, Day: day}
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).
+		CreateInBatches(refreshes, 500).Error
+}
+
+// TakeStale removes and returns up to limit of the oldest stale days. Days
+// another instance is taking at the same time are skipped.
+func (r *Repository) TakeStale(limit int) ([]models.RollupRefresh, error) {
+	var refreshes []models.RollupRefresh
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Order("created_at").
+			Limit(limit).
+			Find(&refreshes).Error; err != nil {
+			return err
+		}
+		for _, refresh := range refreshes {
+			if err := tx.Where("tenant_id = ? AND day = ?", refresh.TenantID, refresh.Day).
+				Delete(&models.RollupRefresh{}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return refreshes, err
+}
+
+// GetTenantIDs returns the IDs of all tenants
+func (r *Repository) GetTenantIDs() ([]int, error) {
+	var ids []int
+	err := r.db.Model(&models.Tenant{}).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *Repository) GetTenant(id int) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.First(&tenant, id).Error; err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+func (r *Repository) GetTenantRollup(tenantID int, day time.Time) (*models.DailyTenantRollup, error) {
+	var rollup models.DailyTenantRollup
+	err := r.db.Where("tenant_id = ? AND day = ?", tenantID, day).First(&rollup).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rollup, nil
+}
+
+// SaveTenantRollup creates or replaces the tenant's rollup of its day
+func (r *Repository) SaveTenantRollup(rollup *models.DailyTenantRollup) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"products", "active_plans", "team_members", "new_users", "active_purchases", "active_users", "last_activity_at", "updated_at"}),
+	}).Create(rollup).Error
+}
+
+// ReplaceRevenueRollups replaces the tenant's revenue rollups of the day
+func (r *Repository) ReplaceRevenueRollups(tenantID int, day time.Time, rollups []models.DailyRevenueRollup) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND day = ?", tenantID, day).
+			Delete(&models.DailyRevenueRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rollups) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "day"}, {Name: "plan_id"}, {Name: "currency"}},
+			UpdateAll: true,
+		}).Create(&rollups).Error
+	})
+}
+
+// DeleteTenantRollups deletes all of the tenant's rollups
+func (r *Repository) DeleteTenantRollups(tenantID int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ?", tenantID).Delete(&models.DailyRevenueRollup{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("tenant_id = ?", tenantID).Delete(&models.DailyTenantRollup{}).Error
+	})
+}
+
+// CountTenant counts the tenant's products, plans and users as they were at end
+func (r *Repository) CountTenant(tenantID int, start, end time.Time) (*models.DailyTenantRollup, error) {
+	rollup := &models.DailyTenantRollup{TenantID: tenantID, Day: start}
+	var products, plans, members, newUsers, activePurchases, activeUsers int64
+
+	err := r.db.Unscoped().Model(&models.Product{}).
+		Where("tenant_id = ? AND active = ? AND created_at < ?", tenantID, true, end).
+		Where("(deleted_at IS NULL OR deleted_at >= ?) AND (archived_at IS NULL OR archived_at >= ?)", end, end).
+		Count(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Unscoped().Model(&models.Plan{}).
+		Joins("JOIN products ON plans.product_id = products.id").
+		Where("plans.tenant_id = ? AND products.active = ? AND plans.created_at < ?", tenantID, true, end).
+		Where("(plans.deleted_at IS NULL OR plans.deleted_at >= ?) AND (plans.archived_at IS NULL OR plans.archived_at >= ?)", end, end).
+		Where("(products.deleted_at IS NULL OR products.deleted_at >= ?) AND (products.archived_at IS NULL OR products.archived_at >= ?)", end, end).
+		Count(&plans).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Unscoped().Model(&models.User{}).
+		Where("tenant_id = ? AND created_at < ? AND (deleted_at IS NULL OR deleted_at >= ?)", tenantID, end, end).
+		Count(&members).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Unscoped().Model(&models.User{}).
+		Where("tenant_id = ? AND created_at >= ? AND created_at < ?", tenantID, start, end).
+		Count(&newUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Purchase{}).
+		Where("tenant_id = ? AND created_at < ?", tenantID, end).
+		Where(purchaseStatusAt+" = ?", end, models.PurchaseStatusActive).
+		Count(&activePurchases).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&models.Purchase{}).
+		Distinct("user_id").
+		Where("tenant_id = ? AND created_at < ?", tenantID, end).
+		Where(purchaseStatusAt+" IN ?", end, models.EntitledPurchaseStatuses).
+		Count(&activeUsers).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var lastActivity struct{ At *time.Time }
+	err = r.db.Model(&models.Activity{}).
+		Where("tenant_id = ? AND created_at < ?", tenantID, end).
+		Select("MAX(created_at) AS at").
+		Scan(&lastActivity).Error
+	if err != nil {
+		return nil, err
+	}
+
+	rollup.Products = int(products)
+	rollup.ActivePlans = int(plans)
+	rollup.TeamMembers = int(members)
+	rollup.NewUsers = int(newUsers)
+	rollup.ActivePurchases = int(activePurchases)
+	rollup.ActiveUsers = int(activeUsers)
+	rollup.LastActivityAt = lastActivity.At
+	return rollup, nil
+}
+
+// planCurrencyTotals holds the sums of one plan and currency
+type planCurrencyTotals struct {
+	PlanID   int
+	Currency string
+	Type     string
+	Count    int
+	Amount   float64
+	Tax      float64
+}
+
+// SumRevenue aggregates the tenant's payments, declined payments, refunds and MRR movements
+// between start and end by plan and currency, with the MRR at end
+func (r *Repository) SumRevenue(tenantID int, start, end time.Time) ([]models.DailyRevenueRollup, error) {
+	type planCurrency struct {
+		planID   int
+		currency string
+	}
+	rollups := make(map[planCurrency]*models.DailyRevenueRollup)
+	rollup := func(planID int, currency string) *models.DailyRevenueRollup {
+		key := planCurrency{planID, currency}
+		if rollups[key] == nil {
+			rollups[key] = &models.DailyRevenueRollup{TenantID: tenantID, Day: start, PlanID: planID, Currency: currency}
+		}
+		return rollups[key]
+	}
+
+	var payments []planCurrencyTotals
+	err := r.db.Model(&models.Payment{}).
+		Joins("JOIN purchases ON purchases.id = payments.purchase_id").
+		Where("payments.tenant_id = ? AND payments.status IN ? AND payments.created_at >= ? AND payments.created_at < ?",
+			tenantID, models.CollectedPaymentStatuses, start, end).
+		Select("purchases.plan_id, payments.currency, COUNT(*) AS count, " +
+			"COALESCE(SUM(COALESCE(payments.net_amount, payments.amount)), 0) AS amount, COALESCE(SUM(payments.tax_amount), 0) AS tax").
+		Group("purchases.plan_id, payments.currency").
+		Scan(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range payments {
+		row := rollup(total.PlanID, total.Currency)
+		row.Payments = total.Count
+		row.Revenue = total.Amount
+		row.Tax += total.Tax
+	}
+
+	var declined []planCurrencyTotals
+	err = r.db.Model(&models.Payment{}).
+		Joins("JOIN purchases ON purchases.id = payments.purchase_id").
+		Where("payments.tenant_id = ? AND payments.status = ? AND payments.created_at >= ? AND payments.created_at < ?",
+			tenantID, coreDomain.PaymentStatusDeclined, start, end).
+		Select("purchases.plan_id, payments.currency, COUNT(*) AS count").
+		Group("purchases.plan_id, payments.currency").
+		Scan(&declined).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range declined {
+		rollup(total.PlanID, total.Currency).FailedPayments = total.Count
+	}
+
+	var refunds []planCurrencyTotals
+	err = r.db.Model(&models.Refund{}).
+		Joins("JOIN purchases ON purchases.id = refunds.purchase_id").
+		Where("refunds.tenant_id = ? AND refunds.created_at >= ? AND refunds.created_at < ?", tenantID, start, end).
+		Select("purchases.plan_id, refunds.currency, COALESCE(SUM(refunds.net_amount), 0) AS amount, COALESCE(SUM(refunds.tax_amount), 0) AS tax").
+		Group("purchases.plan_id, refunds.currency").
+		Scan(&refunds).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range refunds {
+		row := rollup(total.PlanID, total.Currency)
+		row.Refunds = total.Amount
+		row.Tax -= total.Tax
+	}
+
+	var movements []planCurrencyTotals
+	err = r.db.Model(&models.MRRMovement{}).
+		Where("tenant_id = ? AND occurred_at >= ? AND occurred_at < ?", tenantID, start, end).
+		Select("plan_id, currency, type, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS amount").
+		Group("plan_id, currency, type").
+		Scan(&movements).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range movements {
+		row := rollup(total.PlanID, total.Currency)
+		switch total.Type {
+		case models.MRRMovementNew:
+			row.NewSubscriptions = total.Count
+			row.NewMRR = total.Amount
+		case models.MRRMovementExpansion:
+			row.ExpansionMRR = total.Amount
+		case models.MRRMovementContraction:
+			row.ContractionMRR = -total.Amount
+		case models.MRRMovementChurn:
+			row.CancelledSubscriptions = total.Count
+			row.ChurnedMRR = -total.Amount
+		}
+	}
+
+	// The latest movement of each purchase holds its MRR at end
+	latest := r.db.Model(&models.MRRMovement{}).
+		Select("DISTINCT ON (purchase_id) plan_id, currency, mrr").
+		Where("tenant_id = ? AND occurred_at < ?", tenantID, end).
+		Order("purchase_id, occurred_at DESC, id DESC")
+	var closing []planCurrencyTotals
+	err = r.db.Table("(?) AS latest", latest).
+		Select("plan_id, currency, COUNT(*) AS count, SUM(mrr) AS amount").
+		Where("mrr > 0").
+		Group("plan_id, currency").
+		Scan(&closing).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, total := range closing {
+		row := rollup(total.PlanID, total.Currency)
+		row.Subscribers = total.Count
+		row.MRR = total.Amount
+	}
+
+	result := make([]models.DailyRevenueRollup, 0, len(rollups))
+	for _, row := range rollups {
+		result = append(result, *row)
+	}
+	return result, nil
+}
@@ -0,0 +1,225 @@
+package rollup
+
+import (
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// refreshBatchSize bounds the stale days refreshed per run
+const refreshBatchSize = 200
+
+// Service maintains the daily analytics rollups. Days start at midnight in
+// the tenant's timezone. Changes mark the tenant's day stale, and a
+// background job refreshes stale days shortly after. A nightly
+// reconciliation refreshes recent days of every tenant in case a change was
+// missed.
+type Service struct {
+	repo domain.RollupRepository
+}
+
+func NewService(repo domain.RollupRepository) *Service {
+	return &Service{repo: repo}
+}
+
+// Touch marks the tenant's rollups of the day containing at as out of date.
+// A day it fails to mark is recomputed by the next reconciliation.
+func (s *Service) Touch(tenantID int, at time.Time) {
+	location, err := s.location(tenantID)
+	if err == nil {
+		err = s.repo.MarkStale(tenantID, dayStart(at, location))
+	}
+	if err != nil {
+		log.Printf("Failed to mark rollups of tenant %d stale: %v", tenantID, err)
+	}
+}
+
+// Refresh recomputes the tenant's rollups of the day containing at, in the
+// tenant's timezone, from the raw tables
+func (s *Service) Refresh(tenantID int, at time.Time) error {
+	location, err := s.location(tenantID)
+	if err != nil {
+		return err
+	}
+	return s.refreshDay(tenantID, dayStart(at, location))
+}
+
+// refreshDay recomputes the tenant's rollups of the day starting at day. The
+// day ends at the next midnight of day's location, so it may be 23 or 25
+// hours long across a DST change.
+func (s *Service) refreshDay(tenantID int, day time.Time) error {
+	end := day.AddDate(0, 0, 1)
+
+	revenue, err := s.repo.SumRevenue(tenantID, day, end)
+	if err != nil {
+		return fmt.Errorf("failed to sum revenue: %w", err)
+	}
+	for i := range revenue {
+		roundRevenue(&revenue[i])
+	}
+	if err := s.repo.ReplaceRevenueRollups(tenantID, day, revenue); err != nil {
+		return fmt.Errorf("failed to save revenue rollups: %w", err)
+	}
+
+	counts, err := s.repo.CountTenant(tenantID, day, end)
+	if err != nil {
+		return fmt.Errorf("failed to count tenant: %w", err)
+	}
+	if err := s.repo.SaveTenantRollup(counts); err != nil {
+		return fmt.Errorf("failed to save tenant rollup: %w", err)
+	}
+	return nil
+}
+
+// GetTenantDay returns the tenant's rollup of the day containing at,
+// computing it first if it doesn't exist yet
+func (s *Service) GetTenantDay(tenantID int, at time.Time) (*models.DailyTenantRollup, error) {
+	location, err := s.location(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	day := dayStart(at, location)
+	rollup, err := s.repo.GetTenantRollup(tenantID, day)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if err := s.refreshDay(tenantID, day); err != nil {
+			return nil, err
+		}
+		rollup, err = s.repo.GetTenantRollup(tenantID, day)
+	}
+	return rollup, err
+}
+
+// ProcessStale refreshes the days marked stale. A day that fails is marked
+// stale again for the next run.
+func (s *Service) ProcessStale() (int, error) {
+	refreshes, err := s.repo.TakeStale(refreshBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, refresh := range refreshes {
+		if err := s.Refresh(refresh.TenantID, refresh.Day); err != nil {
+			log.Printf("Failed to refresh rollups of tenant %d for %s: %v", refresh.TenantID, refresh.Day.Format("2006-01-02"), err)
+			if err := s.repo.MarkStale(refresh.TenantID, refresh.Day); err != nil {
+				log.Printf("Failed to mark rollups of tenant %d stale: %v", refresh.TenantID, err)
+			}
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Reconcile refreshes the last days days of every tenant up to the one
+// containing now. A tenant that fails is logged and left to the next run.
+func (s *Service) Reconcile(now time.Time, days int) (int, error) {
+	tenantIDs, err := s.repo.GetTenantIDs()
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, tenantID := range tenantIDs {
+		location, err := s.location(tenantID)
+		if err != nil {
+			log.Printf("Failed to reconcile rollups of tenant %d: %v", tenantID, err)
+			continue
+		}
+		today := dayStart(now, location)
+		for i := days - 1; i >= 0; i-- {
+			day := today.AddDate(0, 0, -i)
+			if err := s.refreshDay(tenantID, day); err != nil {
+				log.Printf("Failed to reconcile rollups of tenant %d for %s: %v", tenantID, day.Format("2006-01-02"), err)
+				break
+			}
+			refreshed++
+		}
+	}
+	return refreshed, nil
+}
+
+// Backfill builds the tenant's rollups of every day from the date from
+// through the date to, in the tenant's timezone; only the dates' year, month
+// and day are used. A zero from starts at the day the tenant was created and
+// a zero to ends today.
+func (s *Service) Backfill(tenantID int, from, to time.Time) (int, error) {
+	tenant, err := s.repo.GetTenant(tenantID)
+	if err != nil {
+		return 0, errors.New("tenant not found")
+	}
+	location := tenant.Location()
+
+	first := dayStart(tenant.CreatedAt, location)
+	if !from.IsZero() {
+		first = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)
+	}
+	last := dayStart(time.Now(), location)
+	if !to.IsZero() {
+		last = time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, location)
+	}
+
+	refreshed := 0
+	for day := first; !day.After(last); day = day.AddDate(0, 0, 1) {
+		if err := s.refreshDay(tenantID, day); err != nil {
+			return refreshed, fmt.Errorf("%s: %w", day.Format("2006-01-02"), err)
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Rebuild drops the tenant's rollups and marks every day since the tenant
+// was created stale, so the refresh job rebuilds them in the background.
+// It's needed when the tenant's timezone, and so where its days start,
+// changes.
+func (s *Service) Rebuild(tenantID int) error {
+	tenant, err := s.repo.GetTenant(tenantID)
+	if err != nil {
+		return errors.New("tenant not found")
+	}
+	location := tenant.Location()
+
+	if err := s.repo.DeleteTenantRollups(tenantID); err != nil {
+		return fmt.Errorf("failed to delete rollups: %w", err)
+	}
+
+	var days []time.Time
+	today := dayStart(time.Now(), location)
+	for day := dayStart(tenant.CreatedAt, location); !day.After(today); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return s.repo.MarkStale(tenantID, days...)
+}
+
+// location returns the timezone the tenant's days start in
+func (s *Service) location(tenantID int) (*time.Location, error) {
+	tenant, err := s.repo.GetTenant(tenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+	return tenant.Location(), nil
+}
+
+// dayStart returns the start of the day containing t in location
+func dayStart(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+func roundRevenue(row *models.DailyRevenueRollup) {
+	row.Revenue = money.Round(row.Revenue)
+	row.Refunds = money.Round(row.Refunds)
+	row.Tax = money.Round(row.Tax)
+	row.NewMRR = money.Round(row.NewMRR)
+	row.ExpansionMRR = money.Round(row.ExpansionMRR)
+	row.ContractionMRR = money.Round(row.ContractionMRR)
+	row.ChurnedMRR = money.Round(row.ChurnedMRR)
+	row.MRR = money.Round(row.MRR)
+}
@@ -0,0 +1,119 @@
+package rollup
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"testing"
+	"time"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("failed to load %s: %v", name, err)
+	}
+	return location
+}
+
+// Days start at midnight in the tenant's timezone, so those of a DST change
+// are 23 or 25 hours long
+func TestDayStart(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	sydney := mustLoad(t, "Australia/Sydney")
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		location *time.Location
+		want     time.Time
+		hours    float64
+	}{
+		{"new york before spring forward", time.Date(2026, 3, 7, 17, 0, 0, 0, time.UTC), newYork, time.Date(2026, 3, 7, 0, 0, 0, 0, newYork), 24},
+		{"new york spring forward, standard time", time.Date(2026, 3, 8, 6, 30, 0, 0, time.UTC), newYork, time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), 23},
+		{"new york spring forward, daylight time", time.Date(2026, 3, 9, 3, 30, 0, 0, time.UTC), newYork, time.Date(2026, 3, 8, 0, 0, 0, 0, newYork), 23},
+		{"new york after spring forward", time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC), newYork, time.Date(2026, 3, 9, 0, 0, 0, 0, newYork), 24},
+		{"new york fall back, first 1:30", time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), newYork, time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), 25},
+		{"new york fall back, second 1:30", time.Date(2026, 11, 1, 6, 30, 0, 0, time.UTC), newYork, time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), 25},
+		{"new york fall back, late evening", time.Date(2026, 11, 2, 4, 30, 0, 0, time.UTC), newYork, time.Date(2026, 11, 1, 0, 0, 0, 0, newYork), 25},
+		{"sydney fall back", time.Date(2026, 4, 4, 16, 0, 0, 0, time.UTC), sydney, time.Date(2026, 4, 5, 0, 0, 0, 0, sydney), 25},
+		{"sydney before spring forward", time.Date(2026, 10, 3, 13, 30, 0, 0, time.UTC), sydney, time.Date(2026, 10, 3, 0, 0, 0, 0, sydney), 24},
+		{"sydney spring forward", time.Date(2026, 10, 3, 14, 30, 0, 0, time.UTC), sydney, time.Date(2026, 10, 4, 0, 0, 0, 0, sydney), 23},
+		{"sydney after spring forward", time.Date(2026, 10, 4, 13, 30, 0, 0, time.UTC), sydney, time.Date(2026, 10, 5, 0, 0, 0, 0, sydney), 24},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := dayStart(tt.at, tt.location)
+			if !got.Equal(tt.want) {
+				t.Fatalf("dayStart(%s) = %s, want %s", tt.at, got, tt.want)
+			}
+			if hours := got.AddDate(0, 0, 1).Sub(got).Hours(); hours != tt.hours {
+				t.Fatalf("day of %s is %vh long, want %vh", tt.at, hours, tt.hours)
+			}
+		})
+	}
+}
+
+// windowRepository records the windows rollups are computed over
+type windowRepository struct {
+	domain.RollupRepository
+	tenant  models.Tenant
+	windows [][2]time.Time
+}
+
+func (r *windowRepository) GetTenant(id int) (*models.Tenant, error) {
+	return &r.tenant, nil
+}
+
+func (r *windowRepository) SumRevenue(tenantID int, start, end time.Time) ([]models.DailyRevenueRollup, error) {
+	r.windows = append(r.windows, [2]time.Time{start, end})
+	return nil, nil
+}
+
+func (r *windowRepository) ReplaceRevenueRollups(tenantID int, day time.Time, rollups []models.DailyRevenueRollup) error {
+	return nil
+}
+
+func (r *windowRepository) CountTenant(tenantID int, start, end time.Time) (*models.DailyTenantRollup, error) {
+	r.windows = append(r.windows, [2]time.Time{start, end})
+	return &models.DailyTenantRollup{TenantID: tenantID, Day: start}, nil
+}
+
+func (r *windowRepository) SaveTenantRollup(rollup *models.DailyTenantRollup) error {
+	return nil
+}
+
+// A day's rollups cover the whole local day, not 24 hours from its start
+func TestRefreshCoversLocalDay(t *testing.T) {
+	newYork := mustLoad(t, "America/New_York")
+	sydney := mustLoad(t, "Australia/Sydney")
+
+	tests := []struct {
+		name     string
+		timezone string
+		at       time.Time
+		start    time.Time
+		end      time.Time
+	}{
+		{"new york 23h day", "America/New_York", time.Date(2026, 3, 8, 12, 0, 0, 0, newYork), time.Date(2026, 3, 8, 5, 0, 0, 0, time.UTC), time.Date(2026, 3, 9, 4, 0, 0, 0, time.UTC)},
+		{"new york 25h day", "America/New_York", time.Date(2026, 11, 1, 12, 0, 0, 0, newYork), time.Date(2026, 11, 1, 4, 0, 0, 0, time.UTC), time.Date(2026, 11, 2, 5, 0, 0, 0, time.UTC)},
+		{"sydney 25h day", "Australia/Sydney", time.Date(2026, 4, 5, 12, 0, 0, 0, sydney), time.Date(2026, 4, 4, 13, 0, 0, 0, time.UTC), time.Date(2026, 4, 5, 14, 0, 0, 0, time.UTC)},
+		{"sydney 23h day", "Australia/Sydney", time.Date(2026, 10, 4, 12, 0, 0, 0, sydney), time.Date(2026, 10, 3, 14, 0, 0, 0, time.UTC), time.Date(2026, 10, 4, 13, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &windowRepository{tenant: models.Tenant{ID: 1, Timezone: tt.timezone}}
+			if err := NewService(repo).Refresh(1, tt.at); err != nil {
+				t.Fatalf("Refresh: %v", err)
+			}
+			if len(repo.windows) == 0 {
+				t.Fatal("no rollups were computed")
+			}
+			for _, window := range repo.windows {
+				if !window[0].Equal(tt.start) || !window[1].Equal(tt.end) {
+					t.Fatalf("rollups cover [%s, %s), want [%s, %s)", window[0].UTC(), window[1].UTC(), tt.start, tt.end)
+				}
+			}
+		})
+	}
+}
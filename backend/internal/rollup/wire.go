@@ -0,0 +1,16 @@
+//go:build wireinject
+// +build wireinject
+
+package rollup
+
+import (
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewServiceWire(db *gorm.DB) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
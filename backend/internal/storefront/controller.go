@@ -0,0 +1,60 @@
+package storefront
+
+import (
+	"backend/internal/domain"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.StorefrontService
+}
+
+func NewController(service domain.StorefrontService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetCatalog returns the tenant's public catalogue of active products and plans
+func (c *Controller) GetCatalog(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(int)
+	tenantDomain := ctx.Locals("tenantDomain").(string)
+	locales, _ := ctx.Locals("locales").([]string)
+
+	catalog, err := c.service.GetCatalog(tenantID, tenantDomain, ctx.BaseURL(), locales)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  catalog,
+	})
+}
+
+// GetProductImage serves the decoded image of an active product
+func (c *Controller) GetProductImage(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid product ID",
+		})
+	}
+
+	image, contentType, err := c.service.GetProductImage(id, tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentType, contentType)
+	return ctx.Send(image)
+}
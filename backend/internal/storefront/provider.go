@@ -0,0 +1,22 @@
+package storefront
+
+import (
+	"backend/internal/domain"
+	"backend/internal/translation"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	translation.NewService,
+	translation.NewRepository,
+
+	wire.Bind(new(domain.StorefrontControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.StorefrontService), new(*Service)),
+	wire.Bind(new(domain.StorefrontRepository), new(*Repository)),
+	wire.Bind(new(domain.TranslationService), new(*translation.Service)),
+	wire.Bind(new(domain.TranslationRepository), new(*translation.Repository)),
+)
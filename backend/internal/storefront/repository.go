@@ -0,0 +1,48 @@
+package storefront
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetActiveProducts returns the tenant's active, unarchived products with their unarchived plans
+func (r *Repository) GetActiveProducts(tenantID int) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.Preload("Plans", func(db *gorm.DB) *gorm.DB {
+		return db.Where("archived_at IS NULL").Order("price ASC, id ASC")
+	}).
+		Where("tenant_id = ? AND active = ? AND archived_at IS NULL", tenantID, true).
+		Order("id ASC").
+		Find(&products).Error
+	return products, err
+}
+
+func (r *Repository) GetActiveProduct(id int, tenantID int) (*models.Product, error) {
+	var product models.Product
+	err := r.db.Where("id = ? AND tenant_id = ? AND active = ? AND archived_at IS NULL", id, tenantID, true).
+		First(&product).Error
+	if err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *Repository) GetFeaturesByProducts(productIDs []int, tenantID int) ([]models.Feature, error) {
+	var features []models.Feature
+	if len(productIDs) == 0 {
+		return features, nil
+	}
+	err := r.db.Where("product_id IN ? AND tenant_id = ?", productIDs, tenantID).
+		Order("id ASC").
+		Find(&features).Error
+	return features, err
+}
@@ -0,0 +1,122 @@
+package storefront
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type Service struct {
+	repo               domain.StorefrontRepository
+	translationService domain.TranslationService
+}
+
+func NewService(repo domain.StorefrontRepository, translationService domain.TranslationService) *Service {
+	return &Service{
+		repo:               repo,
+		translationService: translationService,
+	}
+}
+
+func (s *Service) GetCatalog(tenantID int, tenantDomain string, baseURL string, locales []string) (*models.PublicCatalog, error) {
+	products, err := s.repo.GetActiveProducts(tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load catalog")
+	}
+
+	if err := s.translationService.LocalizeProducts(products, tenantID, locales); err != nil {
+		return nil, err
+	}
+
+	productIDs := make([]int, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	features, err := s.repo.GetFeaturesByProducts(productIDs, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load catalog")
+	}
+
+	featuresByProduct := make(map[int][]models.PublicFeature)
+	for _, feature := range features {
+		featuresByProduct[feature.ProductID] = append(featuresByProduct[feature.ProductID], models.PublicFeature{
+			Key:         feature.Key,
+			Name:        feature.Name,
+			Description: feature.Description,
+			Type:        feature.Type,
+			Options:     feature.Options,
+		})
+	}
+
+	catalog := &models.PublicCatalog{
+		Tenant:   tenantDomain,
+		Products: make([]models.PublicProduct, 0, len(products)),
+	}
+
+	for _, product := range products {
+		publicProduct := models.PublicProduct{
+			ID:          product.ID,
+			Name:        product.Name,
+			Description: product.Description,
+			URL:         product.URL,
+			Locale:      product.Locale,
+			Features:    featuresByProduct[product.ID],
+			Plans:       make([]models.PublicPlan, 0, len(product.Plans)),
+		}
+		if publicProduct.Features == nil {
+			publicProduct.Features = []models.PublicFeature{}
+		}
+		if product.Image != "" {
+			// Images are served separately so the catalogue stays small and cacheable
+			publicProduct.ImageURL = fmt.Sprintf("%s/api/v1/public/catalog/products/%d/image?tenant=%s",
+				baseURL, product.ID, url.QueryEscape(tenantDomain))
+		}
+
+		for _, plan := range product.Plans {
+			publicProduct.Plans = append(publicProduct.Plans, models.PublicPlan{
+				ID:          plan.ID,
+				Name:        plan.Name,
+				Description: plan.Description,
+				Price:       plan.Price,
+				Currency:    plan.Currency,
+				Interval:    plan.Interval,
+				Features:    plan.Features,
+				Locale:      plan.Locale,
+			})
+		}
+
+		catalog.Products = append(catalog.Products, publicProduct)
+	}
+
+	return catalog, nil
+}
+
+// GetProductImage decodes the stored base64 data URL of an active product's image
+func (s *Service) GetProductImage(productID int, tenantID int) ([]byte, string, error) {
+	product, err := s.repo.GetActiveProduct(productID, tenantID)
+	if err != nil || product.Image == "" {
+		return nil, "", errors.New("image not found")
+	}
+
+	// Images are stored as "data:image/<type>;base64,<data>" by the product service
+	header, data, found := strings.Cut(product.Image, ",")
+	if !found || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		return nil, "", errors.New("invalid image data")
+	}
+	contentType := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", errors.New("invalid image data")
+	}
+
+	image, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, "", errors.New("invalid image data")
+	}
+
+	return image, contentType, nil
+}
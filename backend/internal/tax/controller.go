@@ -0,0 +1,124 @@
+package tax
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.TaxService
+}
+
+func NewController(service domain.TaxService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetTaxRules lists the tenant's tax rules
+func (c *Controller) GetTaxRules(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	rules, err := c.service.GetTaxRules(*tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  rules,
+	})
+}
+
+// CreateTaxRule adds a tax rate for a country or region
+func (c *Controller) CreateTaxRule(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	var req models.TaxRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	rule, err := c.service.CreateTaxRule(*tenantID, req)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"error": false,
+		"data":  rule,
+	})
+}
+
+// UpdateTaxRule replaces a tax rule
+func (c *Controller) UpdateTaxRule(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid tax rule ID",
+		})
+	}
+
+	var req models.TaxRuleRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	rule, err := c.service.UpdateTaxRule(id, *tenantID, req)
+	if err != nil {
+		status := fiber.StatusBadRequest
+		if err.Error() == "tax rule not found" {
+			status = fiber.StatusNotFound
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  rule,
+	})
+}
+
+// DeleteTaxRule removes a tax rule, falling back to the default rate
+func (c *Controller) DeleteTaxRule(ctx *fiber.Ctx) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid tax rule ID",
+		})
+	}
+
+	if err := c.service.DeleteTaxRule(id, *tenantID); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error":   false,
+		"message": "Tax rule deleted successfully",
+	})
+}
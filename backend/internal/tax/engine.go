@@ -0,0 +1,118 @@
+package tax
+
+import (
+	"backend/core/money"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"strings"
+)
+
+// reverseChargeName labels the zero-rated tax line of reverse charged sales
+const reverseChargeName = "VAT reverse charge"
+
+// RuleEngine calculates tax from the tenant's tax rules, falling back to the
+// platform's default rate table
+type RuleEngine struct {
+	repo      domain.TaxRepository
+	validator VATIDValidator
+}
+
+func NewRuleEngine(repo domain.TaxRepository, validator VATIDValidator) *RuleEngine {
+	return &RuleEngine{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+// Calculate splits the sale amount into net, tax and gross. The most specific
+// rule wins: the tenant's rule for the buyer's region, then for the country,
+// then the default table in the same order. Sales without a known buyer
+// country or a matching rule are untaxed. Cross-border EU sales to buyers
+// with a valid VAT ID are reverse charged at 0%.
+func (e *RuleEngine) Calculate(req models.TaxRequest) (*models.TaxQuote, error) {
+	if req.Amount < 0 {
+		return nil, errors.New("amount cannot be negative")
+	}
+	req.BuyerCountry = strings.ToUpper(req.BuyerCountry)
+	req.BuyerRegion = strings.ToUpper(strings.TrimSpace(req.BuyerRegion))
+
+	quote := &models.TaxQuote{
+		Country:          req.BuyerCountry,
+		Region:           req.BuyerRegion,
+		PricesIncludeTax: req.PricesIncludeTax,
+	}
+
+	switch {
+	case req.BuyerCountry == "":
+	case e.isReverseCharge(req):
+		quote.Name = reverseChargeName
+		quote.ReverseCharge = true
+	default:
+		name, rate, err := e.findRate(req.TenantID, req.BuyerCountry, req.BuyerRegion)
+		if err != nil {
+			return nil, err
+		}
+		quote.Name = name
+		quote.Rate = rate
+	}
+
+	if req.PricesIncludeTax {
+		quote.GrossAmount = money.Round(req.Amount)
+		quote.NetAmount = money.Round(req.Amount / (1 + quote.Rate/100))
+		quote.TaxAmount = money.Round(quote.GrossAmount - quote.NetAmount)
+	} else {
+		quote.NetAmount = money.Round(req.Amount)
+		quote.TaxAmount = money.Round(quote.NetAmount * quote.Rate / 100)
+		quote.GrossAmount = money.Round(quote.NetAmount + quote.TaxAmount)
+	}
+
+	return quote, nil
+}
+
+func (e *RuleEngine) isReverseCharge(req models.TaxRequest) bool {
+	return euCountries[req.SellerCountry] &&
+		euCountries[req.BuyerCountry] &&
+		req.SellerCountry != req.BuyerCountry &&
+		req.BuyerVATID != "" &&
+		e.validator.Validate(req.BuyerVATID, req.BuyerCountry)
+}
+
+// findRate resolves the tax name and rate for a buyer location
+func (e *RuleEngine) findRate(tenantID int, country, region string) (string, float64, error) {
+	rules, err := e.repo.GetRulesForCountry(tenantID, country)
+	if err != nil {
+		return "", 0, errors.New("failed to load tax rules")
+	}
+
+	var countryRule *models.TaxRule
+	for i := range rules {
+		if region != "" && rules[i].Region == region {
+			return rules[i].Name, rules[i].Rate, nil
+		}
+		if rules[i].Region == "" {
+			countryRule = &rules[i]
+		}
+	}
+	if countryRule != nil {
+		return countryRule.Name, countryRule.Rate, nil
+	}
+
+	var fallback *defaultRule
+	for i := range defaultRules {
+		if defaultRules[i].country != country {
+			continue
+		}
+		if region != "" && defaultRules[i].region == region {
+			return defaultRules[i].name, defaultRules[i].rate, nil
+		}
+		if defaultRules[i].region == "" {
+			fallback = &defaultRules[i]
+		}
+	}
+	if fallback != nil {
+		return fallback.name, fallback.rate, nil
+	}
+
+	return "", 0, nil
+}
@@ -0,0 +1,131 @@
+package tax
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"testing"
+)
+
+// memoryTaxRepository holds the tax rules of tenant 1
+type memoryTaxRepository struct {
+	domain.TaxRepository
+	rules []models.TaxRule
+}
+
+func (r *memoryTaxRepository) GetRulesForCountry(tenantID int, country string) ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	for _, rule := range r.rules {
+		if rule.TenantID == tenantID && rule.Country == country {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func newTestEngine(rules ...models.TaxRule) *RuleEngine {
+	return NewRuleEngine(&memoryTaxRepository{rules: rules}, NewFormatValidator())
+}
+
+// Exclusive prices get tax added on top of the rounded net; inclusive prices
+// are split so that net and tax add up to the price
+func TestCalculateRounding(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    float64
+		inclusive bool
+		want      models.TaxQuote
+	}{
+		{"exclusive", 9.99, false, models.TaxQuote{NetAmount: 9.99, TaxAmount: 1.9, GrossAmount: 11.89}},
+		{"inclusive", 11.89, true, models.TaxQuote{NetAmount: 9.99, TaxAmount: 1.9, GrossAmount: 11.89}},
+		{"inclusive round price", 10, true, models.TaxQuote{NetAmount: 8.4, TaxAmount: 1.6, GrossAmount: 10}},
+		{"exclusive fractional cents", 10.005, false, models.TaxQuote{NetAmount: 10.01, TaxAmount: 1.9, GrossAmount: 11.91}},
+		{"exclusive tax under a cent", 0.02, false, models.TaxQuote{NetAmount: 0.02, TaxAmount: 0, GrossAmount: 0.02}},
+		{"inclusive tax under a cent", 0.02, true, models.TaxQuote{NetAmount: 0.02, TaxAmount: 0, GrossAmount: 0.02}},
+		{"zero", 0, true, models.TaxQuote{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := newTestEngine().Calculate(models.TaxRequest{TenantID: 1, Amount: tt.amount, SellerCountry: "DE", BuyerCountry: "DE", PricesIncludeTax: tt.inclusive})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			if quote.NetAmount != tt.want.NetAmount || quote.TaxAmount != tt.want.TaxAmount || quote.GrossAmount != tt.want.GrossAmount {
+				t.Errorf("net %v + tax %v = %v, want %v + %v = %v", quote.NetAmount, quote.TaxAmount, quote.GrossAmount, tt.want.NetAmount, tt.want.TaxAmount, tt.want.GrossAmount)
+			}
+			if quote.Rate != 19 || quote.PricesIncludeTax != tt.inclusive {
+				t.Errorf("rate = %v, inclusive = %v", quote.Rate, quote.PricesIncludeTax)
+			}
+		})
+	}
+}
+
+// Only cross-border sales within the EU to a buyer with a valid VAT ID of
+// their country are reverse charged
+func TestCalculateReverseCharge(t *testing.T) {
+	tests := []struct {
+		name          string
+		seller, buyer string
+		vatID         string
+		wantReverse   bool
+		wantRate      float64
+	}{
+		{"valid VAT ID", "DE", "FR", "FR12345678901", true, 0},
+		{"lowercase VAT ID", "DE", "FR", "fr12345678901", true, 0},
+		{"Greek prefix", "DE", "GR", "EL123456789", true, 0},
+		{"no VAT ID", "DE", "FR", "", false, 20},
+		{"VAT ID of another country", "DE", "FR", "DE123456789", false, 20},
+		{"malformed VAT ID", "DE", "FR", "FR1", false, 20},
+		{"same country", "DE", "DE", "DE123456789", false, 19},
+		{"seller outside the EU", "US", "FR", "FR12345678901", false, 20},
+		{"buyer outside the EU", "DE", "GB", "GB123456789", false, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := newTestEngine().Calculate(models.TaxRequest{TenantID: 1, Amount: 100, SellerCountry: tt.seller, BuyerCountry: tt.buyer, BuyerVATID: tt.vatID})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			if quote.ReverseCharge != tt.wantReverse || quote.Rate != tt.wantRate {
+				t.Errorf("reverse charge = %v at %v%%, want %v at %v%%", quote.ReverseCharge, quote.Rate, tt.wantReverse, tt.wantRate)
+			}
+			if tt.wantReverse && (quote.Name != reverseChargeName || quote.TaxAmount != 0 || quote.GrossAmount != 100) {
+				t.Errorf("reverse charged quote = %+v", quote)
+			}
+		})
+	}
+}
+
+// The tenant's rule for the region wins over its rule for the country, which
+// wins over the default table, where the region again comes first
+func TestCalculateRulePrecedence(t *testing.T) {
+	tenantRules := []models.TaxRule{
+		{TenantID: 1, Country: "CA", Region: "QC", Name: "GST + QST", Rate: 14.975},
+		{TenantID: 1, Country: "US", Name: "Sales tax", Rate: 5},
+		{TenantID: 2, Country: "CA", Name: "Other tenant", Rate: 1},
+	}
+	tests := []struct {
+		name            string
+		country, region string
+		wantName        string
+		wantRate        float64
+	}{
+		{"tenant region rule", "CA", "QC", "GST + QST", 14.975},
+		{"default region rule", "CA", "ON", "HST", 13},
+		{"default country rule", "CA", "BC", "GST", 5},
+		{"tenant country rule over default region rule", "US", "NY", "Sales tax", 5},
+		{"normalized location", "ca", " on ", "HST", 13},
+		{"no rule", "BR", "", "", 0},
+		{"no buyer country", "", "", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := newTestEngine(tenantRules...).Calculate(models.TaxRequest{TenantID: 1, Amount: 100, SellerCountry: "US", BuyerCountry: tt.country, BuyerRegion: tt.region})
+			if err != nil {
+				t.Fatalf("Calculate: %v", err)
+			}
+			if quote.Name != tt.wantName || quote.Rate != tt.wantRate {
+				t.Errorf("%s at %v%%, want %s at %v%%", quote.Name, quote.Rate, tt.wantName, tt.wantRate)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package tax
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	NewRuleEngine,
+	NewFormatValidator,
+
+	wire.Bind(new(domain.TaxControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.TaxService), new(*Service)),
+	wire.Bind(new(domain.TaxEngine), new(*RuleEngine)),
+	wire.Bind(new(domain.TaxRepository), new(*Repository)),
+	wire.Bind(new(VATIDValidator), new(*FormatValidator)),
+)
@@ -0,0 +1,69 @@
+package tax
+
+// defaultRule is a row of the platform's built-in rate table
+type defaultRule struct {
+	country string
+	region  string
+	name    string
+	rate    float64
+}
+
+// defaultRules are the standard rates applied when a tenant has no rule of
+// its own for the buyer's location. Tenants override them with tax rules.
+var defaultRules = []defaultRule{
+	// EU VAT
+	{"AT", "", "VAT", 20},
+	{"BE", "", "VAT", 21},
+	{"BG", "", "VAT", 20},
+	{"CY", "", "VAT", 19},
+	{"CZ", "", "VAT", 21},
+	{"DE", "", "VAT", 19},
+	{"DK", "", "VAT", 25},
+	{"EE", "", "VAT", 22},
+	{"ES", "", "VAT", 21},
+	{"FI", "", "VAT", 25.5},
+	{"FR", "", "VAT", 20},
+	{"GR", "", "VAT", 24},
+	{"HR", "", "VAT", 25},
+	{"HU", "", "VAT", 27},
+	{"IE", "", "VAT", 23},
+	{"IT", "", "VAT", 22},
+	{"LT", "", "VAT", 21},
+	{"LU", "", "VAT", 17},
+	{"LV", "", "VAT", 21},
+	{"MT", "", "VAT", 18},
+	{"NL", "", "VAT", 21},
+	{"PL", "", "VAT", 23},
+	{"PT", "", "VAT", 23},
+	{"RO", "", "VAT", 19},
+	{"SE", "", "VAT", 25},
+	{"SI", "", "VAT", 22},
+	{"SK", "", "VAT", 23},
+
+	// Elsewhere
+	{"GB", "", "VAT", 20},
+	{"CH", "", "VAT", 8.1},
+	{"NO", "", "VAT", 25},
+	{"AU", "", "GST", 10},
+	{"NZ", "", "GST", 15},
+	{"CA", "", "GST", 5},
+	{"CA", "ON", "HST", 13},
+	{"CA", "NS", "HST", 15},
+	{"CA", "NB", "HST", 15},
+	{"CA", "NL", "HST", 15},
+	{"CA", "PE", "HST", 15},
+	{"US", "CA", "Sales tax", 7.25},
+	{"US", "NY", "Sales tax", 4},
+	{"US", "TX", "Sales tax", 6.25},
+	{"US", "WA", "Sales tax", 6.5},
+}
+
+// euCountries are the EU member states, where B2B sales across borders are
+// reverse charged
+var euCountries = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "CY": true, "CZ": true, "DE": true,
+	"DK": true, "EE": true, "ES": true, "FI": true, "FR": true, "GR": true,
+	"HR": true, "HU": true, "IE": true, "IT": true, "LT": true, "LU": true,
+	"LV": true, "MT": true, "NL": true, "PL": true, "PT": true, "RO": true,
+	"SE": true, "SI": true, "SK": true,
+}
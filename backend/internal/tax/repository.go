@@ -0,0 +1,50 @@
+package tax
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetRules(tenantID int) ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	err := r.db.Where("tenant_id = ?", tenantID).
+		Order("country ASC, region ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *Repository) GetRulesForCountry(tenantID int, country string) ([]models.TaxRule, error) {
+	var rules []models.TaxRule
+	err := r.db.Where("tenant_id = ? AND country = ?", tenantID, country).
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *Repository) GetRule(id, tenantID int) (*models.TaxRule, error) {
+	var rule models.TaxRule
+	if err := r.db.Where("id = ? AND tenant_id = ?", id, tenantID).First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *Repository) CreateRule(rule *models.TaxRule) error {
+	return r.db.Create(rule).Error
+}
+
+func (r *Repository) UpdateRule(rule *models.TaxRule) error {
+	return r.db.Save(rule).Error
+}
+
+func (r *Repository) DeleteRule(id, tenantID int) error {
+	return r.db.Where("id = ? AND tenant_id = ?", id, tenantID).Delete(&models.TaxRule{}).Error
+}
@@ -0,0 +1,113 @@
+package tax
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	regionPattern  = regexp.MustCompile(`^[A-Z0-9]{1,3}$`)
+)
+
+// Service manages the tenant's tax rules
+type Service struct {
+	repo domain.TaxRepository
+}
+
+func NewService(repo domain.TaxRepository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetTaxRules(tenantID int) ([]models.TaxRule, error) {
+	return s.repo.GetRules(tenantID)
+}
+
+func (s *Service) CreateTaxRule(tenantID int, req models.TaxRuleRequest) (*models.TaxRule, error) {
+	if err := normalizeRule(&req); err != nil {
+		return nil, err
+	}
+	if s.findRule(tenantID, req.Country, req.Region) != nil {
+		return nil, errors.New("a tax rule for this location already exists")
+	}
+
+	rule := &models.TaxRule{
+		Country:  req.Country,
+		Region:   req.Region,
+		Name:     req.Name,
+		Rate:     req.Rate,
+		TenantID: tenantID,
+	}
+	if err := s.repo.CreateRule(rule); err != nil {
+		return nil, errors.New("failed to create tax rule")
+	}
+
+	return rule, nil
+}
+
+func (s *Service) UpdateTaxRule(id, tenantID int, req models.TaxRuleRequest) (*models.TaxRule, error) {
+	rule, err := s.repo.GetRule(id, tenantID)
+	if err != nil {
+		return nil, errors.New("tax rule not found")
+	}
+	if err := normalizeRule(&req); err != nil {
+		return nil, err
+	}
+	if existing := s.findRule(tenantID, req.Country, req.Region); existing != nil && existing.ID != rule.ID {
+		return nil, errors.New("a tax rule for this location already exists")
+	}
+
+	rule.Country = req.Country
+	rule.Region = req.Region
+	rule.Name = req.Name
+	rule.Rate = req.Rate
+	if err := s.repo.UpdateRule(rule); err != nil {
+		return nil, errors.New("failed to update tax rule")
+	}
+
+	return rule, nil
+}
+
+func (s *Service) DeleteTaxRule(id, tenantID int) error {
+	if _, err := s.repo.GetRule(id, tenantID); err != nil {
+		return errors.New("tax rule not found")
+	}
+	return s.repo.DeleteRule(id, tenantID)
+}
+
+func (s *Service) findRule(tenantID int, country, region string) *models.TaxRule {
+	rules, err := s.repo.GetRulesForCountry(tenantID, country)
+	if err != nil {
+		return nil
+	}
+	for i := range rules {
+		if rules[i].Region == region {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// normalizeRule upper-cases the location codes and validates the rule
+func normalizeRule(req *models.TaxRuleRequest) error {
+	req.Country = strings.ToUpper(strings.TrimSpace(req.Country))
+	req.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+	req.Name = strings.TrimSpace(req.Name)
+
+	if !countryPattern.MatchString(req.Country) {
+		return errors.New("country must be a 2-letter ISO code")
+	}
+	if req.Region != "" && !regionPattern.MatchString(req.Region) {
+		return errors.New("region must be an ISO 3166-2 subdivision code, such as CA or ON")
+	}
+	if req.Name == "" {
+		return errors.New("tax name is required")
+	}
+	if req.Rate < 0 || req.Rate > 100 {
+		return errors.New("tax rate must be between 0 and 100 percent")
+	}
+	return nil
+}
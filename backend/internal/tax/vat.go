@@ -0,0 +1,40 @@
+package tax
+
+import (
+	"regexp"
+	"strings"
+)
+
+var vatIDPattern = regexp.MustCompile(`^([A-Z]{2})[0-9A-Z]{2,13}$`)
+
+// VATIDValidator decides whether a buyer's VAT ID is valid for their country.
+// The default checks the format only; an implementation backed by VIES can
+// replace it.
+type VATIDValidator interface {
+	Validate(vatID, country string) bool
+}
+
+// FormatValidator accepts VAT IDs made of the country's VAT prefix and 2 to 13
+// letters or digits
+type FormatValidator struct{}
+
+func NewFormatValidator() *FormatValidator {
+	return &FormatValidator{}
+}
+
+func (v *FormatValidator) Validate(vatID, country string) bool {
+	match := vatIDPattern.FindStringSubmatch(strings.ToUpper(vatID))
+	if match == nil {
+		return false
+	}
+	return match[1] == vatPrefix(country)
+}
+
+// vatPrefix returns the VAT ID prefix of a country, which is the ISO code
+// except for Greece
+func vatPrefix(country string) string {
+	if country == "GR" {
+		return "EL"
+	}
+	return country
+}
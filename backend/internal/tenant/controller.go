@@ -40,7 +40,8 @@ func (h *Controller) CreateTenant(c *fiber.Ctx) error {
 		})
 	}
 
-	tenant, err := h.tenantService.CreateTenant(req)
+	userID := c.Locals("userID").(int)
+	tenant, err := h.tenantService.CreateTenant(req, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -94,7 +95,8 @@ func (h *Controller) UpdateTenant(c *fiber.Ctx) error {
 		})
 	}
 
-	tenant, err := h.tenantService.UpdateTenant(id, req)
+	userID := c.Locals("userID").(int)
+	tenant, err := h.tenantService.UpdateTenant(id, req, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
@@ -117,7 +119,8 @@ func (h *Controller) DeleteTenant(c *fiber.Ctx) error {
 		})
 	}
 
-	err = h.tenantService.DeleteTenant(id)
+	userID := c.Locals("userID").(int)
+	err = h.tenantService.DeleteTenant(id, userID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error":   true,
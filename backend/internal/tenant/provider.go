@@ -1,8 +1,10 @@
 package tenant
 
 import (
+	"backend/internal/activity"
 	"backend/internal/auth"
 	"backend/internal/domain"
+	"backend/internal/rollup"
 	"github.com/google/wire"
 )
 
@@ -12,6 +14,8 @@ var ProviderSet = wire.NewSet(
 	NewTenantService,
 	NewTenantRepository,
 	auth.NewUserRepository,
+	rollup.ProviderSet,
+	activity.ProviderSet,
 
 	wire.Bind(new(domain.TenantControllerInterface), new(*Controller)),
 	wire.Bind(new(domain.TenantService), new(*Service)),
@@ -1,39 +1,60 @@
 package tenant
 
 import (
+	"backend/core/i18n"
 	"backend/internal/domain"
 	"backend/models"
 	"errors"
+	"fmt"
+	"log"
+	"time"
 )
 
 type Service struct {
 	tenantRepo domain.TenantRepository
 	userRepo   domain.UserRepository
+	rollups    domain.RollupService
+	activities domain.ActivityService
 }
 
-func NewTenantService(tenantRepo domain.TenantRepository, userRepo domain.UserRepository) *Service {
+func NewTenantService(tenantRepo domain.TenantRepository, userRepo domain.UserRepository, rollups domain.RollupService, activities domain.ActivityService) *Service {
 	return &Service{
 		tenantRepo: tenantRepo,
 		userRepo:   userRepo,
+		rollups:    rollups,
+		activities: activities,
 	}
 }
 
-func (s *Service) CreateTenant(req models.CreateTenantRequest) (*models.Tenant, error) {
+func (s *Service) CreateTenant(req models.CreateTenantRequest, userID int) (*models.Tenant, error) {
 	// Check if tenant domain already exists
 	existingTenant, _ := s.tenantRepo.GetByDomain(req.TenantDomain)
 	if existingTenant != nil {
 		return nil, errors.New("tenant domain already exists")
 	}
 
+	defaultLocale, err := normalizeDefaultLocale(req.DefaultLocale)
+	if err != nil {
+		return nil, err
+	}
+
+	timezone, err := normalizeTimezone(req.Timezone)
+	if err != nil {
+		return nil, err
+	}
+
 	tenant := &models.Tenant{
-		TenantName:   req.TenantName,
-		TenantDomain: req.TenantDomain,
-		TenantCode:   req.TenantCode,
+		TenantName:    req.TenantName,
+		TenantDomain:  req.TenantDomain,
+		TenantCode:    req.TenantCode,
+		DefaultLocale: defaultLocale,
+		Timezone:      timezone,
 	}
 
 	if err := s.tenantRepo.Create(tenant); err != nil {
 		return nil, errors.New("failed to create tenant")
 	}
+	s.logActivity(tenant, models.ActivityTenantCreated, fmt.Sprintf("Created tenant '%s'", tenant.TenantName), userID, tenantPayload(tenant))
 
 	return tenant, nil
 }
@@ -50,11 +71,12 @@ func (s *Service) GetTenantByID(id int) (*models.Tenant, error) {
 	return tenant, nil
 }
 
-func (s *Service) UpdateTenant(id int, req models.CreateTenantRequest) (*models.Tenant, error) {
+func (s *Service) UpdateTenant(id int, req models.CreateTenantRequest, userID int) (*models.Tenant, error) {
 	tenant, err := s.tenantRepo.GetByID(id)
 	if err != nil {
 		return nil, errors.New("tenant not found")
 	}
+	before := tenantPayload(tenant)
 
 	// Check if new domain conflicts with existing tenant
 	if tenant.TenantDomain != req.TenantDomain {
@@ -67,22 +89,100 @@ func (s *Service) UpdateTenant(id int, req models.CreateTenantRequest) (*models.
 	tenant.TenantName = req.TenantName
 	tenant.TenantDomain = req.TenantDomain
 	tenant.TenantCode = req.TenantCode
+	if req.DefaultLocale != "" {
+		defaultLocale, err := normalizeDefaultLocale(req.DefaultLocale)
+		if err != nil {
+			return nil, err
+		}
+		tenant.DefaultLocale = defaultLocale
+	}
+	timezoneChanged := false
+	if req.Timezone != "" {
+		timezone, err := normalizeTimezone(req.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		timezoneChanged = timezone != tenant.Timezone
+		tenant.Timezone = timezone
+	}
 
 	if err := s.tenantRepo.Update(tenant); err != nil {
 		return nil, errors.New("failed to update tenant")
 	}
+	s.logActivity(tenant, models.ActivityTenantUpdated, fmt.Sprintf("Updated tenant '%s'", tenant.TenantName), userID,
+		models.JSONB{"changes": models.ActivityChanges(before, tenantPayload(tenant))})
+
+	// The rollups' days started in the old timezone
+	if timezoneChanged {
+		if err := s.rollups.Rebuild(tenant.ID); err != nil {
+			log.Printf("Failed to rebuild rollups of tenant %d: %v", tenant.ID, err)
+		}
+	}
 
 	return tenant, nil
 }
 
-func (s *Service) DeleteTenant(id int) error {
+func (s *Service) DeleteTenant(id int, userID int) error {
 	// Check if tenant exists
-	_, err := s.tenantRepo.GetByID(id)
+	tenant, err := s.tenantRepo.GetByID(id)
 	if err != nil {
 		return errors.New("tenant not found")
 	}
 
 	// Note: In a real application, you might want to soft delete
 	// or handle cascading deletes more carefully
-	return s.tenantRepo.Delete(id)
+	if err := s.tenantRepo.Delete(id); err != nil {
+		return err
+	}
+	s.logActivity(tenant, models.ActivityTenantDeleted, fmt.Sprintf("Deleted tenant '%s'", tenant.TenantName), userID, tenantPayload(tenant))
+	return nil
+}
+
+// logActivity records a change of the tenant, made by a super admin, in
+// the tenant's own feed
+func (s *Service) logActivity(tenant *models.Tenant, activityType models.ActivityType, description string, userID int, payload models.JSONB) {
+	s.activities.Record(&models.Activity{
+		UserID:      userID,
+		TenantID:    tenant.ID,
+		Type:        activityType,
+		Description: description,
+		EntityType:  models.ActivityEntityTenant,
+		EntityID:    &tenant.ID,
+		Payload:     payload,
+	})
+}
+
+// tenantPayload is the tenant as recorded in activities
+func tenantPayload(tenant *models.Tenant) models.JSONB {
+	return models.JSONB{
+		"tenant_name":    tenant.TenantName,
+		"tenant_domain":  tenant.TenantDomain,
+		"tenant_code":    tenant.TenantCode,
+		"default_locale": tenant.DefaultLocale,
+		"timezone":       tenant.Timezone,
+	}
+}
+
+// normalizeDefaultLocale validates a tenant default locale, defaulting to English
+func normalizeDefaultLocale(locale string) (string, error) {
+	if locale == "" {
+		return "en", nil
+	}
+	normalized, ok := i18n.Normalize(locale)
+	if !ok {
+		return "", errors.New("invalid default locale")
+	}
+	return normalized, nil
+}
+
+// normalizeTimezone validates a tenant timezone, defaulting to UTC
+func normalizeTimezone(timezone string) (string, error) {
+	if timezone == "" {
+		return "UTC", nil
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil || timezone == "Local" {
+		return "", errors.New("invalid timezone")
+	}
+	return location.String(), nil
 }
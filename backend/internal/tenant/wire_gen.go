@@ -7,7 +7,9 @@
 package tenant
 
 import (
+	"backend/internal/activity"
 	"backend/internal/auth"
+	"backend/internal/rollup"
 	"gorm.io/gorm"
 )
 
@@ -16,7 +18,11 @@ import (
 func NewControllerWire(db *gorm.DB) *Controller {
 	repository := NewTenantRepository(db)
 	userRepository := auth.NewUserRepository(db)
-	service := NewTenantService(repository, userRepository)
+	rollupRepository := rollup.NewRepository(db)
+	rollupService := rollup.NewService(rollupRepository)
+	activityRepository := activity.NewRepository(db)
+	activityService := activity.NewService(activityRepository)
+	service := NewTenantService(repository, userRepository, rollupService, activityService)
 	controller := NewTenantController(service)
 	return controller
 }
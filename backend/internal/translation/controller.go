@@ -0,0 +1,129 @@
+package translation
+
+import (
+	"backend/internal/domain"
+	"backend/models"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Controller struct {
+	service domain.TranslationService
+}
+
+func NewController(service domain.TranslationService) *Controller {
+	return &Controller{service: service}
+}
+
+// GetProductTranslations lists all translations of a product
+func (c *Controller) GetProductTranslations(ctx *fiber.Ctx) error {
+	return c.list(ctx, models.TranslationEntityProduct)
+}
+
+// UpsertProductTranslation creates or replaces a product's translation for a locale
+func (c *Controller) UpsertProductTranslation(ctx *fiber.Ctx) error {
+	return c.upsert(ctx, models.TranslationEntityProduct)
+}
+
+// DeleteProductTranslation removes a product's translation for a locale
+func (c *Controller) DeleteProductTranslation(ctx *fiber.Ctx) error {
+	return c.delete(ctx, models.TranslationEntityProduct)
+}
+
+// GetPlanTranslations lists all translations of a plan
+func (c *Controller) GetPlanTranslations(ctx *fiber.Ctx) error {
+	return c.list(ctx, models.TranslationEntityPlan)
+}
+
+// UpsertPlanTranslation creates or replaces a plan's translation for a locale
+func (c *Controller) UpsertPlanTranslation(ctx *fiber.Ctx) error {
+	return c.upsert(ctx, models.TranslationEntityPlan)
+}
+
+// DeletePlanTranslation removes a plan's translation for a locale
+func (c *Controller) DeletePlanTranslation(ctx *fiber.Ctx) error {
+	return c.delete(ctx, models.TranslationEntityPlan)
+}
+
+func (c *Controller) list(ctx *fiber.Ctx, entityType string) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid " + entityType + " ID",
+		})
+	}
+
+	translations, err := c.service.GetTranslations(entityType, id, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  translations,
+	})
+}
+
+func (c *Controller) upsert(ctx *fiber.Ctx, entityType string) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid " + entityType + " ID",
+		})
+	}
+
+	var req models.TranslationRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid request body",
+		})
+	}
+
+	translation, err := c.service.UpsertTranslation(entityType, id, ctx.Params("locale"), req, *tenantID)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  translation,
+	})
+}
+
+func (c *Controller) delete(ctx *fiber.Ctx, entityType string) error {
+	tenantID := ctx.Locals("tenantID").(*int)
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid " + entityType + " ID",
+		})
+	}
+
+	if err := c.service.DeleteTranslation(entityType, id, ctx.Params("locale"), *tenantID); err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error":   false,
+		"message": "Translation deleted successfully",
+	})
+}
@@ -0,0 +1,17 @@
+package translation
+
+import (
+	"backend/internal/domain"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+
+	wire.Bind(new(domain.TranslationControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.TranslationService), new(*Service)),
+	wire.Bind(new(domain.TranslationRepository), new(*Repository)),
+)
@@ -0,0 +1,71 @@
+package translation
+
+import (
+	"backend/models"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetByEntity(entityType string, entityID int, tenantID int) ([]models.Translation, error) {
+	var translations []models.Translation
+	err := r.db.Where("entity_type = ? AND entity_id = ? AND tenant_id = ?", entityType, entityID, tenantID).
+		Order("locale ASC").
+		Find(&translations).Error
+	return translations, err
+}
+
+func (r *Repository) GetByEntities(entityType string, entityIDs []int, locales []string, tenantID int) ([]models.Translation, error) {
+	var translations []models.Translation
+	if len(entityIDs) == 0 || len(locales) == 0 {
+		return translations, nil
+	}
+	err := r.db.Where("entity_type = ? AND entity_id IN ? AND locale IN ? AND tenant_id = ?", entityType, entityIDs, locales, tenantID).
+		Find(&translations).Error
+	return translations, err
+}
+
+// Upsert creates the translation or replaces the existing one for the same entity and locale
+func (r *Repository) Upsert(translation *models.Translation) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "entity_type"}, {Name: "entity_id"}, {Name: "locale"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "description", "updated_at"}),
+	}).Create(translation).Error
+}
+
+func (r *Repository) Delete(entityType string, entityID int, locale string, tenantID int) error {
+	result := r.db.Where("entity_type = ? AND entity_id = ? AND locale = ? AND tenant_id = ?", entityType, entityID, locale, tenantID).
+		Delete(&models.Translation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *Repository) EntityExists(entityType string, entityID int, tenantID int) (bool, error) {
+	var model interface{}
+	switch entityType {
+	case models.TranslationEntityProduct:
+		model = &models.Product{}
+	case models.TranslationEntityPlan:
+		model = &models.Plan{}
+	default:
+		return false, errors.New("unsupported entity type")
+	}
+
+	var count int64
+	err := r.db.Model(model).Where("id = ? AND tenant_id = ?", entityID, tenantID).Count(&count).Error
+	return count > 0, err
+}
@@ -0,0 +1,194 @@
+package translation
+
+import (
+	"backend/core/i18n"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+)
+
+type Service struct {
+	repo domain.TranslationRepository
+}
+
+func NewService(repo domain.TranslationRepository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) GetTranslations(entityType string, entityID int, tenantID int) ([]models.Translation, error) {
+	if err := s.checkEntity(entityType, entityID, tenantID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetByEntity(entityType, entityID, tenantID)
+}
+
+func (s *Service) UpsertTranslation(entityType string, entityID int, locale string, req models.TranslationRequest, tenantID int) (*models.Translation, error) {
+	if err := s.checkEntity(entityType, entityID, tenantID); err != nil {
+		return nil, err
+	}
+
+	normalized, ok := i18n.Normalize(locale)
+	if !ok {
+		return nil, errors.New("invalid locale")
+	}
+	if req.Name == "" {
+		return nil, errors.New("translated name is required")
+	}
+
+	translation := &models.Translation{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Locale:      normalized,
+		Name:        req.Name,
+		Description: req.Description,
+		TenantID:    tenantID,
+	}
+
+	if err := s.repo.Upsert(translation); err != nil {
+		return nil, errors.New("failed to save translation")
+	}
+
+	return translation, nil
+}
+
+func (s *Service) DeleteTranslation(entityType string, entityID int, locale string, tenantID int) error {
+	if err := s.checkEntity(entityType, entityID, tenantID); err != nil {
+		return err
+	}
+
+	normalized, ok := i18n.Normalize(locale)
+	if !ok {
+		return errors.New("invalid locale")
+	}
+
+	if err := s.repo.Delete(entityType, entityID, normalized, tenantID); err != nil {
+		return errors.New("translation not found")
+	}
+	return nil
+}
+
+// LocalizeProducts replaces the name and description of the products and
+// their preloaded plans with the first available translation in locales
+func (s *Service) LocalizeProducts(products []models.Product, tenantID int, locales []string) error {
+	if len(products) == 0 || len(locales) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(products))
+	var plans []*models.Plan
+	for i := range products {
+		ids = append(ids, products[i].ID)
+		for j := range products[i].Plans {
+			plans = append(plans, &products[i].Plans[j])
+		}
+	}
+
+	translations, err := s.load(models.TranslationEntityProduct, ids, tenantID, locales)
+	if err != nil {
+		return err
+	}
+	for i := range products {
+		if translation := pick(translations[products[i].ID], locales); translation != nil {
+			products[i].Name = translation.Name
+			products[i].Description = translation.Description
+			products[i].Locale = translation.Locale
+		}
+	}
+
+	return s.localizePlans(plans, tenantID, locales)
+}
+
+// LocalizePlans replaces the name and description of the plans and their
+// preloaded products with the first available translation in locales
+func (s *Service) LocalizePlans(plans []models.Plan, tenantID int, locales []string) error {
+	if len(plans) == 0 || len(locales) == 0 {
+		return nil
+	}
+
+	pointers := make([]*models.Plan, 0, len(plans))
+	var products []models.Product
+	for i := range plans {
+		pointers = append(pointers, &plans[i])
+		if plans[i].Product != nil {
+			products = append(products, *plans[i].Product)
+		}
+	}
+
+	if err := s.localizePlans(pointers, tenantID, locales); err != nil {
+		return err
+	}
+
+	if err := s.LocalizeProducts(products, tenantID, locales); err != nil {
+		return err
+	}
+	localized := make(map[int]models.Product, len(products))
+	for _, product := range products {
+		localized[product.ID] = product
+	}
+	for i := range plans {
+		if plans[i].Product != nil {
+			product := localized[plans[i].Product.ID]
+			plans[i].Product = &product
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) localizePlans(plans []*models.Plan, tenantID int, locales []string) error {
+	if len(plans) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(plans))
+	for _, plan := range plans {
+		ids = append(ids, plan.ID)
+	}
+
+	translations, err := s.load(models.TranslationEntityPlan, ids, tenantID, locales)
+	if err != nil {
+		return err
+	}
+	for _, plan := range plans {
+		if translation := pick(translations[plan.ID], locales); translation != nil {
+			plan.Name = translation.Name
+			plan.Description = translation.Description
+			plan.Locale = translation.Locale
+		}
+	}
+	return nil
+}
+
+// load returns the translations of the entities in the candidate locales, grouped by entity ID
+func (s *Service) load(entityType string, ids []int, tenantID int, locales []string) (map[int][]models.Translation, error) {
+	translations, err := s.repo.GetByEntities(entityType, ids, locales, tenantID)
+	if err != nil {
+		return nil, errors.New("failed to load translations")
+	}
+
+	grouped := make(map[int][]models.Translation)
+	for _, translation := range translations {
+		grouped[translation.EntityID] = append(grouped[translation.EntityID], translation)
+	}
+	return grouped, nil
+}
+
+func (s *Service) checkEntity(entityType string, entityID int, tenantID int) error {
+	exists, err := s.repo.EntityExists(entityType, entityID, tenantID)
+	if err != nil || !exists {
+		return errors.New(entityType + " not found")
+	}
+	return nil
+}
+
+// pick returns the translation matching the earliest candidate locale
+func pick(translations []models.Translation, locales []string) *models.Translation {
+	for _, locale := range locales {
+		for i := range translations {
+			if translations[i].Locale == locale {
+				return &translations[i]
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"backend/internal/domain"
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultEventLimit = 50
+	maxEventLimit     = 200
+)
+
+type Controller struct {
+	service domain.WebhookService
+}
+
+func NewController(service domain.WebhookService) *Controller {
+	return &Controller{service: service}
+}
+
+// ReceivePaymentWebhook verifies, stores and queues a payment provider event.
+// Redeliveries of a stored event are acknowledged without processing.
+func (c *Controller) ReceivePaymentWebhook(ctx *fiber.Ctx) error {
+	event, duplicate, err := c.service.Ingest(ctx.Params("provider"), ctx.Body(), ctx.GetReqHeaders())
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrUnknownProvider):
+			status = fiber.StatusNotFound
+		case errors.Is(err, domain.ErrInvalidWebhook):
+			status = fiber.StatusBadRequest
+		}
+		return ctx.Status(status).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	if duplicate {
+		return ctx.JSON(fiber.Map{
+			"error":   false,
+			"message": "Event already received",
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"error": false,
+		"data":  fiber.Map{"id": event.ID, "event_id": event.EventID},
+	})
+}
+
+// GetWebhookEvents lists stored webhook events, newest first
+func (c *Controller) GetWebhookEvents(ctx *fiber.Ctx) error {
+	limit := ctx.QueryInt("limit", defaultEventLimit)
+	if limit <= 0 || limit > maxEventLimit {
+		limit = defaultEventLimit
+	}
+
+	events, err := c.service.GetEvents(ctx.Query("provider"), ctx.Query("status"), limit)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"error": false,
+		"data":  events,
+	})
+}
+
+// ReplayWebhookEvent processes a stored webhook event again
+func (c *Controller) ReplayWebhookEvent(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   true,
+			"message": "Invalid webhook event ID",
+		})
+	}
+
+	event, err := c.service.ReplayEvent(id)
+	if err != nil {
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error":   true,
+			"message": err.Error(),
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"error": false,
+		"data":  event,
+	})
+}
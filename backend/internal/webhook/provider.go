@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"backend/internal/domain"
+	"backend/internal/purchase"
+	"github.com/google/wire"
+)
+
+// ProviderSet defines the set of providers for dependency injection
+var ProviderSet = wire.NewSet(
+	NewController,
+	NewService,
+	NewRepository,
+	purchase.ProviderSet,
+
+	wire.Bind(new(domain.WebhookControllerInterface), new(*Controller)),
+	wire.Bind(new(domain.WebhookService), new(*Service)),
+	wire.Bind(new(domain.WebhookRepository), new(*Repository)),
+)
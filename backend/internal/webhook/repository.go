@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"backend/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// CreateIfNew stores the event unless the provider already delivered an event
+// with the same ID, reporting whether it was stored
+func (r *Repository) CreateIfNew(event *models.WebhookEvent) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *Repository) GetByID(id int) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.First(&event, id).Error; err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (r *Repository) GetEvents(provider, status string, limit int) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	query := r.db.Order("created_at DESC, id DESC").Limit(limit)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&events).Error
+	return events, err
+}
+
+// GetDue returns pending events whose next attempt is due, and events stuck
+// in processing since before staleBefore
+func (r *Repository) GetDue(now, staleBefore time.Time, limit int) ([]models.WebhookEvent, error) {
+	var events []models.WebhookEvent
+	err := r.db.Where("(status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)) OR (status = ? AND updated_at < ?)",
+		models.WebhookStatusPending, now, models.WebhookStatusProcessing, staleBefore).
+		Order("created_at ASC, id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+// Claim marks the event as processing and counts the attempt. It reports
+// false when another worker holds the event.
+func (r *Repository) Claim(id int, staleBefore time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND (status = ? OR (status = ? AND updated_at < ?))",
+			id, models.WebhookStatusPending, models.WebhookStatusProcessing, staleBefore).
+		Updates(map[string]interface{}{
+			"status":     models.WebhookStatusProcessing,
+			"attempts":   gorm.Expr("attempts + 1"),
+			"updated_at": time.Now(),
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *Repository) Update(event *models.WebhookEvent) error {
+	return r.db.Save(event).Error
+}
+
+func (r *Repository) ResetForReplay(id int) error {
+	return r.db.Model(&models.WebhookEvent{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookStatusPending,
+			"attempts":        0,
+			"last_error":      "",
+			"next_attempt_at": nil,
+			"processed_at":    nil,
+		}).Error
+}
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/internal/domain"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	// dueEventBatchSize bounds the events retried per scheduler run
+	dueEventBatchSize = 100
+	// staleProcessingAfter releases events whose worker died mid-processing
+	staleProcessingAfter = 5 * time.Minute
+	retryBaseDelay       = 30 * time.Second
+	retryMaxDelay        = 6 * time.Hour
+)
+
+type Service struct {
+	repo            domain.WebhookRepository
+	purchaseService domain.PurchaseService
+	gateway         coreDomain.PaymentGateway
+	cfg             *core.Config
+}
+
+func NewService(repo domain.WebhookRepository, purchaseService domain.PurchaseService, gateway coreDomain.PaymentGateway, cfg *core.Config) *Service {
+	return &Service{
+		repo:            repo,
+		purchaseService: purchaseService,
+		gateway:         gateway,
+		cfg:             cfg,
+	}
+}
+
+// Ingest verifies and stores a raw provider webhook and starts processing it
+// in the background. Redelivered events are reported as duplicates and not
+// processed again.
+func (s *Service) Ingest(provider string, payload []byte, headers map[string][]string) (*models.WebhookEvent, bool, error) {
+	if provider != s.gateway.Name() {
+		return nil, false, domain.ErrUnknownProvider
+	}
+
+	verified, err := s.gateway.VerifyWebhook(payload, headers)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", domain.ErrInvalidWebhook, err)
+	}
+
+	event := &models.WebhookEvent{
+		Provider: provider,
+		EventID:  verified.ID,
+		Type:     verified.Type,
+		IntentID: verified.IntentID,
+		RefundID: verified.RefundID,
+		Amount:   verified.Amount,
+		Reason:   verified.Reason,
+		Payload:  string(payload),
+		Status:   models.WebhookStatusPending,
+	}
+
+	created, err := s.repo.CreateIfNew(event)
+	if err != nil {
+		return nil, false, errors.New("failed to store webhook event")
+	}
+	if !created {
+		return nil, true, nil
+	}
+
+	go s.processInBackground(event.ID)
+
+	return event, false, nil
+}
+
+// ProcessEvent applies a stored event to its purchase. Failures are scheduled
+// for retry with exponential backoff until WebhookMaxAttempts is reached.
+func (s *Service) ProcessEvent(id int) error {
+	claimed, err := s.repo.Claim(id, time.Now().Add(-staleProcessingAfter))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	event, err := s.repo.GetByID(id)
+	if err != nil {
+		return err
+	}
+
+	applyErr := s.purchaseService.ApplyPaymentEvent(event.Provider, coreDomain.PaymentWebhookEvent{
+		ID:       event.EventID,
+		Type:     event.Type,
+		IntentID: event.IntentID,
+		RefundID: event.RefundID,
+		Amount:   event.Amount,
+		Reason:   event.Reason,
+	})
+
+	now := time.Now()
+	switch {
+	case applyErr == nil:
+		event.Status = models.WebhookStatusProcessed
+		event.LastError = ""
+		event.NextAttemptAt = nil
+		event.ProcessedAt = &now
+	case event.Attempts >= s.cfg.WebhookMaxAttempts:
+		event.Status = models.WebhookStatusFailed
+		event.LastError = applyErr.Error()
+		event.NextAttemptAt = nil
+	default:
+		next := now.Add(retryDelay(event.Attempts))
+		event.Status = models.WebhookStatusPending
+		event.LastError = applyErr.Error()
+		event.NextAttemptAt = &next
+	}
+
+	if err := s.repo.Update(event); err != nil {
+		return err
+	}
+	return applyErr
+}
+
+// ProcessDue retries pending events whose next attempt is due and returns
+// how many were processed successfully
+func (s *Service) ProcessDue(now time.Time) (int, error) {
+	events, err := s.repo.GetDue(now, now.Add(-staleProcessingAfter), dueEventBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, event := range events {
+		if err := s.ProcessEvent(event.ID); err != nil {
+			log.Printf("Failed to process webhook event %d: %v", event.ID, err)
+			continue
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+func (s *Service) GetEvents(provider, status string, limit int) ([]models.WebhookEvent, error) {
+	return s.repo.GetEvents(provider, status, limit)
+}
+
+// ReplayEvent resets a stored event, including processed and failed ones, and
+// processes it again in the background
+func (s *Service) ReplayEvent(id int) (*models.WebhookEvent, error) {
+	if _, err := s.repo.GetByID(id); err != nil {
+		return nil, errors.New("webhook event not found")
+	}
+
+	if err := s.repo.ResetForReplay(id); err != nil {
+		return nil, errors.New("failed to reset webhook event")
+	}
+
+	go s.processInBackground(id)
+
+	return s.repo.GetByID(id)
+}
+
+func (s *Service) processInBackground(id int) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Processing webhook event %d panicked: %v", id, r)
+		}
+	}()
+
+	if err := s.ProcessEvent(id); err != nil {
+		log.Printf("Failed to process webhook event %d: %v", id, err)
+	}
+}
+
+// retryDelay doubles the wait after every failed attempt
+func retryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
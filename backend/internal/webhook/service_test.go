@@ -0,0 +1,326 @@
+package webhook
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"backend/core/payment"
+	"backend/internal/domain"
+	"backend/internal/purchase"
+	"backend/models"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// memoryWebhookRepository stores webhook events in memory and reports every
+// processed event on updates
+type memoryWebhookRepository struct {
+	domain.WebhookRepository
+	mu      sync.Mutex
+	events  map[int]*models.WebhookEvent
+	updates chan models.WebhookEvent
+}
+
+func newMemoryWebhookRepository() *memoryWebhookRepository {
+	return &memoryWebhookRepository{
+		events:  make(map[int]*models.WebhookEvent),
+		updates: make(chan models.WebhookEvent, 16),
+	}
+}
+
+func (r *memoryWebhookRepository) CreateIfNew(event *models.WebhookEvent) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.events {
+		if existing.Provider == event.Provider && existing.EventID == event.EventID {
+			return false, nil
+		}
+	}
+	event.ID = len(r.events) + 1
+	stored := *event
+	r.events[event.ID] = &stored
+	return true, nil
+}
+
+func (r *memoryWebhookRepository) Claim(id int, staleBefore time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event, ok := r.events[id]
+	if !ok || event.Status != models.WebhookStatusPending {
+		return false, nil
+	}
+	event.Status = models.WebhookStatusProcessing
+	event.Attempts++
+	return true, nil
+}
+
+func (r *memoryWebhookRepository) GetByID(id int) (*models.WebhookEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event, ok := r.events[id]
+	if !ok {
+		return nil, errors.New("webhook event not found")
+	}
+	found := *event
+	return &found, nil
+}
+
+func (r *memoryWebhookRepository) Update(event *models.WebhookEvent) error {
+	r.mu.Lock()
+	stored := *event
+	r.events[event.ID] = &stored
+	r.mu.Unlock()
+	r.updates <- stored
+	return nil
+}
+
+func (r *memoryWebhookRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.events)
+}
+
+// memoryPurchaseRepository holds one purchase and its payment
+type memoryPurchaseRepository struct {
+	domain.PurchaseRepository
+	mu          sync.Mutex
+	purchase    models.Purchase
+	payment     models.Payment
+	transitions []models.PurchaseTransition
+	refunds     []models.Refund
+}
+
+func (r *memoryPurchaseRepository) GetPaymentByIntent(provider, intentID string) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.payment.Provider != provider || r.payment.IntentID != intentID {
+		return nil, errors.New("payment not found")
+	}
+	payment := r.payment
+	return &payment, nil
+}
+
+func (r *memoryPurchaseRepository) GetPurchase(id, tenantID int) (*models.Purchase, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.purchase.ID != id || r.purchase.TenantID != tenantID {
+		return nil, errors.New("purchase not found")
+	}
+	purchase := r.purchase
+	return &purchase, nil
+}
+
+func (r *memoryPurchaseRepository) UpdatePayment(payment *models.Payment) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.payment = *payment
+	return nil
+}
+
+func (r *memoryPurchaseRepository) TransitionPurchase(purchase *models.Purchase, fromStatus string, transition *models.PurchaseTransition) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.purchase.Status != fromStatus {
+		return false, nil
+	}
+	r.purchase = *purchase
+	r.transitions = append(r.transitions, *transition)
+	return true, nil
+}
+
+func (r *memoryPurchaseRepository) GetPeriodPayment(purchaseID, tenantID int) (*models.Payment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	payment := r.payment
+	return &payment, nil
+}
+
+func (r *memoryPurchaseRepository) RecordMRRMovement(purchase *models.Purchase, movement *models.MRRMovement) error {
+	return nil
+}
+
+func (r *memoryPurchaseRepository) CreateActivity(activity *models.Activity) error {
+	return nil
+}
+
+func (r *memoryPurchaseRepository) RecordRefund(refund *models.Refund) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.refunds {
+		if existing.ProviderRefundID == refund.ProviderRefundID {
+			payment := r.payment
+			*refund = existing
+			refund.Payment = &payment
+			return false, nil
+		}
+	}
+	if refund.Amount > r.payment.Amount-r.payment.RefundedAmount+0.005 {
+		return false, domain.ErrRefundExceedsBalance
+	}
+	r.payment.RefundedAmount += refund.Amount
+	if r.payment.RefundedAmount >= r.payment.Amount-0.005 {
+		r.payment.Status = models.PaymentStatusRefunded
+	}
+	r.purchase.RefundedAmount += refund.Amount
+	refund.ID = len(r.refunds) + 1
+	r.refunds = append(r.refunds, *refund)
+	payment := r.payment
+	refund.Payment = &payment
+	return true, nil
+}
+
+func (r *memoryPurchaseRepository) SetRefundCreditNote(refund *models.Refund) error {
+	return nil
+}
+
+type stubInvoices struct {
+	domain.InvoiceService
+}
+
+func (stubInvoices) IssueForPayment(payment *models.Payment) (*models.Invoice, error) {
+	return &models.Invoice{ID: 1}, nil
+}
+
+func (stubInvoices) IssueCreditNote(refund *models.Refund) (*models.Invoice, error) {
+	return &models.Invoice{ID: 2}, nil
+}
+
+type stubRollups struct {
+	domain.RollupService
+}
+
+func (stubRollups) Touch(tenantID int, at time.Time) {}
+
+// loadFixture reads a recorded fake webhook, for the intent
+func loadFixture(t *testing.T, name, intentID string) []byte {
+	t.Helper()
+	payload, err := os.ReadFile(filepath.Join("testdata", "fake", name+".json"))
+	if err != nil {
+		t.Fatalf("failed to read fixture %s: %v", name, err)
+	}
+	return bytes.ReplaceAll(payload, []byte("{{intent_id}}"), []byte(intentID))
+}
+
+func signedHeaders(payload []byte) map[string][]string {
+	return map[string][]string{payment.FakeSignatureHeader: {payment.SignFakeWebhook(testWebhookSecret, payload)}}
+}
+
+// The recorded fake webhooks move the purchase of their intent to the state
+// the provider reported, once however often they're delivered
+func TestIngestFakeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture        string
+		purchaseStatus string
+		paymentStatus  string
+		wantPurchase   string
+		wantPayment    string
+		wantRefunded   float64
+	}{
+		{"payment_succeeded", models.PurchaseStatusIncomplete, coreDomain.PaymentStatusRequiresAction, models.PurchaseStatusActive, coreDomain.PaymentStatusSucceeded, 0},
+		{"payment_failed", models.PurchaseStatusIncomplete, coreDomain.PaymentStatusRequiresAction, models.PurchaseStatusExpired, coreDomain.PaymentStatusDeclined, 0},
+		{"payment_refunded", models.PurchaseStatusActive, coreDomain.PaymentStatusSucceeded, models.PurchaseStatusCancelled, models.PaymentStatusRefunded, 20},
+		{"payment_disputed", models.PurchaseStatusActive, coreDomain.PaymentStatusSucceeded, models.PurchaseStatusCancelled, models.PaymentStatusDisputed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			const intentID = "pi_fake_fixture"
+			cfg := &core.Config{PaymentWebhookSecret: testWebhookSecret, WebhookMaxAttempts: 3}
+			plan := &models.Plan{ID: 3, Name: "Pro", Price: 20, Currency: "USD", Interval: "monthly"}
+			purchases := &memoryPurchaseRepository{
+				purchase: models.Purchase{
+					ID: 1, UserID: 2, PlanID: plan.ID, Plan: plan, TransactionID: intentID, PaymentProvider: payment.FakeProviderName,
+					Amount: 20, Currency: "USD", Status: tt.purchaseStatus, TenantID: 4,
+				},
+				payment: models.Payment{
+					ID: 5, PurchaseID: 1, UserID: 2, Provider: payment.FakeProviderName, IntentID: intentID, Kind: models.PaymentKindInitial,
+					Status: tt.paymentStatus, Amount: 20, Currency: "USD", TenantID: 4,
+				},
+			}
+			purchaseService := purchase.NewService(purchases, cfg, nil, stubInvoices{}, nil, nil, nil, nil, stubRollups{})
+			repo := newMemoryWebhookRepository()
+			service := NewService(repo, purchaseService, payment.NewFakeGateway(cfg), cfg)
+
+			payload := loadFixture(t, tt.fixture, intentID)
+			event, duplicate, err := service.Ingest(payment.FakeProviderName, payload, signedHeaders(payload))
+			if err != nil || duplicate {
+				t.Fatalf("Ingest = %v, %v, want a new event", duplicate, err)
+			}
+
+			select {
+			case processed := <-repo.updates:
+				if processed.ID != event.ID || processed.Status != models.WebhookStatusProcessed {
+					t.Fatalf("event %d is %s (%s), want processed", processed.ID, processed.Status, processed.LastError)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("event was not processed")
+			}
+
+			purchases.mu.Lock()
+			got := purchases.purchase
+			gotPayment := purchases.payment
+			transitions := len(purchases.transitions)
+			purchases.mu.Unlock()
+			if got.Status != tt.wantPurchase {
+				t.Errorf("purchase is %s, want %s", got.Status, tt.wantPurchase)
+			}
+			if gotPayment.Status != tt.wantPayment {
+				t.Errorf("payment is %s, want %s", gotPayment.Status, tt.wantPayment)
+			}
+			if got.RefundedAmount != tt.wantRefunded {
+				t.Errorf("purchase refunded %v, want %v", got.RefundedAmount, tt.wantRefunded)
+			}
+
+			// The provider redelivers the same event ID
+			again, duplicate, err := service.Ingest(payment.FakeProviderName, payload, signedHeaders(payload))
+			if err != nil || !duplicate || again != nil {
+				t.Fatalf("redelivered Ingest = %v, %v, %v, want a duplicate", again, duplicate, err)
+			}
+			select {
+			case processed := <-repo.updates:
+				t.Fatalf("redelivered event %d was processed again", processed.ID)
+			case <-time.After(50 * time.Millisecond):
+			}
+			purchases.mu.Lock()
+			defer purchases.mu.Unlock()
+			if len(purchases.transitions) != transitions || purchases.purchase.RefundedAmount != tt.wantRefunded {
+				t.Errorf("redelivered event changed the purchase")
+			}
+			if repo.count() != 1 {
+				t.Errorf("stored %d events, want 1", repo.count())
+			}
+		})
+	}
+}
+
+func TestIngestRejectsBadSignature(t *testing.T) {
+	cfg := &core.Config{PaymentWebhookSecret: testWebhookSecret, WebhookMaxAttempts: 3}
+	repo := newMemoryWebhookRepository()
+	service := NewService(repo, nil, payment.NewFakeGateway(cfg), cfg)
+	payload := loadFixture(t, "payment_succeeded", "pi_fake_fixture")
+
+	tests := []struct {
+		name    string
+		headers map[string][]string
+	}{
+		{"missing", map[string][]string{}},
+		{"wrong secret", map[string][]string{payment.FakeSignatureHeader: {payment.SignFakeWebhook("other-secret", payload)}}},
+		{"other payload", signedHeaders(loadFixture(t, "payment_succeeded", "pi_fake_other"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.Ingest(payment.FakeProviderName, payload, tt.headers)
+			if !errors.Is(err, domain.ErrInvalidWebhook) {
+				t.Fatalf("Ingest error = %v, want ErrInvalidWebhook", err)
+			}
+		})
+	}
+	if repo.count() != 0 {
+		t.Errorf("stored %d events, want none", repo.count())
+	}
+}
@@ -0,0 +1,26 @@
+//go:build wireinject
+// +build wireinject
+
+package webhook
+
+import (
+	"backend/core"
+	coreDomain "backend/core/domain"
+	"github.com/google/wire"
+	"gorm.io/gorm"
+)
+
+func NewControllerWire(db *gorm.DB, cfg *core.Config, gateway coreDomain.PaymentGateway) *Controller {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Controller{}
+}
+
+// NewServiceWire builds the webhook service for background jobs
+func NewServiceWire(db *gorm.DB, cfg *core.Config, gateway coreDomain.PaymentGateway) *Service {
+	wire.Build(
+		ProviderSet,
+	)
+	return &Service{}
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 
 	"backend/core"
+	"backend/core/payment"
 	appInit "backend/init"
 	"backend/migrations"
 	"backend/server"
@@ -19,6 +20,12 @@ func main() {
 	// Load configuration
 	cfg := core.LoadConfig()
 
+	// Refuse to start without a usable payment provider before touching the
+	// database
+	if _, err := payment.NewGateway(cfg); err != nil {
+		log.Fatal("Invalid payment configuration: ", err)
+	}
+
 	// Initialize database
 	db, err := core.InitializeDatabase(cfg.GetDatabaseURL())
 	if err != nil {
@@ -35,6 +42,12 @@ func main() {
 		log.Printf("Warning: Failed to seed initial data: %v", err)
 	}
 
+	// One-time data migrations, such as the MRR ledger of purchases made
+	// before it existed
+	if err := migrations.RunDataMigrations(db); err != nil {
+		log.Printf("Warning: Failed to run data migrations: %v", err)
+	}
+
 	// Initialize Fiber app
 	fiberApp := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -17,10 +17,35 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.User{},
 		&models.Product{},
 		&models.Plan{},
+		&models.Feature{},
+		&models.Translation{},
 		&models.Purchase{},
+		&models.PurchaseTransition{},
+		&models.TrialUsage{},
+		&models.Payment{},
+		&models.Refund{},
+		&models.MRRMovement{},
+		&models.DailyRevenueRollup{},
+		&models.DailyTenantRollup{},
+		&models.RollupRefresh{},
+		&models.DigestSubscription{},
+		&models.ExportJob{},
+		&models.ExportChunk{},
+		&models.WebhookEvent{},
+		&models.TenantSettings{},
+		&models.BillingProfile{},
+		&models.Invoice{},
+		&models.InvoiceLine{},
+		&models.InvoiceTaxLine{},
+		&models.InvoiceSequence{},
+		&models.TaxRule{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
 		&models.Activity{},
+		&models.IdempotencyKey{},
 		&models.Role{},
 		&models.Permission{},
 		&models.RolePermission{},
+		&models.SchemaMigration{},
 	)
 }
\ No newline at end of file
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"backend/models"
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// dataMigration changes existing rows once, after the schema is migrated
+type dataMigration struct {
+	version string
+	run     func(db *gorm.DB) error
+}
+
+// dataMigrations run in this order. A version is recorded once its migration
+// succeeded and must never change or be reused.
+var dataMigrations = []dataMigration{
+	{"2026-10-19-mrr-backfill", BackfillMRRMovements},
+	{"2026-10-19-catalog-external-keys", AssignExternalKeys},
+}
+
+// dataMigrationLock is the advisory lock held while data migrations run, so
+// that instances starting together run each of them once
+const dataMigrationLock = 7263100
+
+// RunDataMigrations runs the data migrations that haven't run on the
+// database yet. Each runs in a transaction with the record of its version,
+// so a failed one is rolled back and retried on the next start.
+func RunDataMigrations(db *gorm.DB) error {
+	for _, migration := range dataMigrations {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", dataMigrationLock).Error; err != nil {
+				return err
+			}
+			var applied int64
+			if err := tx.Model(&models.SchemaMigration{}).Where("version = ?", migration.version).Count(&applied).Error; err != nil {
+				return err
+			}
+			if applied > 0 {
+				return nil
+			}
+
+			log.Printf("Running data migration %s...", migration.version)
+			if err := migration.run(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{Version: migration.version}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("data migration %s: %w", migration.version, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package migrations
+
+import (
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// AssignExternalKeys gives products and plans created before keys were
+// assigned on creation the key they'd get now, derived from their ID
+func AssignExternalKeys(db *gorm.DB) error {
+	if err := db.Model(&models.Product{}).
+		Where("external_key IS NULL OR external_key = ''").
+		UpdateColumn("external_key", gorm.Expr("'product_' || id")).Error; err != nil {
+		return err
+	}
+	return db.Model(&models.Plan{}).
+		Where("external_key IS NULL OR external_key = ''").
+		UpdateColumn("external_key", gorm.Expr("'plan_' || id")).Error
+}
@@ -10,19 +10,7 @@ import (
 func RunMigrations(db *gorm.DB) error {
 	log.Println("Running database migrations...")
 
-	err := db.AutoMigrate(
-		&models.Tenant{},
-		&models.User{},
-		&models.Product{},
-		&models.Plan{},
-		&models.Role{},
-		&models.Permission{},
-		&models.RolePermission{},
-		&models.Purchase{},
-		&models.Activity{},
-	)
-
-	if err != nil {
+	if err := AutoMigrate(db); err != nil {
 		return err
 	}
 
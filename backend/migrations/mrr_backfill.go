@@ -0,0 +1,88 @@
+package migrations
+
+import (
+	"backend/models"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// BackfillMRRMovements builds the MRR ledger of recurring purchases made
+// before it existed, by replaying their status transitions at their current
+// price. It runs once, as a data migration; purchases that already have
+// movements are skipped.
+func BackfillMRRMovements(db *gorm.DB) error {
+	var batch []models.Purchase
+	backfilled := 0
+	result := db.Joins("JOIN plans ON plans.id = purchases.plan_id AND plans.interval IN ?", []string{"monthly", "yearly"}).
+		Where("purchases.status NOT IN ?", []string{models.PurchaseStatusIncomplete, models.PurchaseStatusTrialing}).
+		Where("NOT EXISTS (SELECT 1 FROM mrr_movements WHERE mrr_movements.purchase_id = purchases.id)").
+		Preload("Plan", func(tx *gorm.DB) *gorm.DB { return tx.Unscoped() }).
+		FindInBatches(&batch, 200, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				created, err := backfillPurchaseMRR(db, &batch[i])
+				if err != nil {
+					return err
+				}
+				backfilled += created
+			}
+			return nil
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if backfilled > 0 {
+		log.Printf("Backfilled %d MRR movements", backfilled)
+	}
+	return nil
+}
+
+// backfillPurchaseMRR records the MRR movements of the purchase's history
+func backfillPurchaseMRR(db *gorm.DB, purchase *models.Purchase) (int, error) {
+	var transitions []models.PurchaseTransition
+	if err := db.Where("purchase_id = ?", purchase.ID).Order("created_at, id").Find(&transitions).Error; err != nil {
+		return 0, err
+	}
+
+	replay := *purchase
+	replay.MRR = 0
+	var movements []models.MRRMovement
+	for _, step := range purchaseHistory(purchase, transitions) {
+		replay.Status = step.ToStatus
+		if movement := models.NewMRRMovement(&replay, replay.MonthlyRecurringRevenue(), step.CreatedAt); movement != nil {
+			movements = append(movements, *movement)
+			replay.MRR = movement.MRR
+		}
+	}
+	if len(movements) == 0 {
+		return 0, nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&movements).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Purchase{}).Where("id = ?", purchase.ID).Update("mrr", replay.MRR).Error
+	})
+	return len(movements), err
+}
+
+// purchaseHistory returns the purchase's status changes. Purchases made before
+// transitions were recorded are taken to have been active from their
+// purchase until their last update.
+func purchaseHistory(purchase *models.Purchase, transitions []models.PurchaseTransition) []models.PurchaseTransition {
+	if len(transitions) > 0 {
+		return transitions
+	}
+
+	history := []models.PurchaseTransition{{ToStatus: models.PurchaseStatusActive, CreatedAt: purchase.PurchasedAt}}
+	if purchase.Status != models.PurchaseStatusActive {
+		endedAt := purchase.UpdatedAt
+		if purchase.CancelledAt != nil {
+			endedAt = *purchase.CancelledAt
+		}
+		history = append(history, models.PurchaseTransition{ToStatus: purchase.Status, CreatedAt: endedAt})
+	}
+	return history
+}
@@ -0,0 +1,135 @@
+package models
+
+import (
+	"reflect"
+	"time"
+)
+
+// ActivityType identifies what an activity records
+type ActivityType string
+
+// Activity types
+const (
+	ActivityProductCreated        ActivityType = "product_created"
+	ActivityProductUpdated        ActivityType = "product_updated"
+	ActivityProductDeleted        ActivityType = "product_deleted"
+	ActivityProductArchived       ActivityType = "product_archived"
+	ActivityProductUnarchived     ActivityType = "product_unarchived"
+	ActivityPlanCreated           ActivityType = "plan_created"
+	ActivityPlanUpdated           ActivityType = "plan_updated"
+	ActivityPlanDeleted           ActivityType = "plan_deleted"
+	ActivityPlanArchived          ActivityType = "plan_archived"
+	ActivityPlanUnarchived        ActivityType = "plan_unarchived"
+	ActivityUserRegistered        ActivityType = "user_registered"
+	ActivityTenantCreated         ActivityType = "tenant_created"
+	ActivityTenantUpdated         ActivityType = "tenant_updated"
+	ActivityTenantDeleted         ActivityType = "tenant_deleted"
+	ActivityPurchaseMade          ActivityType = "purchase_made"
+	ActivityPurchaseCancelled     ActivityType = "purchase_cancelled"
+	ActivityPurchaseExpired       ActivityType = "purchase_expired"
+	ActivityCancellationScheduled ActivityType = "cancellation_scheduled"
+	ActivityTrialStarted          ActivityType = "trial_started"
+	ActivityTrialConverted        ActivityType = "trial_converted"
+	ActivityPlanChanged           ActivityType = "plan_changed"
+	ActivityRefundIssued          ActivityType = "refund_issued"
+)
+
+// ActivityTypes lists every activity type
+var ActivityTypes = []ActivityType{
+	ActivityProductCreated,
+	ActivityProductUpdated,
+	ActivityProductDeleted,
+	ActivityProductArchived,
+	ActivityProductUnarchived,
+	ActivityPlanCreated,
+	ActivityPlanUpdated,
+	ActivityPlanDeleted,
+	ActivityPlanArchived,
+	ActivityPlanUnarchived,
+	ActivityUserRegistered,
+	ActivityTenantCreated,
+	ActivityTenantUpdated,
+	ActivityTenantDeleted,
+	ActivityPurchaseMade,
+	ActivityPurchaseCancelled,
+	ActivityPurchaseExpired,
+	ActivityCancellationScheduled,
+	ActivityTrialStarted,
+	ActivityTrialConverted,
+	ActivityPlanChanged,
+	ActivityRefundIssued,
+}
+
+// Valid reports whether t is a known activity type
+func (t ActivityType) Valid() bool {
+	for _, known := range ActivityTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Activity entity types
+const (
+	ActivityEntityProduct  = "product"
+	ActivityEntityPlan     = "plan"
+	ActivityEntityPurchase = "purchase"
+	ActivityEntityRefund   = "refund"
+	ActivityEntityUser     = "user"
+	ActivityEntityTenant   = "tenant"
+)
+
+// ActivityQuery filters and pages a tenant's activities, newest first. Zero
+// values don't filter.
+type ActivityQuery struct {
+	Types      []ActivityType
+	EntityType string
+	EntityID   *int
+	UserID     *int
+	From       time.Time
+	To         time.Time // exclusive
+	Before     int       // cursor: only activities with a lower ID
+	Limit      int
+}
+
+// ActivityPage is one page of activities and the cursor of the next, empty
+// on the last page
+type ActivityPage struct {
+	Activities []Activity
+	NextCursor string
+}
+
+// ActivityChanges returns the fields whose values differ between before and
+// after, each as {"from": ..., "to": ...}, for the payload of an update
+func ActivityChanges(before, after JSONB) JSONB {
+	changes := JSONB{}
+	for field, to := range after {
+		if from := before[field]; !reflect.DeepEqual(from, to) {
+			changes[field] = map[string]interface{}{"from": from, "to": to}
+		}
+	}
+	return changes
+}
+
+// ProductPayload is the product as recorded in activities, without its image
+func ProductPayload(product *Product) JSONB {
+	return JSONB{
+		"name":        product.Name,
+		"description": product.Description,
+		"url":         product.URL,
+		"active":      product.Active != nil && *product.Active,
+	}
+}
+
+// PlanPayload is the plan as recorded in activities
+func PlanPayload(plan *Plan) JSONB {
+	return JSONB{
+		"name":       plan.Name,
+		"product_id": plan.ProductID,
+		"price":      plan.Price,
+		"currency":   plan.Currency,
+		"interval":   plan.Interval,
+		"trial_days": plan.TrialDays,
+	}
+}
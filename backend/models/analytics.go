@@ -0,0 +1,263 @@
+package models
+
+import (
+	"backend/core/money"
+	"time"
+)
+
+// MRR movement types
+const (
+	MRRMovementNew         = "new"         // a purchase starts paying
+	MRRMovementExpansion   = "expansion"   // a paying purchase pays more per month
+	MRRMovementContraction = "contraction" // a paying purchase pays less per month
+	MRRMovementChurn       = "churn"       // a purchase stops paying
+)
+
+// MRRMovement records a change of a purchase's monthly recurring revenue.
+// Replaying a tenant's movements gives its MRR at any point in time.
+type MRRMovement struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PurchaseID int       `json:"purchase_id" gorm:"not null;index"`
+	UserID     int       `json:"user_id" gorm:"not null;index"`
+	PlanID     int       `json:"plan_id" gorm:"not null;index"`
+	Currency   string    `json:"currency" gorm:"not null"`
+	Type       string    `json:"type" gorm:"not null"`   // new, expansion, contraction, churn
+	Amount     float64   `json:"amount" gorm:"not null"` // change of MRR, negative for contraction and churn
+	MRR        float64   `json:"mrr" gorm:"not null"`    // MRR of the purchase after the change
+	TenantID   int       `json:"tenant_id" gorm:"not null;index:idx_mrr_movements_tenant_occurred,priority:1"`
+	OccurredAt time.Time `json:"occurred_at" gorm:"not null;index:idx_mrr_movements_tenant_occurred,priority:2"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// MonthlyRecurringRevenue is the purchase's net price per month while it's
+// paid for: yearly plans count a twelfth of their price, one-off plans and
+// trials nothing. The plan must be loaded for a paying purchase.
+func (p *Purchase) MonthlyRecurringRevenue() float64 {
+	if p.Status != PurchaseStatusActive && p.Status != PurchaseStatusPastDue {
+		return 0
+	}
+	if p.Plan == nil {
+		return 0
+	}
+
+	net := p.Amount
+	if p.NetAmount != nil {
+		net = *p.NetAmount
+	}
+	switch p.Plan.Interval {
+	case "monthly":
+		return money.Round(net)
+	case "yearly":
+		return money.Round(net / 12)
+	}
+	return 0
+}
+
+// NewMRRMovement returns the movement taking the purchase from its recorded
+// MRR to mrr, or nil when its MRR didn't change
+func NewMRRMovement(p *Purchase, mrr float64, at time.Time) *MRRMovement {
+	change := money.Round(mrr - p.MRR)
+	if change == 0 {
+		return nil
+	}
+
+	movementType := MRRMovementContraction
+	switch {
+	case p.MRR == 0:
+		movementType = MRRMovementNew
+	case mrr == 0:
+		movementType = MRRMovementChurn
+	case change > 0:
+		movementType = MRRMovementExpansion
+	}
+	return &MRRMovement{
+		PurchaseID: p.ID,
+		UserID:     p.UserID,
+		PlanID:     p.PlanID,
+		Currency:   p.Currency,
+		Type:       movementType,
+		Amount:     change,
+		MRR:        mrr,
+		TenantID:   p.TenantID,
+		OccurredAt: at,
+	}
+}
+
+// Revenue series intervals
+const (
+	RevenueIntervalDay   = "day"
+	RevenueIntervalWeek  = "week"
+	RevenueIntervalMonth = "month"
+)
+
+// RevenueEntry is money collected (positive) or refunded (negative), net of tax
+type RevenueEntry struct {
+	UserID     int       `json:"user_id"`
+	Currency   string    `json:"currency"`
+	Amount     float64   `json:"amount"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
+
+// RevenuePoint holds the revenue metrics of one period of a series. MRR, ARR
+// and customers are as at the end of the period; churn rates are relative to
+// the start of the period and nil when there was nothing to churn.
+type RevenuePoint struct {
+	PeriodStart       time.Time `json:"period_start"`
+	PeriodEnd         time.Time `json:"period_end"` // exclusive
+	Revenue           float64   `json:"revenue"`    // net payments collected less refunds
+	NewMRR            float64   `json:"new_mrr"`
+	ExpansionMRR      float64   `json:"expansion_mrr"`
+	ContractionMRR    float64   `json:"contraction_mrr"`
+	ChurnedMRR        float64   `json:"churned_mrr"`
+	NetNewMRR         float64   `json:"net_new_mrr"`
+	MRR               float64   `json:"mrr"`
+	ARR               float64   `json:"arr"`
+	Customers         int       `json:"customers"` // users paying for at least one purchase
+	NewCustomers      int       `json:"new_customers"`
+	ChurnedCustomers  int       `json:"churned_customers"`
+	CustomerChurnRate *float64  `json:"customer_churn_rate"` // percent
+	RevenueChurnRate  *float64  `json:"revenue_churn_rate"`  // percent of MRR lost to churn and contraction
+}
+
+// RevenueSeries is the series of one currency
+type RevenueSeries struct {
+	Currency string         `json:"currency"`
+	Points   []RevenuePoint `json:"points"`
+}
+
+// RevenueAnalytics holds revenue series per currency over a date range
+type RevenueAnalytics struct {
+	From     time.Time       `json:"from"`
+	To       time.Time       `json:"to"`       // exclusive
+	Interval string          `json:"interval"` // day, week, month
+	Timezone string          `json:"timezone"` // IANA name periods start in
+	Series   []RevenueSeries `json:"series"`
+}
+
+// RevenueQuery selects the range and granularity of revenue series. Periods
+// start in From's location.
+type RevenueQuery struct {
+	From     time.Time
+	To       time.Time // exclusive
+	Interval string
+}
+
+// AnalyticsFilter restricts analytics to purchases of one product or plan
+type AnalyticsFilter struct {
+	ProductID *int
+	PlanID    *int
+}
+
+// Cohort groupings
+const (
+	CohortBySignup        = "signup"         // month the user registered
+	CohortByFirstPurchase = "first_purchase" // month of the user's first completed purchase
+)
+
+// CohortMember is a user and the time they joined their cohort
+type CohortMember struct {
+	UserID   int       `json:"user_id"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// CohortCell holds one cohort's activity in one month after it joined. A
+// user is active in a month they paid for a recurring plan at any point of,
+// or made a payment in.
+type CohortCell struct {
+	Period           int      `json:"period"` // months since the cohort's month, 0 for the month itself
+	ActiveUsers      int      `json:"active_users"`
+	Retention        *float64 `json:"retention"`         // percent of the cohort's users, nil for an empty cohort
+	Revenue          float64  `json:"revenue"`           // net payments less refunds in the query's currency
+	RevenueRetention *float64 `json:"revenue_retention"` // percent of period 0's revenue, nil when that was nothing
+}
+
+// Cohort is one row of a cohort matrix, with cells up to the current month
+type Cohort struct {
+	Month time.Time    `json:"month"`
+	Users int          `json:"users"`
+	Cells []CohortCell `json:"cells"`
+}
+
+// CohortAnalytics is a retention matrix of monthly cohorts
+type CohortAnalytics struct {
+	By       string   `json:"by"` // signup, first_purchase
+	Timezone string   `json:"timezone"`
+	Currency string   `json:"currency"`
+	Periods  int      `json:"periods"`
+	Cohorts  []Cohort `json:"cohorts"`
+}
+
+// CohortQuery selects the cohorts of the months starting in [From, To),
+// which must be month starts in the location months start in
+type CohortQuery struct {
+	By       string
+	From     time.Time
+	To       time.Time // exclusive
+	Periods  int       // months tracked after each cohort's month, including it
+	Currency string
+	Filter   AnalyticsFilter
+}
+
+// Performance breakdowns
+const (
+	PerformanceByProduct = "product"
+	PerformanceByPlan    = "plan"
+)
+
+// ItemPerformance holds the metrics of one product or plan over a range.
+// Subscribers are purchases paying for a recurring plan.
+type ItemPerformance struct {
+	ID                  int      `json:"id"`
+	Name                string   `json:"name"`
+	ProductID           *int     `json:"product_id,omitempty"` // plans only
+	Archived            bool     `json:"archived"`
+	Deleted             bool     `json:"deleted"`            // deleted but still has purchases
+	ActiveSubscribers   int      `json:"active_subscribers"` // at the end of the range
+	Revenue             float64  `json:"revenue"`            // net payments less refunds in the query's currency
+	PayingUsers         int      `json:"paying_users"`       // users who made a payment in the query's currency
+	ARPU                *float64 `json:"arpu"`               // revenue per paying user, nil without any
+	TrialsEnded         int      `json:"trials_ended"`
+	TrialsConverted     int      `json:"trials_converted"`      // trials that went on to a paid period
+	TrialConversionRate *float64 `json:"trial_conversion_rate"` // percent, nil when no trial ended
+	ChurnedSubscribers  int      `json:"churned_subscribers"`
+	ChurnRate           *float64 `json:"churn_rate"` // percent of the subscribers at the start of the range
+}
+
+// ItemTotal is a count and an amount of one product or plan
+type ItemTotal struct {
+	ID     int
+	Count  int
+	Amount float64
+}
+
+// ItemTrials counts the trials of one product or plan that ended
+type ItemTrials struct {
+	ID        int
+	Ended     int
+	Converted int
+}
+
+// PerformanceQuery selects, sorts and pages product or plan performance
+type PerformanceQuery struct {
+	From     time.Time
+	To       time.Time // exclusive
+	Currency string
+	Sort     string
+	Order    string // asc, desc
+	Page     int    // from 1
+	PerPage  int
+}
+
+// PerformancePage is one page of product or plan performance
+type PerformancePage struct {
+	From     time.Time         `json:"from"`
+	To       time.Time         `json:"to"` // exclusive
+	Timezone string            `json:"timezone"`
+	Currency string            `json:"currency"`
+	Sort     string            `json:"sort"`
+	Order    string            `json:"order"`
+	Page     int               `json:"page"`
+	PerPage  int               `json:"per_page"`
+	Total    int               `json:"total"`
+	Items    []ItemPerformance `json:"items"`
+}
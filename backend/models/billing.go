@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// TenantSettings holds a tenant's seller details, printed on its invoices
+type TenantSettings struct {
+	ID               int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID         int       `json:"tenant_id" gorm:"not null;uniqueIndex"`
+	LegalName        string    `json:"legal_name"`
+	Email            string    `json:"email"`
+	AddressLine1     string    `json:"address_line1"`
+	AddressLine2     string    `json:"address_line2"`
+	City             string    `json:"city"`
+	PostalCode       string    `json:"postal_code"`
+	Region           string    `json:"region"`
+	Country          string    `json:"country"` // ISO 3166-1 alpha-2
+	VATID            string    `json:"vat_id"`
+	PricesIncludeTax bool      `json:"prices_include_tax" gorm:"default:false"` // plan prices are gross
+	InvoicePrefix    string    `json:"invoice_prefix" gorm:"default:'INV-'"`
+	InvoiceFooter    string    `json:"invoice_footer"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// BillingProfile holds a user's buyer details, printed on their invoices
+type BillingProfile struct {
+	ID           int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID       int       `json:"user_id" gorm:"not null;uniqueIndex:idx_billing_profiles_user_tenant,priority:1"`
+	TenantID     int       `json:"tenant_id" gorm:"not null;uniqueIndex:idx_billing_profiles_user_tenant,priority:2"`
+	Name         string    `json:"name"`
+	Company      string    `json:"company"`
+	AddressLine1 string    `json:"address_line1"`
+	AddressLine2 string    `json:"address_line2"`
+	City         string    `json:"city"`
+	PostalCode   string    `json:"postal_code"`
+	Region       string    `json:"region"`
+	Country      string    `json:"country"` // ISO 3166-1 alpha-2
+	VATID        string    `json:"vat_id"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// UpdateTenantSettingsRequest replaces the tenant's seller details
+type UpdateTenantSettingsRequest struct {
+	LegalName        string `json:"legal_name"`
+	Email            string `json:"email"`
+	AddressLine1     string `json:"address_line1"`
+	AddressLine2     string `json:"address_line2"`
+	City             string `json:"city"`
+	PostalCode       string `json:"postal_code"`
+	Region           string `json:"region"`
+	Country          string `json:"country"`
+	VATID            string `json:"vat_id"`
+	PricesIncludeTax bool   `json:"prices_include_tax"`
+	InvoicePrefix    string `json:"invoice_prefix"`
+	InvoiceFooter    string `json:"invoice_footer"`
+}
+
+// UpdateBillingProfileRequest replaces the current user's buyer details
+type UpdateBillingProfileRequest struct {
+	Name         string `json:"name"`
+	Company      string `json:"company"`
+	AddressLine1 string `json:"address_line1"`
+	AddressLine2 string `json:"address_line2"`
+	City         string `json:"city"`
+	PostalCode   string `json:"postal_code"`
+	Region       string `json:"region"`
+	Country      string `json:"country"`
+	VATID        string `json:"vat_id"`
+}
@@ -0,0 +1,68 @@
+package models
+
+// Catalogue import/export formats
+const (
+	CatalogFormatJSON = "json"
+	CatalogFormatCSV  = "csv"
+)
+
+// CatalogDocument is the JSON representation of a tenant's catalogue used by
+// bulk import and export. Products and plans are matched by ExternalKey,
+// features by Key within their product.
+type CatalogDocument struct {
+	Products []CatalogProduct `json:"products"`
+}
+
+type CatalogProduct struct {
+	ExternalKey string           `json:"external_key"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	URL         string           `json:"url"`
+	Active      *bool            `json:"active,omitempty"`
+	Features    []CatalogFeature `json:"features,omitempty"`
+	Plans       []CatalogPlan    `json:"plans"`
+}
+
+// CatalogFeature is an entry of a product's feature catalogue. Imports create
+// or update the features they list and leave others alone.
+type CatalogFeature struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type CatalogPlan struct {
+	ExternalKey string  `json:"external_key"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Currency    string  `json:"currency"`
+	Interval    string  `json:"interval"`
+	Features    JSONB   `json:"features"`
+}
+
+// CatalogImportError describes a validation failure. Row is the CSV line
+// number, or the 1-based position of the product in a JSON document.
+type CatalogImportError struct {
+	Row        int    `json:"row"`
+	ProductKey string `json:"product_key,omitempty"`
+	PlanKey    string `json:"plan_key,omitempty"`
+	Message    string `json:"message"`
+}
+
+type CatalogImportResult struct {
+	DryRun            bool                 `json:"dry_run"`
+	Applied           bool                 `json:"applied"`
+	ProductsCreated   int                  `json:"products_created"`
+	ProductsUpdated   int                  `json:"products_updated"`
+	ProductsUnchanged int                  `json:"products_unchanged"`
+	FeaturesCreated   int                  `json:"features_created"`
+	FeaturesUpdated   int                  `json:"features_updated"`
+	FeaturesUnchanged int                  `json:"features_unchanged"`
+	PlansCreated      int                  `json:"plans_created"`
+	PlansUpdated      int                  `json:"plans_updated"`
+	PlansUnchanged    int                  `json:"plans_unchanged"`
+	Errors            []CatalogImportError `json:"errors"`
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Coupon discount types
+const (
+	CouponTypePercent = "percent" // PercentOff of the plan price
+	CouponTypeFixed   = "fixed"   // AmountOff in the coupon's currency
+)
+
+// Coupon durations
+const (
+	CouponDurationOnce      = "once"      // first period only
+	CouponDurationRepeating = "repeating" // first DurationPeriods periods
+	CouponDurationForever   = "forever"   // every period
+)
+
+// IntArray is a list of integers stored as a PostgreSQL JSONB array
+type IntArray []int
+
+func (a IntArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *IntArray) Scan(value interface{}) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return errors.New("cannot scan non-string value into IntArray")
+	}
+}
+
+// Coupon is a tenant's discount, redeemed at purchase time through its promo code
+type Coupon struct {
+	ID                    int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Code                  string         `json:"code" gorm:"not null;uniqueIndex:idx_coupons_tenant_code,priority:2"` // upper case
+	Name                  string         `json:"name"`
+	Type                  string         `json:"type" gorm:"not null"` // percent, fixed
+	PercentOff            float64        `json:"percent_off"`
+	AmountOff             float64        `json:"amount_off"`
+	Currency              string         `json:"currency,omitempty"`         // required for fixed discounts
+	PlanIDs               IntArray       `json:"plan_ids" gorm:"type:jsonb"` // empty for all plans
+	Duration              string         `json:"duration" gorm:"not null"`   // once, repeating, forever
+	DurationPeriods       int            `json:"duration_periods"`           // periods discounted when repeating
+	MaxRedemptions        *int           `json:"max_redemptions"`            // nil for unlimited
+	MaxRedemptionsPerUser *int           `json:"max_redemptions_per_user"`   // nil for unlimited
+	TimesRedeemed         int            `json:"times_redeemed" gorm:"default:0"`
+	ValidFrom             *time.Time     `json:"valid_from"`
+	ValidUntil            *time.Time     `json:"valid_until"`
+	Active                bool           `json:"active" gorm:"default:true"`
+	TenantID              int            `json:"tenant_id" gorm:"not null;index;uniqueIndex:idx_coupons_tenant_code,priority:1"`
+	CreatedAt             time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt             time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt             gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+}
+
+// CouponRedemption records a coupon applied to a purchase. It is reserved
+// before the payment is taken and released again if the payment fails.
+type CouponRedemption struct {
+	ID             int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	CouponID       int       `json:"coupon_id" gorm:"not null;index"`
+	PurchaseID     *int      `json:"purchase_id" gorm:"index"` // nil while the payment is pending
+	UserID         int       `json:"user_id" gorm:"not null;index"`
+	DiscountAmount float64   `json:"discount_amount"` // per discounted period
+	Currency       string    `json:"currency"`
+	TenantID       int       `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	User     *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Purchase *Purchase `json:"purchase,omitempty" gorm:"foreignKey:PurchaseID"`
+}
+
+// CouponRequest creates or replaces a coupon
+type CouponRequest struct {
+	Code                  string     `json:"code"`
+	Name                  string     `json:"name"`
+	Type                  string     `json:"type"`
+	PercentOff            float64    `json:"percent_off"`
+	AmountOff             float64    `json:"amount_off"`
+	Currency              string     `json:"currency"`
+	PlanIDs               []int      `json:"plan_ids"`
+	Duration              string     `json:"duration"`
+	DurationPeriods       int        `json:"duration_periods"`
+	MaxRedemptions        *int       `json:"max_redemptions"`
+	MaxRedemptionsPerUser *int       `json:"max_redemptions_per_user"`
+	ValidFrom             *time.Time `json:"valid_from"`
+	ValidUntil            *time.Time `json:"valid_until"`
+	Active                *bool      `json:"active"`
+}
+
+// CouponReport summarizes the redemptions of a coupon
+type CouponReport struct {
+	Coupon          Coupon             `json:"coupon"`
+	Redemptions     int                `json:"redemptions"`
+	UniqueCustomers int                `json:"unique_customers"`
+	TotalDiscount   float64            `json:"total_discount"` // first-period discounts granted
+	Revenue         float64            `json:"revenue"`        // net first-period revenue of the redeeming purchases
+	Recent          []CouponRedemption `json:"recent"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Digest frequencies
+const (
+	DigestDaily   = "daily"   // the previous day, sent every morning
+	DigestWeekly  = "weekly"  // the previous week, sent on Monday mornings
+	DigestMonthly = "monthly" // the previous month, sent on the morning of the 1st
+)
+
+// DigestSubscription subscribes a tenant admin to analytics digest emails.
+// Digests cover whole days in the tenant's timezone.
+type DigestSubscription struct {
+	ID               int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID         int        `json:"tenant_id" gorm:"not null;uniqueIndex:idx_digest_subscriptions_user,priority:1"`
+	UserID           int        `json:"user_id" gorm:"not null;uniqueIndex:idx_digest_subscriptions_user,priority:2"`
+	Frequency        string     `json:"frequency" gorm:"not null"` // daily, weekly, monthly
+	UnsubscribeToken string     `json:"-" gorm:"not null;uniqueIndex"`
+	NextSendAt       time.Time  `json:"next_send_at" gorm:"not null;index"`
+	LastSentAt       *time.Time `json:"last_sent_at"`
+	CreatedAt        time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt        time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	User   *User   `json:"-" gorm:"foreignKey:UserID"`
+	Tenant *Tenant `json:"-" gorm:"foreignKey:TenantID"`
+}
+
+// DigestSubscriptionRequest subscribes to digests or changes their frequency
+type DigestSubscriptionRequest struct {
+	Frequency string `json:"frequency" validate:"required"` // daily, weekly, monthly
+}
+
+// Digest is the content of one digest email
+type Digest struct {
+	TenantName     string
+	Frequency      string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time         // exclusive
+	Metrics        *DashboardMetrics // of the period, revenue growth relative to Previous
+	Previous       *DashboardMetrics // of the period before, for comparison
+	Trend          *RevenueAnalytics // the periods up to and including the digest's
+	Events         []Activity        // newest first
+	MoreEvents     bool              // whether there were more events than listed
+	UnsubscribeURL string
+}
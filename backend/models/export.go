@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Export kinds
+const (
+	ExportPurchases = "purchases"
+	ExportRevenue   = "revenue"
+	ExportActivity  = "activity"
+)
+
+// Export formats
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// Export job statuses
+const (
+	ExportStatusPending   = "pending"
+	ExportStatusRunning   = "running"
+	ExportStatusCompleted = "completed"
+	ExportStatusFailed    = "failed"
+)
+
+// ExportJob builds an export too large to stream in the background. The
+// file is stored as Chunks ExportChunks and kept until ExpiresAt.
+type ExportJob struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID    int        `json:"tenant_id" gorm:"not null;index"`
+	UserID      int        `json:"user_id" gorm:"not null"`
+	Kind        string     `json:"kind" gorm:"not null"`   // purchases, revenue, activity
+	Format      string     `json:"format" gorm:"not null"` // csv, xlsx
+	Query       JSONB      `json:"-" gorm:"type:jsonb"`    // the ExportQuery
+	Status      string     `json:"status" gorm:"not null;index"`
+	Rows        int        `json:"rows"`
+	Error       string     `json:"error,omitempty"`
+	Filename    string     `json:"filename"`
+	Size        int64      `json:"size"` // of the file, in bytes
+	Chunks      int        `json:"-"`
+	DownloadURL string     `json:"download_url,omitempty" gorm:"-"` // set once completed
+	CompletedAt *time.Time `json:"completed_at"`
+	ExpiresAt   *time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// ExportChunk is the Seq-th part of the file of an export job
+type ExportChunk struct {
+	ID    int    `gorm:"primaryKey;autoIncrement"`
+	JobID int    `gorm:"not null;uniqueIndex:idx_export_chunks_job_seq,priority:1"`
+	Seq   int    `gorm:"not null;uniqueIndex:idx_export_chunks_job_seq,priority:2"`
+	Data  []byte `gorm:"not null"`
+}
+
+// PurchaseQuery filters the tenant's purchases
+type PurchaseQuery struct {
+	Statuses  []string
+	UserID    *int
+	ProductID *int
+	PlanID    *int
+	From      time.Time // of creation
+	To        time.Time // exclusive
+}
+
+// ExportQuery holds the filters of an export, those of its kind set. Times
+// are shown in Timezone.
+type ExportQuery struct {
+	Timezone  string         `json:"timezone"`
+	Purchases *PurchaseQuery `json:"purchases,omitempty"`
+	Revenue   *RevenueQuery  `json:"revenue,omitempty"`
+	Activity  *ActivityQuery `json:"activity,omitempty"`
+}
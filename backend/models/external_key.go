@@ -0,0 +1,37 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// AfterCreate gives a product created without an external key one derived
+// from its ID, so that catalogue exports can be re-imported
+func (p *Product) AfterCreate(tx *gorm.DB) error {
+	if p.ExternalKey != "" {
+		return nil
+	}
+	p.ExternalKey = ProductExternalKey(p.ID)
+	return tx.Model(p).UpdateColumn("external_key", p.ExternalKey).Error
+}
+
+// AfterCreate gives a plan created without an external key one derived from
+// its ID
+func (p *Plan) AfterCreate(tx *gorm.DB) error {
+	if p.ExternalKey != "" {
+		return nil
+	}
+	p.ExternalKey = PlanExternalKey(p.ID)
+	return tx.Model(p).UpdateColumn("external_key", p.ExternalKey).Error
+}
+
+// ProductExternalKey is the external key of a product created without one
+func ProductExternalKey(id int) string {
+	return fmt.Sprintf("product_%d", id)
+}
+
+// PlanExternalKey is the external key of a plan created without one
+func PlanExternalKey(id int) string {
+	return fmt.Sprintf("plan_%d", id)
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Feature value types supported by the product feature catalogue
+const (
+	FeatureTypeBoolean = "boolean" // on/off flag
+	FeatureTypeLimit   = "limit"   // numeric quota, -1 means unlimited
+	FeatureTypeEnum    = "enum"    // one of Options, ordered from lowest to highest tier
+)
+
+// StringArray is a list of strings stored as a PostgreSQL JSONB array
+type StringArray []string
+
+func (a StringArray) Value() (driver.Value, error) {
+	if a == nil {
+		return nil, nil
+	}
+	return json.Marshal(a)
+}
+
+func (a *StringArray) Scan(value interface{}) error {
+	if value == nil {
+		*a = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, a)
+	case string:
+		return json.Unmarshal([]byte(v), a)
+	default:
+		return errors.New("cannot scan non-string value into StringArray")
+	}
+}
+
+// Feature is an entry of a product's feature catalogue. Plans of the product
+// reference features by Key in Plan.Features.
+type Feature struct {
+	ID          int            `json:"id" gorm:"primaryKey;autoIncrement"`
+	Key         string         `json:"key" gorm:"not null;index"`
+	Name        string         `json:"name" gorm:"not null"`
+	Description string         `json:"description"`
+	Type        string         `json:"type" gorm:"not null"` // boolean, limit, enum
+	Options     StringArray    `json:"options,omitempty" gorm:"type:jsonb"`
+	ProductID   int            `json:"product_id" gorm:"not null;index"`
+	TenantID    int            `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt   time.Time      `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time      `json:"updated_at" gorm:"autoUpdateTime"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
+	// Relationships
+	Product *Product `json:"product,omitempty" gorm:"foreignKey:ProductID"`
+	Tenant  *Tenant  `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+}
+
+type CreateFeatureRequest struct {
+	Key         string   `json:"key" validate:"required"`
+	Name        string   `json:"name" validate:"required"`
+	Description string   `json:"description"`
+	Type        string   `json:"type" validate:"required"`
+	Options     []string `json:"options"`
+}
+
+// Entitlement is the merged set of feature values a user holds for a product
+// through their active purchases
+type Entitlement struct {
+	ProductID   int    `json:"product_id"`
+	ProductName string `json:"product_name"`
+	PlanIDs     []int  `json:"plan_ids"`
+	Features    JSONB  `json:"features"`
+}
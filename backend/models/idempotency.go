@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// Idempotency key statuses
+const (
+	IdempotencyStatusInProgress = "in_progress"
+	IdempotencyStatusCompleted  = "completed"
+)
+
+// IdempotencyKey stores the first response to a mutating request sent with an
+// Idempotency-Key header, so retries with the same key are answered with it
+// instead of running the request again. Keys are scoped to a tenant's user.
+type IdempotencyKey struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	TenantID    int        `json:"tenant_id" gorm:"not null;uniqueIndex:idx_idempotency_keys_tenant_user_key,priority:1"`
+	UserID      int        `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_keys_tenant_user_key,priority:2"`
+	Key         string     `json:"key" gorm:"not null;uniqueIndex:idx_idempotency_keys_tenant_user_key,priority:3"`
+	Fingerprint string     `json:"fingerprint" gorm:"not null"` // hash of the request's method, path and body
+	Status      string     `json:"status" gorm:"not null"`
+	LockedAt    *time.Time `json:"locked_at"` // when the request holding the key started
+
+	ResponseStatus      int    `json:"response_status"`
+	ResponseContentType string `json:"response_content_type"`
+	ResponseBody        []byte `json:"-" gorm:"type:bytea"`
+
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Invoice statuses
+const (
+	InvoiceStatusDraft     = "draft"
+	InvoiceStatusFinalized = "finalized"
+)
+
+// Invoice types
+const (
+	InvoiceTypeInvoice    = "invoice"
+	InvoiceTypeCreditNote = "credit_note" // negative amounts crediting a refund against an invoice
+)
+
+// ErrInvoiceFinalized is returned when changing an invoice that was finalized
+var ErrInvoiceFinalized = errors.New("finalized invoices cannot be changed")
+
+// Invoice is issued for each successful payment of a purchase, and as a
+// credit note for each refund. Seller and buyer details are copied at issue
+// time, and the invoice is numbered from a gap-free per-tenant sequence when
+// it is finalized.
+type Invoice struct {
+	ID                    int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Number                *string    `json:"number" gorm:"uniqueIndex:idx_invoices_tenant_number,priority:2"` // nil while draft
+	Sequence              int        `json:"sequence"`
+	Status                string     `json:"status" gorm:"not null;default:'draft'"`
+	Type                  string     `json:"type" gorm:"not null;default:'invoice'"`
+	PurchaseID            int        `json:"purchase_id" gorm:"not null;index"`
+	PaymentID             *int       `json:"payment_id" gorm:"uniqueIndex"` // nil on credit notes
+	RefundID              *int       `json:"refund_id" gorm:"uniqueIndex"`  // refund a credit note credits
+	CreditedInvoiceID     *int       `json:"credited_invoice_id" gorm:"index"`
+	CreditedInvoiceNumber string     `json:"credited_invoice_number,omitempty"`
+	UserID                int        `json:"user_id" gorm:"not null;index"`
+	Currency              string     `json:"currency" gorm:"not null"`
+	Subtotal              float64    `json:"subtotal"` // net amount
+	TaxTotal              float64    `json:"tax_total"`
+	Total                 float64    `json:"total"` // gross amount
+	IssuedAt              *time.Time `json:"issued_at"`
+
+	SellerName    string `json:"seller_name"`
+	SellerAddress string `json:"seller_address"`
+	SellerEmail   string `json:"seller_email"`
+	SellerVATID   string `json:"seller_vat_id"`
+	BuyerName     string `json:"buyer_name"`
+	BuyerEmail    string `json:"buyer_email"`
+	BuyerAddress  string `json:"buyer_address"`
+	BuyerVATID    string `json:"buyer_vat_id"`
+	Footer        string `json:"footer"`
+
+	TenantID  int       `json:"tenant_id" gorm:"not null;index;uniqueIndex:idx_invoices_tenant_number,priority:1"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	Lines    []InvoiceLine    `json:"lines" gorm:"foreignKey:InvoiceID"`
+	TaxLines []InvoiceTaxLine `json:"tax_lines" gorm:"foreignKey:InvoiceID"`
+}
+
+// Title names the document, e.g. in headings and email subjects
+func (i *Invoice) Title() string {
+	if i.Type == InvoiceTypeCreditNote {
+		return "Credit note"
+	}
+	return "Invoice"
+}
+
+// InvoiceLine is a billed item of an invoice
+type InvoiceLine struct {
+	ID          int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	InvoiceID   int        `json:"invoice_id" gorm:"not null;index"`
+	Description string     `json:"description" gorm:"not null"`
+	Quantity    int        `json:"quantity" gorm:"not null;default:1"`
+	UnitAmount  float64    `json:"unit_amount"`
+	Amount      float64    `json:"amount"` // net amount of the line
+	PeriodStart *time.Time `json:"period_start"`
+	PeriodEnd   *time.Time `json:"period_end"`
+}
+
+// InvoiceTaxLine is the tax charged at one rate on an invoice
+type InvoiceTaxLine struct {
+	ID            int     `json:"id" gorm:"primaryKey;autoIncrement"`
+	InvoiceID     int     `json:"invoice_id" gorm:"not null;index"`
+	Name          string  `json:"name" gorm:"not null"`
+	Rate          float64 `json:"rate"` // percent
+	TaxableAmount float64 `json:"taxable_amount"`
+	Amount        float64 `json:"amount"`
+}
+
+// InvoiceSequence is the last invoice number issued by a tenant
+type InvoiceSequence struct {
+	TenantID   int `gorm:"primaryKey;autoIncrement:false"`
+	LastNumber int `gorm:"not null;default:0"`
+}
+
+// BeforeUpdate keeps finalized invoices immutable
+func (i *Invoice) BeforeUpdate(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, i.ID)
+}
+
+// BeforeDelete keeps finalized invoices immutable
+func (i *Invoice) BeforeDelete(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, i.ID)
+}
+
+func (l *InvoiceLine) BeforeUpdate(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, l.InvoiceID)
+}
+
+func (l *InvoiceLine) BeforeDelete(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, l.InvoiceID)
+}
+
+func (l *InvoiceTaxLine) BeforeUpdate(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, l.InvoiceID)
+}
+
+func (l *InvoiceTaxLine) BeforeDelete(tx *gorm.DB) error {
+	return checkInvoiceDraft(tx, l.InvoiceID)
+}
+
+// checkInvoiceDraft fails when the stored invoice is already finalized
+func checkInvoiceDraft(tx *gorm.DB, invoiceID int) error {
+	if invoiceID == 0 {
+		return nil
+	}
+	var statuses []string
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&Invoice{}).
+		Where("id = ?", invoiceID).
+		Pluck("status", &statuses).Error
+	if err != nil {
+		return err
+	}
+	if len(statuses) > 0 && statuses[0] == InvoiceStatusFinalized {
+		return ErrInvoiceFinalized
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// SchemaMigration records a data migration that has run, so that it runs
+// once per database
+type SchemaMigration struct {
+	Version   string    `json:"version" gorm:"primaryKey"`
+	AppliedAt time.Time `json:"applied_at" gorm:"autoCreateTime"`
+}
@@ -41,6 +41,8 @@ type Tenant struct {
 	TenantName   string    `json:"tenant_name" gorm:"not null"`
 	TenantDomain string    `json:"tenant_domain" gorm:"unique;not null"`
 	TenantCode   string    `json:"tenant_code" gorm:"unique;not null"`
+	DefaultLocale string   `json:"default_locale" gorm:"default:'en'"` // locale of untranslated product and plan fields
+	Timezone     string    `json:"timezone" gorm:"default:'UTC'"` // IANA name, analytics days and months start in it
 	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -50,6 +52,18 @@ type Tenant struct {
 	Products []Product `json:"products,omitempty" gorm:"foreignKey:TenantID"`
 }
 
+// Location returns the tenant's timezone, UTC if it's unset or unknown
+func (t *Tenant) Location() *time.Location {
+	if t.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(t.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
 type User struct {
 	ID           int        `json:"id" gorm:"primaryKey;autoIncrement"`
 	Email        string     `json:"email" gorm:"unique;not null"`
@@ -74,7 +88,10 @@ type Product struct {
 	URL         string    `json:"url"`
 	Image       string    `json:"image" gorm:"type:text"` // Base64 encoded image
 	Active      *bool     `json:"active" gorm:"default:true"`
-	TenantID    int       `json:"tenant_id" gorm:"not null;index"`
+	ExternalKey string    `json:"external_key" gorm:"index:idx_products_tenant_external_key,unique,priority:2,where:external_key <> '' AND deleted_at IS NULL"` // stable key for bulk import/export
+	ArchivedAt  *time.Time `json:"archived_at" gorm:"index"` // hidden from catalogue listings when set
+	TenantID    int       `json:"tenant_id" gorm:"not null;index;index:idx_products_tenant_external_key,priority:1"`
+	Locale      string    `json:"locale,omitempty" gorm:"-"` // translation applied to Name/Description, if any
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -91,9 +108,14 @@ type Plan struct {
 	Price       float64   `json:"price" gorm:"not null"`
 	Currency    string    `json:"currency" gorm:"default:'USD'"`
 	Interval    string    `json:"interval" gorm:"default:'monthly'"` // monthly, yearly
+	TrialDays   int       `json:"trial_days" gorm:"default:0"` // free days before the first charge, 0 for no trial
+	TrialRequiresPaymentMethod bool `json:"trial_requires_payment_method" gorm:"default:false"`
 	Features    JSONB     `json:"features" gorm:"type:jsonb"`
 	ProductID   int       `json:"product_id" gorm:"not null;index"`
-	TenantID    int       `json:"tenant_id" gorm:"not null;index"`
+	ExternalKey string    `json:"external_key" gorm:"index:idx_plans_tenant_external_key,unique,priority:2,where:external_key <> '' AND deleted_at IS NULL"` // stable key for bulk import/export
+	ArchivedAt  *time.Time `json:"archived_at" gorm:"index"` // hidden from catalogue listings when set
+	TenantID    int       `json:"tenant_id" gorm:"not null;index;index:idx_plans_tenant_external_key,priority:1"`
+	Locale      string    `json:"locale,omitempty" gorm:"-"` // translation applied to Name/Description, if any
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
@@ -109,12 +131,33 @@ type Purchase struct {
 	ID            int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	UserID        int       `json:"user_id" gorm:"not null;index"`
 	PlanID        int       `json:"plan_id" gorm:"not null;index"`
-	TransactionID string    `json:"transaction_id" gorm:"unique;not null"`
-	Amount        float64   `json:"amount" gorm:"not null"`
+	TransactionID string    `json:"transaction_id" gorm:"unique;not null"` // payment intent ID at the provider
+	PaymentProvider string  `json:"payment_provider"`
+	PaymentMethod   string  `json:"payment_method"`
+	NextActionURL   string  `json:"next_action_url,omitempty" gorm:"-"` // set while a payment awaits customer action
+	Amount        float64   `json:"amount" gorm:"not null"` // gross amount charged per period
+	NetAmount     *float64  `json:"net_amount"` // nil on purchases made before tax was tracked
+	TaxAmount     float64   `json:"tax_amount" gorm:"default:0"`
+	TaxRate       float64   `json:"tax_rate" gorm:"default:0"` // percent
+	TaxName       string    `json:"tax_name"`
+	TaxCountry    string    `json:"tax_country"`
+	ReverseCharge bool      `json:"reverse_charge" gorm:"default:false"`
+	ListPrice     float64   `json:"list_price"` // plan price before discount
+	CouponID      *int      `json:"coupon_id" gorm:"index"`
+	DiscountAmount float64  `json:"discount_amount" gorm:"default:0"` // off the list price of each discounted period
+	DiscountPeriodsLeft *int `json:"discount_periods_left"` // renewals still discounted, nil while the discount lasts forever
+	ScheduledPlanID     *int `json:"scheduled_plan_id"` // downgrade applied at the next renewal
+	RefundedAmount      float64 `json:"refunded_amount" gorm:"default:0"` // gross amount refunded across the purchase's payments
+	MRR                 float64 `json:"mrr" gorm:"default:0"` // monthly recurring revenue as last recorded in the MRR ledger
 	Currency      string    `json:"currency" gorm:"not null"`
-	Status        string    `json:"status" gorm:"default:'active'"` // active, expired, cancelled
+	Status        string    `json:"status" gorm:"default:'active';index"` // incomplete, trialing, active, past_due, cancelled, expired
 	PurchasedAt   time.Time `json:"purchased_at" gorm:"autoCreateTime"`
-	ExpiresAt     *time.Time `json:"expires_at"`
+	ExpiresAt     *time.Time `json:"expires_at" gorm:"index"` // end of the current period
+	CurrentPeriodStart *time.Time `json:"current_period_start"`
+	CancelAtPeriodEnd  bool       `json:"cancel_at_period_end" gorm:"default:false"`
+	CancelledAt        *time.Time `json:"cancelled_at"`
+	TrialEndsAt        *time.Time `json:"trial_ends_at"`
+	TrialReminderSentAt *time.Time `json:"trial_reminder_sent_at"`
 	TenantID      int       `json:"tenant_id" gorm:"not null;index"`
 	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt     time.Time `json:"updated_at" gorm:"autoUpdateTime"`
@@ -131,10 +174,11 @@ type Activity struct {
 	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
 	UserID      int       `json:"user_id" gorm:"not null;index"`
 	TenantID    int       `json:"tenant_id" gorm:"not null;index"`
-	Type        string    `json:"type" gorm:"not null"` // product_created, plan_created, purchase_made, etc.
+	Type        ActivityType `json:"type" gorm:"not null;index"` // product_created, plan_created, purchase_made, etc.
 	Description string    `json:"description" gorm:"not null"`
-	EntityType  string    `json:"entity_type"` // product, plan, purchase, user
+	EntityType  string    `json:"entity_type"` // product, plan, purchase, refund, user, tenant
 	EntityID    *int      `json:"entity_id"`
+	Payload     JSONB     `json:"payload" gorm:"type:jsonb"` // details of the event, depending on its type
 	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
 	
 	// Relationships
@@ -191,6 +235,8 @@ type CreateTenantRequest struct {
 	TenantName   string `json:"tenant_name" validate:"required"`
 	TenantDomain string `json:"tenant_domain" validate:"required"`
 	TenantCode   string `json:"tenant_code" validate:"required"`
+	DefaultLocale string `json:"default_locale"`
+	Timezone     string `json:"timezone"` // IANA name such as Australia/Sydney
 }
 
 type CreateProductRequest struct {
@@ -207,13 +253,15 @@ type CreatePlanRequest struct {
 	Price       float64 `json:"price" validate:"required,min=0"`
 	Currency    string  `json:"currency"`
 	Interval    string  `json:"interval"`
+	TrialDays   int     `json:"trial_days" validate:"min=0"`
+	TrialRequiresPaymentMethod bool `json:"trial_requires_payment_method"`
 	Features    JSONB   `json:"features"`
 }
 
 type CreatePurchaseRequest struct {
 	PlanID        int    `json:"plan_id" validate:"required"`
-	TransactionID string `json:"transaction_id" validate:"required"`
-	PaymentMethod string `json:"payment_method" validate:"required"`
+	PaymentMethod string `json:"payment_method"` // optional when starting a trial that doesn't require one
+	PromoCode     string `json:"promo_code,omitempty"`
 }
 
 // Dashboard metrics DTOs
@@ -221,7 +269,12 @@ type DashboardMetrics struct {
 	TotalProducts  int     `json:"total_products"`
 	ActivePlans    int     `json:"active_plans"`
 	TeamMembers    int     `json:"team_members"`
-	RevenueGrowth  float64 `json:"revenue_growth"`
-	TotalRevenue   float64 `json:"total_revenue"`
+	RevenueGrowth  *float64 `json:"revenue_growth"` // percent, nil when last month had no revenue
+	TotalRevenue   float64 `json:"total_revenue"` // net of tax
+	TaxCollected   float64 `json:"tax_collected"`
+	RefundedAmount float64 `json:"refunded_amount"` // net, already deducted from TotalRevenue
 	ActivePurchases int    `json:"active_purchases"`
+	ActiveUsers    int     `json:"active_users"` // users with a trialing, active or past-due purchase
+	MRR            float64 `json:"mrr"`
+	ARR            float64 `json:"arr"`
 }
\ No newline at end of file
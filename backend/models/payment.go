@@ -0,0 +1,51 @@
+package models
+
+import (
+	coreDomain "backend/core/domain"
+	"time"
+)
+
+// Payment kinds
+const (
+	PaymentKindInitial   = "initial"
+	PaymentKindRenewal   = "renewal"
+	PaymentKindProration = "proration" // prorated charge of a plan upgrade
+)
+
+// Payment statuses set by provider events, beyond the gateway's intent statuses
+const (
+	PaymentStatusRefunded = "refunded"
+	PaymentStatusDisputed = "disputed"
+)
+
+// CollectedPaymentStatuses are the statuses of payments that took money,
+// including ones refunded or disputed afterwards
+var CollectedPaymentStatuses = []string{
+	coreDomain.PaymentStatusSucceeded,
+	PaymentStatusRefunded,
+	PaymentStatusDisputed,
+}
+
+// Payment records one charge of a purchase made through the payment gateway
+type Payment struct {
+	ID             int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PurchaseID     int       `json:"purchase_id" gorm:"not null;index"`
+	UserID         int       `json:"user_id" gorm:"not null;index"`
+	Provider       string    `json:"provider" gorm:"not null;uniqueIndex:idx_payments_provider_intent,priority:1"`
+	IntentID       string    `json:"intent_id" gorm:"not null;uniqueIndex:idx_payments_provider_intent,priority:2"`
+	Kind           string    `json:"kind" gorm:"not null"`   // initial, renewal, proration
+	Status         string    `json:"status" gorm:"not null"` // requires_action, succeeded, declined, refunded, disputed
+	Amount         float64   `json:"amount" gorm:"not null"` // gross amount charged
+	NetAmount      *float64  `json:"net_amount"`             // nil on payments made before tax was tracked
+	TaxAmount      float64   `json:"tax_amount" gorm:"default:0"`
+	RefundedAmount float64   `json:"refunded_amount" gorm:"default:0"`
+	Currency       string    `json:"currency" gorm:"not null"`
+	PaymentMethod  string    `json:"payment_method"`
+	DeclineReason  string    `json:"decline_reason,omitempty"`
+	TenantID       int       `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt      time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	Purchase *Purchase `json:"purchase,omitempty" gorm:"foreignKey:PurchaseID"`
+}
@@ -0,0 +1,140 @@
+package models
+
+import "time"
+
+// Tenant statuses of the platform analytics
+const (
+	TenantStatusActive   = "active"   // activity within TenantActiveDays
+	TenantStatusInactive = "inactive" // no activity within TenantActiveDays
+	TenantStatusDeleted  = "deleted"
+)
+
+// TenantActiveDays is how recent a tenant's last activity must be for it to
+// count as active
+const TenantActiveDays = 30
+
+// PlatformQuery selects the range, currency and size of platform analytics
+type PlatformQuery struct {
+	From     time.Time
+	To       time.Time // exclusive
+	Currency string
+	Limit    int
+}
+
+// CurrencyRevenue totals the platform's revenue rollups in one currency
+type CurrencyRevenue struct {
+	Currency       string  `json:"currency"`
+	Payments       int     `json:"payments"`
+	FailedPayments int     `json:"failed_payments"`
+	GMV            float64 `json:"gmv"` // net amounts collected
+	Refunds        float64 `json:"refunds"`
+	NetRevenue     float64 `json:"net_revenue"`
+	MRR            float64 `json:"mrr"` // as of each tenant's latest rollup
+	ARR            float64 `json:"arr"`
+}
+
+// PlatformOverview sums the platform's rollups over a range of dates
+type PlatformOverview struct {
+	From            time.Time         `json:"from"`
+	To              time.Time         `json:"to"` // exclusive
+	Revenue         []CurrencyRevenue `json:"revenue"`
+	TenantsByStatus map[string]int    `json:"tenants_by_status"`
+	TotalTenants    int               `json:"total_tenants"` // excluding deleted ones
+	NewTenants      int               `json:"new_tenants"`
+	NewUsers        int               `json:"new_users"`
+	ActiveUsers     int               `json:"active_users"` // as of each tenant's latest rollup
+	FailedPayments  int               `json:"failed_payments"`
+}
+
+// TenantTotal sums one tenant's revenue rollups over a range of dates
+type TenantTotal struct {
+	TenantID       int
+	Revenue        float64 // less refunds, in one currency
+	Payments       int     // in any currency
+	FailedPayments int     // in any currency
+}
+
+// TenantAmount is an amount of one tenant in one currency
+type TenantAmount struct {
+	TenantID int
+	Currency string
+	Amount   float64
+}
+
+// TenantRevenue is a tenant's revenue over a range and the range before it
+type TenantRevenue struct {
+	TenantID        int      `json:"tenant_id"`
+	TenantName      string   `json:"tenant_name"`
+	Revenue         float64  `json:"revenue"`
+	PreviousRevenue float64  `json:"previous_revenue"`
+	Growth          *float64 `json:"growth"` // percent, nil without previous revenue
+}
+
+// TopTenants ranks tenants by revenue and by revenue growth
+type TopTenants struct {
+	From      time.Time       `json:"from"`
+	To        time.Time       `json:"to"` // exclusive
+	Currency  string          `json:"currency"`
+	ByRevenue []TenantRevenue `json:"by_revenue"`
+	ByGrowth  []TenantRevenue `json:"by_growth"`
+}
+
+// DateCount is a count on one calendar date
+type DateCount struct {
+	Date  time.Time
+	Count int
+}
+
+// SignupPoint counts the signups of one period
+type SignupPoint struct {
+	Start      time.Time `json:"start"`
+	NewTenants int       `json:"new_tenants"`
+	NewUsers   int       `json:"new_users"`
+}
+
+// SignupTrends is a series of the tenants and users who signed up
+type SignupTrends struct {
+	Interval string        `json:"interval"`
+	Points   []SignupPoint `json:"points"`
+}
+
+// TenantHealthQuery selects, sorts and pages tenant health
+type TenantHealthQuery struct {
+	From     time.Time
+	To       time.Time // exclusive
+	Currency string
+	Status   string // active, inactive or empty for both
+	Sort     string
+	Order    string // asc, desc
+	Page     int    // from 1
+	PerPage  int
+}
+
+// TenantHealth holds a tenant's health indicators over a range of dates
+type TenantHealth struct {
+	TenantID          int        `json:"tenant_id"`
+	TenantName        string     `json:"tenant_name"`
+	TenantDomain      string     `json:"tenant_domain"`
+	Status            string     `json:"status"`
+	CreatedAt         time.Time  `json:"created_at"`
+	LastActivityAt    *time.Time `json:"last_activity_at"`
+	ActiveUsers       int        `json:"active_users"`
+	Revenue           float64    `json:"revenue"`
+	MRR               float64    `json:"mrr"`
+	Payments          int        `json:"payments"`
+	FailedPayments    int        `json:"failed_payments"`
+	FailedPaymentRate *float64   `json:"failed_payment_rate"` // percent of all payments, nil without any
+}
+
+// TenantHealthPage is one page of tenant health
+type TenantHealthPage struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"` // exclusive
+	Currency string         `json:"currency"`
+	Sort     string         `json:"sort"`
+	Order    string         `json:"order"`
+	Page     int            `json:"page"`
+	PerPage  int            `json:"per_page"`
+	Total    int            `json:"total"`
+	Tenants  []TenantHealth `json:"tenants"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Refund returns all or part of a payment to the customer through the payment
+// gateway. Each refund is credited on a credit note against the payment's
+// invoice.
+type Refund struct {
+	ID               int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PaymentID        int       `json:"payment_id" gorm:"not null;index"`
+	PurchaseID       int       `json:"purchase_id" gorm:"not null;index"`
+	UserID           int       `json:"user_id" gorm:"not null;index"` // customer refunded
+	Provider         string    `json:"provider" gorm:"not null;index:idx_refunds_provider_refund,unique,priority:1,where:provider_refund_id <> ''"`
+	ProviderRefundID string    `json:"provider_refund_id" gorm:"index:idx_refunds_provider_refund,unique,priority:2,where:provider_refund_id <> ''"`
+	Amount           float64   `json:"amount" gorm:"not null"` // gross amount refunded
+	NetAmount        float64   `json:"net_amount"`
+	TaxAmount        float64   `json:"tax_amount"`
+	Currency         string    `json:"currency" gorm:"not null"`
+	Reason           string    `json:"reason"`
+	CreditNoteID     *int      `json:"credit_note_id"`
+	RefundedByID     *int      `json:"refunded_by_id"` // admin who issued it, nil when reported by the provider
+	TenantID         int       `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt        time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	Payment    *Payment `json:"payment,omitempty" gorm:"foreignKey:PaymentID"`
+	CreditNote *Invoice `json:"credit_note,omitempty" gorm:"foreignKey:CreditNoteID"`
+}
+
+// RefundRequest refunds a payment of a purchase
+type RefundRequest struct {
+	PaymentID int     `json:"payment_id,omitempty"` // defaults to the purchase's latest payment
+	Amount    float64 `json:"amount,omitempty"`     // defaults to the whole refundable balance
+	Reason    string  `json:"reason"`
+}
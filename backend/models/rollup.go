@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// DailyRevenueRollup pre-aggregates a tenant's revenue and subscriptions of
+// one plan in one currency on one day. Payments and refunds count towards
+// the plan their purchase is on. Day is the instant the day starts.
+type DailyRevenueRollup struct {
+	ID                     int       `json:"-" gorm:"primaryKey;autoIncrement"`
+	TenantID               int       `json:"tenant_id" gorm:"not null;uniqueIndex:idx_daily_revenue_rollups_key,priority:1"`
+	Day                    time.Time `json:"day" gorm:"not null;uniqueIndex:idx_daily_revenue_rollups_key,priority:2"`
+	PlanID                 int       `json:"plan_id" gorm:"not null;uniqueIndex:idx_daily_revenue_rollups_key,priority:3"`
+	Currency               string    `json:"currency" gorm:"not null;uniqueIndex:idx_daily_revenue_rollups_key,priority:4"`
+	Payments               int       `json:"payments"`
+	FailedPayments         int       `json:"failed_payments"` // declined payments
+	Revenue                float64   `json:"revenue"`         // net amounts collected
+	Refunds                float64   `json:"refunds"`         // net amounts refunded
+	Tax                    float64   `json:"tax"`             // tax collected less tax refunded
+	NewSubscriptions       int       `json:"new_subscriptions"`
+	CancelledSubscriptions int       `json:"cancelled_subscriptions"`
+	NewMRR                 float64   `json:"new_mrr"`
+	ExpansionMRR           float64   `json:"expansion_mrr"`
+	ContractionMRR         float64   `json:"contraction_mrr"`
+	ChurnedMRR             float64   `json:"churned_mrr"`
+	MRR                    float64   `json:"mrr"`         // at the end of the day
+	Subscribers            int       `json:"subscribers"` // paying purchases at the end of the day
+	UpdatedAt              time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// DailyTenantRollup holds a tenant's counts at the end of one day, or as of
+// its last refresh for the current day
+type DailyTenantRollup struct {
+	ID              int        `json:"-" gorm:"primaryKey;autoIncrement"`
+	TenantID        int        `json:"tenant_id" gorm:"not null;uniqueIndex:idx_daily_tenant_rollups_key,priority:1"`
+	Day             time.Time  `json:"day" gorm:"not null;uniqueIndex:idx_daily_tenant_rollups_key,priority:2"`
+	Products        int        `json:"products"`         // active, unarchived products
+	ActivePlans     int        `json:"active_plans"`     // unarchived plans of those products
+	TeamMembers     int        `json:"team_members"`     // users of the tenant
+	NewUsers        int        `json:"new_users"`        // users who signed up that day
+	ActivePurchases int        `json:"active_purchases"` // purchases in the active status
+	ActiveUsers     int        `json:"active_users"`     // users with a trialing, active or past-due purchase
+	LastActivityAt  *time.Time `json:"last_activity_at"` // of the latest activity up to the end of the day
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RollupRefresh marks a tenant's day whose rollups are out of date
+type RollupRefresh struct {
+	TenantID  int       `json:"tenant_id" gorm:"primaryKey;autoIncrement:false"`
+	Day       time.Time `json:"day" gorm:"primaryKey"` // start of the day
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// RevenueTotals sums revenue rollups over a range of days
+type RevenueTotals struct {
+	Revenue float64 `json:"revenue"`
+	Refunds float64 `json:"refunds"`
+	Tax     float64 `json:"tax"`
+}
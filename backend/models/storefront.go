@@ -0,0 +1,40 @@
+package models
+
+// Public storefront DTOs. These are returned to unauthenticated callers, so
+// they only carry catalogue fields and never internal bookkeeping such as
+// tenant IDs or timestamps.
+
+type PublicFeature struct {
+	Key         string   `json:"key"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type"`
+	Options     []string `json:"options,omitempty"`
+}
+
+type PublicPlan struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Price       float64 `json:"price"`
+	Currency    string  `json:"currency"`
+	Interval    string  `json:"interval"`
+	Features    JSONB   `json:"features"`
+	Locale      string  `json:"locale,omitempty"`
+}
+
+type PublicProduct struct {
+	ID          int             `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	URL         string          `json:"url"`
+	ImageURL    string          `json:"image_url,omitempty"`
+	Features    []PublicFeature `json:"features"`
+	Plans       []PublicPlan    `json:"plans"`
+	Locale      string          `json:"locale,omitempty"`
+}
+
+type PublicCatalog struct {
+	Tenant   string          `json:"tenant"`
+	Products []PublicProduct `json:"products"`
+}
@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Purchase statuses of the subscription lifecycle
+const (
+	PurchaseStatusIncomplete = "incomplete" // payment awaits customer action
+	PurchaseStatusTrialing   = "trialing"
+	PurchaseStatusActive     = "active"
+	PurchaseStatusPastDue    = "past_due"
+	PurchaseStatusCancelled  = "cancelled"
+	PurchaseStatusExpired    = "expired"
+)
+
+// EntitledPurchaseStatuses are the statuses in which a purchase still grants
+// access to its plan
+var EntitledPurchaseStatuses = []string{
+	PurchaseStatusTrialing,
+	PurchaseStatusActive,
+	PurchaseStatusPastDue,
+}
+
+// PurchaseTransition records a status change of a purchase
+type PurchaseTransition struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	PurchaseID int       `json:"purchase_id" gorm:"not null;index"`
+	FromStatus string    `json:"from_status" gorm:"not null"`
+	ToStatus   string    `json:"to_status" gorm:"not null"`
+	Reason     string    `json:"reason" gorm:"not null"` // created, payment_confirmed, renewed, cancelled, expired, ...
+	UserID     *int      `json:"user_id"`                // nil when made by the scheduler
+	TenantID   int       `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	// Relationships
+	Purchase *Purchase `json:"purchase,omitempty" gorm:"foreignKey:PurchaseID"`
+}
+
+// Plan change kinds
+const (
+	PlanChangeUpgrade   = "upgrade"   // applied immediately with a prorated charge
+	PlanChangeDowngrade = "downgrade" // applied when the current period ends
+)
+
+// ChangePlanRequest moves a purchase to another plan of the same product
+type ChangePlanRequest struct {
+	PlanID        int    `json:"plan_id"`
+	PaymentMethod string `json:"payment_method,omitempty"` // defaults to the purchase's payment method
+}
+
+// PlanChangePreview shows what changing a purchase's plan would charge. The
+// credit is the unused part of the current period's net price and the charge
+// is the new plan's net price for the rest of the period, or for a whole new
+// period when the billing interval changes.
+type PlanChangePreview struct {
+	PurchaseID       int       `json:"purchase_id"`
+	CurrentPlanID    int       `json:"current_plan_id"`
+	NewPlanID        int       `json:"new_plan_id"`
+	Change           string    `json:"change"` // upgrade, downgrade
+	EffectiveAt      time.Time `json:"effective_at"`
+	PeriodEnd        time.Time `json:"period_end"` // end of the period after the change
+	CreditAmount     float64   `json:"credit_amount"`
+	ChargeAmount     float64   `json:"charge_amount"`
+	NetAmount        float64   `json:"net_amount"` // charge less credit, due now
+	TaxAmount        float64   `json:"tax_amount"`
+	TotalDue         float64   `json:"total_due"`
+	NextPeriodAmount float64   `json:"next_period_amount"` // gross price of the new plan per period
+	Currency         string    `json:"currency"`
+}
+
+// TrialUsage records that a user started a free trial of a product. Each user
+// gets one trial per product.
+type TrialUsage struct {
+	ID         int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID     int       `json:"user_id" gorm:"not null;uniqueIndex:idx_trial_usages_tenant_user_product,priority:2"`
+	ProductID  int       `json:"product_id" gorm:"not null;uniqueIndex:idx_trial_usages_tenant_user_product,priority:3"`
+	PurchaseID int       `json:"purchase_id" gorm:"not null;index"`
+	TenantID   int       `json:"tenant_id" gorm:"not null;uniqueIndex:idx_trial_usages_tenant_user_product,priority:1"`
+	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// UpdatePaymentMethodRequest sets the payment method future periods are charged to
+type UpdatePaymentMethodRequest struct {
+	PaymentMethod string `json:"payment_method"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TaxRule is a tenant's tax rate for a country, or for one region of it when
+// Region is set. Tenant rules override the platform's default rate table.
+type TaxRule struct {
+	ID        int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	Country   string    `json:"country" gorm:"not null;uniqueIndex:idx_tax_rules_tenant_location,priority:2"` // ISO 3166-1 alpha-2
+	Region    string    `json:"region" gorm:"not null;default:'';uniqueIndex:idx_tax_rules_tenant_location,priority:3"`
+	Name      string    `json:"name" gorm:"not null"` // VAT, GST, Sales tax, ...
+	Rate      float64   `json:"rate" gorm:"not null"` // percent
+	TenantID  int       `json:"tenant_id" gorm:"not null;uniqueIndex:idx_tax_rules_tenant_location,priority:1"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// TaxRuleRequest creates or replaces a tax rule
+type TaxRuleRequest struct {
+	Country string  `json:"country"`
+	Region  string  `json:"region"`
+	Name    string  `json:"name"`
+	Rate    float64 `json:"rate"`
+}
+
+// TaxRequest describes a sale to calculate tax for
+type TaxRequest struct {
+	TenantID         int
+	Amount           float64 // list price, tax-inclusive when PricesIncludeTax is set
+	SellerCountry    string
+	BuyerCountry     string
+	BuyerRegion      string
+	BuyerVATID       string
+	PricesIncludeTax bool
+}
+
+// TaxQuote is the tax breakdown of a sale
+type TaxQuote struct {
+	NetAmount        float64 `json:"net_amount"`
+	TaxAmount        float64 `json:"tax_amount"`
+	GrossAmount      float64 `json:"gross_amount"`
+	Rate             float64 `json:"rate"` // percent
+	Name             string  `json:"name"`
+	Country          string  `json:"country"`
+	Region           string  `json:"region,omitempty"`
+	ReverseCharge    bool    `json:"reverse_charge"`
+	PricesIncludeTax bool    `json:"prices_include_tax"`
+}
+
+// PurchaseQuote previews what purchasing a plan charges per period: the
+// coupon's discount comes off the list price before tax
+type PurchaseQuote struct {
+	PlanID         int     `json:"plan_id"`
+	ListPrice      float64 `json:"list_price"`
+	DiscountAmount float64 `json:"discount_amount"`
+	CouponCode     string  `json:"coupon_code,omitempty"`
+	TrialDays      int     `json:"trial_days"` // free days before the first charge, 0 when no trial applies
+	TaxQuote
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Translatable entity types
+const (
+	TranslationEntityProduct = "product"
+	TranslationEntityPlan    = "plan"
+)
+
+// Translation holds the localized name and description of a product or plan.
+// The untranslated fields of the entity are in the tenant's default locale.
+type Translation struct {
+	ID          int       `json:"id" gorm:"primaryKey;autoIncrement"`
+	EntityType  string    `json:"entity_type" gorm:"not null;uniqueIndex:idx_translations_entity_locale"` // product, plan
+	EntityID    int       `json:"entity_id" gorm:"not null;uniqueIndex:idx_translations_entity_locale"`
+	Locale      string    `json:"locale" gorm:"not null;uniqueIndex:idx_translations_entity_locale"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	TenantID    int       `json:"tenant_id" gorm:"not null;index"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	// Relationships
+	Tenant *Tenant `json:"tenant,omitempty" gorm:"foreignKey:TenantID"`
+}
+
+type TranslationRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Webhook event processing statuses
+const (
+	WebhookStatusPending    = "pending"
+	WebhookStatusProcessing = "processing"
+	WebhookStatusProcessed  = "processed"
+	WebhookStatusFailed     = "failed" // gave up after the maximum number of attempts
+)
+
+// WebhookEvent is a verified payment provider event, stored as received and
+// processed asynchronously
+type WebhookEvent struct {
+	ID            int        `json:"id" gorm:"primaryKey;autoIncrement"`
+	Provider      string     `json:"provider" gorm:"not null;uniqueIndex:idx_webhook_events_provider_event,priority:1"`
+	EventID       string     `json:"event_id" gorm:"not null;uniqueIndex:idx_webhook_events_provider_event,priority:2"`
+	Type          string     `json:"type" gorm:"not null"`
+	IntentID      string     `json:"intent_id" gorm:"index"`
+	RefundID      string     `json:"refund_id,omitempty"`
+	Amount        float64    `json:"amount"`
+	Reason        string     `json:"reason,omitempty"`
+	Payload       string     `json:"payload" gorm:"type:text;not null"` // raw request body
+	Status        string     `json:"status" gorm:"not null;default:'pending';index"`
+	Attempts      int        `json:"attempts" gorm:"default:0"`
+	LastError     string     `json:"last_error,omitempty"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	ProcessedAt   *time.Time `json:"processed_at"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
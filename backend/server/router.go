@@ -7,24 +7,46 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/etag"
 )
 
 func SetupRoutes(router *fiber.App, app *appInit.App, db *gorm.DB) {
 
 	health.RegisterHealth(router)
+	// Payment provider webhooks aren't tenant-scoped, so they're registered
+	// ahead of the tenant middleware
+	router.Post("/api/v1/webhooks/payments/:provider", app.WebhookHandler.ReceivePaymentWebhook)
+	// Digest unsubscribe links are opened from emails and carry their own token
+	router.Get("/api/v1/digests/unsubscribe", app.DigestHandler.UnsubscribeByToken)
+	router.Post("/api/v1/digests/unsubscribe", app.DigestHandler.UnsubscribeByToken)
+
 	// API version 1
 	api := router.Group("/api/v1")
 
 	// Tenant middleware for all v1 routes
 	api.Use(middleware.TenantMiddleware(db))
+	api.Use(middleware.Locale())
 
 	// Auth routes
 	auth := api.Group("/auth")
 	auth.Post("/login", app.AuthHandler.Login)
 	auth.Post("/register", app.AuthHandler.Register)
 
-	// Protected tenant routes
-	protected := api.Group("", middleware.JWTAuth(app.Config), middleware.RequireTenant())
+	// Public storefront routes (unauthenticated, read-only)
+	public := api.Group("/public",
+		middleware.PublicRateLimit(app.Config),
+		middleware.CacheControl(app.Config.PublicCacheMaxAge),
+		etag.New(),
+	)
+	public.Get("/catalog", app.StorefrontHandler.GetCatalog)
+	public.Get("/catalog/products/:id/image", app.StorefrontHandler.GetProductImage)
+
+	// Server-sent event stream (admin); browsers' EventSource can't set
+	// headers, so it takes a short-lived stream token as the token parameter
+	api.Get("/events/stream", middleware.StreamTokenAuth(app.Config), middleware.RequireTenant(), middleware.RequireRole("admin"), app.EventsHandler.Stream)
+
+	// Protected tenant routes; mutations may be retried with an Idempotency-Key
+	protected := api.Group("", middleware.JWTAuth(app.Config), middleware.RequireTenant(), middleware.Idempotency(app.Idempotency))
 
 	// Product routes
 	products := protected.Group("/products")
@@ -33,10 +55,26 @@ func SetupRoutes(router *fiber.App, app *appInit.App, db *gorm.DB) {
 	products.Get("/:id", app.ProductHandler.GetProduct)
 	products.Put("/:id", app.ProductHandler.UpdateProduct)
 	products.Delete("/:id", app.ProductHandler.DeleteProduct)
+	products.Post("/:id/archive", app.ProductHandler.ArchiveProduct)
+	products.Post("/:id/unarchive", app.ProductHandler.UnarchiveProduct)
+
+	// Product translation routes (tenant admins)
+	products.Get("/:id/translations", middleware.RequireRole("admin"), app.TranslationHandler.GetProductTranslations)
+	products.Put("/:id/translations/:locale", middleware.RequireRole("admin"), app.TranslationHandler.UpsertProductTranslation)
+	products.Delete("/:id/translations/:locale", middleware.RequireRole("admin"), app.TranslationHandler.DeleteProductTranslation)
 	
 	// Plan routes nested under products
 	products.Get("/:product_id/plans", app.PlanHandler.GetPlansByProduct)
 	products.Post("/:product_id/plans", app.PlanHandler.CreatePlan)
+
+	// Feature catalogue routes nested under products
+	products.Get("/:product_id/features", app.FeatureHandler.GetFeatures)
+	products.Post("/:product_id/features", app.FeatureHandler.CreateFeature)
+
+	// Feature routes for direct access
+	features := protected.Group("/features")
+	features.Put("/:id", app.FeatureHandler.UpdateFeature)
+	features.Delete("/:id", app.FeatureHandler.DeleteFeature)
 	
 	// Plan routes for direct access
 	plans := protected.Group("/plans")
@@ -44,18 +82,95 @@ func SetupRoutes(router *fiber.App, app *appInit.App, db *gorm.DB) {
 	plans.Get("/:id", app.PlanHandler.GetPlan)
 	plans.Put("/:id", app.PlanHandler.UpdatePlan)
 	plans.Delete("/:id", app.PlanHandler.DeletePlan)
+	plans.Post("/:id/archive", app.PlanHandler.ArchivePlan)
+	plans.Post("/:id/unarchive", app.PlanHandler.UnarchivePlan)
+
+	// Plan translation routes (tenant admins)
+	plans.Get("/:id/translations", middleware.RequireRole("admin"), app.TranslationHandler.GetPlanTranslations)
+	plans.Put("/:id/translations/:locale", middleware.RequireRole("admin"), app.TranslationHandler.UpsertPlanTranslation)
+	plans.Delete("/:id/translations/:locale", middleware.RequireRole("admin"), app.TranslationHandler.DeletePlanTranslation)
 
 	// Purchase routes
 	purchases := protected.Group("/purchases")
 	purchases.Post("/", app.PurchaseHandler.CreatePurchase)
 	purchases.Get("/", app.PurchaseHandler.GetUserPurchases)
 	purchases.Get("/active", app.PurchaseHandler.GetActivePurchases)
+	purchases.Get("/quote", app.PurchaseHandler.QuotePurchase)
 	purchases.Get("/:id", app.PurchaseHandler.GetPurchaseByID)
+	purchases.Get("/:id/transitions", app.PurchaseHandler.GetPurchaseTransitions)
+	purchases.Post("/:id/confirm", app.PurchaseHandler.ConfirmPurchase)
+	purchases.Post("/:id/cancel", app.PurchaseHandler.CancelPurchase)
+	purchases.Post("/:id/cancel-at-period-end", app.PurchaseHandler.CancelPurchaseAtPeriodEnd)
+	purchases.Post("/:id/resume", app.PurchaseHandler.ResumePurchase)
+	purchases.Post("/:id/change-plan/preview", app.PurchaseHandler.PreviewPlanChange)
+	purchases.Post("/:id/change-plan", app.PurchaseHandler.ChangePlan)
+	purchases.Put("/:id/payment-method", app.PurchaseHandler.UpdatePaymentMethod)
+	purchases.Get("/:id/refunds", middleware.RequireRole("admin"), app.PurchaseHandler.GetRefunds)
+	purchases.Post("/:id/refunds", middleware.RequireRole("admin"), app.PurchaseHandler.RefundPurchase)
+
+	// Invoice routes (own invoices; tenant admins see all)
+	invoices := protected.Group("/invoices")
+	invoices.Get("/", app.InvoiceHandler.GetInvoices)
+	invoices.Get("/:id", app.InvoiceHandler.GetInvoice)
+	invoices.Get("/:id/pdf", app.InvoiceHandler.DownloadInvoicePDF)
+	invoices.Get("/:id/html", app.InvoiceHandler.DownloadInvoiceHTML)
+	invoices.Post("/:id/send", app.InvoiceHandler.SendInvoice)
+
+	// Billing details: seller settings (tenant admins) and buyer profile
+	protected.Get("/settings/billing", middleware.RequireRole("admin"), app.BillingHandler.GetTenantSettings)
+	protected.Put("/settings/billing", middleware.RequireRole("admin"), app.BillingHandler.UpdateTenantSettings)
+	protected.Get("/billing-profile", app.BillingHandler.GetBillingProfile)
+	protected.Put("/billing-profile", app.BillingHandler.UpdateBillingProfile)
+
+	// Tax rules overriding the default rate table (tenant admins)
+	taxRules := protected.Group("/settings/tax-rules", middleware.RequireRole("admin"))
+	taxRules.Get("/", app.TaxHandler.GetTaxRules)
+	taxRules.Post("/", app.TaxHandler.CreateTaxRule)
+	taxRules.Put("/:id", app.TaxHandler.UpdateTaxRule)
+	taxRules.Delete("/:id", app.TaxHandler.DeleteTaxRule)
+
+	// Coupon routes (tenant admins)
+	coupons := protected.Group("/coupons", middleware.RequireRole("admin"))
+	coupons.Get("/", app.CouponHandler.GetCoupons)
+	coupons.Post("/", app.CouponHandler.CreateCoupon)
+	coupons.Get("/:id", app.CouponHandler.GetCoupon)
+	coupons.Put("/:id", app.CouponHandler.UpdateCoupon)
+	coupons.Delete("/:id", app.CouponHandler.DeleteCoupon)
+	coupons.Get("/:id/redemptions", app.CouponHandler.GetCouponReport)
+
+	// Catalogue bulk import/export routes
+	catalog := protected.Group("/catalog")
+	catalog.Post("/import", app.CatalogHandler.ImportCatalog)
+	catalog.Get("/export", app.CatalogHandler.ExportCatalog)
+
+	// Entitlement routes
+	protected.Get("/entitlements", app.FeatureHandler.GetEntitlements)
 
 	// Analytics routes
 	analytics := protected.Group("/analytics")
 	analytics.Get("/dashboard", app.AnalyticsHandler.GetDashboardMetrics)
-	analytics.Get("/activity", app.AnalyticsHandler.GetRecentActivity)
+	analytics.Get("/revenue", app.AnalyticsHandler.GetRevenueSeries)
+	analytics.Get("/cohorts", app.AnalyticsHandler.GetCohorts)
+	analytics.Get("/products", app.AnalyticsHandler.GetProductPerformance)
+	analytics.Get("/plans", app.AnalyticsHandler.GetPlanPerformance)
+	analytics.Get("/activity", app.AnalyticsHandler.GetActivities)
+
+	// Analytics digest emails (tenant admins)
+	digests := protected.Group("/digests/subscription", middleware.RequireRole("admin"))
+	digests.Get("/", app.DigestHandler.GetSubscription)
+	digests.Put("/", app.DigestHandler.Subscribe)
+	digests.Delete("/", app.DigestHandler.Unsubscribe)
+
+	// CSV and XLSX exports (tenant admins)
+	exports := protected.Group("/exports", middleware.RequireRole("admin"))
+	exports.Get("/purchases", app.ExportHandler.ExportPurchases)
+	exports.Get("/revenue", app.ExportHandler.ExportRevenue)
+	exports.Get("/activity", app.ExportHandler.ExportActivity)
+	exports.Get("/:id", app.ExportHandler.GetExport)
+	exports.Get("/:id/download", app.ExportHandler.DownloadExport)
+
+	// Stream tokens opening the event stream (tenant admins)
+	protected.Post("/events/token", middleware.RequireRole("admin"), app.EventsHandler.CreateStreamToken)
 
 	// Super Admin routes
 	superAdmin := router.Group("/api/super")
@@ -71,4 +186,14 @@ func SetupRoutes(router *fiber.App, app *appInit.App, db *gorm.DB) {
 	superProtected.Get("/tenants/:id", app.TenantHandler.GetTenant)
 	superProtected.Put("/tenants/:id", app.TenantHandler.UpdateTenant)
 	superProtected.Delete("/tenants/:id", app.TenantHandler.DeleteTenant)
+
+	// Platform analytics
+	superProtected.Get("/analytics/overview", app.AnalyticsHandler.GetPlatformOverview)
+	superProtected.Get("/analytics/top-tenants", app.AnalyticsHandler.GetTopTenants)
+	superProtected.Get("/analytics/signups", app.AnalyticsHandler.GetSignupTrends)
+	superProtected.Get("/analytics/tenants", app.AnalyticsHandler.GetTenantHealth)
+
+	// Payment webhook inspection and replay
+	superProtected.Get("/webhooks/events", app.WebhookHandler.GetWebhookEvents)
+	superProtected.Post("/webhooks/events/:id/replay", app.WebhookHandler.ReplayWebhookEvent)
 }
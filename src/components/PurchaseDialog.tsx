@@ -1,4 +1,4 @@
-import React, { useState } from 'react';
+import React, { useMemo, useState } from 'react';
 import { Dialog, DialogContent, DialogHeader, DialogTitle } from '@/components/ui/dialog';
 import { Button } from '@/components/ui/button';
 import { Input } from '@/components/ui/input';
@@ -17,6 +17,7 @@ interface Plan {
   price: number;
   currency: string;
   interval: string;
+  trial_days?: number;
   features: Record<string, any>;
 }
 
@@ -37,6 +38,7 @@ const PurchaseDialog: React.FC<PurchaseDialogProps> = ({
   const [paymentMethod, setPaymentMethod] = useState<'upi' | 'card'>('upi');
   const [loading, setLoading] = useState(false);
   const [upiId, setUpiId] = useState('');
+  const [promoCode, setPromoCode] = useState('');
   const [cardDetails, setCardDetails] = useState({
     number: '',
     expiry: '',
@@ -44,22 +46,22 @@ const PurchaseDialog: React.FC<PurchaseDialogProps> = ({
     name: ''
   });
 
+  // Retrying the same purchase reuses its key, so it can't be charged twice
+  const idempotencyKey = useMemo(() => crypto.randomUUID(), [plan?.id, paymentMethod, promoCode]);
+
   const handlePurchase = async () => {
     if (!plan) return;
 
     setLoading(true);
     try {
-      // Generate transaction ID
-      const transactionId = `TXN_${Date.now()}_${Math.random().toString(36).substr(2, 9)}`;
-      
-      // Create purchase record
-      await purchaseApi.create({
+      // The payment gateway assigns the transaction ID
+      const response = await purchaseApi.create({
         plan_id: plan.id,
-        transaction_id: transactionId,
         payment_method: paymentMethod,
-      });
+        promo_code: promoCode.trim() || undefined,
+      }, idempotencyKey);
       
-      onPurchaseComplete(plan.id, transactionId);
+      onPurchaseComplete(plan.id, response.data.transaction_id);
       
       toast({
         title: "Payment Successful!",
@@ -70,6 +72,7 @@ const PurchaseDialog: React.FC<PurchaseDialogProps> = ({
       
       // Reset form
       setUpiId('');
+      setPromoCode('');
       setCardDetails({ number: '', expiry: '', cvv: '', name: '' });
     } catch (error) {
       toast({
@@ -117,6 +120,11 @@ const PurchaseDialog: React.FC<PurchaseDialogProps> = ({
             </CardHeader>
             <CardContent>
               <p className="text-muted-foreground mb-4">{plan.description}</p>
+              {plan.trial_days ? (
+                <p className="text-sm font-medium text-primary mb-4">
+                  {plan.trial_days}-day free trial for new customers
+                </p>
+              ) : null}
               <div className="space-y-2">
                 <h4 className="font-medium">Features included:</h4>
                 <ul className="space-y-1">
@@ -213,6 +221,18 @@ const PurchaseDialog: React.FC<PurchaseDialogProps> = ({
             </div>
           )}
 
+          {/* Promo Code */}
+          <div className="space-y-2">
+            <Label htmlFor="promo_code">Promo Code</Label>
+            <Input
+              id="promo_code"
+              placeholder="Optional"
+              value={promoCode}
+              onChange={(e) => setPromoCode(e.target.value.toUpperCase())}
+              disabled={loading}
+            />
+          </div>
+
           <Separator />
 
           {/* Total */}
@@ -3,7 +3,7 @@ import DashboardLayout from '@/components/Layout/DashboardLayout';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
 import { Button } from '@/components/ui/button';
 import { useAuth } from '@/contexts/AuthContext';
-import { analyticsApi } from '@/services/api';
+import { analyticsApi, eventsApi } from '@/services/api';
 import { useToast } from '@/hooks/use-toast';
 import { 
   Package, 
@@ -56,7 +56,9 @@ const Dashboard: React.FC = () => {
           },
           {
             title: 'Revenue Growth',
-            value: `${metricsData.revenue_growth >= 0 ? '+' : ''}${metricsData.revenue_growth.toFixed(1)}%`,
+            value: metricsData.revenue_growth == null
+              ? 'N/A'
+              : `${metricsData.revenue_growth >= 0 ? '+' : ''}${metricsData.revenue_growth.toFixed(1)}%`,
             change: 'vs last month',
             icon: TrendingUp,
             color: 'text-warning'
@@ -77,7 +79,13 @@ const Dashboard: React.FC = () => {
     };
 
     fetchDashboardData();
-  }, [toast]);
+
+    // Refresh as purchases, products and plans change instead of polling;
+    // the event stream is only open to admins
+    if (user?.role === 'admin') {
+      return eventsApi.subscribe(() => fetchDashboardData());
+    }
+  }, [toast, user?.role]);
 
   const quickActions = [
     {
@@ -1,4 +1,4 @@
-import { apiRequest } from '@/config/api';
+import { apiRequest, getApiBaseUrl, getTenantId } from '@/config/api';
 
 // Product API functions
 export const productApi = {
@@ -63,6 +63,8 @@ export const planApi = {
     price: number;
     currency: string;
     interval: string;
+    trial_days?: number;
+    trial_requires_payment_method?: boolean;
     features: Record<string, any>;
   }) => {
     return await apiRequest(`/api/v1/products/${productId}/plans`, {
@@ -77,6 +79,8 @@ export const planApi = {
     price: number;
     currency: string;
     interval: string;
+    trial_days?: number;
+    trial_requires_payment_method?: boolean;
     features: Record<string, any>;
   }) => {
     return await apiRequest(`/api/v1/plans/${id}`, {
@@ -96,12 +100,13 @@ export const planApi = {
 export const purchaseApi = {
   create: async (data: {
     plan_id: number;
-    transaction_id: string;
     payment_method: string;
-  }) => {
+    promo_code?: string;
+  }, idempotencyKey?: string) => {
     return await apiRequest('/api/v1/purchases', {
       method: 'POST',
       body: JSON.stringify(data),
+      headers: idempotencyKey ? { 'Idempotency-Key': idempotencyKey } : undefined,
     });
   },
   
@@ -118,14 +123,235 @@ export const purchaseApi = {
   },
 };
 
+// Query parameters of the product and plan performance endpoints
+type PerformanceParams = {
+  from?: string;
+  to?: string;
+  currency?: string;
+  sort?: 'name' | 'revenue' | 'active_subscribers' | 'paying_users' | 'arpu' | 'trial_conversion_rate' | 'churn_rate';
+  order?: 'asc' | 'desc';
+  page?: string;
+  per_page?: string;
+  tz?: string;
+};
+
 // Analytics API functions
 export const analyticsApi = {
   getDashboardMetrics: async () => {
     return await apiRequest('/api/v1/analytics/dashboard');
   },
+
+  getRevenueSeries: async (params: { from?: string; to?: string; interval?: 'day' | 'week' | 'month'; tz?: string } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/v1/analytics/revenue${query ? `?${query}` : ''}`);
+  },
+
+  getCohorts: async (params: {
+    by?: 'signup' | 'first_purchase';
+    from?: string;
+    to?: string;
+    periods?: string;
+    currency?: string;
+    product_id?: string;
+    plan_id?: string;
+    tz?: string;
+  } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/v1/analytics/cohorts${query ? `?${query}` : ''}`);
+  },
+
+  getProductPerformance: async (params: PerformanceParams = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/v1/analytics/products${query ? `?${query}` : ''}`);
+  },
+
+  getPlanPerformance: async (params: PerformanceParams = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/v1/analytics/plans${query ? `?${query}` : ''}`);
+  },
   
-  getRecentActivity: async () => {
-    return await apiRequest('/api/v1/analytics/activity');
+  // type takes a comma-separated list of activity types; pass the response's
+  // next_cursor as cursor to load the next page
+  getRecentActivity: async (params: {
+    type?: string;
+    entity_type?: 'product' | 'plan' | 'purchase' | 'refund' | 'user' | 'tenant';
+    entity_id?: string;
+    user_id?: string;
+    from?: string;
+    to?: string;
+    cursor?: string;
+    limit?: string;
+    tz?: string;
+  } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/v1/analytics/activity${query ? `?${query}` : ''}`);
+  },
+};
+
+// Analytics digest email API functions (tenant admins)
+export const digestApi = {
+  getSubscription: async () => {
+    return await apiRequest('/api/v1/digests/subscription');
+  },
+
+  subscribe: async (frequency: 'daily' | 'weekly' | 'monthly') => {
+    return await apiRequest('/api/v1/digests/subscription', {
+      method: 'PUT',
+      body: JSON.stringify({ frequency }),
+    });
+  },
+
+  unsubscribe: async () => {
+    return await apiRequest('/api/v1/digests/subscription', {
+      method: 'DELETE',
+    });
+  },
+};
+
+// exportRequest fetches an export, which is either the file itself or, for
+// large exports, the job building it (HTTP 202)
+const exportRequest = async (endpoint: string) => {
+  const headers: Record<string, string> = {};
+  const token = localStorage.getItem('auth_token');
+  if (token) {
+    headers.Authorization = `Bearer ${token}`;
+  }
+  const tenantId = getTenantId();
+  if (tenantId) {
+    headers['X-Tenant-ID'] = tenantId;
+  }
+
+  const response = await fetch(`${getApiBaseUrl()}${endpoint}`, { headers });
+  if (!response.ok) {
+    const data = await response.json();
+    throw new Error(data.message || `HTTP error! status: ${response.status}`);
+  }
+  if (response.status === 202) {
+    const data = await response.json();
+    return { job: data.data };
+  }
+
+  const disposition = response.headers.get('Content-Disposition') || '';
+  const filename = /filename="([^"]+)"/.exec(disposition)?.[1] || 'export';
+  return { file: await response.blob(), filename };
+};
+
+type ExportFormat = { format?: 'csv' | 'xlsx'; tz?: string };
+
+// Export API functions (tenant admins). Each export takes the filters of the
+// matching list API and resolves to { file, filename } or, when too large to
+// stream, to { job }; poll getJob until its status is completed, then call
+// download.
+export const exportApi = {
+  purchases: async (params: ExportFormat & {
+    status?: string;
+    user_id?: string;
+    product_id?: string;
+    plan_id?: string;
+    from?: string;
+    to?: string;
+  } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await exportRequest(`/api/v1/exports/purchases${query ? `?${query}` : ''}`);
+  },
+
+  revenue: async (params: ExportFormat & { from?: string; to?: string; interval?: 'day' | 'week' | 'month' } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await exportRequest(`/api/v1/exports/revenue${query ? `?${query}` : ''}`);
+  },
+
+  activity: async (params: ExportFormat & {
+    type?: string;
+    entity_type?: 'product' | 'plan' | 'purchase' | 'refund' | 'user' | 'tenant';
+    entity_id?: string;
+    user_id?: string;
+    from?: string;
+    to?: string;
+  } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await exportRequest(`/api/v1/exports/activity${query ? `?${query}` : ''}`);
+  },
+
+  getJob: async (id: number) => {
+    return await apiRequest(`/api/v1/exports/${id}`);
+  },
+
+  download: async (id: number) => {
+    return await exportRequest(`/api/v1/exports/${id}/download`);
+  },
+};
+
+// Event stream API functions. subscribe opens the tenant's server-sent event
+// stream and calls onEvent with each event's type and activity; the browser
+// reconnects by itself and resumes after the last event it got. It returns a
+// function closing the stream.
+export const eventsApi = {
+  // Stream tokens are short-lived, so a stream the server closed is reopened
+  // with a new one, resuming after the last event received
+  subscribe: (onEvent: (type: string, activity: any) => void, types: string[] = []) => {
+    const eventTypes = types.length > 0 ? types : [
+      'product_created', 'product_updated', 'product_deleted', 'product_archived', 'product_unarchived',
+      'plan_created', 'plan_updated', 'plan_deleted', 'plan_archived', 'plan_unarchived',
+      'purchase_made', 'purchase_cancelled', 'purchase_expired', 'cancellation_scheduled', 'trial_started', 'trial_converted', 'plan_changed', 'refund_issued',
+      'user_registered', 'tenant_created', 'tenant_updated', 'tenant_deleted',
+    ];
+    let source: EventSource | null = null;
+    let retry: ReturnType<typeof setTimeout> | null = null;
+    let lastEventId = '';
+    let closed = false;
+
+    const open = async () => {
+      try {
+        const response = await apiRequest('/api/v1/events/token', { method: 'POST' });
+        if (closed) {
+          return;
+        }
+
+        const params = new URLSearchParams({ token: response.data.token });
+        const tenantId = getTenantId();
+        if (tenantId) {
+          params.set('tenant', tenantId);
+        }
+        if (types.length > 0) {
+          params.set('type', types.join(','));
+        }
+        if (lastEventId) {
+          params.set('last_event_id', lastEventId);
+        }
+
+        source = new EventSource(`${getApiBaseUrl()}/api/v1/events/stream?${params.toString()}`);
+        eventTypes.forEach((type) => {
+          source?.addEventListener(type, (event) => {
+            const message = event as MessageEvent;
+            lastEventId = message.lastEventId || lastEventId;
+            onEvent(type, JSON.parse(message.data));
+          });
+        });
+        source.onerror = () => {
+          if (source?.readyState === EventSource.CLOSED) {
+            source = null;
+            reopen();
+          }
+        };
+      } catch {
+        reopen();
+      }
+    };
+
+    const reopen = () => {
+      if (!closed) {
+        retry = setTimeout(open, 3000);
+      }
+    };
+
+    open();
+    return () => {
+      closed = true;
+      if (retry) {
+        clearTimeout(retry);
+      }
+      source?.close();
+    };
   },
 };
 
@@ -143,6 +369,7 @@ export const tenantApi = {
     tenant_name: string;
     tenant_domain: string;
     tenant_code: string;
+    timezone?: string;
   }) => {
     return await apiRequest('/api/super/tenants', {
       method: 'POST',
@@ -154,6 +381,7 @@ export const tenantApi = {
     tenant_name: string;
     tenant_domain: string;
     tenant_code: string;
+    timezone?: string;
   }) => {
     return await apiRequest(`/api/super/tenants/${id}`, {
       method: 'PUT',
@@ -166,4 +394,36 @@ export const tenantApi = {
       method: 'DELETE',
     });
   },
+};
+
+// Platform analytics API functions (Super Admin)
+export const platformAnalyticsApi = {
+  getOverview: async (params: { from?: string; to?: string } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/super/analytics/overview${query ? `?${query}` : ''}`);
+  },
+
+  getTopTenants: async (params: { from?: string; to?: string; currency?: string; limit?: string } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/super/analytics/top-tenants${query ? `?${query}` : ''}`);
+  },
+
+  getSignupTrends: async (params: { from?: string; to?: string; interval?: 'day' | 'week' | 'month' } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/super/analytics/signups${query ? `?${query}` : ''}`);
+  },
+
+  getTenantHealth: async (params: {
+    from?: string;
+    to?: string;
+    currency?: string;
+    status?: 'active' | 'inactive';
+    sort?: 'name' | 'last_activity' | 'revenue' | 'mrr' | 'active_users' | 'failed_payments' | 'failed_payment_rate';
+    order?: 'asc' | 'desc';
+    page?: string;
+    per_page?: string;
+  } = {}) => {
+    const query = new URLSearchParams(params as Record<string, string>).toString();
+    return await apiRequest(`/api/super/analytics/tenants${query ? `?${query}` : ''}`);
+  },
 };
\ No newline at end of file